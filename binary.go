@@ -0,0 +1,84 @@
+package places
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// binaryVersion is the version of the binary format of a Template
+const binaryVersion = 2
+
+var ErrInvalidBinary = errors.New("invalid binary template")
+
+// MarshalBinary returns the parsed template in a binary form, that can be loaded
+// via UnmarshalBinary without parsing the template again
+func (t *Template) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 1+binary.MaxVarintLen64*(2+len(t.places))+len(t.template))
+	b = append(b, binaryVersion)
+	b = appendUvarint(b, uint64(len(t.template)))
+	b = append(b, t.template...)
+	b = appendUvarint(b, uint64(len(t.places)))
+	for _, p := range t.places {
+		b = appendUvarint(b, uint64(p))
+	}
+	return b, nil
+}
+
+// UnmarshalBinary loads a template from the binary form returned by MarshalBinary
+func (t *Template) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != binaryVersion {
+		return ErrInvalidBinary
+	}
+	data = data[1:]
+
+	l, data, ok := uvarint(data)
+	if !ok || l > uint64(len(data)) {
+		return ErrInvalidBinary
+	}
+	tpl := append([]byte(nil), data[:l]...)
+	data = data[l:]
+
+	n, data, ok := uvarint(data)
+	if !ok || n%2 != 0 || n > uint64(len(data)) {
+		return ErrInvalidBinary
+	}
+
+	places := make([]int, n)
+	last := 0
+	for i := range places {
+		var p uint64
+		if p, data, ok = uvarint(data); !ok || p > uint64(len(tpl)) {
+			return ErrInvalidBinary
+		}
+		places[i] = int(p)
+	}
+
+	// the places must be valid pairs of delimiters in order, see Find
+	for i := 0; i < len(places); i += 2 {
+		if places[i] < last || places[i+1] < places[i]+2 || places[i+1]+2 > len(tpl) {
+			return ErrInvalidBinary
+		}
+		last = places[i+1] + 2
+	}
+
+	if len(data) != 0 {
+		return ErrInvalidBinary
+	}
+
+	t.template, t.places, t.literals = tpl, places, unescaped(tpl, places)
+	return nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func uvarint(b []byte) (uint64, []byte, bool) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, b, false
+	}
+	return v, b[n:], true
+}
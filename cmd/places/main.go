@@ -0,0 +1,210 @@
+/*
+Command places checks the templates of a directory.
+
+Usage:
+
+	places unused [flags] dir
+
+reports the templates that are not reachable from the entry templates and the placeholders
+that no mapper can satisfy (see placesmap.HTMLTemplate.Unused). The exit status is 1, if
+anything is found. The flags are
+
+	-entry    comma separated names of the entry templates (required)
+	-mappers  comma separated names of the registered mappers
+	-ext      the extension of the templates (default .html)
+	-ignore   a regular expression for directories to ignore
+	-include  comma separated glob patterns of the files to load (see placesmap.LoadRules)
+	-exclude  comma separated glob patterns of the files and directories to skip
+	-json     print the report as JSON
+
+	places js [flags] dir
+
+writes a JavaScript module that renders templates client-side (see package jsrender).
+The flags are
+
+	-templates  comma separated names of the templates (required), included ones are added
+	-o          the file to write, default is stdout
+	-ext, -ignore, -include and -exclude as for unused
+
+	places links [flags] dir
+
+checks the pages of a static export for internal links and assets that don't resolve within
+the export (see placesmap.CheckLinks). The exit status is 1, if a link is broken. The flags are
+
+	-json     print the report as JSON
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/metakeule/places/placesmap"
+	"github.com/metakeule/places/placesmap/jsrender"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "unused":
+		os.Exit(unused(os.Args[2:]))
+	case "js":
+		os.Exit(js(os.Args[2:]))
+	case "links":
+		os.Exit(links(os.Args[2:]))
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: places unused [flags] dir")
+	fmt.Fprintln(os.Stderr, "       places js [flags] dir")
+	fmt.Fprintln(os.Stderr, "       places links [flags] dir")
+	os.Exit(2)
+}
+
+// list splits a comma separated list
+func list(s string) (res []string) {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return
+}
+
+// load loads the templates of the given directory
+func load(dir, ext, ignore string, rules placesmap.LoadRules) (*placesmap.HTMLTemplate, error) {
+	var ignoreDirs *regexp.Regexp
+	if ignore != "" {
+		var err error
+		if ignoreDirs, err = regexp.Compile(ignore); err != nil {
+			return nil, err
+		}
+	}
+	l := placesmap.NewTemplateLoader(dir, ext, ignoreDirs)
+	if err := l.SetRules(rules); err != nil {
+		return nil, err
+	}
+	rs, err := l.Load()
+	if err != nil {
+		return nil, err
+	}
+	return placesmap.NewHTMLTemplate(rs), nil
+}
+
+func unused(args []string) int {
+	fs := flag.NewFlagSet("unused", flag.ExitOnError)
+	var (
+		entry   = fs.String("entry", "", "comma separated names of the entry templates")
+		mappers = fs.String("mappers", "", "comma separated names of the registered mappers")
+		ext     = fs.String("ext", ".html", "the extension of the templates")
+		ignore  = fs.String("ignore", "", "a regular expression for directories to ignore")
+		include = fs.String("include", "", "comma separated glob patterns of the files to load")
+		exclude = fs.String("exclude", "", "comma separated glob patterns of the files and directories to skip")
+		asJSON  = fs.Bool("json", false, "print the report as JSON")
+	)
+	fs.Parse(args)
+	if fs.NArg() != 1 || *entry == "" {
+		usage()
+	}
+
+	t, err := load(fs.Arg(0), *ext, *ignore, placesmap.LoadRules{Include: list(*include), Exclude: list(*exclude)})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	report := t.Unused(list(*entry), list(*mappers))
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		for _, name := range report.Templates {
+			fmt.Printf("unused template %s\n", name)
+		}
+		for _, p := range report.Placeholders {
+			fmt.Printf("unresolved placeholder %s in %s\n", p.Key, p.Template)
+		}
+	}
+
+	if len(report.Templates) > 0 || len(report.Placeholders) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func js(args []string) int {
+	fs := flag.NewFlagSet("js", flag.ExitOnError)
+	var (
+		templates = fs.String("templates", "", "comma separated names of the templates")
+		out       = fs.String("o", "", "the file to write, default is stdout")
+		ext       = fs.String("ext", ".html", "the extension of the templates")
+		ignore    = fs.String("ignore", "", "a regular expression for directories to ignore")
+		include   = fs.String("include", "", "comma separated glob patterns of the files to load")
+		exclude   = fs.String("exclude", "", "comma separated glob patterns of the files and directories to skip")
+	)
+	fs.Parse(args)
+	if fs.NArg() != 1 || *templates == "" {
+		usage()
+	}
+
+	t, err := load(fs.Arg(0), *ext, *ignore, placesmap.LoadRules{Include: list(*include), Exclude: list(*exclude)})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := jsrender.Generate(w, t, list(*templates)...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func links(args []string) int {
+	fs := flag.NewFlagSet("links", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	report, err := placesmap.CheckLinks(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		for _, b := range report.Broken {
+			fmt.Println(b)
+		}
+	}
+
+	if len(report.Broken) > 0 {
+		return 1
+	}
+	return 0
+}
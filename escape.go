@@ -0,0 +1,121 @@
+package places
+
+import (
+	"bytes"
+	"io"
+)
+
+// escapedStart is an escaped start delimiter
+var escapedStart = []byte(`\<@`)
+
+// backslashes returns the number of backslashes at the end of b
+func backslashes(b []byte) (n int) {
+	for n < len(b) && b[len(b)-1-n] == '\\' {
+		n++
+	}
+	return
+}
+
+// indexStart returns the offset of the first start delimiter within the template at or after from,
+// that is not escaped, -1 if there is none
+func indexStart(template []byte, from int) int {
+	for {
+		idx := bytes.Index(template[from:], startDel)
+		if idx == -1 {
+			return -1
+		}
+		idx += from
+		if backslashes(template[:idx])%2 == 0 {
+			return idx
+		}
+		from = idx + 2
+	}
+}
+
+// writeLiteral writes the given literal part of a template with the escape sequences resolved,
+// i.e. the backslashes before start delimiters are halved. placeholder is whether the literal is
+// followed by a placeholder.
+func writeLiteral(wr io.Writer, literal []byte, placeholder bool) {
+	for {
+		idx := bytes.Index(literal, escapedStart)
+		if idx == -1 {
+			break
+		}
+		idx++ // the offset of the start delimiter
+		n := backslashes(literal[:idx])
+		wr.Write(literal[:idx-n+n/2])
+		wr.Write(startDel)
+		literal = literal[idx+2:]
+	}
+	if placeholder {
+		n := backslashes(literal)
+		literal = literal[:len(literal)-n+n/2]
+	}
+	wr.Write(literal)
+}
+
+// Escape returns the template source that renders as the given text, i.e. every start delimiter of
+// the text is escaped, so that it is no placeholder. Backslashes at the end of the text are
+// kept as they are, so the result should not be followed by a placeholder if the text ends with
+// a backslash.
+func Escape(text []byte) []byte {
+	if !bytes.Contains(text, startDel) {
+		return text
+	}
+	var bf bytes.Buffer
+	for {
+		idx := bytes.Index(text, startDel)
+		if idx == -1 {
+			break
+		}
+		bf.Write(text[:idx])
+		bf.Write(bytes.Repeat([]byte(`\`), backslashes(text[:idx])+1))
+		bf.Write(startDel)
+		text = text[idx+2:]
+	}
+	bf.Write(text)
+	return bf.Bytes()
+}
+
+// Literals returns the literal parts of the template before, between and after the placeholders
+// as they are rendered, i.e. with the escape sequences resolved. There is always one more literal
+// than there are placeholders. The literals must not be modified.
+func (t *Template) Literals() [][]byte {
+	literals := make([][]byte, len(t.places)/2+1)
+	for i := range literals {
+		literals[i] = segment(t.template, t.places, t.literals, i)
+	}
+	return literals
+}
+
+// unescaped returns the literal parts of the template before, between and after the given places
+// with the escape sequences resolved, nil if the template has no escape sequences, so that its
+// literal parts are written as they are
+func unescaped(template []byte, places []int) [][]byte {
+	if !bytes.Contains(template, escapedStart) {
+		return nil
+	}
+	n := len(places) / 2
+	literals := make([][]byte, n+1)
+	for i := range literals {
+		var bf bytes.Buffer
+		writeLiteral(&bf, segment(template, places, nil, i), i < n)
+		literals[i] = bf.Bytes()
+	}
+	return literals
+}
+
+// segment returns the i-th literal part of the template, taken from literals unless they are nil
+func segment(template []byte, places []int, literals [][]byte, i int) []byte {
+	if literals != nil {
+		return literals[i]
+	}
+	start, end := 0, len(template)
+	if i > 0 {
+		start = places[i*2-1] + 2
+	}
+	if i < len(places)/2 {
+		end = places[i*2]
+	}
+	return template[start:end]
+}
@@ -0,0 +1,118 @@
+package places
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrNoMatch              = errors.New("rendered document does not match the template")
+	ErrAdjacentPlaceholders = errors.New("template has adjacent placeholders")
+)
+
+// Extract runs the template in reverse: it matches the literal parts of the template against the
+// rendered document and returns the values of the placeholders by their names. The values are
+// returned as they have been rendered, i.e. escaped values stay escaped. A placeholder that appears
+// more than once must have the same value everywhere. Values are matched as short as possible,
+// longer ones are tried, if the rest of the document does not match otherwise.
+// If the document does not match, ErrNoMatch is returned. Templates with placeholders that are
+// not separated by literal text can't be reversed and return ErrAdjacentPlaceholders.
+func (t *Template) Extract(rendered []byte) (map[string]string, error) {
+	n := len(t.places) / 2
+	names := make([]string, n)
+	literals := t.Literals()
+	last := 0
+	for i := 0; i < n; i++ {
+		first := t.places[i*2]
+		if i > 0 && first == last {
+			return nil, ErrAdjacentPlaceholders
+		}
+		names[i] = string(t.template[first+2 : t.places[i*2+1]])
+		last = t.places[i*2+1] + 2
+	}
+
+	if !bytes.HasPrefix(rendered, literals[0]) || !bytes.HasSuffix(rendered, literals[n]) ||
+		n > 0 && len(rendered) < len(literals[0])+len(literals[n]) {
+		return nil, ErrNoMatch
+	}
+	e := &extractor{rendered: rendered, names: names, literals: literals[1:], failed: map[string]bool{}}
+	values := make(map[string]string, n)
+	if !e.match(len(literals[0]), 0, values) {
+		return nil, ErrNoMatch
+	}
+	return values, nil
+}
+
+// extractor matches the placeholders of a template, each followed by its literal, against
+// a rendered document. The states that failed to match are remembered, so that every state
+// is tried at most once.
+type extractor struct {
+	rendered []byte
+	names    []string
+	literals [][]byte
+	failed   map[string]bool // by state, see state
+}
+
+// state returns the key of the state, where the i-th placeholder starts at pos. It includes the
+// values of the names that are bound before and appear again, since they restrict the match.
+func (e *extractor) state(pos, i int, values map[string]string) string {
+	key := strconv.Itoa(pos) + ":" + strconv.Itoa(i)
+	for _, name := range e.names[i:] {
+		if val, had := values[name]; had {
+			key += "\x00" + name + "\x00" + val
+		}
+	}
+	return key
+}
+
+// match matches the placeholders starting with the i-th one against the rendered document
+// starting at pos and adds their values
+func (e *extractor) match(pos, i int, values map[string]string) bool {
+	if i == len(e.names) {
+		return pos == len(e.rendered)
+	}
+	key := e.state(pos, i, values)
+	if e.failed[key] {
+		return false
+	}
+
+	name, lit, last := e.names[i], e.literals[i], i == len(e.names)-1
+	prev, had := values[name]
+	for from := pos; from <= len(e.rendered); {
+		var end int
+		if last {
+			// the last literal ends the document, see Extract
+			end = len(e.rendered) - len(lit)
+			if end < pos {
+				break
+			}
+		} else {
+			idx := bytes.Index(e.rendered[from:], lit)
+			if idx == -1 {
+				break
+			}
+			end = from + idx
+		}
+
+		val := string(e.rendered[pos:end])
+		if !had || val == prev {
+			values[name] = val
+			if e.match(end+len(lit), i+1, values) {
+				return true
+			}
+		}
+		if last {
+			break
+		}
+		from = end + 1
+	}
+
+	if had {
+		values[name] = prev
+	} else {
+		delete(values, name)
+	}
+	e.failed[key] = true
+	return false
+}
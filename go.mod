@@ -1 +1,3 @@
 module github.com/metakeule/places
+
+go 1.19
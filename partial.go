@@ -0,0 +1,82 @@
+package places
+
+import (
+	"bytes"
+	"errors"
+)
+
+var ErrDelimiterInReplacement = errors.New("replacement creates or breaks a placeholder")
+
+// Resolver may be implemented by mappers passed to Template.Partial to decide explicitly,
+// whether a placeholder is resolved (e.g. to resolve a placeholder to the empty string).
+type Resolver interface {
+	Mapper
+	Resolve(placeholder string) (replacement string, ok bool)
+}
+
+// Partial returns a new template, where the placeholders that are resolved by m are replaced and
+// all other placeholders are preserved, so that a page may be rendered in two passes: the static parts
+// once and the per request parts on every request.
+// A placeholder is resolved, if m returns a non empty string for it or, if m is a Resolver, if Resolve
+// returns true. The replacements and the literal parts are escaped (see Escape), so that the second
+// pass renders them as they are, even if they contain placeholder delimiters or backslashes.
+// If the preserved placeholders change nevertheless, ErrDelimiterInReplacement is returned.
+func (t *Template) Partial(m Mapper) (*Template, error) {
+	resolver, isResolver := m.(Resolver)
+
+	var (
+		bf       bytes.Buffer
+		text     []byte // the rendered text since the last preserved placeholder
+		expected []int  // the places of the preserved placeholders within the new template
+		literals = t.Literals()
+	)
+	bf.Grow(len(t.template))
+
+	// flush writes the text escaped, before a placeholder the backslashes at its end are escaped as well
+	flush := func(placeholder bool) {
+		bf.Write(Escape(text))
+		if placeholder {
+			bf.Write(bytes.Repeat([]byte(`\`), backslashes(text)))
+		}
+		text = text[:0]
+	}
+
+	for i := 0; i < len(t.places); i += 2 {
+		text = append(text, literals[i/2]...)
+		name := string(t.template[t.places[i]+2 : t.places[i+1]])
+
+		var (
+			replacement string
+			ok          bool
+		)
+		if isResolver {
+			replacement, ok = resolver.Resolve(name)
+		} else {
+			replacement = m.Map(name)
+			ok = replacement != ""
+		}
+
+		if ok {
+			text = append(text, replacement...)
+			continue
+		}
+
+		flush(true)
+		start := bf.Len()
+		bf.Write(t.template[t.places[i] : t.places[i+1]+2])
+		expected = append(expected, start, bf.Len()-2)
+	}
+	text = append(text, literals[len(literals)-1]...)
+	flush(false)
+
+	res := NewTemplate(bf.Bytes())
+	if len(res.places) != len(expected) {
+		return nil, ErrDelimiterInReplacement
+	}
+	for i := range expected {
+		if res.places[i] != expected[i] {
+			return nil, ErrDelimiterInReplacement
+		}
+	}
+	return res, nil
+}
@@ -13,31 +13,84 @@ var (
 type Template struct {
 	places   []int
 	template []byte
+	literals [][]byte // the literal parts with the escape sequences resolved, nil if there are none
 }
 
 func NewTemplate(t []byte) *Template {
-	return &Template{template: t, places: Find(t)}
+	return newTemplate(t, Find(t))
+}
+
+// newTemplate returns the template for the given source and places. The escape sequences are
+// resolved once, so that the replacements write the literal parts as they are.
+func newTemplate(t []byte, places []int) *Template {
+	return &Template{template: t, places: places, literals: unescaped(t, places)}
 }
 
 func (t *Template) ReplaceBytes(wr io.Writer, replacements map[string][]byte) {
-	ReplaceBytes(t.template, wr, t.places, replacements)
+	replaceBytes(t.template, wr, t.places, t.literals, replacements)
 }
 
 func (t *Template) Replace(wr io.Writer, replacements map[string]io.ReadSeeker) {
-	Replace(t.template, wr, t.places, replacements)
+	replace(t.template, wr, t.places, t.literals, replacements)
 }
 
 func (t *Template) ReplaceString(bf Buffer, replacements map[string]string) {
-	ReplaceString(t.template, bf, t.places, replacements)
+	replaceString(t.template, bf, t.places, t.literals, replacements)
 }
 
 func (t *Template) ReplaceMapper(bf Buffer, mapper Mapper) {
-	ReplaceMapper(t.template, bf, t.places, mapper)
+	replaceMapper(t.template, bf, t.places, t.literals, mapper)
+}
+
+// Bytes returns the source of the template, it must not be modified
+func (t *Template) Bytes() []byte {
+	return t.template
+}
+
+// Placeholders returns the names of the placeholders of the template in the order of their appearance
+func (t *Template) Placeholders() []string {
+	names := make([]string, 0, len(t.places)/2)
+	for i := 0; i < len(t.places); i += 2 {
+		names = append(names, string(t.template[t.places[i]+2:t.places[i+1]]))
+	}
+	return names
+}
+
+// KeysMapper is a Mapper that knows the keys it needs to map a placeholder, e.g. the keys
+// used by the templates that a directive includes.
+type KeysMapper interface {
+	Mapper
+	Keys(placeholder string) []string
+}
+
+// RequiredKeys returns the keys that are needed to map the placeholders of the template with the
+// given mapper, in the order of their first appearance. If the mapper is a KeysMapper, it reports the
+// keys for every placeholder, otherwise the keys are the placeholders themselves. m may be nil.
+func (t *Template) RequiredKeys(m Mapper) []string {
+	km, _ := m.(KeysMapper)
+	seen := map[string]bool{}
+	var keys []string
+	for _, ph := range t.Placeholders() {
+		ks := []string{ph}
+		if km != nil {
+			ks = km.Keys(ph)
+		}
+		for _, k := range ks {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
 }
 
 // Find looks for placeholders written in the style "<@placeholdername@>" inside the given template.
 // It returns a slice containing the positions of the placeholders that is meant to be passed to
 // Replace or ReplaceString.
+// A start delimiter preceded by a backslash is no placeholder but written as "<@" by the Replace
+// functions, i.e. \<@ renders as <@. Backslashes before a start delimiter escape each other:
+// \\<@name@> renders as a backslash followed by the replacement of name (see Escape).
 func Find(template []byte) (places []int) {
 
 	places = make([]int, 0, 22)
@@ -51,11 +104,10 @@ func Find(template []byte) (places []int) {
 
 	for i := 0; i < length; i++ {
 
-		found = bytes.Index(template[i:], startDel)
-		if -1 == found {
+		start = indexStart(template, i)
+		if -1 == start {
 			break
 		}
-		start = found + i
 
 		found = bytes.Index(template[start+2:], endDel)
 		if -1 == found {
@@ -76,8 +128,13 @@ func Find(template []byte) (places []int) {
 // The given template must be the unchanged byte array that was passed to Find in order to get the
 // places. For strings as replacements see the optimized ReplaceString function for bytes use ReplaceBytes.
 func Replace(template []byte, wr io.Writer, places []int, replacements map[string]io.ReadSeeker) {
+	replace(template, wr, places, unescaped(template, places), replacements)
+}
+
+// replace is like Replace, literals are the literal parts of the template with the escape
+// sequences resolved, nil if there are none
+func replace(template []byte, wr io.Writer, places []int, literals [][]byte, replacements map[string]io.ReadSeeker) {
 	var (
-		last        int
 		first       int
 		has         bool
 		replacement io.ReadSeeker
@@ -99,14 +156,14 @@ func Replace(template []byte, wr io.Writer, places []int, replacements map[strin
 	//   - everything after the place with
 	//       template[places[i+1]+2:]
 	//
-	// instead of going just from the beginning to the end, we iterate over a cursor (last)
-	// from placeholder to placeholder until we are through the template
+	// instead of going just from the beginning to the end, we iterate from placeholder to
+	// placeholder and write the literal part before each one until we are through the template
 	for i := 0; i < length; i += 2 {
 		// track the first position of the placeholder
 		first = places[i]
 
-		// take the bytes from the last position within the template up to the placeholder
-		wr.Write(template[last:first])
+		// take the literal part before the placeholder
+		wr.Write(segment(template, places, literals, i/2))
 
 		// lookup the placeholder name within the replacements and
 		// write the replacement if we found one
@@ -115,12 +172,9 @@ func Replace(template []byte, wr io.Writer, places []int, replacements map[strin
 			replacement.Seek(0, 0)
 			io.Copy(wr, replacement)
 		}
-
-		// track the last position for the next iteration
-		last = places[i+1] + 2
 	}
 
-	wr.Write(template[last:]) // write any remaining parts of the template that don't have any placeholders
+	wr.Write(segment(template, places, literals, length/2)) // write any remaining parts of the template that don't have any placeholders
 }
 
 // The Buffer interface is fullfilled by *bytes.Buffer. However since for performance reasons
@@ -135,8 +189,13 @@ type Buffer interface {
 // The given template must be the unchanged byte array that was passed to Find in order to get the
 // places.
 func ReplaceString(template []byte, bf Buffer, places []int, replacements map[string]string) {
+	replaceString(template, bf, places, unescaped(template, places), replacements)
+}
+
+// replaceString is like ReplaceString, literals are the literal parts of the template with the escape
+// sequences resolved, nil if there are none
+func replaceString(template []byte, bf Buffer, places []int, literals [][]byte, replacements map[string]string) {
 	var (
-		last        int
 		first       int
 		has         bool
 		replacement string
@@ -158,16 +217,14 @@ func ReplaceString(template []byte, bf Buffer, places []int, replacements map[st
 	//   - everything after the place with
 	//       template[places[i+1]+2:]
 	//
-	// instead of going just from the beginning to the end, we iterate over a cursor (last)
-	// from placeholder to placeholder until we are through the template
+	// instead of going just from the beginning to the end, we iterate from placeholder to
+	// placeholder and write the literal part before each one until we are through the template
 	for i := 0; i < length; i += 2 {
 		// track the first position of the placeholder
 		first = places[i]
 
-		// take the bytes from the last position within the template up to the placeholder
-		bf.Write(template[last:first])
-
-		// track the last position for the next iteration
+		// take the literal part before the placeholder
+		bf.Write(segment(template, places, literals, i/2))
 
 		// lookup the placeholder name within the replacements and
 		// write the replacement if we found one
@@ -175,11 +232,9 @@ func ReplaceString(template []byte, bf Buffer, places []int, replacements map[st
 		if has {
 			bf.WriteString(replacement)
 		}
-
-		last = places[i+1] + 2
 	}
 
-	bf.Write(template[last:]) // write any remaining parts of the template that don't have any placeholders
+	bf.Write(segment(template, places, literals, length/2)) // write any remaining parts of the template that don't have any placeholders
 }
 
 // ReplaceBytes replaces the placeholders at the given places inside the template with
@@ -187,8 +242,13 @@ func ReplaceString(template []byte, bf Buffer, places []int, replacements map[st
 // The given template must be the unchanged byte array that was passed to Find in order to get the
 // places.
 func ReplaceBytes(template []byte, wr io.Writer, places []int, replacements map[string][]byte) {
+	replaceBytes(template, wr, places, unescaped(template, places), replacements)
+}
+
+// replaceBytes is like ReplaceBytes, literals are the literal parts of the template with the escape
+// sequences resolved, nil if there are none
+func replaceBytes(template []byte, wr io.Writer, places []int, literals [][]byte, replacements map[string][]byte) {
 	var (
-		last        int
 		first       int
 		has         bool
 		replacement []byte
@@ -210,16 +270,14 @@ func ReplaceBytes(template []byte, wr io.Writer, places []int, replacements map[
 	//   - everything after the place with
 	//       template[places[i+1]+2:]
 	//
-	// instead of going just from the beginning to the end, we iterate over a cursor (last)
-	// from placeholder to placeholder until we are through the template
+	// instead of going just from the beginning to the end, we iterate from placeholder to
+	// placeholder and write the literal part before each one until we are through the template
 	for i := 0; i < length; i += 2 {
 		// track the first position of the placeholder
 		first = places[i]
 
-		// take the bytes from the last position within the template up to the placeholder
-		wr.Write(template[last:first])
-
-		// track the last position for the next iteration
+		// take the literal part before the placeholder
+		wr.Write(segment(template, places, literals, i/2))
 
 		// lookup the placeholder name within the replacements and
 		// write the replacement if we found one
@@ -227,11 +285,9 @@ func ReplaceBytes(template []byte, wr io.Writer, places []int, replacements map[
 		if has {
 			wr.Write(replacement)
 		}
-
-		last = places[i+1] + 2
 	}
 
-	wr.Write(template[last:]) // write any remaining parts of the template that don't have any placeholders
+	wr.Write(segment(template, places, literals, length/2)) // write any remaining parts of the template that don't have any placeholders
 }
 
 // Mapper maps strings.
@@ -246,8 +302,13 @@ type Mapper interface {
 // The given template must be the unchanged byte array that was passed to Find in order to get the
 // places.
 func ReplaceMapper(template []byte, bf Buffer, places []int, mapper Mapper) {
+	replaceMapper(template, bf, places, unescaped(template, places), mapper)
+}
+
+// replaceMapper is like ReplaceMapper, literals are the literal parts of the template with the escape
+// sequences resolved, nil if there are none
+func replaceMapper(template []byte, bf Buffer, places []int, literals [][]byte, mapper Mapper) {
 	var (
-		last        int
 		first       int
 		replacement string
 		length      = len(places)
@@ -268,16 +329,14 @@ func ReplaceMapper(template []byte, bf Buffer, places []int, mapper Mapper) {
 	//   - everything after the place with
 	//       template[places[i+1]+2:]
 	//
-	// instead of going just from the beginning to the end, we iterate over a cursor (last)
-	// from placeholder to placeholder until we are through the template
+	// instead of going just from the beginning to the end, we iterate from placeholder to
+	// placeholder and write the literal part before each one until we are through the template
 	for i := 0; i < length; i += 2 {
 		// track the first position of the placeholder
 		first = places[i]
 
-		// take the bytes from the last position within the template up to the placeholder
-		bf.Write(template[last:first])
-
-		// track the last position for the next iteration
+		// take the literal part before the placeholder
+		bf.Write(segment(template, places, literals, i/2))
 
 		// lookup the placeholder name within the replacements and
 		// write the replacement if we found one
@@ -285,11 +344,9 @@ func ReplaceMapper(template []byte, bf Buffer, places []int, mapper Mapper) {
 		if len(replacement) > 0 {
 			bf.WriteString(replacement)
 		}
-
-		last = places[i+1] + 2
 	}
 
-	bf.Write(template[last:]) // write any remaining parts of the template that don't have any placeholders
+	bf.Write(segment(template, places, literals, length/2)) // write any remaining parts of the template that don't have any placeholders
 }
 
 // FindAndReplace finds placeholders and replaces them in one go.
@@ -6,6 +6,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 )
 
 var _template2 = []byte{}
@@ -47,6 +48,60 @@ func TestAjacentPlaceholders(t *testing.T) {
 	}
 }
 
+func TestBytes(t *testing.T) {
+	if got := string(NewTemplate([]byte("a <@b@>")).Bytes()); got != "a <@b@>" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	got := fmt.Sprintf("%q", NewTemplate([]byte("a <@b@><@-c d@> <@e")).Placeholders())
+	if exp := `["b" "-c d"]`; got != exp {
+		t.Errorf("unexpected result: %s, expected: %s", got, exp)
+	}
+}
+
+type keysMapper map[string][]string
+
+func (k keysMapper) Map(string) string       { return "" }
+func (k keysMapper) Keys(ph string) []string { return k[ph] }
+
+func TestRequiredKeys(t *testing.T) {
+	tpl := NewTemplate([]byte("<@b@><@-c d@><@b@><@e@>"))
+	got := fmt.Sprintf("%q", tpl.RequiredKeys(nil))
+	if exp := `["b" "-c d" "e"]`; got != exp {
+		t.Errorf("unexpected result: %s, expected: %s", got, exp)
+	}
+
+	got = fmt.Sprintf("%q", tpl.RequiredKeys(keysMapper{"b": {"b"}, "-c d": {"d", "b", "x"}}))
+	if exp := `["b" "d" "x"]`; got != exp {
+		t.Errorf("unexpected result: %s, expected: %s", got, exp)
+	}
+}
+
+func TestNewTemplateStrict(t *testing.T) {
+	tests := map[string]error{
+		"a <@b@>\n<@-c d@>": nil,
+		"a@> b":             nil,
+		"a\nbä <@c":         ParseError{2, 4, "unterminated placeholder"},
+		"<@a@> <@b<@c@>":    ParseError{1, 10, "start delimiter within placeholder"},
+		"x\n\n <@a\nb@>":    ParseError{3, 5, "control character 0xa within placeholder"},
+		"<@a@>\xff":         ParseError{1, 6, "invalid UTF-8"},
+	}
+
+	for tpl, exp := range tests {
+		_, err := NewTemplateStrict([]byte(tpl))
+		if err != exp {
+			t.Errorf("%#v: unexpected error: %v, expected: %v", tpl, err, exp)
+		}
+	}
+
+	tpl, _ := NewTemplateStrict([]byte("<@a@><@b@>"))
+	if got := fmt.Sprint(tpl.Placeholders()); got != "[a b]" {
+		t.Errorf("unexpected placeholders: %s", got)
+	}
+}
+
 func TestReplaceString(t *testing.T) {
 	Prepare()
 	var buffer bytes.Buffer
@@ -54,3 +109,212 @@ func TestReplaceString(t *testing.T) {
 		t.Errorf("unexpected result: %#v, expected: %#v", buffer.String(), expected)
 	}
 }
+
+func FuzzFind(f *testing.F) {
+	f.Add([]byte("a string with <@replacement0@><@replacement1@> after"))
+	f.Add([]byte("<@<@@>@>"))
+	f.Add([]byte("<@@@>"))
+	f.Add([]byte("<@ unterminated"))
+
+	f.Fuzz(func(t *testing.T, tpl []byte) {
+		places := Find(tpl)
+		if len(places)%2 != 0 {
+			t.Fatalf("odd number of places: %v", places)
+		}
+		last := 0
+		for i := 0; i < len(places); i += 2 {
+			if places[i] < last || places[i+1] < places[i]+2 || places[i+1]+2 > len(tpl) {
+				t.Fatalf("invalid places: %v", places)
+			}
+			last = places[i+1] + 2
+		}
+
+		// placeholders without replacement are removed, the rest is kept
+		var bf bytes.Buffer
+		NewTemplate(tpl).ReplaceString(&bf, map[string]string{})
+		if bf.Len() > len(tpl) {
+			t.Fatalf("result longer than template: %#v", bf.String())
+		}
+	})
+}
+
+func TestMarshalBinary(t *testing.T) {
+	Prepare()
+	b, err := NewTemplate(_template2).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tpl Template
+	if err := tpl.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if tpl.ReplaceString(&buffer, _map); buffer.String() != expected {
+		t.Errorf("unexpected result: %#v, expected: %#v", buffer.String(), expected)
+	}
+
+	for _, invalid := range [][]byte{nil, b[:len(b)-1], append(b, 0), {binaryVersion, 1, 'a', 2, 0, 5}} {
+		if err := tpl.UnmarshalBinary(invalid); err != ErrInvalidBinary {
+			t.Errorf("%v: unexpected error: %v", invalid, err)
+		}
+	}
+}
+
+type mapperFunc func(string) string
+
+func (m mapperFunc) Map(s string) string { return m(s) }
+
+func TestPartial(t *testing.T) {
+	tpl := NewTemplate([]byte("<@title@>: <@user@>, <@footer@>"))
+	static := mapperFunc(func(name string) string {
+		switch name {
+		case "title":
+			return "Welcome"
+		case "footer":
+			return "(c)"
+		}
+		return ""
+	})
+
+	partial, err := tpl.Partial(static)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	partial.ReplaceString(&buffer, map[string]string{"user": "Donald"})
+	if exp := "Welcome: Donald, (c)"; buffer.String() != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", buffer.String(), exp)
+	}
+
+	// replacements with delimiters and backslashes are rendered as they are by the second pass
+	for _, evil := range []string{"<@user@>", "<", "<@x", `C:\`, `\<@`} {
+		evil := evil
+		tpl := NewTemplate([]byte(`<@title@>@<@user@> \\<@title@><@user@>`))
+		m := mapperFunc(func(name string) string {
+			if name == "title" {
+				return evil
+			}
+			return ""
+		})
+		partial, err := tpl.Partial(m)
+		if err != nil {
+			t.Errorf("%#v: unexpected error: %v", evil, err)
+			continue
+		}
+		var once, twice bytes.Buffer
+		tpl.ReplaceString(&once, map[string]string{"title": evil, "user": "U"})
+		partial.ReplaceString(&twice, map[string]string{"user": "U"})
+		if once.String() != twice.String() {
+			t.Errorf("%#v: unexpected result: %#v, expected: %#v", evil, twice.String(), once.String())
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		template, rendered string
+		exp                string
+		err                error
+	}{
+		{"<h1><@title@></h1><p><@body@></p>", "<h1>Hello</h1><p>a &lt;b&gt; c</p>", `map[body:a &lt;b&gt; c title:Hello]`, nil},
+		{"<@a@>, <@b@>", "x, y, z", `map[a:x b:y, z]`, nil},
+		{"<@a@>-<@b@>-<@a@>", "x-y-z-x-y", `map[a:x-y b:z]`, nil},
+		{"no placeholders", "no placeholders", `map[]`, nil},
+		{"<p><@a@></p>", "<div>x</div>", "", ErrNoMatch},
+		{"<@a@>-<@a@>", "x-y", "", ErrNoMatch},
+		{"<@a@><@b@>", "xy", "", ErrAdjacentPlaceholders},
+	}
+
+	for _, test := range tests {
+		got, err := NewTemplate([]byte(test.template)).Extract([]byte(test.rendered))
+		if err != test.err {
+			t.Errorf("%#v: unexpected error: %v, expected: %v", test.template, err, test.err)
+			continue
+		}
+		if err == nil && fmt.Sprint(got) != test.exp {
+			t.Errorf("%#v: unexpected result: %v, expected: %s", test.template, got, test.exp)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		template, exp string
+	}{
+		{`a \<@b@> c`, `a <@b@> c`},
+		{`a \\<@b@> c`, `a \B c`},
+		{`a \\\<@b@> c`, `a \<@b@> c`},
+		{`a \ b\\ <@b@>\`, `a \ b\\ B\`},
+		{`\<@a@><@b@>\<@`, `<@a@>B<@`},
+		// templates of earlier versions with backslashes before placeholders render differently
+		{`C:\<@b@>`, `C:<@b@>`},
+		{`C:\\<@b@>`, `C:\B`},
+	}
+
+	for _, test := range tests {
+		tpl := NewTemplate([]byte(test.template))
+		var copied Template
+		if b, _ := tpl.MarshalBinary(); copied.UnmarshalBinary(b) != nil {
+			t.Fatalf("%#v: can't unmarshal", test.template)
+		}
+		replacements := map[string]string{"b": "B"}
+		results := map[string]func(*bytes.Buffer){
+			"ReplaceString": func(bf *bytes.Buffer) { tpl.ReplaceString(bf, replacements) },
+			"ReplaceMapper": func(bf *bytes.Buffer) {
+				tpl.ReplaceMapper(bf, mapperFunc(func(k string) string { return replacements[k] }))
+			},
+			"ReplaceBytes": func(bf *bytes.Buffer) { tpl.ReplaceBytes(bf, map[string][]byte{"b": []byte("B")}) },
+			"Replace":      func(bf *bytes.Buffer) { tpl.Replace(bf, map[string]io.ReadSeeker{"b": strings.NewReader("B")}) },
+			"FindAndReplaceString": func(bf *bytes.Buffer) {
+				FindAndReplaceString([]byte(test.template), bf, replacements)
+			},
+			"UnmarshalBinary": func(bf *bytes.Buffer) { copied.ReplaceString(bf, replacements) },
+		}
+		for name, replace := range results {
+			var bf bytes.Buffer
+			replace(&bf)
+			if bf.String() != test.exp {
+				t.Errorf("%s %#v: unexpected result: %#v, expected: %#v", name, test.template, bf.String(), test.exp)
+			}
+		}
+	}
+
+	for _, text := range []string{`<@a@>`, `x \<@a@> \\<@`, `no delimiters \ `} {
+		var bf bytes.Buffer
+		tpl := NewTemplate(Escape([]byte(text)))
+		tpl.ReplaceString(&bf, map[string]string{"a": "A"})
+		if bf.String() != text || len(tpl.Placeholders()) != 0 {
+			t.Errorf("%#v: unexpected result of escaped text: %#v", text, bf.String())
+		}
+	}
+
+	if _, err := NewTemplateStrict([]byte(`a \<@ b`)); err != nil {
+		t.Errorf("unexpected error for escaped start delimiter: %v", err)
+	}
+}
+
+func TestExtractBacktracking(t *testing.T) {
+	tpl := NewTemplate([]byte(strings.Repeat("<@a@>-", 10) + "<@b@>!"))
+	done := make(chan error, 1)
+	go func() {
+		_, err := tpl.Extract([]byte(strings.Repeat("x-", 20) + "x?"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != ErrNoMatch {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Extract must not backtrack exponentially")
+	}
+
+	// the failed states depend on the values of repeated names
+	got, err := NewTemplate([]byte("<@a@>-<@a@>.<@b@>")).Extract([]byte("x-y-x-y.z"))
+	if err != nil || fmt.Sprint(got) != "map[a:x-y b:z]" {
+		t.Errorf("unexpected result: %v, %v", got, err)
+	}
+}
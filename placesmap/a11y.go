@@ -0,0 +1,312 @@
+package placesmap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/metakeule/places"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rules of A11yIssues
+const (
+	A11yMissingAlt  = "missing-alt"  // an <img> without alt attribute
+	A11yEmptyLink   = "empty-link"   // an <a> without text or label
+	A11yEmptyButton = "empty-button" // a <button> without text or label
+	A11yDuplicateID = "duplicate-id" // an element with the id of a previous element
+)
+
+// A11yIssue is an accessibility problem of a rendered page, see Options.A11yLint
+type A11yIssue struct {
+	// Position is the position of the start tag of the element within the templates. It is found
+	// by matching the literal parts of the rendered templates, Line is 0 if the position is unknown,
+	// Template is empty if the element stems from a value.
+	Position
+	Rule    string
+	Element string // the start tag of the element as rendered
+}
+
+func (a A11yIssue) String() string {
+	if a.Template == "" {
+		return fmt.Sprintf("%s: %s", a.Rule, a.Element)
+	}
+	return fmt.Sprintf("%s: %s: %s", a.Position, a.Rule, a.Element)
+}
+
+// tag is a start tag of a page
+type tag struct {
+	name   string
+	attrs  map[string]string
+	offset int
+	end    int // the offset after the tag
+}
+
+// nextTag returns the next start or end tag of the page starting at offset i, end tags have
+// names starting with /. Comments and the contents of script and style elements are skipped.
+func nextTag(page string, i int) (t tag, ok bool) {
+	for {
+		idx := strings.IndexByte(page[i:], '<')
+		if idx == -1 {
+			return t, false
+		}
+		i += idx
+		if strings.HasPrefix(page[i:], "<!--") {
+			end := strings.Index(page[i+4:], "-->")
+			if end == -1 {
+				return t, false
+			}
+			i += 4 + end + 3
+			continue
+		}
+		j := i + 1
+		if j < len(page) && page[j] == '/' {
+			j++
+		}
+		for j < len(page) && (isAlnum(page[j]) || page[j] == '-') {
+			j++
+		}
+		if j == i+1 || page[j-1] == '/' {
+			i++
+			continue
+		}
+		t = tag{name: strings.ToLower(page[i+1 : j]), attrs: map[string]string{}, offset: i}
+		t.end = parseAttrs(page, j, t.attrs)
+		if t.name == "script" || t.name == "style" {
+			if end := strings.Index(strings.ToLower(page[t.end:]), "</"+t.name); end != -1 {
+				t.end += end
+			}
+		}
+		return t, true
+	}
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parseAttrs parses the attributes of a tag starting at offset i into attrs and returns the
+// offset after the tag
+func parseAttrs(page string, i int, attrs map[string]string) int {
+	for i < len(page) {
+		for i < len(page) && (page[i] == ' ' || page[i] == '\t' || page[i] == '\n' || page[i] == '\r' || page[i] == '/') {
+			i++
+		}
+		if i >= len(page) {
+			return i
+		}
+		if page[i] == '>' {
+			return i + 1
+		}
+		start := i
+		for i < len(page) && !strings.ContainsRune(" \t\n\r/>=", rune(page[i])) {
+			i++
+		}
+		name := strings.ToLower(page[start:i])
+		if i >= len(page) || page[i] != '=' {
+			attrs[name] = ""
+			continue
+		}
+		i++
+		var val string
+		if i < len(page) && (page[i] == '"' || page[i] == '\'') {
+			q := page[i]
+			end := strings.IndexByte(page[i+1:], q)
+			if end == -1 {
+				return len(page)
+			}
+			val, i = page[i+1:i+1+end], i+2+end
+		} else {
+			start := i
+			for i < len(page) && !strings.ContainsRune(" \t\n\r>", rune(page[i])) {
+				i++
+			}
+			val = page[start:i]
+		}
+		attrs[name] = val
+	}
+	return i
+}
+
+// labelled returns whether the tag has an accessible name via its attributes
+func (t tag) labelled() bool {
+	return strings.TrimSpace(t.attrs["aria-label"]) != "" || t.attrs["aria-labelledby"] != "" || strings.TrimSpace(t.attrs["title"]) != ""
+}
+
+// CheckA11y returns the accessibility problems of the given page: images without alt attribute,
+// links and buttons without text, label (aria-label, aria-labelledby or title) or image with
+// alternative text, and duplicate ids. The issues have no Position.
+func CheckA11y(page string) []A11yIssue {
+	var (
+		issues []A11yIssue
+		ids    = map[string]bool{}
+		open   []tag // the open links and buttons
+		named  []bool
+	)
+	issue := func(t tag, rule string) {
+		issues = append(issues, A11yIssue{Rule: rule, Element: page[t.offset:t.end]})
+	}
+	last := 0
+	for {
+		t, ok := nextTag(page, last)
+		if !ok {
+			break
+		}
+		if len(open) > 0 && strings.TrimSpace(page[last:t.offset]) != "" {
+			named[len(named)-1] = true
+		}
+		last = t.end
+
+		if id, has := t.attrs["id"]; has && id != "" {
+			if ids[id] {
+				issue(t, A11yDuplicateID)
+			}
+			ids[id] = true
+		}
+		switch t.name {
+		case "img":
+			alt, has := t.attrs["alt"]
+			if !has {
+				issue(t, A11yMissingAlt)
+			}
+			if len(open) > 0 && strings.TrimSpace(alt) != "" {
+				named[len(named)-1] = true
+			}
+		case "a", "button":
+			open, named = append(open, t), append(named, t.labelled())
+		case "/a", "/button":
+			if n := len(open) - 1; n >= 0 && open[n].name == t.name[1:] {
+				if !named[n] {
+					rule := A11yEmptyLink
+					if t.name == "/button" {
+						rule = A11yEmptyButton
+					}
+					issue(open[n], rule)
+				}
+				// the text of an inner element names the outer one as well
+				if n > 0 && named[n] {
+					named[n-1] = true
+				}
+				open, named = open[:n], named[:n]
+			}
+		}
+	}
+	return issues
+}
+
+// lintedTemplate is a template of a linted render
+type lintedTemplate struct {
+	name string
+	t    *places.Template
+}
+
+// lint records the given template for the positions of A11yIssues
+func (r *RenderContext) lint(name string, t *places.Template) {
+	for _, l := range r.linted {
+		if l.t == t {
+			return
+		}
+	}
+	r.linted = append(r.linted, lintedTemplate{name: name, t: t})
+}
+
+// lintA11y reports the A11yIssues of the given page to Hooks.A11y or via log.Print
+func (r *RenderContext) lintA11y(input, page string) {
+	issues := CheckA11y(page)
+	if len(issues) == 0 {
+		return
+	}
+	for i := range issues {
+		issues[i].Position = r.elementPosition(issues[i].Element)
+	}
+	if r.hooks.A11y != nil {
+		r.hooks.A11y(input, issues)
+		return
+	}
+	for _, is := range issues {
+		log.Print("a11y: ", is)
+	}
+}
+
+// elementPosition returns the position of the given start tag within the rendered templates.
+// The template whose literal text matches the longest part of the element wins, an element that
+// matches no template has no position.
+func (r *RenderContext) elementPosition(element string) (p Position) {
+	key := []byte(element[:strings.IndexAny(element+" ", " >\t\n")])
+	best := 0
+	for _, l := range r.linted {
+		src := l.t.Bytes()
+		pos := places.Find(src)
+		for from := 0; ; {
+			idx := bytes.Index(src[from:], key)
+			if idx == -1 {
+				break
+			}
+			o := from + idx
+			from = o + 1
+
+			lit := src[o:]
+			if end := bytes.Index(lit, []byte("<@")); end != -1 {
+				lit = lit[:end]
+			}
+			n := 0
+			for n < len(lit) && n < len(element) && lit[n] == element[n] {
+				n++
+			}
+			// the literal text must match up to its end or the end of the element
+			if (n == len(lit) || n == len(element)) && n > len(key) && n > best {
+				best, p = n, r.offsetPosition(l, pos, o)
+			}
+		}
+	}
+	return p
+}
+
+// offsetPosition returns the position of the given offset within the template, using the
+// positions of the placeholders (given by their places) as anchors
+func (r *RenderContext) offsetPosition(l lintedTemplate, pos []int, offset int) Position {
+	p := Position{Template: blockOwner(l.name)}
+	var anchors []Position
+	for h := r.hm.HTMLTemplate; h != nil; {
+		if a, has := h.positions.Load(l.t); has {
+			anchors = a.([]Position)
+			break
+		}
+		h.RLock()
+		fb := h.fallback
+		h.RUnlock()
+		h = fb
+	}
+	src := l.t.Bytes()
+
+	// the nearest placeholder before the offset
+	for i := len(pos)/2 - 1; i >= 0; i-- {
+		if pos[i*2] > offset || i >= len(anchors) || anchors[i].Line == 0 {
+			continue
+		}
+		between := src[pos[i*2]:offset]
+		p.Line = anchors[i].Line + bytes.Count(between, []byte("\n"))
+		if idx := bytes.LastIndexByte(between, '\n'); idx != -1 {
+			p.Column = utf8.RuneCount(between[idx+1:]) + 1
+		} else {
+			p.Column = anchors[i].Column + utf8.RuneCount(between)
+		}
+		return p
+	}
+
+	// the nearest placeholder after the offset
+	for i := 0; i < len(pos)/2 && i < len(anchors); i++ {
+		if pos[i*2] < offset || anchors[i].Line == 0 {
+			continue
+		}
+		p.Line = anchors[i].Line - bytes.Count(src[offset:pos[i*2]], []byte("\n"))
+		p.Column = utf8.RuneCount(src[bytes.LastIndexByte(src[:offset], '\n')+1:offset]) + 1
+		return p
+	}
+
+	// without placeholders, the position is known for templates without front matter
+	if _, has := r.hm.Meta(l.name); !has && p.Template == l.name {
+		p.Line, p.Column = lineColumn(src, offset)
+	}
+	return p
+}
@@ -0,0 +1,71 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestA11yLintIncludeTimeout(t *testing.T) {
+	rs := NewReadSeekerMap()
+	rs.Add("page.html", strings.NewReader("<main><@-require nav.html@></main>"))
+	rs.Add("nav.html", strings.NewReader("<nav><@-require logo.html@></nav>"))
+	rs.Add("logo.html", strings.NewReader("<img src=\"<@logo@>\">"))
+	tpl := NewHTMLTemplate(rs, Development(), WithLogging(LogOff))
+	tpl.SetTimeouts(Timeouts{Include: time.Second})
+
+	var got []A11yIssue
+	tpl.SetHooks(Hooks{A11y: func(input string, issues []A11yIssue) { got = issues }})
+	tpl.NewMapper(map[string]places.Mapper{"logo": String("logo.png")}).Map("-require page.html")
+
+	// logo.html is rendered by a copy of the render, see renderTimeout
+	if len(got) != 1 || got[0].String() != `logo.html:1:1: missing-alt: <img src="logo.png">` {
+		t.Errorf("unexpected issues: %v", got)
+	}
+
+	r := tpl.NewMapper(nil).newRender()
+	r.linted = make([]lintedTemplate, 1, 4)
+	c := r.clone(r.ctx)
+	c.lint("x.html", places.NewTemplate([]byte("x")))
+	if r.linted[:2][1].t != nil {
+		t.Errorf("the clone shares the linted templates")
+	}
+}
+
+func TestA11yLint(t *testing.T) {
+	rs := NewReadSeekerMap()
+	rs.Add("page.html", strings.NewReader("<main>\n  <img src=\"<@logo@>\">\n  <@-require nav.html@><@-raw banner@>\n</main>"))
+	rs.Add("nav.html", strings.NewReader("<nav id=\"nav\">\n<a href=\"/\"><img src=\"home.png\" alt=\"Home\"></a>\n<a href=\"/x\" id=\"nav\"> </a><button><@label@></button></nav>"))
+	tpl := NewHTMLTemplate(rs, Development(), WithLogging(LogOff))
+
+	var got []A11yIssue
+	tpl.SetHooks(Hooks{A11y: func(input string, issues []A11yIssue) { got = issues }})
+	tpl.NewMapper(map[string]places.Mapper{"logo": String("logo.png"), "banner": String(`<img class="b">`)}).Map("-require page.html")
+
+	exp := []string{
+		`page.html:2:3: missing-alt: <img src="logo.png">`,
+		`nav.html:3:1: duplicate-id: <a href="/x" id="nav">`,
+		`nav.html:3:1: empty-link: <a href="/x" id="nav">`,
+		`nav.html:3:28: empty-button: <button>`,
+		`missing-alt: <img class="b">`,
+	}
+	if len(got) != len(exp) {
+		t.Fatalf("unexpected issues: %v", got)
+	}
+	for i, is := range got {
+		if is.String() != exp[i] {
+			t.Errorf("unexpected issue: %s, expected: %s", is, exp[i])
+		}
+	}
+
+	for page, exp := range map[string]int{
+		`<a href="/" aria-label="Home"></a><button title="Close"></button><img alt="">`: 0,
+		`<!-- <img> --><script>"<img>"</script><a><span>x</span></a>`:                   0,
+		`<a href="/"><span></span></a>`:                                                 1,
+	} {
+		if issues := CheckA11y(page); len(issues) != exp {
+			t.Errorf("%s: unexpected issues: %v", page, issues)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package placesmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/metakeule/places"
+	"strings"
+	"sync"
+)
+
+// TemplateVersion is a template that has been rendered for a response, see Composition
+type TemplateVersion struct {
+	Name string `json:"name"`
+
+	// Version is the start of the SHA-256 checksum of the template, so that it changes
+	// with every change of the template
+	Version string `json:"version"`
+}
+
+// Composition lists the templates that composed a response, see SetAccessLog
+type Composition struct {
+	Page string `json:"page"`
+
+	// Templates are the page and its includes in the order of their first render.
+	// The includes of fragments that are served from the cache (see EnableFragmentCache) are missing.
+	Templates []TemplateVersion `json:"templates"`
+}
+
+// String returns the templates as comma separated list of name@version
+func (c Composition) String() string {
+	parts := make([]string, len(c.Templates))
+	for i, t := range c.Templates {
+		parts[i] = t.Name + "@" + t.Version
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AccessLog configures the recording of the templates that composed the responses of
+// Render, Negotiate and Stream, e.g. to find out which version of a template produced a broken page
+type AccessLog struct {
+	// Header is the name of the response header that lists the templates (see Composition.String).
+	// Stream sends it as trailer, if the response is started before the page is complete.
+	// The empty string disables the header.
+	Header string
+
+	// Record is called with the composition of every response, nil disables the records
+	Record func(c Composition)
+}
+
+// SetAccessLog sets the recording of the templates that compose the responses of Render, Negotiate and Stream
+func (h *HTMLTemplate) SetAccessLog(a AccessLog) {
+	h.Lock()
+	h.access = a
+	h.Unlock()
+}
+
+// composition collects the templates of a render. It is shared with the copies of the render for timeouts.
+type composition struct {
+	mx        sync.Mutex
+	seen      map[string]bool
+	templates []TemplateVersion
+}
+
+// include records the given template, unless it is an inline body (see extractBlocks)
+func (c *composition) include(h *HTMLTemplate, name string, t *places.Template) {
+	if blockOwner(name) != name {
+		return
+	}
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.seen[name] {
+		return
+	}
+	c.seen[name] = true
+	c.templates = append(c.templates, TemplateVersion{Name: name, Version: h.version(t)})
+}
+
+// result returns the composition of the given page
+func (c *composition) result(page string) Composition {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return Composition{Page: page, Templates: append([]TemplateVersion(nil), c.templates...)}
+}
+
+// version returns the version of the given template, see TemplateVersion
+func (h *HTMLTemplate) version(t *places.Template) string {
+	if v, has := h.versions.Load(t); has {
+		return v.(string)
+	}
+	sum := sha256.Sum256(t.Bytes())
+	v := hex.EncodeToString(sum[:6])
+	h.versions.Store(t, v)
+	return v
+}
@@ -0,0 +1,39 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@-require nav.html@><@-each items@><@-require nav.html@><@-require item.html@><@-end@>",
+		"nav.html", "nav",
+		"item.html", "<@name@>",
+	)
+	var records []Composition
+	tpl.SetAccessLog(AccessLog{Header: "X-Templates", Record: func(c Composition) { records = append(records, c) }})
+	version := func(name string) string {
+		t, _ := tpl.template(name)
+		return tpl.version(t)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := Render(rec, tpl, "page.html", map[string]places.Mapper{"items": list{keyed("a"), keyed("b")}}); err != nil {
+		t.Fatal(err)
+	}
+	exp := "page.html@" + version("page.html") + ", nav.html@" + version("nav.html") + ", item.html@" + version("item.html")
+	if got := rec.Header().Get("X-Templates"); got != exp {
+		t.Errorf("unexpected header: %#v, expected: %#v", got, exp)
+	}
+	if len(records) != 1 || records[0].Page != "page.html" || records[0].String() != exp {
+		t.Errorf("unexpected records: %v", records)
+	}
+
+	before := version("nav.html")
+	tpl.Override("nav.html", []byte("new nav"))
+	if version("nav.html") == before {
+		t.Errorf("the version must change with the template")
+	}
+}
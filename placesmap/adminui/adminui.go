@@ -0,0 +1,196 @@
+/*
+package adminui provides an http.Handler for developers to browse the templates of a
+placesmap.HTMLTemplate and to test-render them with sample data.
+
+The Handler must be mounted with a trailing slash, e.g.
+
+	http.Handle("/admin/templates/", http.StripPrefix("/admin/templates", adminui.New(set, isDeveloper)))
+
+and serves
+
+	/                      the list of the templates
+	/template?name=x       the placeholders, dependencies and dependents of the template x,
+	                       and a form to render it with sample JSON data
+	/render                renders the template of the posted form field name with the JSON object
+	                       of the form field data (POST)
+
+The sample data is a JSON object, its keys are registered as mappers. Objects are namespaces
+("user.name"), arrays are collections for "-each" and may be indexed ("items.0.title").
+Rendered templates are served with the Content-Security-Policy sandbox, so that their scripts
+can't access the origin of the admin UI.
+*/
+package adminui
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Templates provides the current templates, e.g. a placesmap.TemplateSet or a
+// placesmap.HTMLTemplateRegistry
+type Templates interface {
+	Load() *placesmap.HTMLTemplate
+}
+
+// Handler is the admin UI
+type Handler struct {
+	templates  Templates
+	authorized func(*http.Request) bool
+}
+
+// New returns the admin UI for the given templates. Only requests for which authorized returns
+// true are served, all others are forbidden. Since sample data may be rendered with every
+// template, authorized must not be nil and should be restricted to developers.
+func New(t Templates, authorized func(*http.Request) bool) *Handler {
+	if authorized == nil {
+		panic("adminui: authorized must not be nil")
+	}
+	return &Handler{templates: t, authorized: authorized}
+}
+
+// ui are the templates of the admin UI
+var ui = newUI()
+
+func newUI() *placesmap.HTMLTemplate {
+	rs := placesmap.NewReadSeekerMap()
+	rs.Add("header.html", strings.NewReader(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title><@title@></title></head>
+<body>
+`))
+	rs.Add("footer.html", strings.NewReader(`
+</body></html>`))
+	rs.Add("index.html", strings.NewReader(`<@-require header.html@><h1>Templates</h1>
+<ul><@-each templates@><li><a href="<@href@>"><@name@></a></li><@-else@><li>no templates</li><@-end@></ul><@-require footer.html@>`))
+	rs.Add("template.html", strings.NewReader(`<@-require header.html@><p><a href="./">Templates</a></p>
+<h1><@name@></h1>
+<h2>Placeholders</h2>
+<ul><@-each placeholders@><li><code><@name@></code></li><@-else@><li>none</li><@-end@></ul>
+<h2>Dependencies</h2>
+<ul><@-each dependencies@><li><a href="<@href@>"><@name@></a></li><@-else@><li>none</li><@-end@></ul>
+<h2>Dependents</h2>
+<ul><@-each dependents@><li><a href="<@href@>"><@name@></a></li><@-else@><li>none</li><@-end@></ul>
+<h2>Test render</h2>
+<form method="post" action="render" target="_blank">
+<input type="hidden" name="name" value="<@name@>">
+<textarea name="data" rows="20" cols="80"><@sample@></textarea>
+<p><button type="submit">Render</button></p>
+</form><@-require footer.html@>`))
+	return placesmap.NewHTMLTemplate(rs)
+}
+
+func (h *Handler) ServeHTTP(wr http.ResponseWriter, rq *http.Request) {
+	if !h.authorized(rq) {
+		http.Error(wr, "forbidden", http.StatusForbidden)
+		return
+	}
+	switch strings.TrimPrefix(rq.URL.Path, "/") {
+	case "":
+		h.index(wr)
+	case "template":
+		h.template(wr, rq.URL.Query().Get("name"))
+	case "render":
+		if rq.Method != http.MethodPost {
+			wr.Header().Set("Allow", http.MethodPost)
+			http.Error(wr, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.render(wr, rq)
+	default:
+		http.NotFound(wr, rq)
+	}
+}
+
+// page renders the template of the admin UI with the given name
+func page(wr http.ResponseWriter, name string, m map[string]places.Mapper) {
+	wr.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(wr, ui.NewMapper(m).Map("-require "+name))
+}
+
+// links are the names of templates that are linked to their pages, mapping "name" and "href"
+type links []string
+
+func (l links) Map(string) string { return "" }
+func (l links) Len() int          { return len(l) }
+
+func (l links) NMap(n int, _ string) places.Mapper {
+	name := l[n]
+	return placesmap.MapFunc(func(key string) string {
+		switch key {
+		case "name":
+			return name
+		case "href":
+			return "template?name=" + url.QueryEscape(name)
+		}
+		return ""
+	})
+}
+
+// codes are placeholders, mapped by every key
+type codes []string
+
+func (c codes) Map(string) string { return "" }
+func (c codes) Len() int          { return len(c) }
+
+func (c codes) NMap(n int, _ string) places.Mapper {
+	return placesmap.String(c[n])
+}
+
+// index serves the list of the templates
+func (h *Handler) index(wr http.ResponseWriter) {
+	page(wr, "index.html", map[string]places.Mapper{
+		"title":     placesmap.String("Templates"),
+		"templates": links(h.templates.Load().Names()),
+	})
+}
+
+// template serves the page of the template with the given name
+func (h *Handler) template(wr http.ResponseWriter, name string) {
+	tpl := h.templates.Load()
+	phs, ok := tpl.Placeholders(name)
+	if !ok {
+		http.Error(wr, fmt.Sprintf("template %#v not found", name), http.StatusNotFound)
+		return
+	}
+	keys, _ := tpl.RequiredKeys(name)
+	sample, err := json.MarshalIndent(skeleton(keys), "", "  ")
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	page(wr, "template.html", map[string]places.Mapper{
+		"title":        placesmap.String(name),
+		"name":         placesmap.String(name),
+		"placeholders": codes(phs),
+		"dependencies": links(tpl.Dependencies(name)),
+		"dependents":   links(tpl.Dependents(name)),
+		"sample":       placesmap.String(sample),
+	})
+}
+
+// render serves the given template rendered with the posted sample data
+func (h *Handler) render(wr http.ResponseWriter, rq *http.Request) {
+	tpl := h.templates.Load()
+	name := rq.PostFormValue("name")
+	if !tpl.Has(name) {
+		http.Error(wr, fmt.Sprintf("template %#v not found", name), http.StatusNotFound)
+		return
+	}
+	m, err := mappers(rq.PostFormValue("data"))
+	if err != nil {
+		http.Error(wr, "invalid sample data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := tpl.NewMapperContext(rq.Context(), m).MapStrict("-require " + name)
+	if err != nil {
+		http.Error(wr, "render error: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	wr.Header().Set("Content-Security-Policy", "sandbox")
+	wr.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(wr, res)
+}
@@ -0,0 +1,79 @@
+package adminui
+
+import (
+	"github.com/metakeule/places/placesmap"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	rs := placesmap.NewReadSeekerMap()
+	rs.Add("page.html", strings.NewReader(`<h1><@user.name@></h1><@-each items@><i><@title@></i><@-end@><@items.1.title@>|<@-require footer.html@>`))
+	rs.Add("footer.html", strings.NewReader(`<footer><@year@></footer>`))
+	set := placesmap.NewTemplateSet(placesmap.NewHTMLTemplate(rs))
+	h := New(set, func(rq *http.Request) bool { return rq.Header.Get("X-Developer") == "1" })
+
+	serve := func(method, target string, form url.Values) *httptest.ResponseRecorder {
+		var rq *http.Request
+		if form != nil {
+			rq = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+			rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			rq = httptest.NewRequest(method, target, nil)
+		}
+		rq.Header.Set("X-Developer", "1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, rq)
+		return rec
+	}
+
+	rec := serve("GET", "/", nil)
+	for _, exp := range []string{`<a href="template?name=footer.html">footer.html</a>`, `<a href="template?name=page.html">page.html</a>`} {
+		if !strings.Contains(rec.Body.String(), exp) {
+			t.Errorf("index does not contain %#v: %s", exp, rec.Body.String())
+		}
+	}
+
+	rec = serve("GET", "/template?name=page.html", nil)
+	for _, exp := range []string{
+		"<h1>page.html</h1>",
+		"<code>user.name</code>",
+		"<code>title</code>",
+		`<a href="template?name=footer.html">footer.html</a>`,
+		`&#34;name&#34;: &#34;&#34;`,
+	} {
+		if !strings.Contains(rec.Body.String(), exp) {
+			t.Errorf("template page does not contain %#v: %s", exp, rec.Body.String())
+		}
+	}
+	if rec = serve("GET", "/template?name=missing.html", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unexpected status for missing template: %d", rec.Code)
+	}
+
+	data := `{"user": {"name": "<Donald>"}, "items": [{"title": "a&b"}, {"title": "c"}], "year": 2020}`
+	rec = serve("POST", "/render", url.Values{"name": {"page.html"}, "data": {data}})
+	if got, exp := rec.Body.String(), "<h1>&lt;Donald&gt;</h1><i>a&amp;b</i><i>c</i>c|<footer>2020</footer>"; got != exp {
+		t.Errorf("unexpected rendering: %#v, expected: %#v", got, exp)
+	}
+	if got := rec.Header().Get("Content-Security-Policy"); got != "sandbox" {
+		t.Errorf("unexpected Content-Security-Policy: %#v", got)
+	}
+
+	rec = serve("POST", "/render", url.Values{"name": {"page.html"}, "data": {"{"}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status for invalid data: %d", rec.Code)
+	}
+	if rec = serve("GET", "/render", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("unexpected status for GET of render: %d", rec.Code)
+	}
+
+	rq := httptest.NewRequest("GET", "/", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, rq)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("unexpected status for unauthorized request: %d", rec.Code)
+	}
+}
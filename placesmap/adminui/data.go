@@ -0,0 +1,129 @@
+package adminui
+
+import (
+	"encoding/json"
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"strconv"
+	"strings"
+)
+
+// mappers returns the mappers for the given JSON object of sample data
+func mappers(data string) (map[string]places.Mapper, error) {
+	var obj map[string]interface{}
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	if strings.TrimSpace(data) != "" {
+		if err := dec.Decode(&obj); err != nil {
+			return nil, err
+		}
+	}
+	m := make(map[string]places.Mapper, len(obj))
+	for k, v := range obj {
+		m[k] = wrap(v)
+	}
+	return m, nil
+}
+
+// wrap returns the mapper for the given JSON value
+func wrap(v interface{}) places.Mapper {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return object(x)
+	case []interface{}:
+		return array(x)
+	}
+	return placesmap.String(scalar(v))
+}
+
+// scalar returns the string of the given JSON value, the empty string for objects, arrays and null
+func scalar(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return ""
+}
+
+// get returns the value at the given dotted path within v, e.g. "address.city" or "items.0"
+func get(v interface{}, path string) (interface{}, bool) {
+	for _, k := range strings.Split(path, ".") {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			var has bool
+			if v, has = x[k]; !has {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// object is a JSON object, a placesmap.NamespaceMapper for the values within
+type object map[string]interface{}
+
+func (o object) Map(key string) string {
+	v, _ := get(map[string]interface{}(o), key)
+	return scalar(v)
+}
+
+func (o object) Lookup(name string) (places.Mapper, bool) {
+	v, ok := get(map[string]interface{}(o), name)
+	if !ok {
+		return nil, false
+	}
+	return wrap(v), true
+}
+
+// array is a JSON array, a placesmap.NMapper and a placesmap.NamespaceMapper for the
+// values at the indexes
+type array []interface{}
+
+func (a array) Map(string) string { return "" }
+func (a array) Len() int          { return len(a) }
+
+func (a array) NMap(n int, _ string) places.Mapper {
+	return wrap(a[n])
+}
+
+func (a array) Lookup(name string) (places.Mapper, bool) {
+	v, ok := get([]interface{}(a), name)
+	if !ok {
+		return nil, false
+	}
+	return wrap(v), true
+}
+
+// skeleton returns the sample data for the given keys with empty strings as values
+func skeleton(keys []string) map[string]interface{} {
+	root := map[string]interface{}{}
+	for _, key := range keys {
+		m := root
+		parts := strings.Split(key, ".")
+		for _, p := range parts[:len(parts)-1] {
+			sub, is := m[p].(map[string]interface{})
+			if !is {
+				sub = map[string]interface{}{}
+				m[p] = sub
+			}
+			m = sub
+		}
+		last := parts[len(parts)-1]
+		if _, has := m[last]; !has {
+			m[last] = ""
+		}
+	}
+	return root
+}
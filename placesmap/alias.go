@@ -0,0 +1,105 @@
+package placesmap
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type AliasCycleError struct {
+	Old, New string
+}
+
+func (a AliasCycleError) Error() string {
+	return fmt.Sprintf("alias %#v for %#v would create a cycle", a.Old, a.New)
+}
+
+// Deprecation is the usage of an alias within a template
+type Deprecation struct {
+	Template string // the innermost include that used the alias, empty for Map calls outside of templates
+	Old      string
+	New      string
+	Count    int
+}
+
+type deprecationKey struct {
+	template, old string
+}
+
+// deprecations counts the usages of aliases
+type deprecations struct {
+	mx     sync.Mutex
+	counts map[deprecationKey]int
+}
+
+// Alias lets the placeholder name old resolve to the mapper registered for new (both for
+// registered mappers and the elements of loops), so that renamed keys keep working within
+// templates that are not updated yet. Every usage of old is counted per template
+// (see Deprecations) and reported to Hooks.Deprecated.
+func (h *HTMLTemplate) Alias(old, new string) error {
+	h.Lock()
+	defer h.Unlock()
+	for n, ok := new, true; ok; n, ok = h.aliases[n] {
+		if n == old {
+			return AliasCycleError{Old: old, New: new}
+		}
+	}
+	aliases := make(map[string]string, len(h.aliases)+1)
+	for k, v := range h.aliases {
+		aliases[k] = v
+	}
+	aliases[old] = new
+	h.aliases = aliases
+	if h.usages == nil {
+		h.usages = &deprecations{counts: map[deprecationKey]int{}}
+	}
+	return nil
+}
+
+// Deprecations returns the usages of the aliases, sorted by template and old name
+func (h *HTMLTemplate) Deprecations() []Deprecation {
+	h.RLock()
+	d, aliases := h.usages, h.aliases
+	h.RUnlock()
+	if d == nil {
+		return nil
+	}
+
+	d.mx.Lock()
+	res := make([]Deprecation, 0, len(d.counts))
+	for k, n := range d.counts {
+		res = append(res, Deprecation{Template: k.template, Old: k.old, New: aliases[k.old], Count: n})
+	}
+	d.mx.Unlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Template != res[j].Template {
+			return res[i].Template < res[j].Template
+		}
+		return res[i].Old < res[j].Old
+	})
+	return res
+}
+
+// alias returns the name the given old name is an alias for, following aliases of aliases
+func (r *RenderContext) alias(old string) (string, bool) {
+	name, ok := r.aliases[old]
+	for n, has := name, ok; has; n, has = r.aliases[n] {
+		name = n
+	}
+	return name, ok
+}
+
+// deprecated records the usage of the alias old
+func (r *RenderContext) deprecated(old string) {
+	var tpl string
+	if len(r.includes) > 0 {
+		tpl = r.includes[len(r.includes)-1]
+	}
+	r.usages.mx.Lock()
+	r.usages.counts[deprecationKey{tpl, old}]++
+	r.usages.mx.Unlock()
+	if r.hooks.Deprecated != nil {
+		r.hooks.Deprecated(tpl, old, r.aliases[old])
+	}
+}
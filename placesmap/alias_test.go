@@ -0,0 +1,48 @@
+package placesmap
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@-require header.html@> <@user_name@> <@-each items item.html@>",
+		"header.html", "<@title@>/<@headline@>",
+		"item.html", "[<@label@>]",
+	)
+	var hooked []string
+	tpl.SetHooks(Hooks{Deprecated: func(template, old, new string) {
+		hooked = append(hooked, template+":"+old+">"+new)
+	}})
+	for _, a := range [][2]string{{"user_name", "user.name"}, {"headline", "title"}, {"label", "name"}} {
+		if err := tpl.Alias(a[0], a[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tpl.Alias("title", "headline"); err != (AliasCycleError{Old: "title", New: "headline"}) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	hm := tpl.NewMapper(map[string]places.Mapper{
+		"user.name": String("Donald"),
+		"title":     String("Hi"),
+		"items":     list{keyed("a"), keyed("b")},
+	})
+	if got, exp := hm.Map("-require page.html"), "Hi/Hi Donald [a][b]"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	exp := []Deprecation{
+		{Template: "header.html", Old: "headline", New: "title", Count: 1},
+		{Template: "item.html", Old: "label", New: "name", Count: 2},
+		{Template: "page.html", Old: "user_name", New: "user.name", Count: 1},
+	}
+	if got := tpl.Deprecations(); fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("unexpected deprecations: %v, expected: %v", got, exp)
+	}
+	if len(hooked) != 4 || hooked[0] != "header.html:headline>title" {
+		t.Errorf("unexpected hook calls: %#v", hooked)
+	}
+}
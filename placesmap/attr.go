@@ -0,0 +1,96 @@
+package placesmap
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AttrEscape escapes the given string for the usage as HTML attribute value.
+// In addition to the characters escaped by html.EscapeString, the backtick, the equal sign,
+// whitespace and control characters are escaped, so that the value is safe even
+// within unquoted attributes.
+func AttrEscape(s string) string {
+	var bf bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '&':
+			bf.WriteString("&amp;")
+		case r == '<':
+			bf.WriteString("&lt;")
+		case r == '>':
+			bf.WriteString("&gt;")
+		case r == '"':
+			bf.WriteString("&#34;")
+		case r == '\'' || r == '`' || r == '=' || r <= ' ' || r == 0x7F:
+			bf.WriteString("&#")
+			bf.WriteString(strconv.Itoa(int(r)))
+			bf.WriteByte(';')
+		default:
+			bf.WriteRune(r)
+		}
+	}
+	return bf.String()
+}
+
+// AttrMapper renders a list of HTML attributes via "-attrs name", where name is
+// the name under which the AttrMapper is registered.
+// Attributes are sorted by name, attributes with empty values and attributes
+// with invalid names are skipped and the values are escaped via AttrEscape.
+type AttrMapper map[string]string
+
+// validAttrName returns whether the given name is a safe attribute name
+func validAttrName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+		case i > 0 && (r >= '0' && r <= '9' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Map returns the rendered attribute list, starting with a space, if it is not empty
+func (a AttrMapper) Map(string) string {
+	keys := make([]string, 0, len(a))
+	for k, v := range a {
+		if v != "" && validAttrName(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var bf bytes.Buffer
+	for _, k := range keys {
+		bf.WriteByte(' ')
+		bf.WriteString(k)
+		bf.WriteString(`="`)
+		bf.WriteString(AttrEscape(a[k]))
+		bf.WriteByte('"')
+	}
+	return bf.String()
+}
+
+// boolAttr handles "-bool checked name", that renders the boolean attribute checked, if the value
+// for name is truthy, i.e. not empty, "0" or "false", and nothing otherwise
+func (r *RenderContext) boolAttr(rest string) string {
+	attr, name := nextArg(rest)
+	name = strings.TrimSpace(name)
+	if name == "" || !truthy(r.Value(name)) {
+		return ""
+	}
+	if !validAttrName(attr) {
+		r.fail(UnsafeNameError(attr))
+		return ""
+	}
+	return attr
+}
+
+// AttrEscaper is a places.Mapper that escapes for attribute context
+var AttrEscaper = MapFunc(AttrEscape)
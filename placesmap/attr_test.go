@@ -0,0 +1,42 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestAttr(t *testing.T) {
+	tpl := newTemplate("input.html", `<input value=<@-attr value@><@-attrs attrs@>>`)
+	got := tpl.NewMapper(map[string]places.Mapper{
+		"value": String(`a b"' onclick=x`),
+		"attrs": AttrMapper{"class": "big <red>", "id": "", "onclick=\"x\"": "y", "data-x": "1"},
+	}).Map("-require input.html")
+
+	exp := `<input value=a&#32;b&#34;&#39;&#32;onclick&#61;x class="big&#32;&lt;red&gt;" data-x="1">`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	if got := tpl.NewMapper(map[string]places.Mapper{"attrs": String(" onclick=alert(1)")}).Map("-attrs attrs"); got != "" {
+		t.Errorf("-attrs must only render AttrMapper, got: %#v", got)
+	}
+}
+
+func TestBoolAttr(t *testing.T) {
+	tpl := newTemplate("input.html", `<input type="checkbox" <@-bool checked isAdmin@>><@-each items@><option <@-bool selected active@>><@-end@>`)
+	for val, exp := range map[string]string{"true": "checked", "1": "checked", "yes": "checked", "": "", "0": "", "false": ""} {
+		got := tpl.NewMapper(map[string]places.Mapper{"isAdmin": String(val)}).Map("-require input.html")
+		if got != `<input type="checkbox" `+exp+`>` {
+			t.Errorf("unexpected result for %#v: %#v", val, got)
+		}
+	}
+
+	m := map[string]places.Mapper{"items": list{Nested{"active": "1"}, Nested{"active": "0"}}}
+	if got, exp := tpl.NewMapper(m).Map("-require input.html"), `<input type="checkbox" ><option selected><option >`; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	if _, err := tpl.NewMapper(map[string]places.Mapper{"x": String("1")}).MapStrict(`-bool on"click x`); err != UnsafeNameError(`on"click`) {
+		t.Errorf("expected UnsafeNameError, got %v", err)
+	}
+}
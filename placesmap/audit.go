@@ -0,0 +1,148 @@
+package placesmap
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+)
+
+// audit records the placeholders, includes and mappers that are used across renders
+type audit struct {
+	mx           sync.Mutex
+	placeholders map[string]int
+	includes     map[string]int
+	registered   map[string]bool
+	consulted    map[string]int
+	missing      map[string]int // names without mapper, only recorded for Debug
+}
+
+func newAudit() *audit {
+	return &audit{
+		placeholders: map[string]int{},
+		includes:     map[string]int{},
+		registered:   map[string]bool{},
+		consulted:    map[string]int{},
+		missing:      map[string]int{},
+	}
+}
+
+func (a *audit) placeholder(input string) {
+	a.mx.Lock()
+	a.placeholders[input]++
+	a.mx.Unlock()
+}
+
+func (a *audit) include(name string) {
+	a.mx.Lock()
+	a.includes[name]++
+	a.mx.Unlock()
+}
+
+func (a *audit) consult(name string) {
+	a.mx.Lock()
+	a.consulted[name]++
+	a.mx.Unlock()
+}
+
+func (a *audit) miss(name string) {
+	a.mx.Lock()
+	a.missing[name]++
+	a.mx.Unlock()
+}
+
+func (a *audit) register(names []string) {
+	a.mx.Lock()
+	for _, name := range names {
+		a.registered[name] = true
+	}
+	a.mx.Unlock()
+}
+
+// AuditReport lists what has been used during the renders since the audit started.
+type AuditReport struct {
+	// Placeholders maps the resolved placeholders to the number of resolutions
+	Placeholders map[string]int `json:"placeholders"`
+
+	// Includes maps the rendered templates to the number of renders
+	Includes map[string]int `json:"includes"`
+
+	// Mappers maps the names of the consulted mappers to the number of lookups
+	Mappers map[string]int `json:"mappers"`
+
+	// UnusedMappers are the sorted names of mappers that have been passed to NewMapper but were never consulted
+	UnusedMappers []string `json:"unused_mappers"`
+
+	// UnusedIncludes are the sorted names of the loaded templates that have never been rendered
+	UnusedIncludes []string `json:"unused_includes"`
+}
+
+// WriteJSON writes the report as JSON to the given writer
+func (r AuditReport) WriteJSON(wr io.Writer) error {
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	res := make(map[string]int, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+// StartAudit starts recording which placeholders and includes are resolved and which mappers
+// are consulted during real renders. Previously recorded data is discarded.
+func (h *HTMLTemplate) StartAudit() {
+	h.Lock()
+	h.audit = newAudit()
+	h.Unlock()
+}
+
+// StopAudit stops the recording
+func (h *HTMLTemplate) StopAudit() {
+	h.Lock()
+	h.audit = nil
+	h.Unlock()
+}
+
+// getAudit returns the running audit or nil
+func (h *HTMLTemplate) getAudit() *audit {
+	h.RLock()
+	a := h.audit
+	h.RUnlock()
+	return a
+}
+
+// AuditReport returns the report of the running audit. If no audit is running, false is returned.
+func (h *HTMLTemplate) AuditReport() (r AuditReport, running bool) {
+	a := h.getAudit()
+	if a == nil {
+		return
+	}
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	r.Placeholders = copyCounts(a.placeholders)
+	r.Includes = copyCounts(a.includes)
+	r.Mappers = copyCounts(a.consulted)
+	r.UnusedMappers = []string{}
+	r.UnusedIncludes = []string{}
+
+	for name := range a.registered {
+		if a.consulted[name] == 0 {
+			r.UnusedMappers = append(r.UnusedMappers, name)
+		}
+	}
+
+	for _, name := range h.names() {
+		if a.includes[name] == 0 {
+			r.UnusedIncludes = append(r.UnusedIncludes, name)
+		}
+	}
+
+	sort.Strings(r.UnusedMappers)
+	return r, true
+}
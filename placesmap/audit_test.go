@@ -0,0 +1,38 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	tpl := newTemplate("page.html", "<@title@><@-require footer.html@>", "footer.html", "footer", "stale.html", "old")
+
+	if _, running := tpl.AuditReport(); running {
+		t.Errorf("audit should not be running")
+	}
+
+	tpl.StartAudit()
+	tpl.NewMapper(map[string]places.Mapper{"title": String("t"), "unused": String("u")}).Map("-require page.html")
+
+	r, running := tpl.AuditReport()
+	if !running {
+		t.Fatalf("audit should be running")
+	}
+
+	if r.Includes["page.html"] != 1 || r.Includes["footer.html"] != 1 {
+		t.Errorf("unexpected includes: %#v", r.Includes)
+	}
+
+	if r.Placeholders["title"] != 1 || r.Mappers["title"] != 1 {
+		t.Errorf("unexpected placeholders: %#v, mappers: %#v", r.Placeholders, r.Mappers)
+	}
+
+	if len(r.UnusedMappers) != 1 || r.UnusedMappers[0] != "unused" {
+		t.Errorf("unexpected unused mappers: %#v", r.UnusedMappers)
+	}
+
+	if len(r.UnusedIncludes) != 1 || r.UnusedIncludes[0] != "stale.html" {
+		t.Errorf("unexpected unused includes: %#v", r.UnusedIncludes)
+	}
+}
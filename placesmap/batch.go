@@ -0,0 +1,125 @@
+package placesmap
+
+import (
+	"context"
+	"github.com/metakeule/places"
+	"sync"
+)
+
+// BatchFetcher fetches the values for many keys with a single call, e.g. to a microservice.
+// Keys without value may be missing in the result.
+type BatchFetcher interface {
+	Fetch(ctx context.Context, keys []string) (map[string]string, error)
+}
+
+// BatchFetchFunc is a function that is a BatchFetcher
+type BatchFetchFunc func(ctx context.Context, keys []string) (map[string]string, error)
+
+func (b BatchFetchFunc) Fetch(ctx context.Context, keys []string) (map[string]string, error) {
+	return b(ctx, keys)
+}
+
+// BatchMapper maps the names within the namespace it is registered for (see NamespaceMapper)
+// to the values of its BatchFetcher. HTMLTemplateMapper.Prefetch fetches the values for all names
+// that are used by a template and its includes with one call, before the template is rendered,
+// instead of one call per placeholder. The keys are the full names, e.g. "product.42.title" for the
+// BatchMapper registered as "product". Names that are not known in advance (e.g. within loops) are
+// fetched one by one on their first use. A BatchMapper should be created per request.
+type BatchMapper struct {
+	Fetcher BatchFetcher
+
+	mx      sync.Mutex
+	values  map[string]string
+	fetched map[string]bool
+}
+
+// NewBatchMapper returns a BatchMapper for the given BatchFetcher
+func NewBatchMapper(f BatchFetcher) *BatchMapper {
+	return &BatchMapper{Fetcher: f, values: map[string]string{}, fetched: map[string]bool{}}
+}
+
+// Lookup returns the BatchMapper for every name
+func (b *BatchMapper) Lookup(string) (places.Mapper, bool) {
+	return b, true
+}
+
+// Map returns the value for the given key
+func (b *BatchMapper) Map(key string) string {
+	return b.MapContext(context.Background(), key)
+}
+
+// MapContext returns the value for the given key, fetching it, if it has not been fetched yet
+func (b *BatchMapper) MapContext(ctx context.Context, key string) string {
+	b.mx.Lock()
+	val, fetched := b.values[key], b.fetched[key]
+	b.mx.Unlock()
+	if fetched {
+		return val
+	}
+	b.fetch(ctx, []string{key})
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.values[key]
+}
+
+// fetch fetches the values for the given keys, that have not been fetched yet
+func (b *BatchMapper) fetch(ctx context.Context, keys []string) error {
+	b.mx.Lock()
+	var missing []string
+	for _, k := range keys {
+		if !b.fetched[k] {
+			missing = append(missing, k)
+		}
+	}
+	b.mx.Unlock()
+	if len(missing) == 0 {
+		return nil
+	}
+
+	res, err := b.Fetcher.Fetch(ctx, missing)
+	if err != nil {
+		return err
+	}
+	b.mx.Lock()
+	for _, k := range missing {
+		b.values[k], b.fetched[k] = res[k], true
+	}
+	b.mx.Unlock()
+	return nil
+}
+
+// Prefetch collects the names that are used by the templates with the given names and their
+// includes (see HTMLTemplate.RequiredKeys) and fetches the values of the names that belong to BatchMappers, one call per BatchMapper.
+// It returns the first error of the BatchFetchers.
+func (h *HTMLTemplateMapper) Prefetch(templates ...string) error {
+	names, includes := map[string]bool{}, map[string]bool{}
+	for _, t := range templates {
+		h.HTMLTemplate.requirements(t, names, includes)
+	}
+
+	r := h.newRender()
+	var (
+		batches []*BatchMapper
+		keys    = map[*BatchMapper][]string{}
+	)
+	for _, name := range sortedKeys(names) {
+		mp, ok := r.mapper(name)
+		if !ok {
+			continue
+		}
+		if b, is := mp.(*BatchMapper); is {
+			if _, has := keys[b]; !has {
+				batches = append(batches, b)
+			}
+			keys[b] = append(keys[b], name)
+		}
+	}
+
+	var first error
+	for _, b := range batches {
+		if err := b.fetch(r.ctx, keys[b]); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,55 @@
+package placesmap
+
+import (
+	"context"
+	"fmt"
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestBatchMapper(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@product.1.title@>|<@-require footer.html@>|<@-each products row.html@>",
+		"footer.html", "<@-html product.2.title@>",
+		"row.html", "<@-require row-inner.html@>,",
+		"row-inner.html", "<@product.3.title@>",
+	)
+
+	var calls [][]string
+	b := NewBatchMapper(BatchFetchFunc(func(ctx context.Context, keys []string) (map[string]string, error) {
+		calls = append(calls, keys)
+		res := map[string]string{}
+		for _, k := range keys {
+			res[k] = "<" + k + ">"
+		}
+		return res, nil
+	}))
+
+	hm := tpl.NewMapper(map[string]places.Mapper{"product": b, "products": list{keyed("a"), keyed("b")}})
+	if err := hm.Prefetch("page.html"); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "[[product.1.title product.2.title product.3.title]]"; fmt.Sprint(calls) != exp {
+		t.Errorf("unexpected calls: %v, expected: %s", calls, exp)
+	}
+
+	got := hm.Map("-require page.html")
+	if exp := "&lt;product.1.title&gt;|<product.2.title>|&lt;product.3.title&gt;,&lt;product.3.title&gt;,"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if len(calls) != 1 {
+		t.Errorf("expected no further calls, got %v", calls[1:])
+	}
+
+	hm.Map("product.4.title")
+	if exp := "[[product.1.title product.2.title product.3.title] [product.4.title]]"; fmt.Sprint(calls) != exp {
+		t.Errorf("unexpected calls: %v, expected: %s", calls, exp)
+	}
+
+	failing := NewBatchMapper(BatchFetchFunc(func(ctx context.Context, keys []string) (map[string]string, error) {
+		return nil, ErrNoLoader
+	}))
+	if err := tpl.NewMapper(map[string]places.Mapper{"product": failing}).Prefetch("page.html"); err != ErrNoLoader {
+		t.Errorf("expected ErrNoLoader, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+)
+
+func BenchmarkSplit(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		split("-each items row.html else empty.html")
+		split("title")
+	}
+}
+
+func BenchmarkNextArg(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		name, rest := nextArg("items row.html else empty.html")
+		splitElse(rest)
+		_ = name
+	}
+}
+
+func BenchmarkMapAdd(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := New()
+		m.Add("html", Empty{})
+		m.Add("js", Empty{})
+	}
+}
+
+// BenchmarkRenderPage renders a page with many placeholders, conditions and a loop
+func BenchmarkRenderPage(b *testing.B) {
+	page := strings.Repeat("<h2><@title@></h2><@-if admin admin.html else user.html@><@-eq status active badge.html@>", 20)
+	tpl := newTemplate(
+		"page.html", page+"<@-each items row.html@>",
+		"row.html", "<li><@name@></li>",
+		"admin.html", "<@-html menu@>",
+		"user.html", "user",
+		"badge.html", "active",
+	)
+	items := make(list, 50)
+	for i := range items {
+		items[i] = keyed("item")
+	}
+	hm := tpl.NewMapper(map[string]places.Mapper{
+		"title":  String("Title & more"),
+		"admin":  String("true"),
+		"menu":   String("<nav></nav>"),
+		"status": String("active"),
+		"items":  items,
+	})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hm.Map("-require page.html")
+	}
+}
@@ -0,0 +1,182 @@
+package placesmap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/metakeule/places"
+	"strings"
+)
+
+// MissingTemplateError is the error for directives like "-each users" or "-if admin" that have neither
+// a template nor an inline body
+type MissingTemplateError string
+
+func (m MissingTemplateError) Error() string {
+	return fmt.Sprintf("directive %#v has no template", string(m))
+}
+
+// blockDirectives are the directives that may have an inline body instead of a template
+var blockDirectives = map[string]bool{"each": true, "if": true, "with": true}
+
+// blockName returns the name of the template for the n-th inline body of the template with the given name
+func blockName(name string, n int) string {
+	return fmt.Sprintf("%s#%d", name, n)
+}
+
+// isBlockStart returns whether the given placeholder starts an inline body, i.e. is a
+// block directive without template, like "-each users"
+func isBlockStart(placeholder string) bool {
+	prefix, rest := split(placeholder)
+	if !blockDirectives[prefix] {
+		return false
+	}
+	arg, rest := nextArg(rest)
+	return arg != "" && strings.TrimSpace(rest) == ""
+}
+
+// part is a part of a template that is being extracted
+type part struct {
+	bytes.Buffer
+	offsets []int // the offsets of the placeholders of the part within the source
+}
+
+// placeholder writes the given placeholder that is found at the given offset within the source
+func (p *part) placeholder(ph string, offset int) {
+	p.offsets = append(p.offsets, offset)
+	p.WriteString("<@" + ph + "@>")
+}
+
+// block is an inline body that is being extracted
+type block struct {
+	directive string // the placeholder that starts the block
+	start     int    // the offset of the directive
+	body      part
+	otherwise *part // the body after -else, nil if there is none
+	elseAt    int   // the offset of -else
+}
+
+// out returns the buffer of the current part of the block
+func (b *block) out() *part {
+	if b.otherwise != nil {
+		return b.otherwise
+	}
+	return &b.body
+}
+
+// extractBlocks extracts the inline bodies of the directives -each, -if and -with of the
+// template with the given name, e.g.
+//
+//	<@-each users@><li><@name@></li><@-else@>no users<@-end@>
+//
+// becomes <@-each users page.html#1 else page.html#2@> and the templates page.html#1 with
+// the body and page.html#2 with the body after -else, that is optional.
+// Blocks may be nested. Blocks without -end are kept as they are.
+// The text between <@-literal@> and the next <@-end@> is kept as it is, i.e. its placeholders
+// are escaped (see places.Escape), e.g. to document the placeholder syntax.
+// It returns the template and the templates of the bodies, along with the offsets of their
+// placeholders within tpl by name (the directive of a block is found at its start).
+func extractBlocks(name string, tpl []byte) ([]byte, map[string][]byte, map[string][]int) {
+	pos := places.Find(tpl)
+	if !bytes.Contains(tpl, []byte("<@-end@>")) {
+		offsets := make([]int, 0, len(pos)/2)
+		for i := 0; i < len(pos); i += 2 {
+			offsets = append(offsets, pos[i])
+		}
+		return tpl, nil, map[string][]int{name: offsets}
+	}
+
+	var (
+		main    part
+		stack   []*block
+		blocks  = map[string][]byte{}
+		offsets = map[string][]int{}
+		n       int
+		last    int
+	)
+	out := func() *part {
+		if len(stack) == 0 {
+			return &main
+		}
+		return stack[len(stack)-1].out()
+	}
+
+	for i := 0; i < len(pos); i += 2 {
+		out().Write(tpl[last:pos[i]])
+		ph := string(tpl[pos[i]+2 : pos[i+1]])
+		last = pos[i+1] + 2
+
+		switch {
+		case ph == "-literal":
+			end := i + 2
+			for end < len(pos) && string(tpl[pos[end]+2:pos[end+1]]) != "-end" {
+				end += 2
+			}
+			if end == len(pos) {
+				out().placeholder(ph, pos[i])
+				continue
+			}
+			out().Write(places.Escape(tpl[last:pos[end]]))
+			last = pos[end+1] + 2
+			i = end
+		case isBlockStart(ph):
+			stack = append(stack, &block{directive: ph, start: pos[i]})
+		case ph == "-else" && len(stack) > 0 && stack[len(stack)-1].otherwise == nil:
+			stack[len(stack)-1].otherwise = &part{}
+			stack[len(stack)-1].elseAt = pos[i]
+		case ph == "-end" && len(stack) > 0:
+			b := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			n++
+			then := blockName(name, n)
+			blocks[then], offsets[then] = b.body.Bytes(), b.body.offsets
+			directive := b.directive + " " + then
+			if b.otherwise != nil {
+				n++
+				otherwise := blockName(name, n)
+				blocks[otherwise], offsets[otherwise] = b.otherwise.Bytes(), b.otherwise.offsets
+				directive += " else " + otherwise
+			}
+			out().placeholder(directive, b.start)
+		default:
+			out().placeholder(ph, pos[i])
+		}
+	}
+	out().Write(tpl[last:])
+
+	// unclosed blocks are kept as they are
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		o := out()
+		o.placeholder(b.directive, b.start)
+		o.Write(b.body.Bytes())
+		o.offsets = append(o.offsets, b.body.offsets...)
+		if b.otherwise != nil {
+			o.placeholder("-else", b.elseAt)
+			o.Write(b.otherwise.Bytes())
+			o.offsets = append(o.offsets, b.otherwise.offsets...)
+		}
+	}
+	offsets[name] = main.offsets
+	return main.Bytes(), blocks, offsets
+}
+
+// parseTemplates returns the templates for the given template source, that is the template itself
+// and the templates of its inline bodies (see extractBlocks), by name, and the positions
+// of their placeholders within src. body is the source without front matter.
+func parseTemplates(name string, src, body []byte) (map[string]*places.Template, map[*places.Template][]Position) {
+	tpl, blocks, offsets := extractBlocks(name, body)
+	base := len(src) - len(body)
+	res := map[string]*places.Template{}
+	positions := map[*places.Template][]Position{}
+	add := func(n string, b []byte) {
+		t := places.NewTemplate(guardNames(injectNonce(b)))
+		res[n] = t
+		positions[t] = sourcePositions(name, src, base, b, offsets[n], t)
+	}
+	add(name, tpl)
+	for bn, b := range blocks {
+		add(bn, b)
+	}
+	return res, positions
+}
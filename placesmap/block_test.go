@@ -0,0 +1,72 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+)
+
+func TestEachInline(t *testing.T) {
+	tpl := newTemplate("list.html", "<ul><@-each items@><li><@name@>:<@-each colors@>(<@name@>)<@-end@></li><@-end@></ul><@-each open@>x")
+	m := map[string]places.Mapper{"items": list{keyed("a"), keyed("b")}, "colors": list{keyed("r"), keyed("g")}, "open": list{keyed("c")}}
+
+	got, err := tpl.NewMapper(m).MapStrict("-require list.html")
+	if exp := "<ul><li>a:(r)(g)</li><li>b:(r)(g)</li></ul>x"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if err != MissingTemplateError("each") {
+		t.Errorf("expected MissingTemplateError for the unclosed block, got %v", err)
+	}
+
+	tpl.Override("list.html", []byte("<@-each items@>[<@name@>]<@-end@>"))
+	if got := tpl.NewMapper(m).Map("-require list.html"); got != "[a][b]" {
+		t.Errorf("unexpected result for override: %#v", got)
+	}
+}
+
+func TestInlineBlocks(t *testing.T) {
+	tpl := newTemplate("page.html", "<@-if admin@>admin<@-else@>user<@-end@>|"+
+		"<@-with user@><@name@>, <@address.city@><@-end@>|"+
+		"<@-with missing@>x<@-else@>no user<@-end@>|"+
+		"<@-each items@><@name@><@-else@>no items<@-end@>")
+
+	m := map[string]places.Mapper{
+		"admin": String("true"),
+		"user":  Nested{"name": "<Tom>", "address": map[string]interface{}{"city": "Berlin"}},
+		"items": list{},
+	}
+	if got, exp := tpl.NewMapper(m).Map("-require page.html"), "admin|&lt;Tom&gt;, Berlin|no user|no items"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	m["admin"] = String("")
+	got, err := tpl.NewMapper(m).MapStrict("-require page.html")
+	if exp := "user|&lt;Tom&gt;, Berlin|no user|no items"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, d := range []string{"if", "with"} {
+		if _, err := tpl.NewMapper(m).MapStrict("-" + d + " admin"); err != MissingTemplateError(d) {
+			t.Errorf("expected MissingTemplateError for %s, got %v", d, err)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	rs := NewReadSeekerMap()
+	rs.Add("doc.html", strings.NewReader(`<p><@title@></p><pre><@-literal@><@-each users@><@name@><@-end@></pre><p>\<@title@></p><@-each users@><@-literal@><@x@><@-end@><@-end@>`))
+	rs.Add("open.html", strings.NewReader(`<@-literal@><@title@>`))
+	tpl := NewHTMLTemplate(rs)
+	m := tpl.NewMapper(map[string]places.Mapper{"title": String("Docs"), "users": list{keyed("a"), keyed("b")}})
+
+	exp := `<p>Docs</p><pre><@-each users@><@name@></pre><p><@title@></p><@x@><@x@>`
+	if got := m.Map("-require doc.html"); got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if got := m.Map("-require open.html"); got != "Docs" {
+		t.Errorf("unclosed literal: unexpected result: %#v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package placesmap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/metakeule/places"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// InvalidScopeError is returned by the function of FuncMap for unsupported scopes
+type InvalidScopeError struct {
+	Template string
+	Scope    interface{}
+}
+
+func (i InvalidScopeError) Error() string {
+	return fmt.Sprintf("invalid scope of type %T for template %#v", i.Scope, i.Template)
+}
+
+// GoTemplates renders templates of html/template within places templates, so that codebases can be
+// migrated gradually. Registered via Register, the directive
+//
+//	-go sidebar
+//
+// executes the template sidebar of Templates. The data of the template is returned by Data,
+// nil means MapperData, so that the template gets the values of the mappers via {{.Get "title"}}.
+// The output is not escaped again, since html/template escapes contextually.
+// Errors are reported to MapStrict and render as empty string.
+type GoTemplates struct {
+	Templates *htmltemplate.Template
+	Data      func(c *RenderContext) interface{}
+}
+
+// Register registers the "-go" directive for the given HTMLTemplate
+func (g *GoTemplates) Register(t *HTMLTemplate) error {
+	return t.Handle("go", g.execute)
+}
+
+// execute handles "-go name"
+func (g *GoTemplates) execute(c *RenderContext, name string) string {
+	var data interface{} = MapperData{c}
+	if g.Data != nil {
+		data = g.Data(c)
+	}
+	var bf bytes.Buffer
+	if err := g.Templates.ExecuteTemplate(&bf, name, data); err != nil {
+		c.Fail(err)
+		return ""
+	}
+	return bf.String()
+}
+
+// MapperData is the data for templates of html/template that are rendered via GoTemplates
+type MapperData struct {
+	c *RenderContext
+}
+
+// Get returns the unescaped value for the given name (see RenderContext.Value)
+func (m MapperData) Get(name string) string {
+	return m.c.Value(name)
+}
+
+// Include renders the places template with the given name within the current scope
+func (m MapperData) Include(name string) htmltemplate.HTML {
+	return htmltemplate.HTML(m.c.Require(name))
+}
+
+// FuncMap returns the function "places" for text/template and html/template (after conversion to
+// html/template.FuncMap), that renders places templates with the mappers of h:
+//
+//	{{places "teaser.html"}}
+//	{{places "row.html" .}}
+//
+// The optional second argument is the scope of the template: a places.Mapper is used as it is,
+// a map[string]string or map[string]interface{} as HTML escaped values (see ComponentParams).
+// The result is of type html/template.HTML, so that html/template does not escape it again.
+func (h *HTMLTemplateMapper) FuncMap() texttemplate.FuncMap {
+	return texttemplate.FuncMap{"places": h.include}
+}
+
+// include renders the template with the given name for FuncMap
+func (h *HTMLTemplateMapper) include(name string, scope ...interface{}) (htmltemplate.HTML, error) {
+	if len(scope) > 1 {
+		return "", InvalidScopeError{Template: name, Scope: scope}
+	}
+
+	r := h.newRender()
+	if _, ok := r.lookup(name); !ok {
+		if r.err != nil {
+			return "", r.err
+		}
+		return "", TemplateNotFoundError(name)
+	}
+
+	var res string
+	if len(scope) == 0 {
+		res = r.Require(name)
+	} else {
+		var m places.Mapper
+		switch v := scope[0].(type) {
+		case places.Mapper:
+			m = v
+		case map[string]string:
+			m = ComponentParams(v)
+		case map[string]interface{}:
+			p := ComponentParams{}
+			for k, val := range v {
+				p[k] = fmt.Sprint(val)
+			}
+			m = p
+		default:
+			return "", InvalidScopeError{Template: name, Scope: v}
+		}
+		res = r.RequireWith(name, m)
+	}
+	return htmltemplate.HTML(res), r.err
+}
@@ -0,0 +1,49 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	htmltemplate "html/template"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+)
+
+func TestGoTemplates(t *testing.T) {
+	tpl := newTemplate("page.html", "<@-go sidebar@>|<@-go missing@>", "teaser.html", "<@title@>")
+	g := &GoTemplates{Templates: htmltemplate.Must(htmltemplate.New("sidebar").Parse(`<a title="{{.Get "title"}}">{{.Include "teaser.html"}}</a>`))}
+	if err := g.Register(tpl); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tpl.NewMapper(map[string]places.Mapper{"title": String(`"a" & b`)}).MapStrict("-require page.html")
+	if exp := `<a title="&#34;a&#34; &amp; b">&#34;a&#34; &amp; b</a>|`; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if err == nil {
+		t.Errorf("expected error for missing template")
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	tpl := newTemplate("teaser.html", "<@title@>", "row.html", "<@name@>")
+	fm := tpl.NewMapper(map[string]places.Mapper{"title": String("<b>")}).FuncMap()
+
+	ht := htmltemplate.Must(htmltemplate.New("page").Funcs(htmltemplate.FuncMap(fm)).Parse(
+		`{{places "teaser.html"}}|{{places "row.html" .}}`))
+	var bf strings.Builder
+	if err := ht.Execute(&bf, map[string]string{"name": "<i>"}); err != nil {
+		t.Fatal(err)
+	}
+	if exp := "&lt;b&gt;|&lt;i&gt;"; bf.String() != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", bf.String(), exp)
+	}
+
+	tt := texttemplate.Must(texttemplate.New("page").Funcs(fm).Parse(`{{places "missing.html"}}`))
+	if err := tt.Execute(&bf, nil); err == nil || !strings.Contains(err.Error(), TemplateNotFoundError("missing.html").Error()) {
+		t.Errorf("expected TemplateNotFoundError, got %v", err)
+	}
+	tt = texttemplate.Must(texttemplate.New("page").Funcs(fm).Parse(`{{places "row.html" 1}}`))
+	if err := tt.Execute(&bf, nil); err == nil || !strings.Contains(err.Error(), "invalid scope of type int") {
+		t.Errorf("expected InvalidScopeError, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package placesmap
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// BundleVersionFile is the name of the file in the root of a bundle that contains the version of the bundle
+var BundleVersionFile = "VERSION"
+
+var ErrUnknownBundleFormat = errors.New("unknown bundle format, expected .zip or .tar.gz")
+
+// BundleLoader loads templates with a given file extension from a bundle, i.e. a zip or gzip compressed
+// tar archive, so that template sets can be shipped and swapped as single artifacts.
+// The names of the templates are their slash separated paths within the bundle.
+// The format is detected by the content, not by the file name.
+type BundleLoader struct {
+	*ReadSeekerMap
+	path      string    // path of the bundle, if rd is nil
+	rd        io.Reader // reader of the bundle
+	extension string
+	version   string
+}
+
+// NewBundleLoader returns a BundleLoader for the bundle at the given path
+func NewBundleLoader(path string, extension string) *BundleLoader {
+	return &BundleLoader{path: path, extension: extension}
+}
+
+// NewBundleReaderLoader returns a BundleLoader that reads the bundle from the given reader
+func NewBundleReaderLoader(rd io.Reader, extension string) *BundleLoader {
+	return &BundleLoader{rd: rd, extension: extension}
+}
+
+// Version returns the version of the loaded bundle. It is the trimmed content of the BundleVersionFile
+// or, if there is none, the comment of a zip archive.
+func (b *BundleLoader) Version() string {
+	return b.version
+}
+
+// Load reads the bundle and returns the templates within
+func (b *BundleLoader) Load() (*ReadSeekerMap, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if b.rd != nil {
+		data, err = ioutil.ReadAll(b.rd)
+	} else {
+		data, err = ioutil.ReadFile(b.path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.ReadSeekerMap = NewReadSeekerMap()
+	b.version = ""
+
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		err = b.loadZip(data)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		err = b.loadTarGz(data)
+	default:
+		err = ErrUnknownBundleFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.ReadSeekerMap, nil
+}
+
+// add adds the file with the given path within the bundle, if it has the extension of the loader
+// or is the version file. Paths that point outside of the bundle are ignored.
+func (b *BundleLoader) add(name string, rd io.Reader) error {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	if name == "." || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return nil
+	}
+
+	isVersion := name == BundleVersionFile
+	if !isVersion && path.Ext(name) != b.extension {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return err
+	}
+
+	if isVersion {
+		b.version = strings.TrimSpace(string(data))
+		return nil
+	}
+	return b.ReadSeekerMap.Add(name, bytes.NewReader(data))
+}
+
+func (b *BundleLoader) loadZip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rd, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = b.add(f.Name, rd)
+		rd.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.version == "" {
+		b.version = strings.TrimSpace(zr.Comment)
+	}
+	return nil
+}
+
+func (b *BundleLoader) loadTarGz(data []byte) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := b.add(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package placesmap
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+var bundleFiles = map[string]string{
+	"VERSION":         "1.2.0\n",
+	"page.html":       "<@-require parts/head.html@>body",
+	"parts/head.html": "head",
+	"notes.txt":       "ignored",
+	"../evil.html":    "ignored",
+}
+
+func zipBundle(t *testing.T) []byte {
+	var bf bytes.Buffer
+	zw := zip.NewWriter(&bf)
+	for name, content := range bundleFiles {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bf.Bytes()
+}
+
+func tarGzBundle(t *testing.T) []byte {
+	var bf bytes.Buffer
+	gz := gzip.NewWriter(&bf)
+	tw := tar.NewWriter(gz)
+	for name, content := range bundleFiles {
+		tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	return bf.Bytes()
+}
+
+func TestBundleLoader(t *testing.T) {
+	bundles := map[string][]byte{
+		"zip":    zipBundle(t),
+		"tar.gz": tarGzBundle(t),
+	}
+
+	for format, data := range bundles {
+		l := NewBundleReaderLoader(bytes.NewReader(data), ".html")
+		rs, err := l.Load()
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		if l.Version() != "1.2.0" {
+			t.Errorf("%s: unexpected version: %#v", format, l.Version())
+		}
+
+		if len(rs.m) != 2 {
+			t.Errorf("%s: unexpected templates: %v", format, rs.m)
+		}
+
+		got := NewHTMLTemplate(rs).NewMapper(nil).Map("-require page.html")
+		if got != "headbody" {
+			t.Errorf("%s: unexpected result: %#v", format, got)
+		}
+	}
+
+	if _, err := NewBundleReaderLoader(bytes.NewReader([]byte("plain")), ".html").Load(); err != ErrUnknownBundleFormat {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package placesmap
+
+import (
+	"encoding/gob"
+	"errors"
+	"github.com/metakeule/places"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// cacheVersion is the version of the cache format, caches of other versions are rejected
+const cacheVersion = 2
+
+var ErrCacheVersion = errors.New("cache has an unsupported version")
+
+// cache is the content of a cache file
+type cache struct {
+	Version   int
+	Templates map[string]*places.Template
+	Meta      map[string]Meta
+}
+
+// SaveCache writes the parsed templates and their metadata to the file at the given path,
+// so that they can be loaded via LoadCache without reading and parsing them again.
+// Overrides are not saved. The file is replaced atomically.
+func (h *HTMLTemplate) SaveCache(path string) error {
+	h.RLock()
+	c := cache{Version: cacheVersion, Templates: h.rsm, Meta: h.meta}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		h.RUnlock()
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(c)
+	h.RUnlock()
+
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// LoadCache replaces the templates and their metadata by the ones of the cache file at the given path,
+// that has been written by SaveCache. Overrides are kept.
+func (h *HTMLTemplate) LoadCache(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var c cache
+	if err := gob.NewDecoder(f).Decode(&c); err != nil {
+		return err
+	}
+	if c.Version != cacheVersion {
+		return ErrCacheVersion
+	}
+	if c.Templates == nil {
+		c.Templates = map[string]*places.Template{}
+	}
+	if c.Meta == nil {
+		c.Meta = map[string]Meta{}
+	}
+
+	var report LoadReport
+	for k := range c.Templates {
+		report.Loaded = append(report.Loaded, k)
+	}
+	sort.Strings(report.Loaded)
+
+	h.Lock()
+	h.rsm, h.meta, h.report = c.Templates, c.Meta, report
+	h.Unlock()
+	return nil
+}
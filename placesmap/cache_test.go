@@ -0,0 +1,45 @@
+package placesmap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placesmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "templates.cache")
+
+	tpl := newTemplate(
+		"page.html", "---\ntitle: Welcome\n---\n<@-require part.html@><script></script>",
+		"part.html", "part",
+	)
+	if err := tpl.SaveCache(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewHTMLTemplate(NewReadSeekerMap())
+	if err := loaded.LoadCache(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := loaded.NewMapper(nil).Map("-require page.html"), "part<script></script>"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if m, _ := loaded.Meta("page.html"); m.Title != "Welcome" {
+		t.Errorf("unexpected meta: %#v", m)
+	}
+	if got := loaded.LoadReport().Loaded; len(got) != 2 {
+		t.Errorf("unexpected report: %v", got)
+	}
+
+	ioutil.WriteFile(path, []byte("invalid"), 0644)
+	if err := loaded.LoadCache(path); err == nil {
+		t.Errorf("expected error for invalid cache")
+	}
+}
@@ -0,0 +1,336 @@
+package placesmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/metakeule/places"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// TypedMapper is a places.Mapper that also provides the typed values behind the strings.
+type TypedMapper interface {
+	places.Mapper
+
+	// Value returns the typed value for the given key, nil if there is none
+	Value(key string) interface{}
+}
+
+// maxCalcLength is the maximum length of an expression for "-calc"
+const maxCalcLength = 256
+
+var (
+	ErrCalcSyntax     = errors.New("syntax error in expression")
+	ErrCalcTooLong    = errors.New("expression too long")
+	ErrDivisionByZero = errors.New("division by zero")
+)
+
+type CalcTypeError string
+
+func (c CalcTypeError) Error() string {
+	return fmt.Sprintf("invalid operands for %#v", c)
+}
+
+// calc handles "-calc expression"
+// The expression may consist of numbers, strings in single or double quotes, names of mappers,
+// the operators + - * / % and parentheses. Adding strings concatenates them, adding a string and
+// a number concatenates the string and the formatted number.
+// Names are resolved via the current element of a loop and the registered mappers. Values of
+// TypedMappers are used as they are, all other values are used as numbers if they can be parsed
+// as such. The result is HTML escaped. If the expression is invalid, the empty string is returned
+// and the error is reported to MapStrict.
+func (r *RenderContext) calc(expr string) string {
+	v, err := evalCalc(expr, r.typedValue)
+	if err != nil {
+		r.fail(err)
+		return ""
+	}
+	return html.EscapeString(formatCalc(v))
+}
+
+// TypedValue returns the typed value for the given name (see TypedMapper). The current loop scope
+// is consulted before the registered mappers. Mappers that are no TypedMappers return their string value.
+func (r *RenderContext) TypedValue(name string) interface{} {
+	return r.typedValue(name)
+}
+
+// typedValue resolves names for calc
+func (r *RenderContext) typedValue(name string) interface{} {
+	if r.preferred != nil {
+		if tm, is := r.preferred.(TypedMapper); is {
+			if v := tm.Value(name); v != nil {
+				return v
+			}
+		} else if v := r.preferred.Map(name); v != "" {
+			return v
+		}
+	}
+
+	mp, ok := r.mapper(name)
+	if !ok {
+		return nil
+	}
+	if tm, is := mp.(TypedMapper); is {
+		return r.typedMapValue(tm, name)
+	}
+	return r.mapValue(mp, name)
+}
+
+// typedMapValue returns the typed value of the given mapper for the given key like mapValue,
+// respecting the context and the placeholder timeout. Strings are normalized and guarded like the
+// values of mapValue, the other values are not memoized.
+func (r *RenderContext) typedMapValue(tm TypedMapper, key string) interface{} {
+	v, ok := r.timed(key, func(context.Context) interface{} {
+		return tm.Value(key)
+	})
+	if !ok {
+		return r.timeouts.Fallback
+	}
+	if s, is := v.(string); is {
+		return r.guardLength(key, r.norm.normalize(s))
+	}
+	return v
+}
+
+// evalCalc evaluates the given expression, resolving names via the given resolver
+func evalCalc(expr string, resolve func(string) interface{}) (interface{}, error) {
+	if len(expr) > maxCalcLength {
+		return nil, ErrCalcTooLong
+	}
+	p := &calcParser{input: expr, resolve: resolve}
+	v, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.input) {
+		return nil, ErrCalcSyntax
+	}
+	return v, nil
+}
+
+// calcPrecision is the number of significant digits of the results of calc, so that the errors
+// of the binary floating point numbers are not shown, e.g. 0.3 for 0.1 + 0.2
+const calcPrecision = 15
+
+func formatCalc(v interface{}) string {
+	switch x := v.(type) {
+	case float64:
+		rounded, _ := strconv.ParseFloat(strconv.FormatFloat(x, 'g', calcPrecision, 64), 64)
+		return strconv.FormatFloat(rounded, 'f', -1, 64)
+	case string:
+		return x
+	}
+	return ""
+}
+
+// toCalc converts the given value to a float64 or a string
+func toCalc(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return x
+	case float32:
+		return float64(x)
+	case int:
+		return float64(x)
+	case int8:
+		return float64(x)
+	case int16:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case uint:
+		return float64(x)
+	case uint8:
+		return float64(x)
+	case uint16:
+		return float64(x)
+	case uint32:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	case bool:
+		if x {
+			return 1.0
+		}
+		return 0.0
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(x), 64); err == nil {
+			return f
+		}
+		return x
+	case fmt.Stringer:
+		return x.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// calcParser is a recursive descent parser that evaluates while parsing
+type calcParser struct {
+	input   string
+	pos     int
+	resolve func(string) interface{}
+}
+
+func (p *calcParser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *calcParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// expr = term { ("+" | "-") term }
+func (p *calcParser) expr() (interface{}, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = calcOp(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// term = unary { ("*" | "/" | "%") unary }
+func (p *calcParser) term() (interface{}, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = calcOp(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// unary = ["-"] factor
+func (p *calcParser) unary() (interface{}, error) {
+	if p.peek() == '-' {
+		p.pos++
+		v, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		f, ok := v.(float64)
+		if !ok {
+			return nil, CalcTypeError("-")
+		}
+		return -f, nil
+	}
+	return p.factor()
+}
+
+// factor = number | string | name | "(" expr ")"
+func (p *calcParser) factor() (interface{}, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		v, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, ErrCalcSyntax
+		}
+		p.pos++
+		return v, nil
+	case c == '"' || c == '\'':
+		end := strings.IndexByte(p.input[p.pos+1:], c)
+		if end == -1 {
+			return nil, ErrCalcSyntax
+		}
+		s := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return s, nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, ErrCalcSyntax
+		}
+		return f, nil
+	case isNameChar(c, true):
+		start := p.pos
+		for p.pos < len(p.input) && isNameChar(p.input[p.pos], false) {
+			p.pos++
+		}
+		return toCalc(p.resolve(p.input[start:p.pos])), nil
+	}
+	return nil, ErrCalcSyntax
+}
+
+func isNameChar(c byte, first bool) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' {
+		return true
+	}
+	return !first && (c >= '0' && c <= '9' || c == '.')
+}
+
+func calcOp(op byte, left, right interface{}) (interface{}, error) {
+	l, lnum := left.(float64)
+	r, rnum := right.(float64)
+
+	if !lnum || !rnum {
+		if op == '+' {
+			return formatCalc(left) + formatCalc(right), nil
+		}
+		return nil, CalcTypeError(string(op))
+	}
+
+	switch op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return l / r, nil
+	case '%':
+		if int64(r) == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return float64(int64(l) % int64(r)), nil
+	}
+	return nil, ErrCalcSyntax
+}
@@ -0,0 +1,104 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+	"time"
+)
+
+// typed is a TypedMapper for the tests
+type typed map[string]interface{}
+
+func (t typed) Map(key string) string        { return formatCalc(toCalc(t[key])) }
+func (t typed) Value(key string) interface{} { return t[key] }
+
+func TestEvalCalc(t *testing.T) {
+	vals := map[string]interface{}{"price": 2.5, "quantity": 4, "name": "Duck", "count": "3"}
+	resolve := func(name string) interface{} { return vals[name] }
+
+	tests := map[string]string{
+		"price * quantity":       "10",
+		"1 + 2 * 3":              "7",
+		"(1 + 2) * 3":            "9",
+		"-price + 1":             "-1.5",
+		"count * 2":              "6",
+		"7 % 4":                  "3",
+		"'Mr. ' + name":          "Mr. Duck",
+		`name + " x" + quantity`: "Duck x4",
+		"quantity / (price * 0)": "error",
+		"name * 2":               "error",
+		"1 +":                    "error",
+		"(1":                     "error",
+		"1 2":                    "error",
+		"7 % 0.5":                "error",
+		"0.1 + 0.2":              "0.3",
+		"1 / 3":                  "0.333333333333333",
+		"price * 1000000":        "2500000",
+	}
+
+	for expr, exp := range tests {
+		v, err := evalCalc(expr, resolve)
+		got := formatCalc(v)
+		if err != nil {
+			got = "error"
+		}
+		if got != exp {
+			t.Errorf("%#v: unexpected result: %#v, expected: %#v", expr, got, exp)
+		}
+	}
+}
+
+func TestCalc(t *testing.T) {
+	tpl := newTemplate("cart.html", "<@-each items row.html@>", "row.html", "<@-calc price * quantity@>;")
+	items := list{typed{"price": 2, "quantity": 3}, typed{"price": 1.5, "quantity": 2}}
+	got := tpl.NewMapper(map[string]places.Mapper{"items": items}).Map("-require cart.html")
+
+	if exp := "6;3;"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	got = tpl.NewMapper(map[string]places.Mapper{"a": String("<b>")}).Map("-calc a + 'c'")
+	if exp := "&lt;b&gt;c"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	// the operands are resolved like placeholders
+	tpl.SetTimeouts(Timeouts{Placeholder: 10 * time.Millisecond, Fallback: "1"})
+	got = tpl.NewMapper(map[string]places.Mapper{"a": slow{}, "b": typed{"b": 2}}).Map("-calc a + b")
+	if exp := "3"; got != exp {
+		t.Errorf("unexpected result with timeout: %#v, expected: %#v", got, exp)
+	}
+	tpl.SetTimeouts(Timeouts{})
+
+	for input, exp := range map[string]error{"-calc 1 +": ErrCalcSyntax, "-calc " + strings.Repeat("1+", maxCalcLength): ErrCalcTooLong} {
+		if got, err := tpl.NewMapper(nil).MapStrict(input); got != "" || err != exp {
+			t.Errorf("%.20s: expected %v, got %#v, %v", input, exp, got, err)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", `<@-set fullTitle title + " - " + site@><title><@fullTitle@></title><@-require h1.html@><@-set n count * 2@><@-calc n + 1@><@-each items@>[<@name@>]<@-end@>`,
+		"h1.html", `<h1 title="<@-attr fullTitle@>"><@fullTitle@></h1>`,
+	)
+	m := map[string]places.Mapper{"title": String("<Home>"), "site": String("Shop"), "count": typed{"count": 2}, "items": list{keyed("a")}}
+
+	got, err := tpl.NewMapper(m).WithMemo().MapStrict("-require page.html")
+	exp := `<title>&lt;Home&gt; - Shop</title><h1 title="&lt;Home&gt;&#32;-&#32;Shop">&lt;Home&gt; - Shop</h1>5[a]`
+	if got != exp || err != nil {
+		t.Errorf("unexpected result: %#v, %v, expected: %#v", got, err, exp)
+	}
+
+	tpl = newTemplate("page.html", `<@-set name "local"@><@name@>|<@-each items@><@name@><@-end@>`)
+	if got, exp := tpl.NewMapper(m).Map("-require page.html"), "local|a"; got != exp {
+		t.Errorf("the loop scope must take precedence, got %#v, expected %#v", got, exp)
+	}
+
+	for input, exp := range map[string]error{`-set "x" 1`: InvalidLocalError(`"x"`), "-set x 1 +": ErrCalcSyntax} {
+		if _, err := tpl.NewMapper(m).MapStrict(input); err != exp {
+			t.Errorf("%s: expected %v, got %v", input, exp, err)
+		}
+	}
+}
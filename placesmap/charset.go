@@ -0,0 +1,115 @@
+package placesmap
+
+import (
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Charset is the encoding of rendered output.
+// Templates and mapper values are UTF-8; the encoder transcodes everything that is written
+// by the render helpers, so that placeholder values are transcoded as well.
+//
+// Encoders of golang.org/x/text/encoding can be used as well, e.g.
+//
+//	placesmap.Charset{
+//		Name:       "Shift_JIS",
+//		NewEncoder: encoding.HTMLEscapeUnsupported(japanese.ShiftJIS.NewEncoder()).Writer,
+//	}
+type Charset struct {
+	// Name is the name of the charset as used within the Content-Type header
+	Name string
+
+	// NewEncoder returns a writer that encodes the UTF-8 written to it and writes the result to w.
+	// Characters that can't be represented should be written as HTML character references.
+	// If NewEncoder is nil, the output is written unchanged.
+	NewEncoder func(w io.Writer) io.Writer
+}
+
+// UTF8 is the default charset
+var UTF8 = Charset{Name: "utf-8"}
+
+// Latin1 encodes ISO-8859-1, characters beyond are written as HTML character references
+var Latin1 = Charset{Name: "ISO-8859-1", NewEncoder: func(w io.Writer) io.Writer {
+	return &runeEncoder{w: w, max: 0xFF}
+}}
+
+// ASCII encodes US-ASCII, characters beyond are written as HTML character references
+var ASCII = Charset{Name: "US-ASCII", NewEncoder: func(w io.Writer) io.Writer {
+	return &runeEncoder{w: w, max: 0x7F}
+}}
+
+// encoder returns a writer that encodes to the charset
+func (c Charset) encoder(w io.Writer) io.Writer {
+	if c.NewEncoder == nil {
+		return w
+	}
+	return c.NewEncoder(w)
+}
+
+// contentType returns the Content-Type header for HTML in the charset
+func (c Charset) contentType() string {
+	name := c.Name
+	if name == "" {
+		name = UTF8.Name
+	}
+	return "text/html; charset=" + name
+}
+
+// runeEncoder encodes runes up to max as single bytes and writes HTML character references for the others
+type runeEncoder struct {
+	w       io.Writer
+	max     rune
+	partial []byte // an incomplete rune of the last write
+}
+
+func (e *runeEncoder) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(e.partial) > 0 {
+		p = append(e.partial, p...)
+		e.partial = nil
+	}
+
+	out := make([]byte, 0, len(p))
+	for len(p) > 0 {
+		if p[0] < utf8.RuneSelf {
+			out = append(out, p[0])
+			p = p[1:]
+			continue
+		}
+
+		if !utf8.FullRune(p) {
+			e.partial = append([]byte(nil), p...)
+			break
+		}
+
+		r, size := utf8.DecodeRune(p)
+		p = p[size:]
+		if r <= e.max && r != utf8.RuneError {
+			out = append(out, byte(r))
+			continue
+		}
+		out = append(out, "&#"...)
+		out = strconv.AppendInt(out, int64(r), 10)
+		out = append(out, ';')
+	}
+
+	if _, err := e.w.Write(out); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// SetCharset sets the charset that is used by the render helpers (Render, Negotiate)
+func (h *HTMLTemplate) SetCharset(c Charset) {
+	h.Lock()
+	h.charset = c
+	h.Unlock()
+}
+
+// Charset returns the charset of the render helpers
+func (h *HTMLTemplate) Charset() Charset {
+	h.RLock()
+	defer h.RUnlock()
+	return h.charset
+}
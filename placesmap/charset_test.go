@@ -0,0 +1,38 @@
+package placesmap
+
+import (
+	"bytes"
+	"github.com/metakeule/places"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRenderCharset(t *testing.T) {
+	tpl := newTemplate("page.html", "Grüße, <@name@>!")
+	tpl.SetCharset(Latin1)
+
+	rec := httptest.NewRecorder()
+	if err := Render(rec, tpl, "page.html", map[string]places.Mapper{"name": String("Zoë ☃")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := rec.Header().Get("Content-Type"), "text/html; charset=ISO-8859-1"; got != exp {
+		t.Errorf("unexpected content type: %#v, expected: %#v", got, exp)
+	}
+
+	if got, exp := rec.Body.String(), "Gr\xfc\xdfe, Zo\xeb &#9731;!"; got != exp {
+		t.Errorf("unexpected body: %#v, expected: %#v", got, exp)
+	}
+}
+
+func TestRuneEncoderPartialWrites(t *testing.T) {
+	var bf bytes.Buffer
+	w := ASCII.NewEncoder(&bf)
+	b := []byte("ä€")
+	for i := range b {
+		w.Write(b[i : i+1])
+	}
+	if got, exp := bf.String(), "&#228;&#8364;"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,135 @@
+package placesmap
+
+import (
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClockKey is the name under which the ClockMapper of a request is registered
+const ClockKey = "clock"
+
+// layouts are the named layouts for "-time"
+var layouts = map[string]string{
+	"date":     "2006-01-02",
+	"time":     "15:04",
+	"datetime": "2006-01-02 15:04",
+	"rfc3339":  time.RFC3339,
+	"kitchen":  time.Kitchen,
+}
+
+// ClockMapper provides the current time and converts times to the time zone of a user.
+// It is registered per request as ClockKey and used by the directive
+//
+//	-time name layout
+//
+// that renders the time value for name converted to the Location and formatted with layout.
+// name is "now" for the current time or the name of a mapper, whose typed value (see TypedMapper)
+// is a time.Time, a *time.Time, a unix timestamp (int64) or a string in RFC 3339 format.
+// layout is a layout of the time package or one of date, time, datetime, rfc3339 and kitchen;
+// it defaults to the Layout of the ClockMapper. Without ClockMapper UTC is used.
+type ClockMapper struct {
+	Location *time.Location   // nil means UTC
+	Layout   string           // default layout, "" means "2006-01-02 15:04"
+	Now      func() time.Time // nil means time.Now
+}
+
+func (c *ClockMapper) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *ClockMapper) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+// format formats t in the location of the clock with the given layout
+func (c *ClockMapper) format(t time.Time, layout string) string {
+	if layout == "" {
+		layout = c.Layout
+	}
+	if layout == "" {
+		layout = layouts["datetime"]
+	}
+	if l, has := layouts[layout]; has {
+		layout = l
+	}
+	return t.In(c.location()).Format(layout)
+}
+
+// Map returns the formatted current time for every key
+func (c *ClockMapper) Map(string) string {
+	return c.format(c.now(), "")
+}
+
+// Value returns the current time in the location of the clock for every key
+func (c *ClockMapper) Value(string) interface{} {
+	return c.now().In(c.location())
+}
+
+// toTime converts the given typed value to a time
+func toTime(v interface{}) (time.Time, bool) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, true
+	case *time.Time:
+		if x != nil {
+			return *x, true
+		}
+	case int64:
+		return time.Unix(x, 0), true
+	case int:
+		return time.Unix(int64(x), 0), true
+	case string:
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(x)); err == nil {
+			return t, true
+		}
+		if n, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64); err == nil {
+			return time.Unix(n, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// time handles "-time name layout"
+func (r *RenderContext) time(rest string) string {
+	s := strings.SplitN(strings.TrimSpace(rest), " ", 2)
+	if s[0] == "" {
+		return ""
+	}
+	var layout string
+	if len(s) == 2 {
+		layout = strings.TrimSpace(s[1])
+	}
+
+	clock := &ClockMapper{}
+	if mp, ok := r.mapper(ClockKey); ok {
+		if c, is := mp.(*ClockMapper); is {
+			clock = c
+		}
+	}
+
+	if s[0] == "now" {
+		now := clock.now()
+		if r.options.Deterministic {
+			now = r.Now()
+		}
+		return html.EscapeString(clock.format(now, layout))
+	}
+
+	t, err := Time(r, s[0])
+	if err != nil {
+		if !errors.Is(err, ErrNoValue) {
+			r.fail(err)
+		}
+		return ""
+	}
+	return html.EscapeString(clock.format(t, layout))
+}
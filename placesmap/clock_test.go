@@ -0,0 +1,45 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	now := time.Date(2020, 6, 1, 10, 30, 0, 0, time.UTC)
+	clock := &ClockMapper{Location: berlin, Now: func() time.Time { return now }}
+
+	tpl := newTemplate()
+	m := tpl.NewMapper(map[string]places.Mapper{
+		ClockKey:  clock,
+		"now":     clock,
+		"created": typed{"created": now.Add(-time.Hour)},
+		"updated": String("2020-01-01T12:00:00Z"),
+	})
+
+	tests := map[string]string{
+		"now":                            "2020-06-01 12:30",
+		"-time now":                      "2020-06-01 12:30",
+		"-time now date":                 "2020-06-01",
+		"-time now 02.01.2006 15:04 MST": "01.06.2020 12:30 CEST",
+		"-time created time":             "11:30",
+		"-time updated":                  "2020-01-01 13:00",
+		"-time missing":                  "",
+	}
+
+	for input, exp := range tests {
+		if got := m.Map(input); got != exp {
+			t.Errorf("%#v: unexpected result: %#v, expected: %#v", input, got, exp)
+		}
+	}
+
+	got := tpl.NewMapper(map[string]places.Mapper{"updated": String("2020-01-01T12:00:00Z")}).Map("-time updated")
+	if exp := "2020-01-01 12:00"; got != exp {
+		t.Errorf("unexpected result without ClockMapper: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,149 @@
+package placesmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/metakeule/places"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoValue is the cause of a CoercionError for keys without value
+var ErrNoValue = errors.New("no value")
+
+// CoercionError is the error of Int, Float, Bool and Time for values that can't be converted
+type CoercionError struct {
+	Template string // the template that requested the value, empty outside of renders
+	Key      string
+	Value    interface{}
+	Type     string // the requested type, i.e. int, float, bool or time
+	Err      error  // ErrNoValue or the error of the parsing, if any
+}
+
+func (c CoercionError) Error() string {
+	msg := fmt.Sprintf("value %#v of %#v is no %s", c.Value, c.Key, c.Type)
+	if c.Err == ErrNoValue {
+		msg = fmt.Sprintf("%#v has no value, expected %s", c.Key, c.Type)
+	}
+	if c.Template != "" {
+		msg = fmt.Sprintf("template %#v: %s", c.Template, msg)
+	}
+	return msg
+}
+
+// Unwrap returns the cause of the error
+func (c CoercionError) Unwrap() error {
+	return c.Err
+}
+
+// valueOf returns the typed value of m for the given key and the template that requests it.
+// For a RenderContext, the current loop scope is consulted first (see RenderContext.TypedValue).
+func valueOf(m places.Mapper, key string) (v interface{}, template string) {
+	switch x := m.(type) {
+	case *RenderContext:
+		if len(x.includes) > 0 {
+			template = blockOwner(x.includes[len(x.includes)-1])
+		}
+		v = x.typedValue(key)
+	case TypedMapper:
+		v = x.Value(key)
+	default:
+		v = m.Map(key)
+	}
+	if s, is := v.(string); is {
+		v = strings.TrimSpace(s)
+	}
+	return v, template
+}
+
+// coerce converts the value of m for the given key with fn, that returns the error of the parsing
+func coerce(m places.Mapper, key, typ string, fn func(v interface{}) error) error {
+	v, template := valueOf(m, key)
+	if v == nil || v == "" {
+		return CoercionError{Template: template, Key: key, Value: v, Type: typ, Err: ErrNoValue}
+	}
+	if err := fn(v); err != nil {
+		return CoercionError{Template: template, Key: key, Value: v, Type: typ, Err: err}
+	}
+	return nil
+}
+
+// errInvalid is the cause of CoercionErrors for typed values of the wrong type
+var errInvalid = errors.New("invalid type")
+
+// Int returns the value of m for the given key as int. The typed values of TypedMappers are used,
+// if they are integers or whole floats, strings are parsed. m may be the RenderContext of a prefix
+// handler, then the error names the template that requested the value.
+// It returns a CoercionError, if the value can't be converted.
+func Int(m places.Mapper, key string) (n int, err error) {
+	err = coerce(m, key, "int", func(v interface{}) error {
+		if s, is := v.(string); is {
+			i, err := strconv.ParseInt(s, 10, 0)
+			n = int(i)
+			return err
+		}
+		switch x := toCalc(v).(type) {
+		case float64:
+			if x != math.Trunc(x) || x > math.MaxInt || x < math.MinInt {
+				return errInvalid
+			}
+			n = int(x)
+		case string:
+			i, err := strconv.ParseInt(strings.TrimSpace(x), 10, 0)
+			n = int(i)
+			return err
+		}
+		return nil
+	})
+	return
+}
+
+// Float returns the value of m for the given key as float64, like Int does for ints
+func Float(m places.Mapper, key string) (f float64, err error) {
+	err = coerce(m, key, "float", func(v interface{}) error {
+		switch x := toCalc(v).(type) {
+		case float64:
+			f = x
+		case string:
+			var err error
+			f, err = strconv.ParseFloat(strings.TrimSpace(x), 64)
+			return err
+		}
+		return nil
+	})
+	return
+}
+
+// Bool returns the value of m for the given key as bool, like Int does for ints.
+// Strings are parsed via strconv.ParseBool.
+func Bool(m places.Mapper, key string) (b bool, err error) {
+	err = coerce(m, key, "bool", func(v interface{}) error {
+		switch x := v.(type) {
+		case bool:
+			b = x
+		case string:
+			var err error
+			b, err = strconv.ParseBool(x)
+			return err
+		default:
+			return errInvalid
+		}
+		return nil
+	})
+	return
+}
+
+// Time returns the value of m for the given key as time.Time, like Int does for ints.
+// Values may be a time.Time, a *time.Time, a unix timestamp or a string in RFC 3339 format.
+func Time(m places.Mapper, key string) (t time.Time, err error) {
+	err = coerce(m, key, "time", func(v interface{}) error {
+		var ok bool
+		if t, ok = toTime(v); !ok {
+			return errInvalid
+		}
+		return nil
+	})
+	return
+}
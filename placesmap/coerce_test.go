@@ -0,0 +1,55 @@
+package placesmap
+
+import (
+	"errors"
+	"github.com/metakeule/places"
+	"testing"
+	"time"
+)
+
+func TestCoercion(t *testing.T) {
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	m := typed{"n": 3, "whole": 4.0, "half": 4.5, "s": " 12 ", "f": "1.5", "yes": "true", "b": false, "when": when, "ts": "1714979289", "word": "abc"}
+
+	if n, err := Int(m, "n"); n != 3 || err != nil {
+		t.Errorf("unexpected int: %v, %v", n, err)
+	}
+	if n, err := Int(m, "whole"); n != 4 || err != nil {
+		t.Errorf("unexpected int: %v, %v", n, err)
+	}
+	if n, err := Int(m, "s"); n != 12 || err != nil {
+		t.Errorf("unexpected int: %v, %v", n, err)
+	}
+	if f, err := Float(m, "f"); f != 1.5 || err != nil {
+		t.Errorf("unexpected float: %v, %v", f, err)
+	}
+	if b, err := Bool(m, "yes"); !b || err != nil {
+		t.Errorf("unexpected bool: %v, %v", b, err)
+	}
+	if b, err := Bool(m, "b"); b || err != nil {
+		t.Errorf("unexpected bool: %v, %v", b, err)
+	}
+	if tm, err := Time(m, "when"); !tm.Equal(when) || err != nil {
+		t.Errorf("unexpected time: %v, %v", tm, err)
+	}
+	if tm, err := Time(String("2024-05-06T07:08:09Z"), "x"); !tm.Equal(when) || err != nil {
+		t.Errorf("unexpected time: %v, %v", tm, err)
+	}
+
+	if _, err := Int(m, "half"); err != (CoercionError{Key: "half", Value: 4.5, Type: "int", Err: errInvalid}) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := Float(m, "missing"); !errors.Is(err, ErrNoValue) {
+		t.Errorf("expected ErrNoValue, got %v", err)
+	}
+	_, err := Bool(m, "word")
+	if exp := `value "abc" of "word" is no bool`; err == nil || err.Error() != exp {
+		t.Errorf("unexpected error: %v, expected: %s", err, exp)
+	}
+
+	tpl := newTemplate("page.html", "<@-each items row.html@>", "row.html", "<@-time at date@>")
+	_, err = tpl.NewMapper(map[string]places.Mapper{"items": list{typed{"at": "soon"}}}).MapStrict("-require page.html")
+	if exp := `template "row.html": value "soon" of "at" is no time`; err == nil || err.Error() != exp {
+		t.Errorf("unexpected error: %v, expected: %s", err, exp)
+	}
+}
@@ -0,0 +1,98 @@
+package placesmap
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// compare returns the handler of a comparison directive like "-eq name value then.html else otherwise.html".
+// If the comparison of the value for name with value (see compareValues) satisfies ok,
+// then.html is rendered, otherwise otherwise.html. The else part is optional.
+// value may be quoted in Go syntax, values starting with $ are the values for the given name.
+func compare(ok func(cmp int) bool) PrefixHandler {
+	return func(r *RenderContext, rest string) string {
+		name, value, byName, then, otherwise, valid := splitCompare(rest)
+		if !valid {
+			return ""
+		}
+		var other interface{} = value
+		if byName {
+			other = r.typedValue(value)
+		}
+		if ok(compareValues(r.typedValue(name), other)) {
+			return r.require(then, r)
+		}
+		return r.require(otherwise, r)
+	}
+}
+
+// splitCompare splits the arguments of a comparison directive
+func splitCompare(rest string) (name, value string, byName bool, then, otherwise string, ok bool) {
+	rest = strings.TrimSpace(rest)
+	idx := strings.IndexAny(rest, " \t")
+	if idx == -1 {
+		return
+	}
+	name, rest = rest[:idx], strings.TrimLeft(rest[idx:], " \t")
+
+	if strings.HasPrefix(rest, `"`) {
+		end := closingQuote(rest)
+		if end == -1 {
+			return
+		}
+		var err error
+		if value, err = strconv.Unquote(rest[:end+1]); err != nil {
+			return
+		}
+		rest = rest[end+1:]
+	} else {
+		idx = strings.IndexAny(rest, " \t")
+		if idx == -1 {
+			return
+		}
+		value, rest = rest[:idx], rest[idx:]
+		if strings.HasPrefix(value, "$") {
+			value, byName = value[1:], true
+		}
+	}
+	then, otherwise = splitElse(rest)
+	return name, value, byName, then, otherwise, then != "" || otherwise != ""
+}
+
+// compareValues compares a and b and returns -1, 0 or 1. Times are compared as times,
+// if the other value can be converted to a time (see toTime), numbers and strings that are numbers
+// are compared as numbers, everything else is compared as strings.
+func compareValues(a, b interface{}) int {
+	_, aTime := a.(time.Time)
+	_, bTime := b.(time.Time)
+	if aTime || bTime {
+		if ta, ok := toTime(a); ok {
+			if tb, ok := toTime(b); ok {
+				switch {
+				case ta.Before(tb):
+					return -1
+				case ta.After(tb):
+					return 1
+				}
+				return 0
+			}
+		}
+	}
+
+	ca, cb := toCalc(a), toCalc(b)
+	fa, aNum := ca.(float64)
+	fb, bNum := cb.(float64)
+	if aNum && bNum {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+
+	sa, sb := formatCalc(ca), formatCalc(cb)
+	return strings.Compare(sa, sb)
+}
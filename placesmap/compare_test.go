@@ -0,0 +1,37 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	tpl := newTemplate("yes.html", "yes", "no.html", "no")
+	tests := []struct {
+		directive string
+		value     interface{}
+		exp       string
+	}{
+		{`-eq status active yes.html else no.html`, "active", "yes"},
+		{`-eq status active yes.html else no.html`, "inactive", "no"},
+		{`-eq status "in progress" yes.html`, "in progress", "yes"},
+		{`-ne status active yes.html else no.html`, "inactive", "yes"},
+		{`-gt count 9 yes.html else no.html`, 10, "yes"},
+		{`-gt count 9 yes.html else no.html`, "10", "yes"},
+		{`-gt count 10 yes.html else no.html`, 10, "no"},
+		{`-ge count 10 yes.html else no.html`, 10.0, "yes"},
+		{`-lt count $limit yes.html else no.html`, 3, "yes"},
+		{`-le name b yes.html else no.html`, "a", "yes"},
+		{`-lt due 2024-01-01T00:00:00Z yes.html else no.html`, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), "yes"},
+		{`-eq status`, "active", ""},
+	}
+
+	for _, test := range tests {
+		m := map[string]places.Mapper{"status": typed{"status": test.value}, "count": typed{"count": test.value},
+			"name": typed{"name": test.value}, "due": typed{"due": test.value}, "limit": String("5")}
+		if got := tpl.NewMapper(m).Map(test.directive); got != test.exp {
+			t.Errorf("%s with %v: unexpected result: %#v, expected: %#v", test.directive, test.value, got, test.exp)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package placesmap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ComponentDir is the default directory of the component templates
+const ComponentDir = "components/"
+
+type ComponentSyntaxError string
+
+func (c ComponentSyntaxError) Error() string {
+	return fmt.Sprintf("invalid component params %#v", string(c))
+}
+
+type MissingComponentParamError struct {
+	Component string
+	Param     string
+}
+
+func (m MissingComponentParamError) Error() string {
+	return fmt.Sprintf("param %#v of component %#v is missing", m.Param, m.Component)
+}
+
+// DefaultComponents are the templates of a small component set, that can be added via
+// ComponentMapper.AddDefaults. They serve as starting point for a design system.
+var DefaultComponents = map[string]string{
+	"button": `---
+type: button
+variant: primary
+required: [label]
+---
+<button type="<@type@>" class="btn btn-<@variant@>"><@label@></button>`,
+
+	"alert": `---
+type: info
+required: [msg]
+---
+<div class="alert alert-<@type@>" role="alert"><@msg@></div>`,
+
+	"modal": `---
+close: Close
+required: [id, title]
+---
+<div class="modal" id="<@id@>" role="dialog" aria-modal="true" aria-labelledby="<@id@>-title">` +
+		`<div class="modal-content"><h2 id="<@id@>-title"><@title@></h2><div class="modal-body"><@msg@></div>` +
+		`<button type="button" class="modal-close" data-close="<@id@>"><@close@></button></div></div>`,
+}
+
+// ComponentMapper renders components, i.e. includes with params. Registered via Register, the directive
+//
+//	-component alert type=warning msg="Please check your input" title=$heading dismissible
+//
+// renders the template alert.html within Dir with the given params as current scope, so that the
+// template maps them via <@type@> etc. Values may be quoted in Go syntax, values starting with $ are
+// taken from the value of the given name (see RenderContext.Value) and params without value are "true".
+// The params are HTML escaped, so attributes must be quoted. The front matter of the component template provides the
+// defaults of the params and the required params (see Meta), e.g.
+//
+//	---
+//	type: info
+//	required: [msg]
+//	---
+//	<div class="alert alert-<@type@>" role="alert"><@msg@></div>
+//
+// Missing required params and invalid params are reported to MapStrict and render as empty string.
+type ComponentMapper struct {
+	Dir string // "" means ComponentDir
+}
+
+func (c *ComponentMapper) dir() string {
+	if c.Dir == "" {
+		return ComponentDir
+	}
+	return c.Dir
+}
+
+// Register registers the "-component" directive for the given HTMLTemplate
+func (c *ComponentMapper) Register(t *HTMLTemplate) error {
+	return t.Handle("component", c.component)
+}
+
+// AddDefaults adds the DefaultComponents to the given HTMLTemplate, that are not already defined
+func (c *ComponentMapper) AddDefaults(t *HTMLTemplate) {
+	for name, tpl := range DefaultComponents {
+		name = c.dir() + name + ".html"
+		if t.Has(name) {
+			continue
+		}
+		t.Lock()
+		t.add(name, []byte(tpl))
+		t.Unlock()
+	}
+}
+
+// ComponentParams are the params of a component. As the scope of the component, they are
+// escaped like the values of the registered mappers.
+type ComponentParams map[string]string
+
+func (c ComponentParams) Map(key string) string {
+	return c[key]
+}
+
+// metaKeys are the keys of the front matter that are no defaults
+var metaKeys = map[string]bool{"layout": true, "cache_ttl": true, "required": true}
+
+// component handles "-component name params..."
+func (c *ComponentMapper) component(r *RenderContext, rest string) string {
+	rest = strings.TrimSpace(rest)
+	name := rest
+	if idx := strings.IndexAny(rest, " \t"); idx != -1 {
+		name, rest = rest[:idx], rest[idx+1:]
+	} else {
+		rest = ""
+	}
+	if name == "" {
+		return ""
+	}
+
+	params, err := parseParams(rest, r.Value)
+	if err != nil {
+		r.fail(err)
+		return ""
+	}
+
+	tpl := c.dir() + name + ".html"
+	if meta, has := r.hm.HTMLTemplate.Meta(tpl); has {
+		for k, v := range meta.Params {
+			if _, set := params[k]; !set && !metaKeys[k] {
+				params[k] = v
+			}
+		}
+		for _, req := range meta.Required {
+			if params[req] == "" {
+				r.fail(MissingComponentParamError{Component: name, Param: req})
+				return ""
+			}
+		}
+	}
+
+	if !r.hm.HTMLTemplate.Has(tpl) {
+		r.fail(TemplateNotFoundError(tpl))
+		return ""
+	}
+	return r.RequireWith(tpl, params)
+}
+
+// parseParams parses params like key=value key="quoted value" key=$name key
+func parseParams(s string, value func(string) string) (ComponentParams, error) {
+	params := ComponentParams{}
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return params, nil
+		}
+
+		end := strings.IndexAny(s, "= \t")
+		if end == -1 {
+			end = len(s)
+		}
+		key := s[:end]
+		if !validAttrName(key) {
+			return nil, ComponentSyntaxError(s)
+		}
+		s = s[end:]
+		if !strings.HasPrefix(s, "=") {
+			params[key] = "true"
+			continue
+		}
+		s = s[1:]
+
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			end = closingQuote(s)
+			if end == -1 {
+				return nil, ComponentSyntaxError(s)
+			}
+			var err error
+			if val, err = strconv.Unquote(s[:end+1]); err != nil {
+				return nil, ComponentSyntaxError(s)
+			}
+			s = s[end+1:]
+		} else {
+			end = strings.IndexAny(s, " \t")
+			if end == -1 {
+				end = len(s)
+			}
+			val, s = s[:end], s[end:]
+			if strings.HasPrefix(val, "$") {
+				val = value(val[1:])
+			}
+		}
+		params[key] = val
+	}
+}
+
+// closingQuote returns the position of the quote that closes the quote at the start of s, -1 if there is none
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,41 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestComponent(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", `<@-component alert type=warning msg="Don't <panic>"@>|<@-component button label=$save disabled@>`,
+		"components/button.html", "---\ntype: button\nrequired: [label]\n---\n<button type=\"<@type@>\"><@label@>|<@disabled@></button>",
+	)
+	var c ComponentMapper
+	if err := c.Register(tpl); err != nil {
+		t.Fatal(err)
+	}
+	c.AddDefaults(tpl)
+
+	hm := tpl.NewMapper(map[string]places.Mapper{"save": String("Save & close")})
+	got, err := hm.MapStrict("-require page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `<div class="alert alert-warning" role="alert">Don&#39;t &lt;panic&gt;</div>|<button type="button">Save &amp; close|true</button>`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	tests := map[string]error{
+		"-component button":                  MissingComponentParamError{Component: "button", Param: "label"},
+		`-component alert msg="unterminated`: ComponentSyntaxError(`"unterminated`),
+		"-component alert =x":                ComponentSyntaxError("=x"),
+		"-component missing":                 TemplateNotFoundError("components/missing.html"),
+	}
+	for input, exp := range tests {
+		got, err := hm.MapStrict(input)
+		if got != "" || err != exp {
+			t.Errorf("%#v: unexpected result %#v, %v, expected error: %v", input, got, err, exp)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package placesmap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strconv"
+	"strings"
+)
+
+// Compressor compresses cached pages for a content encoding, see EnableCompression.
+// Brotli is not part of the standard library, but can be added with a Compressor for
+// "br", e.g. based on github.com/andybalholm/brotli.
+type Compressor interface {
+	Encoding() string // the content encoding, e.g. "gzip" or "br"
+	Compress(b []byte) ([]byte, error)
+}
+
+// Gzip is the Compressor for gzip with the best compression
+var Gzip Compressor = gzipCompressor{}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Encoding() string { return "gzip" }
+
+func (gzipCompressor) Compress(b []byte) ([]byte, error) {
+	var bf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&bf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	return bf.Bytes(), nil
+}
+
+// EnableCompression lets the fragment cache (see EnableFragmentCache) store pages compressed by
+// the given compressors, so that Negotiate serves a page whose template has a cache_ttl with
+// the preferred content encoding of the request (Accept-Encoding) directly from the cache.
+// Every cached page is compressed at most once per encoding. Without compressors the
+// compression is disabled.
+func (h *HTMLTemplate) EnableCompression(cs ...Compressor) {
+	h.Lock()
+	h.compressors = append([]Compressor(nil), cs...)
+	h.Unlock()
+}
+
+// compress returns the page, encoded in the charset of the template, compressed by c.
+// The result is stored, unless the fragment has been rendered again in the meantime.
+func (e *fragment) compress(f *fragments, page string, encoded []byte, c Compressor) ([]byte, error) {
+	enc := c.Encoding()
+	f.mx.Lock()
+	b, has := e.compressed[enc]
+	has = has && e.val == page
+	f.mx.Unlock()
+	if has {
+		return b, nil
+	}
+
+	b, err := c.Compress(encoded)
+	if err != nil {
+		return nil, err
+	}
+	f.mx.Lock()
+	if e.val == page {
+		if e.compressed == nil {
+			e.compressed = map[string][]byte{}
+		}
+		e.compressed[enc] = b
+	}
+	f.mx.Unlock()
+	return b, nil
+}
+
+// acceptedEncoding returns the compressor for the encoding that is preferred by the given
+// Accept-Encoding header, nil if none is accepted
+func acceptedEncoding(accept string, cs []Compressor) Compressor {
+	var (
+		best Compressor
+		bq   float64
+	)
+	for _, c := range cs {
+		if q := encodingQuality(accept, c.Encoding()); q > bq {
+			best, bq = c, q
+		}
+	}
+	return best
+}
+
+// encodingQuality returns the quality of the encoding within the given Accept-Encoding header
+func encodingQuality(accept, enc string) float64 {
+	q := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name != enc && name != "*" {
+			continue
+		}
+		pq := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					pq = f
+				}
+			}
+		}
+		// the exact name takes precedence over the wildcard
+		if name == enc {
+			return pq
+		}
+		q = pq
+	}
+	return q
+}
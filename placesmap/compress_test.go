@@ -0,0 +1,79 @@
+package placesmap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/metakeule/places"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type countingCompressor struct {
+	Compressor
+	calls int
+}
+
+func (c *countingCompressor) Compress(b []byte) ([]byte, error) {
+	c.calls++
+	return c.Compressor.Compress(b)
+}
+
+func TestRenderCompressed(t *testing.T) {
+	tpl := newTemplate(
+		"cached.html", "---\ncache_ttl: 1m\n---\n<p><@name@></p>",
+		"dynamic.html", "<p><@name@></p>",
+	)
+	gz := &countingCompressor{Compressor: Gzip}
+	tpl.EnableFragmentCache(time.Minute)
+	tpl.EnableCompression(gz)
+	m := map[string]places.Mapper{"name": String("Donald")}
+
+	tests := []struct {
+		name, accept, encoding string
+	}{
+		{"cached.html", "gzip, br", "gzip"},
+		{"cached.html", "br;q=1, *;q=0.5", "gzip"},
+		{"cached.html", "gzip;q=0, *", ""},
+		{"cached.html", "", ""},
+		{"dynamic.html", "gzip", ""},
+		{"cached.html", "gzip", "gzip"},
+	}
+	for _, test := range tests {
+		rq := httptest.NewRequest("GET", "/", nil)
+		rq.Header.Set("Accept-Encoding", test.accept)
+		rec := httptest.NewRecorder()
+		if err := Negotiate(rec, rq, tpl, test.name, m); err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != test.encoding {
+			t.Errorf("%s %#v: unexpected encoding %#v, expected: %#v", test.name, test.accept, got, test.encoding)
+		}
+		if got := rec.Header().Values("Vary"); len(got) != 2 || got[1] != "Accept-Encoding" {
+			t.Errorf("%s %#v: unexpected Vary header: %#v", test.name, test.accept, got)
+		}
+		body := rec.Body.Bytes()
+		if test.encoding == "gzip" {
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body, err = ioutil.ReadAll(zr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := string(body); got != "<p>Donald</p>" {
+			t.Errorf("%s %#v: unexpected body: %#v", test.name, test.accept, got)
+		}
+	}
+	if gz.calls != 1 {
+		t.Errorf("page was compressed %d times, expected once", gz.calls)
+	}
+
+	// without request, nothing is compressed
+	rec := httptest.NewRecorder()
+	if err := Render(rec, tpl, "cached.html", m); err != nil || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("unexpected response: %v %#v", err, rec.Header())
+	}
+}
@@ -0,0 +1,336 @@
+/*
+package convert translates templates of other template engines into places templates, to
+smooth migrations.
+
+Mustache converts Mustache and Handlebars templates:
+
+	{{name}}                        <@name@>
+	{{{name}}}, {{& name}}          <@-html name@>
+	{{! comment}}                   removed
+	{{> partial}}                   <@-require partial.html@>
+	{{#items}}...{{/items}}         <@-each items page.1.html@>
+	{{#each items}}...{{/each}}     <@-each items page.1.html@>
+	{{#if x}}...{{else}}...{{/if}}  <@-if x page.1.html else page.2.html@>
+	{{#unless x}}...{{/unless}}     <@-if x  else page.1.html@>
+	{{^x}}...{{/x}}                 <@-if x  else page.1.html@>
+
+Since places has no inline sections, the bodies of sections become templates of their own,
+named after the converted template. Constructs that can't be converted (e.g. other helpers,
+parent paths, set delimiters) are reported as Issues.
+*/
+package convert
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+type ParseError struct {
+	Line   int
+	Reason string
+}
+
+func (p ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Reason)
+}
+
+// Issue is a construct that could not be converted (completely)
+type Issue struct {
+	Line      int
+	Construct string
+	Reason    string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("line %d: %s: %s", i.Line, i.Construct, i.Reason)
+}
+
+// Result is the result of a conversion
+type Result struct {
+	// Templates are the converted template and the templates for the bodies of sections
+	Templates map[string][]byte
+	Issues    []Issue
+}
+
+// Options for the conversion
+type Options struct {
+	Extension string // the extension of partials and generated templates, "" means ".html"
+}
+
+// tag kinds
+const (
+	kindText     = 't'
+	kindVar      = 'v'
+	kindRaw      = '&'
+	kindComment  = '!'
+	kindPartial  = '>'
+	kindSection  = '#'
+	kindInverted = '^'
+	kindClose    = '/'
+	kindDelims   = '='
+	kindElse     = 'e'
+)
+
+type token struct {
+	kind byte
+	name string // the trimmed content of the tag, the text for kindText
+	raw  string // the tag as written
+	line int
+}
+
+type node struct {
+	token
+	children  []node
+	otherwise []node
+	hasElse   bool
+}
+
+// tokenize splits the source into text and tags
+func tokenize(src string) (tokens []token, err error) {
+	line := 1
+	for src != "" {
+		start := strings.Index(src, "{{")
+		if start == -1 {
+			tokens = append(tokens, token{kind: kindText, name: src, line: line})
+			break
+		}
+		if start > 0 {
+			tokens = append(tokens, token{kind: kindText, name: src[:start], line: line})
+			line += strings.Count(src[:start], "\n")
+			src = src[start:]
+		}
+
+		closing := "}}"
+		if strings.HasPrefix(src, "{{{") {
+			closing = "}}}"
+		} else if strings.HasPrefix(src, "{{!--") {
+			closing = "--}}"
+		}
+		end := strings.Index(src, closing)
+		if end == -1 {
+			return nil, ParseError{line, "unterminated tag"}
+		}
+		raw := src[:end+len(closing)]
+		src = src[len(raw):]
+
+		t := token{raw: raw, line: line}
+		content := raw[2 : len(raw)-2]
+		switch {
+		case closing == "}}}":
+			t.kind, t.name = kindRaw, content[1:len(content)-1]
+		case content == "":
+			t.kind = kindVar
+		default:
+			switch c := content[0]; c {
+			case kindRaw, kindComment, kindPartial, kindSection, kindInverted, kindClose, kindDelims:
+				t.kind, t.name = c, content[1:]
+			default:
+				t.kind, t.name = kindVar, content
+			}
+		}
+		t.name = strings.TrimSpace(t.name)
+		if t.kind == kindVar && t.name == "else" {
+			t.kind = kindElse
+		}
+		tokens = append(tokens, t)
+		line += strings.Count(raw, "\n")
+	}
+	return
+}
+
+// parse parses the tokens starting with tokens[i] up to the closing tag of the section
+// with the given name ("" for the top level)
+func parse(tokens []token, i int, section *node) (nodes []node, next int, err error) {
+	for i < len(tokens) {
+		t := tokens[i]
+		i++
+		switch t.kind {
+		case kindClose:
+			if section == nil || t.name != sectionName(section.name) {
+				return nil, 0, ParseError{t.line, fmt.Sprintf("unexpected %s", t.raw)}
+			}
+			if section.hasElse {
+				section.otherwise = nodes
+			} else {
+				section.children = nodes
+			}
+			return nil, i, nil
+		case kindElse:
+			if section == nil || section.hasElse {
+				return nil, 0, ParseError{t.line, "unexpected {{else}}"}
+			}
+			section.children, section.hasElse = nodes, true
+			nodes = nil
+		case kindSection, kindInverted:
+			n := node{token: t}
+			if _, i, err = parse(tokens, i, &n); err != nil {
+				return nil, 0, err
+			}
+			nodes = append(nodes, n)
+		default:
+			nodes = append(nodes, node{token: t})
+		}
+	}
+	if section != nil {
+		return nil, 0, ParseError{section.line, fmt.Sprintf("unclosed %s", section.raw)}
+	}
+	return nodes, i, nil
+}
+
+// sectionName returns the name that closes the section, i.e. the helper of Handlebars block helpers
+func sectionName(name string) string {
+	if f := strings.Fields(name); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
+// converter keeps the state of a conversion
+type converter struct {
+	res  *Result
+	base string // the name of the converted template without extension
+	ext  string
+	n    int // the number of generated templates
+}
+
+func (c *converter) issue(t token, reason string) {
+	c.res.Issues = append(c.res.Issues, Issue{Line: t.line, Construct: t.raw, Reason: reason})
+}
+
+// template adds a template for the given nodes and returns its name
+func (c *converter) template(nodes []node) string {
+	c.n++
+	name := fmt.Sprintf("%s.%d%s", c.base, c.n, c.ext)
+	var b strings.Builder
+	// reserve the name, so that nested sections are numbered after it
+	c.res.Templates[name] = nil
+	c.write(&b, nodes)
+	c.res.Templates[name] = []byte(b.String())
+	return name
+}
+
+// name returns the places name for the given Mustache name
+func (c *converter) name(t token) (string, bool) {
+	name := strings.TrimPrefix(t.name, "this.")
+	switch {
+	case name == "." || name == "this":
+		c.issue(t, "the current element is not supported")
+	case strings.HasPrefix(name, "@"):
+		c.issue(t, "data variables are not supported")
+	case strings.Contains(name, "../"):
+		c.issue(t, "parent paths are not supported")
+	case strings.ContainsAny(name, " \t\r\n"):
+		c.issue(t, "helpers are not supported")
+	case name == "":
+		c.issue(t, "empty tag")
+	default:
+		return name, true
+	}
+	return "", false
+}
+
+// section writes the directive for a section
+func (c *converter) section(b *strings.Builder, n node) {
+	f := strings.Fields(n.name)
+	helper, name := "", n.name
+	if n.kind == kindInverted {
+		helper = "unless"
+	} else if len(f) > 1 {
+		helper, name = f[0], strings.Join(f[1:], " ")
+	}
+
+	switch helper {
+	case "", "each", "if":
+		directive := "-each "
+		if helper == "if" {
+			directive = "-if "
+		}
+		nm, ok := c.name(token{name: name, raw: n.raw, line: n.line})
+		if !ok {
+			return
+		}
+		b.WriteString("<@" + directive + nm + " " + c.template(n.children))
+		if n.hasElse {
+			b.WriteString(" else " + c.template(n.otherwise))
+		}
+		b.WriteString("@>")
+	case "unless":
+		nm, ok := c.name(token{name: name, raw: n.raw, line: n.line})
+		if !ok {
+			return
+		}
+		b.WriteString("<@-if " + nm + " ")
+		if n.hasElse {
+			b.WriteString(c.template(n.otherwise))
+		}
+		b.WriteString(" else " + c.template(n.children) + "@>")
+	default:
+		c.issue(n.token, fmt.Sprintf("block helper %#v is not supported, the body is kept inline", helper))
+		c.write(b, n.children)
+	}
+}
+
+// write writes the places syntax for the given nodes
+func (c *converter) write(b *strings.Builder, nodes []node) {
+	for _, n := range nodes {
+		switch n.kind {
+		case kindText:
+			if strings.Contains(n.name, "<@") {
+				c.issue(n.token, "the text contains the places delimiter <@")
+			}
+			b.WriteString(n.name)
+		case kindVar:
+			if name, ok := c.name(n.token); ok {
+				b.WriteString("<@" + name + "@>")
+			}
+		case kindRaw:
+			if name, ok := c.name(n.token); ok {
+				b.WriteString("<@-html " + name + "@>")
+			}
+		case kindComment:
+		case kindPartial:
+			f := strings.Fields(n.name)
+			if len(f) == 0 {
+				c.issue(n.token, "empty partial")
+				continue
+			}
+			if len(f) > 1 {
+				c.issue(n.token, "partial arguments are not supported")
+			}
+			name := f[0]
+			if path.Ext(name) == "" {
+				name += c.ext
+			}
+			b.WriteString("<@-require " + name + "@>")
+		case kindDelims:
+			c.issue(n.token, "set delimiters are not supported")
+		case kindSection, kindInverted:
+			c.section(b, n)
+		}
+	}
+}
+
+// Mustache converts the Mustache or Handlebars template with the given name into places syntax,
+// see the package documentation. Malformed templates return a ParseError.
+func Mustache(name string, src []byte, opts Options) (*Result, error) {
+	tokens, err := tokenize(string(src))
+	if err != nil {
+		return nil, err
+	}
+	nodes, _, err := parse(tokens, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &converter{res: &Result{Templates: map[string][]byte{}}, ext: opts.Extension}
+	if c.ext == "" {
+		c.ext = ".html"
+	}
+	c.base = strings.TrimSuffix(name, path.Ext(name))
+
+	var b strings.Builder
+	c.write(&b, nodes)
+	c.res.Templates[name] = []byte(b.String())
+	return c.res, nil
+}
@@ -0,0 +1,64 @@
+package convert
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMustache(t *testing.T) {
+	src := `<h1>{{title}}</h1>{{! comment }}{{{body}}}{{& intro}}
+{{> header}}{{#items}}<li>{{name}}{{#tags}}{{this.tag}}{{/tags}}</li>{{/items}}
+{{#if user}}Hi {{user.name}}{{else}}Login{{/if}}{{#unless admin}}no{{/unless}}{{^empty}}none{{/empty}}
+{{#with author}}{{name}}{{/with}}{{@index}}{{../x}}{{format date}}{{=<% %>=}}`
+
+	res, err := Mustache("page.html", []byte(src), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"page.html": `<h1><@title@></h1><@-html body@><@-html intro@>
+<@-require header.html@><@-each items page.1.html@>
+<@-if user page.3.html else page.4.html@><@-if admin  else page.5.html@><@-if empty  else page.6.html@>
+<@name@>`,
+		"page.1.html": "<li><@name@><@-each tags page.2.html@></li>",
+		"page.2.html": "<@tag@>",
+		"page.3.html": "Hi <@user.name@>",
+		"page.4.html": "Login",
+		"page.5.html": "no",
+		"page.6.html": "none",
+	}
+	if len(res.Templates) != len(exp) {
+		t.Errorf("unexpected templates: %q", res.Templates)
+	}
+	for name, e := range exp {
+		if got := string(res.Templates[name]); got != e {
+			t.Errorf("%s: unexpected result %#v, expected %#v", name, got, e)
+		}
+	}
+
+	issues := fmt.Sprint(res.Issues)
+	expIssues := `[line 4: {{#with author}}: block helper "with" is not supported, the body is kept inline` +
+		` line 4: {{@index}}: data variables are not supported` +
+		` line 4: {{../x}}: parent paths are not supported` +
+		` line 4: {{format date}}: helpers are not supported` +
+		` line 4: {{=<% %>=}}: set delimiters are not supported]`
+	if issues != expIssues {
+		t.Errorf("unexpected issues: %s, expected: %s", issues, expIssues)
+	}
+}
+
+func TestMustacheErrors(t *testing.T) {
+	tests := map[string]error{
+		"a\n{{#x}}b":       ParseError{2, "unclosed {{#x}}"},
+		"{{#x}}{{/y}}":     ParseError{1, "unexpected {{/y}}"},
+		"{{else}}":         ParseError{1, "unexpected {{else}}"},
+		"a\n\n{{b":         ParseError{3, "unterminated tag"},
+		"{{#if x}}{{/if}}": nil,
+	}
+	for src, exp := range tests {
+		if _, err := Mustache("x.html", []byte(src), Options{}); err != exp {
+			t.Errorf("%#v: unexpected error %v, expected %v", src, err, exp)
+		}
+	}
+}
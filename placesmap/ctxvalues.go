@@ -0,0 +1,72 @@
+package placesmap
+
+import (
+	"context"
+	"github.com/metakeule/places"
+	"strings"
+)
+
+// ContextValues is a NamespaceMapper for the values of the context of the render (see
+// HTMLTemplate.NewMapperContext), e.g. the request ID or the authenticated user that
+// middlewares put into the context of a request. It maps names to context keys.
+// Registered as "ctx", "ctx.user" maps the value of the context key of "user".
+// Values are formatted like the values of Nested; for "ctx.user.name" the value must be a
+// places.Mapper, that is called with "name" (SubMappers are walked), or a map[string]interface{}.
+type ContextValues map[string]interface{}
+
+// FromContext returns ContextValues for the given context keys by name
+func FromContext(keys map[string]interface{}) ContextValues {
+	c := make(ContextValues, len(keys))
+	for k, v := range keys {
+		c[k] = v
+	}
+	return c
+}
+
+// Map returns the empty string, the values are found via Lookup
+func (c ContextValues) Map(string) string { return "" }
+
+// Lookup returns the mapper for the context value of the given name
+func (c ContextValues) Lookup(name string) (places.Mapper, bool) {
+	first, path := name, ""
+	if idx := strings.IndexByte(name, '.'); idx != -1 {
+		first, path = name[:idx], name[idx+1:]
+	}
+	key, has := c[first]
+	if !has {
+		return nil, false
+	}
+	return contextValue{key: key, path: path}, true
+}
+
+// contextValue maps the value of a context key, path is the name within the value
+type contextValue struct {
+	key  interface{}
+	path string
+}
+
+// Map returns the empty string, since there is no context
+func (v contextValue) Map(string) string { return "" }
+
+func (v contextValue) MapContext(ctx context.Context, _ string) string {
+	val := ctx.Value(v.key)
+	if v.path == "" {
+		return Nested{"": val}.Map("")
+	}
+	var mp places.Mapper
+	switch x := val.(type) {
+	case places.Mapper:
+		mp = x
+	case map[string]interface{}:
+		mp = Nested(x)
+	default:
+		return ""
+	}
+	if sm, is := mp.(SubMapper); is {
+		if mp, is = walk(sm, v.path); !is {
+			return ""
+		}
+		return mp.Map("")
+	}
+	return mp.Map(v.path)
+}
@@ -0,0 +1,27 @@
+package placesmap
+
+import (
+	"context"
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	type ctxKey string
+	tpl := newTemplate("page.html", "<@ctx.requestID@>|<@ctx.user.name@>|<@ctx.user.address.city@>|<@ctx.session.name@>|<@ctx.missing@>|<@-if ctx.admin admin.html@>", "admin.html", "admin")
+
+	ctx := context.WithValue(context.Background(), ctxKey("rid"), 42)
+	ctx = context.WithValue(ctx, ctxKey("user"), map[string]interface{}{"name": "<Tom>", "address": map[string]interface{}{"city": "Berlin"}})
+	ctx = context.WithValue(ctx, ctxKey("session"), keyed("s1"))
+	ctx = context.WithValue(ctx, ctxKey("admin"), true)
+	m := map[string]places.Mapper{"ctx": FromContext(map[string]interface{}{
+		"requestID": ctxKey("rid"), "user": ctxKey("user"), "session": ctxKey("session"), "admin": ctxKey("admin"), "missing": ctxKey("missing"),
+	})}
+
+	if got, exp := tpl.NewMapperContext(ctx, m).Map("-require page.html"), "42|&lt;Tom&gt;|Berlin|s1||admin"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if got, exp := tpl.NewMapper(m).Map("-require page.html"), "|||||"; got != exp {
+		t.Errorf("unexpected result without context values: %#v, expected: %#v", got, exp)
+	}
+}
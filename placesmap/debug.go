@@ -0,0 +1,118 @@
+package placesmap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/metakeule/places"
+	"net"
+	"net/http"
+	"sort"
+)
+
+// DebugParam is the query parameter that requests a DebugDump instead of the page, see Debug
+const DebugParam = "__places_debug"
+
+// DebugDump describes the render of a page
+type DebugDump struct {
+	Template string `json:"template"`
+
+	// Mappers are the values of the registered mappers, serialized like MarshalMappers does.
+	// Iterators are null, since they can only be consumed once.
+	Mappers map[string]interface{} `json:"mappers"`
+
+	// Includes maps the rendered templates to the number of renders
+	Includes map[string]int `json:"includes"`
+
+	// Placeholders maps the resolved placeholders to the number of resolutions
+	Placeholders map[string]int `json:"placeholders"`
+
+	// Unresolved are the sorted names that have been looked up without a registered mapper
+	Unresolved []string `json:"unresolved"`
+
+	// Error is the first error of the render, see MapStrict
+	Error string `json:"error,omitempty"`
+}
+
+// debugWriter marks the responses that get a DebugDump
+type debugWriter struct {
+	http.ResponseWriter
+}
+
+// Flush flushes the underlying ResponseWriter, if it is a http.Flusher
+func (d *debugWriter) Flush() {
+	if f, ok := d.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection of the underlying ResponseWriter, if it is a http.Hijacker
+func (d *debugWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := d.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("placesmap: the ResponseWriter does not support hijacking")
+	}
+	return hj.Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (d *debugWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
+
+// Debug returns a middleware for development: if a request has the query parameter
+// __places_debug=1 and authorized returns true for it, Render, Negotiate and Stream respond with
+// the DebugDump of the page as JSON instead of the page. Since the dump contains all values
+// of the mappers, authorized must not be nil and should be restricted to developers.
+func Debug(authorized func(*http.Request) bool) func(http.Handler) http.Handler {
+	if authorized == nil {
+		panic("placesmap: authorized must not be nil")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(wr http.ResponseWriter, rq *http.Request) {
+			if rq.URL.Query().Get(DebugParam) == "1" && authorized(rq) {
+				wr = &debugWriter{wr}
+			}
+			next.ServeHTTP(wr, rq)
+		})
+	}
+}
+
+// Dump renders the template with the given name and the given mappers and describes the render
+func (h *HTMLTemplate) Dump(name string, m map[string]places.Mapper) DebugDump {
+	r := h.NewMapper(m).newRender()
+	r.debug = newAudit()
+	r.render("-require " + name)
+
+	d := DebugDump{
+		Template:     name,
+		Mappers:      make(map[string]interface{}, len(m)),
+		Includes:     copyCounts(r.debug.includes),
+		Placeholders: copyCounts(r.debug.placeholders),
+		Unresolved:   []string{},
+	}
+	for k, mp := range m {
+		if _, is := mp.(Iterator); is {
+			d.Mappers[k] = nil
+			continue
+		}
+		d.Mappers[k] = jsonValue(k, mp)
+	}
+	for k := range r.debug.missing {
+		d.Unresolved = append(d.Unresolved, k)
+	}
+	sort.Strings(d.Unresolved)
+	if r.err != nil {
+		d.Error = r.err.Error()
+	}
+	return d
+}
+
+// writeDump writes the DebugDump of the page as JSON
+func writeDump(wr http.ResponseWriter, t *HTMLTemplate, name string, m map[string]places.Mapper) error {
+	wr.Header().Set("Content-Type", "application/json; charset=utf-8")
+	wr.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t.Dump(name, m))
+}
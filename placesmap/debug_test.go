@@ -0,0 +1,100 @@
+package placesmap
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/metakeule/places"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestDebugWriter(t *testing.T) {
+	handler := Debug(func(*http.Request) bool { return true })(http.HandlerFunc(func(wr http.ResponseWriter, rq *http.Request) {
+		if _, is := wr.(*debugWriter); !is {
+			t.Fatalf("the ResponseWriter is not wrapped")
+		}
+		wr.(http.Flusher).Flush()
+		_, _, err := wr.(http.Hijacker).Hijack()
+		if _, can := wr.(*debugWriter).ResponseWriter.(*hijackRecorder); !can && err == nil {
+			t.Errorf("expected error, the ResponseRecorder can't be hijacked")
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/?"+DebugParam+"=1", nil))
+	if !rec.Flushed {
+		t.Errorf("the debugWriter does not forward Flush")
+	}
+	hj := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(hj, httptest.NewRequest("GET", "/?"+DebugParam+"=1", nil))
+	if !hj.hijacked {
+		t.Errorf("the debugWriter does not forward Hijack")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Debug must panic without authorized")
+		}
+	}()
+	Debug(nil)
+}
+
+func TestDebug(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@-require header.html@><b><@name@></b><@title@>",
+		"header.html", "<h1><@-require missing.html@></h1>",
+	)
+	m := map[string]places.Mapper{"name": String("Donald")}
+	handler := Debug(func(rq *http.Request) bool {
+		return rq.Header.Get("X-Dev") == "yes"
+	})(http.HandlerFunc(func(wr http.ResponseWriter, rq *http.Request) {
+		Render(wr, tpl, "page.html", m)
+	}))
+
+	// the page fails, since missing.html does not exist
+	for _, target := range []string{"/", "/?" + DebugParam + "=1"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "Donald") {
+			t.Errorf("%#v: unexpected response: %d %#v", target, rec.Code, rec.Body.String())
+		}
+	}
+
+	rq := httptest.NewRequest("GET", "/?"+DebugParam+"=1", nil)
+	rq.Header.Set("X-Dev", "yes")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, rq)
+	if got := rec.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("unexpected content type: %#v", got)
+	}
+
+	var d DebugDump
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Template != "page.html" || d.Mappers["name"] != "Donald" {
+		t.Errorf("unexpected dump: %#v", d)
+	}
+	if d.Includes["page.html"] != 1 || d.Includes["header.html"] != 1 || len(d.Includes) != 2 {
+		t.Errorf("unexpected includes: %#v", d.Includes)
+	}
+	if strings.Join(d.Unresolved, ",") != "title" {
+		t.Errorf("unexpected unresolved names: %#v", d.Unresolved)
+	}
+	if d.Error != TemplateNotFoundError("missing.html").Error() {
+		t.Errorf("unexpected error: %#v", d.Error)
+	}
+}
@@ -0,0 +1,111 @@
+package placesmap
+
+import (
+	"sort"
+	"strings"
+)
+
+// blockOwner returns the name of the template that the inline body with the given name
+// belongs to (see extractBlocks), the name itself for other templates
+func blockOwner(name string) string {
+	idx := strings.LastIndexByte(name, '#')
+	if idx == -1 || idx == len(name)-1 {
+		return name
+	}
+	for _, c := range name[idx+1:] {
+		if c < '0' || c > '9' {
+			return name
+		}
+	}
+	return name[:idx]
+}
+
+// dependencies adds the templates that the template with the given name references
+// via the built-in directives, following its inline bodies
+func (h *HTMLTemplate) dependencies(name string, deps map[string]bool) {
+	t, has := h.template(name)
+	if !has {
+		return
+	}
+	for _, ph := range t.Placeholders() {
+		for _, ref := range references(ph) {
+			if blockOwner(ref) != ref {
+				h.dependencies(ref, deps)
+				continue
+			}
+			deps[ref] = true
+		}
+	}
+}
+
+// cyclic returns whether the template with the given name includes itself, directly or indirectly
+func (h *HTMLTemplate) cyclic(name string) bool {
+	seen := map[string]bool{}
+	queue := []string{name}
+	for len(queue) > 0 {
+		deps := map[string]bool{}
+		h.dependencies(queue[0], deps)
+		queue = queue[1:]
+		for dep := range deps {
+			if dep == name {
+				return true
+			}
+			if !seen[dep] {
+				seen[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return false
+}
+
+// Dependencies returns the sorted names of the templates that the template with the given name
+// includes directly, determined statically like RequiredKeys. Templates that are included
+// but don't exist are part of the result.
+func (h *HTMLTemplate) Dependencies(name string) []string {
+	deps := map[string]bool{}
+	h.dependencies(name, deps)
+	delete(deps, name)
+	return sortedKeys(deps)
+}
+
+// dependents returns the direct dependents by template name
+func (h *HTMLTemplate) dependents() map[string][]string {
+	res := map[string][]string{}
+	for _, name := range h.names() {
+		if blockOwner(name) != name {
+			continue
+		}
+		for _, dep := range h.Dependencies(name) {
+			res[dep] = append(res[dep], name)
+		}
+	}
+	return res
+}
+
+// Dependents returns the sorted names of the templates that include the template with the
+// given name directly (see Dependencies)
+func (h *HTMLTemplate) Dependents(name string) []string {
+	res := h.dependents()[name]
+	sort.Strings(res)
+	return res
+}
+
+// Affected returns the sorted names of the given templates and all templates that include them,
+// directly or indirectly, e.g. to invalidate the caches for changed templates:
+//
+//	t.InvalidateFragments(t.Affected(changed...)...)
+func (h *HTMLTemplate) Affected(names ...string) []string {
+	dependents := h.dependents()
+	affected := map[string]bool{}
+	for len(names) > 0 {
+		name := names[len(names)-1]
+		names = names[:len(names)-1]
+		if affected[name] {
+			continue
+		}
+		affected[name] = true
+		names = append(names, dependents[name]...)
+	}
+	return sortedKeys(affected)
+}
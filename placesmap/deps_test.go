@@ -0,0 +1,34 @@
+package placesmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@-require layout.html@><@-each items@><@-require item.html@><@-else@><@-require empty.html@><@-end@>",
+		"layout.html", "<@-require header.html@><@-if admin menu.html@>",
+		"item.html", "<@-require missing.html@>",
+		"header.html", "",
+		"other.html", "<@-require header.html@>",
+	)
+
+	tests := map[string]string{
+		"page.html":   "empty.html item.html layout.html",
+		"layout.html": "header.html menu.html",
+		"header.html": "",
+	}
+	for name, exp := range tests {
+		if got := strings.Join(tpl.Dependencies(name), " "); got != exp {
+			t.Errorf("dependencies of %#v: %#v, expected: %#v", name, got, exp)
+		}
+	}
+
+	if got, exp := strings.Join(tpl.Dependents("header.html"), " "), "layout.html other.html"; got != exp {
+		t.Errorf("unexpected dependents: %#v, expected: %#v", got, exp)
+	}
+	if got, exp := strings.Join(tpl.Affected("header.html"), " "), "header.html layout.html other.html page.html"; got != exp {
+		t.Errorf("unexpected affected templates: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,62 @@
+package placesmap
+
+import (
+	"time"
+)
+
+// DeterministicTime is the current time of deterministic renders without RenderOptions.Now
+var DeterministicTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DeterministicNonce is the nonce of deterministic renders without RenderOptions.Nonce
+const DeterministicNonce = "AAAAAAAAAAAAAAAAAAAAAAAA"
+
+// RenderOptions are the options of the renders of a HTMLTemplate, see SetRenderOptions
+type RenderOptions struct {
+	// Deterministic makes the built-ins that would render differently on every run resolve via
+	// the providers below, so that golden-file tests of full pages are stable: the current time
+	// (e.g. of "-time now", see RenderContext.Now) and the nonce of the Content-Security-Policy
+	// (see SetCSP). Not for production.
+	Deterministic bool
+
+	// Now returns the current time of deterministic renders, nil means DeterministicTime
+	Now func() time.Time
+
+	// Nonce returns the nonce of deterministic renders, nil means DeterministicNonce
+	Nonce func() string
+}
+
+// SetRenderOptions sets the options of all renders that are started afterwards
+func (h *HTMLTemplate) SetRenderOptions(o RenderOptions) {
+	h.Lock()
+	h.options = o
+	h.Unlock()
+}
+
+// now returns the current time of deterministic renders
+func (o RenderOptions) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return DeterministicTime
+}
+
+// nonce returns the nonce of deterministic renders
+func (o RenderOptions) nonce() *NonceMapper {
+	if o.Nonce != nil {
+		return &NonceMapper{nonce: o.Nonce()}
+	}
+	return &NonceMapper{nonce: DeterministicNonce}
+}
+
+// Now returns the current time of the render, i.e. RenderOptions.Now for deterministic renders,
+// otherwise the typed value of the mapper registered as ClockKey (see ClockMapper) or time.Now.
+// Prefix handlers should use it instead of time.Now.
+func (r *RenderContext) Now() time.Time {
+	if r.options.Deterministic {
+		return r.options.now()
+	}
+	if t, is := r.typedValue(ClockKey).(time.Time); is {
+		return t
+	}
+	return time.Now()
+}
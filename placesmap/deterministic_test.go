@@ -0,0 +1,42 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeterministic(t *testing.T) {
+	tpl := newTemplate("page.html", `<script>x</script><@-time now date@>|<@-nonce@>`)
+	tpl.SetCSP("script-src {nonce}")
+	render := func() (string, string) {
+		rec := httptest.NewRecorder()
+		if err := Render(rec, tpl, "page.html", map[string]places.Mapper{ClockKey: &ClockMapper{}}); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Body.String(), rec.Header().Get("Content-Security-Policy")
+	}
+
+	if a, _ := render(); a == `<script nonce="`+DeterministicNonce+`">x</script>2000-01-01|`+DeterministicNonce {
+		t.Errorf("renders must not be deterministic by default")
+	}
+
+	tpl.SetRenderOptions(RenderOptions{Deterministic: true})
+	body, csp := render()
+	if exp := `<script nonce="` + DeterministicNonce + `">x</script>2000-01-01|` + DeterministicNonce; body != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", body, exp)
+	}
+	if exp := "script-src 'nonce-" + DeterministicNonce + "'"; csp != exp {
+		t.Errorf("unexpected policy: %#v, expected: %#v", csp, exp)
+	}
+
+	tpl.SetRenderOptions(RenderOptions{
+		Deterministic: true,
+		Now:           func() time.Time { return time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC) },
+		Nonce:         func() string { return "n" },
+	})
+	if body, _ := render(); body != `<script nonce="n">x</script>2024-05-06|n` {
+		t.Errorf("unexpected result with providers: %#v", body)
+	}
+}
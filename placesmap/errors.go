@@ -0,0 +1,56 @@
+package placesmap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotNMapper is the error for collections of "-each" and "-tree" that are no NMapper
+var ErrNotNMapper = errors.New("collection is not a NMapper")
+
+// TooDeepPathError is the error for a sub path of "-each" that is deeper than the nested collections
+type TooDeepPathError struct {
+	Path string
+}
+
+func (t TooDeepPathError) Error() string {
+	return fmt.Sprintf("path %#v is deeper than the nested collections", t.Path)
+}
+
+// UnknownCollectionError is the error for collections of "-each" and "-tree" that are not registered
+type UnknownCollectionError struct {
+	Name string
+}
+
+func (u UnknownCollectionError) Error() string {
+	return fmt.Sprintf("collection %#v is not registered", u.Name)
+}
+
+// fail records the given error for the strict render (see MapStrict), the first error wins
+func (r *RenderContext) fail(err error) {
+	if r.hm.all {
+		r.collect(err)
+	}
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// Fail records the given error, so that prefix handlers can report errors to MapStrict.
+// The rendering is not stopped.
+func (r *RenderContext) Fail(err error) {
+	r.fail(err)
+}
+
+// MapStrict renders the given placeholder like Map and returns the first error of the render,
+// i.e. ErrNotNMapper, a TooDeepPathError, an UnknownCollectionError, a TemplateNotFoundError
+// or an error of a prefix handler (see RenderContext.Fail).
+// If all errors are collected (see WithAllErrors), the error is RenderErrors.
+func (h *HTMLTemplateMapper) MapStrict(input string) (string, error) {
+	r := h.newRender()
+	res := r.limited(input)
+	if h.all && len(r.errs) > 0 {
+		return res, r.errs
+	}
+	return res, r.err
+}
@@ -0,0 +1,33 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestMapStrict(t *testing.T) {
+	tpl := newTemplate("row.html", "<@name@>")
+	hm := tpl.NewMapper(map[string]places.Mapper{
+		"items":  list{keyed("a")},
+		"string": String("x"),
+	})
+
+	tests := map[string]error{
+		"-each items row.html":                 nil,
+		"-each string row.html":                ErrNotNMapper,
+		"-each missing row.html":               UnknownCollectionError{Name: "missing"},
+		"-each missing row.html else x":        TemplateNotFoundError("x"),
+		"-each items missing.html":             TemplateNotFoundError("missing.html"),
+		"-tree missing row.html":               UnknownCollectionError{Name: "missing"},
+		"-require missing.html":                TemplateNotFoundError("missing.html"),
+		"-if string missing.html else x":       TemplateNotFoundError("missing.html"),
+		"-each missing row.html else row.html": nil,
+	}
+
+	for input, exp := range tests {
+		_, err := hm.MapStrict(input)
+		if err != exp {
+			t.Errorf("%#v: unexpected error: %v, expected: %v", input, err, exp)
+		}
+	}
+}
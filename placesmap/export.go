@@ -0,0 +1,163 @@
+package placesmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/metakeule/places"
+	"io"
+	"strings"
+)
+
+// ExportVersion is the version of the schema of ExportJSON, it is increased on incompatible changes
+const ExportVersion = 1
+
+// Export is the document written by ExportJSON, e.g.
+//
+//	{
+//	  "version": 1,
+//	  "templates": [
+//	    {
+//	      "name": "page.html",
+//	      "source": "<h1><@title@></h1><@-each users page.html#1@>",
+//	      "placeholders": [
+//	        {"placeholder": "title", "prefix": "", "rest": "title"},
+//	        {"placeholder": "-each users page.html#1", "prefix": "each", "rest": "users page.html#1"}
+//	      ],
+//	      "includes": ["page.html#1"],
+//	      "meta": {"title": "Users", "layout": "", "cache_ttl": "", "required": ["title"], "params": {"title": "Users"}}
+//	    },
+//	    {
+//	      "name": "page.html#1",
+//	      "owner": "page.html",
+//	      "source": "<li><@name@></li>",
+//	      "placeholders": [{"placeholder": "name", "prefix": "", "rest": "name"}],
+//	      "includes": []
+//	    }
+//	  ]
+//	}
+type Export struct {
+	Version   int              `json:"version"`   // ExportVersion
+	Templates []ExportTemplate `json:"templates"` // sorted by name
+}
+
+// ExportTemplate is a template of an Export
+type ExportTemplate struct {
+	Name string `json:"name"`
+
+	// Owner is the template an inline body (see the directives -each, -if and -with) belongs to,
+	// empty for other templates
+	Owner string `json:"owner,omitempty"`
+
+	// Source is the template without front matter and with the inline bodies replaced
+	// by the names of their templates, the placeholders are the ones of the author
+	Source string `json:"source"`
+
+	// Placeholders are the placeholders of the source in the order of their occurrence
+	Placeholders []ExportPlaceholder `json:"placeholders"`
+
+	// Includes are the sorted names of the templates that are included directly,
+	// including inline bodies and templates that don't exist
+	Includes []string `json:"includes"`
+
+	// Meta is the front matter, nil if there is none
+	Meta *ExportMeta `json:"meta,omitempty"`
+}
+
+// ExportPlaceholder is a placeholder of an ExportTemplate
+type ExportPlaceholder struct {
+	Placeholder string `json:"placeholder"` // the placeholder between the delimiters
+	Prefix      string `json:"prefix"`      // the prefix without the leading -, empty for plain placeholders
+	Rest        string `json:"rest"`        // the placeholder without prefix
+
+	// Name is whether the placeholder is at the position of a tag or attribute name, where only
+	// valid names may be rendered
+	Name bool `json:"name,omitempty"`
+}
+
+// ExportMeta is the front matter of an ExportTemplate, see Meta
+type ExportMeta struct {
+	Title    string            `json:"title"`
+	Layout   string            `json:"layout"`
+	CacheTTL string            `json:"cache_ttl"` // a duration like 5m0s, empty if not declared
+	Required []string          `json:"required"`
+	Params   map[string]string `json:"params"`
+}
+
+// Export returns all templates of the HTMLTemplate with their placeholders, see ExportJSON
+func (h *HTMLTemplate) Export() Export {
+	e := Export{Version: ExportVersion, Templates: []ExportTemplate{}}
+	for _, name := range h.names() {
+		t, ok := h.template(name)
+		if !ok {
+			continue
+		}
+		src, phs, names := authored(t)
+		et := ExportTemplate{Name: name, Source: src, Placeholders: []ExportPlaceholder{}}
+		if owner := blockOwner(name); owner != name {
+			et.Owner = owner
+		}
+
+		includes := map[string]bool{}
+		for i, ph := range phs {
+			prefix, rest := split(ph)
+			et.Placeholders = append(et.Placeholders, ExportPlaceholder{Placeholder: ph, Prefix: prefix, Rest: rest, Name: names[i]})
+			for _, ref := range references(ph) {
+				if ref != "" {
+					includes[ref] = true
+				}
+			}
+		}
+		et.Includes = sortedKeys(includes)
+
+		if m, has := h.Meta(name); has {
+			em := &ExportMeta{Title: m.Title, Layout: m.Layout, Required: m.Required, Params: m.Params}
+			if m.CacheTTL > 0 {
+				em.CacheTTL = m.CacheTTL.String()
+			}
+			if em.Required == nil {
+				em.Required = []string{}
+			}
+			if em.Params == nil {
+				em.Params = map[string]string{}
+			}
+			et.Meta = em
+		}
+		e.Templates = append(e.Templates, et)
+	}
+	return e
+}
+
+// ExportJSON writes all templates of the HTMLTemplate with their placeholders as JSON (see Export),
+// so that frontend tooling, e.g. client-side renderers or translation extractors, can use the same
+// templates. The output is stable, i.e. the same templates always result in the same JSON.
+func (h *HTMLTemplate) ExportJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(h.Export())
+}
+
+// authored returns the source and the placeholders of the template as written by the author,
+// i.e. without the rewrites of parseTemplates: the inserted "-nonce-attr" placeholders (see
+// injectNonce) are removed and the "-html-name" guards (see guardNames) are unwrapped. names are
+// whether the placeholders are guarded, i.e. at the position of a tag or attribute name.
+func authored(t *places.Template) (src string, placeholders []string, names []bool) {
+	tpl := t.Bytes()
+	pos := places.Find(tpl)
+	var (
+		bf   bytes.Buffer
+		last int
+	)
+	for i, ph := range t.Placeholders() {
+		bf.Write(tpl[last:pos[i*2]])
+		last = pos[i*2+1] + 2
+		if ph == "-nonce-attr" {
+			continue
+		}
+		name := strings.HasPrefix(ph, "-html-name ")
+		ph = strings.TrimPrefix(ph, "-html-name ")
+		bf.WriteString("<@" + ph + "@>")
+		placeholders, names = append(placeholders, ph), append(names, name)
+	}
+	bf.Write(tpl[last:])
+	return bf.String(), placeholders, names
+}
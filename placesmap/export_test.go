@@ -0,0 +1,54 @@
+package placesmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportJSON(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "---\ntitle: Users\ncache_ttl: 5m\n---\n<h1><@title@></h1><@-each users@><@name@><@-end@><@-require footer.html@>",
+		"footer.html", "<@-t footer@>",
+		"tag.html", "<h<@level@>><script>x</script></h<@level@>>",
+	)
+	var bf bytes.Buffer
+	if err := tpl.ExportJSON(&bf); err != nil {
+		t.Fatal(err)
+	}
+
+	var e Export
+	if err := json.Unmarshal(bf.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Version != ExportVersion || len(e.Templates) != 4 {
+		t.Fatalf("unexpected export: %#v", e)
+	}
+	footer, page, body := e.Templates[0], e.Templates[1], e.Templates[2]
+	if footer.Name != "footer.html" || footer.Meta != nil || len(footer.Includes) != 0 ||
+		footer.Placeholders[0] != (ExportPlaceholder{Placeholder: "-t footer", Prefix: "t", Rest: "footer"}) {
+		t.Errorf("unexpected footer: %#v", footer)
+	}
+	if page.Name != "page.html" || page.Source != "<h1><@title@></h1><@-each users page.html#1@><@-require footer.html@>" ||
+		strings.Join(page.Includes, " ") != "footer.html page.html#1" || page.Meta == nil || page.Meta.Title != "Users" || page.Meta.CacheTTL != "5m0s" {
+		t.Errorf("unexpected page: %#v", page)
+	}
+	if body.Name != "page.html#1" || body.Owner != "page.html" || body.Source != "<@name@>" {
+		t.Errorf("unexpected body: %#v", body)
+	}
+	// the rewrites of the parser are not exported
+	if tag := e.Templates[3]; tag.Source != "<h<@level@>><script>x</script></h<@level@>>" || len(tag.Placeholders) != 2 ||
+		tag.Placeholders[0] != (ExportPlaceholder{Placeholder: "level", Rest: "level", Name: true}) {
+		t.Errorf("unexpected tag: %#v", tag)
+	}
+	if phs, _ := tpl.Placeholders("tag.html"); strings.Join(phs, ",") != "level,level" {
+		t.Errorf("unexpected placeholders: %v", phs)
+	}
+
+	var again bytes.Buffer
+	tpl.ExportJSON(&again)
+	if again.String() != bf.String() {
+		t.Errorf("export is not stable")
+	}
+}
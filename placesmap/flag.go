@@ -0,0 +1,51 @@
+package placesmap
+
+import (
+	"context"
+	"strings"
+)
+
+// FlagProvider decides whether a feature flag is enabled. The context is the context of the
+// render (see NewMapperContext), so that providers can evaluate flags for the current user.
+type FlagProvider interface {
+	Enabled(ctx context.Context, flag string) bool
+}
+
+// FlagFunc is a function that is a FlagProvider
+type FlagFunc func(ctx context.Context, flag string) bool
+
+func (f FlagFunc) Enabled(ctx context.Context, flag string) bool {
+	return f(ctx, flag)
+}
+
+// Flags is a FlagProvider for a fixed set of flags, flags that are not within the map are disabled
+type Flags map[string]bool
+
+func (f Flags) Enabled(_ context.Context, flag string) bool {
+	return f[flag]
+}
+
+// SetFlagProvider sets the FlagProvider for the directive
+//
+//	-flag new-checkout checkout-v2.html else checkout.html
+//
+// that renders checkout-v2.html, if the flag new-checkout is enabled and checkout.html otherwise.
+// The else part is optional. Without FlagProvider all flags are disabled.
+func (h *HTMLTemplate) SetFlagProvider(p FlagProvider) {
+	h.Lock()
+	h.flags = p
+	h.Unlock()
+}
+
+// flag handles "-flag name then.html else otherwise.html"
+func (r *RenderContext) flag(rest string) string {
+	s := strings.SplitN(strings.TrimSpace(rest), " ", 2)
+	if len(s) != 2 {
+		return ""
+	}
+	then, otherwise := splitElse(s[1])
+	if r.flags != nil && r.flags.Enabled(r.ctx, s[0]) {
+		return r.require(then, r)
+	}
+	return r.require(otherwise, r)
+}
@@ -0,0 +1,39 @@
+package placesmap
+
+import (
+	"context"
+	"testing"
+)
+
+type userKey struct{}
+
+func TestFlag(t *testing.T) {
+	tpl := newTemplate("new.html", "new", "old.html", "old", "page.html", "<@-flag beta new.html else old.html@>|<@-flag beta new.html@>")
+
+	tests := []struct {
+		user string
+		exp  string
+	}{
+		{"alice", "new|new"},
+		{"bob", "old|"},
+	}
+
+	if got, exp := tpl.NewMapper(nil).Map("-require page.html"), "old|"; got != exp {
+		t.Errorf("unexpected result without provider: %#v, expected: %#v", got, exp)
+	}
+
+	tpl.SetFlagProvider(FlagFunc(func(ctx context.Context, flag string) bool {
+		return flag == "beta" && ctx.Value(userKey{}) == "alice"
+	}))
+	for _, tt := range tests {
+		ctx := context.WithValue(context.Background(), userKey{}, tt.user)
+		if got := tpl.NewMapperContext(ctx, nil).Map("-require page.html"); got != tt.exp {
+			t.Errorf("%s: unexpected result: %#v, expected: %#v", tt.user, got, tt.exp)
+		}
+	}
+
+	tpl.SetFlagProvider(Flags{"beta": true})
+	if got, exp := tpl.NewMapper(nil).Map("-require page.html"), "new|new"; got != exp {
+		t.Errorf("unexpected result for Flags: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,132 @@
+package placesmap
+
+import (
+	"bytes"
+	"html"
+	"sort"
+	"strings"
+)
+
+// ValidationErrors are the errors of a submitted form
+type ValidationErrors interface {
+	// Fields returns the names of the fields with errors in the order of the form
+	Fields() []string
+
+	// Errors returns the error messages for the given field
+	Errors(field string) []string
+}
+
+// FieldErrors is a ValidationErrors of error messages by field name, the fields being sorted by name
+type FieldErrors map[string][]string
+
+func (f FieldErrors) Fields() []string {
+	fields := make([]string, 0, len(f))
+	for k, v := range f {
+		if len(v) > 0 {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func (f FieldErrors) Errors(field string) []string {
+	return f[field]
+}
+
+// FormMapper renders accessible markup for the errors of a form via the directives
+//
+//	-form-errors name           the summary of all errors with links to the fields
+//	-aria name field            the attributes aria-invalid and aria-describedby for the input of field
+//	-field-errors name field    the errors of field, referenced by aria-describedby
+//
+// where name is the name under which the FormMapper is registered. The id of the input of a field must
+// be IDPrefix followed by the name of the field. Without errors, the directives render the empty string.
+type FormMapper struct {
+	Errors   ValidationErrors
+	Title    string // the heading of the summary, "" means "Please correct the following errors"
+	IDPrefix string // the prefix of the ids of the inputs
+}
+
+// Map returns the empty string, the FormMapper is used via its directives
+func (f *FormMapper) Map(string) string { return "" }
+
+func (f *FormMapper) id(field string) string {
+	return f.IDPrefix + field
+}
+
+func (f *FormMapper) errors(field string) []string {
+	if f.Errors == nil {
+		return nil
+	}
+	return f.Errors.Errors(field)
+}
+
+// Summary returns the summary of all errors, a block with role alert that links
+// every error message to the input of its field
+func (f *FormMapper) Summary() string {
+	if f.Errors == nil {
+		return ""
+	}
+	var bf bytes.Buffer
+	for _, field := range f.Errors.Fields() {
+		for _, msg := range f.errors(field) {
+			bf.WriteString(`<li><a href="#`)
+			bf.WriteString(AttrEscape(f.id(field)))
+			bf.WriteString(`">`)
+			bf.WriteString(html.EscapeString(msg))
+			bf.WriteString("</a></li>")
+		}
+	}
+	if bf.Len() == 0 {
+		return ""
+	}
+
+	title := f.Title
+	if title == "" {
+		title = "Please correct the following errors"
+	}
+	titleID := AttrEscape(f.IDPrefix + "form-errors-title")
+	return `<div class="form-errors" role="alert" tabindex="-1" aria-labelledby="` + titleID + `">` +
+		`<h2 id="` + titleID + `">` + html.EscapeString(title) + "</h2><ul>" + bf.String() + "</ul></div>"
+}
+
+// Aria returns the attributes for the input of the given field, starting with a space
+func (f *FormMapper) Aria(field string) string {
+	if len(f.errors(field)) == 0 {
+		return ""
+	}
+	return ` aria-invalid="true" aria-describedby="` + AttrEscape(f.id(field)+"-error") + `"`
+}
+
+// FieldErrors returns the errors of the given field
+func (f *FormMapper) FieldErrors(field string) string {
+	errs := f.errors(field)
+	if len(errs) == 0 {
+		return ""
+	}
+	msgs := make([]string, len(errs))
+	for i, msg := range errs {
+		msgs[i] = html.EscapeString(msg)
+	}
+	return `<span class="field-error" id="` + AttrEscape(f.id(field)+"-error") + `">` +
+		strings.Join(msgs, "<br>") + "</span>"
+}
+
+// formMapper returns the FormMapper registered under the first of the given args
+// and the remaining args
+func (r *RenderContext) formMapper(rest string) (*FormMapper, string, bool) {
+	s := strings.Fields(rest)
+	if len(s) == 0 {
+		return nil, "", false
+	}
+	mp, ok := r.mapper(s[0])
+	if !ok {
+		return nil, "", false
+	}
+	f, is := mp.(*FormMapper)
+	if !is {
+		return nil, "", false
+	}
+	return f, strings.Join(s[1:], " "), true
+}
@@ -0,0 +1,40 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestFormMapper(t *testing.T) {
+	form := &FormMapper{
+		Errors:   FieldErrors{"email": {"is <required>"}, "name": {"too short", "no digits"}, "zip": nil},
+		IDPrefix: "signup-",
+	}
+	tpl := newTemplate()
+	m := tpl.NewMapper(map[string]places.Mapper{"form": form})
+
+	tests := map[string]string{
+		"-aria form email":         ` aria-invalid="true" aria-describedby="signup-email-error"`,
+		"-aria form zip":           "",
+		"-field-errors form email": `<span class="field-error" id="signup-email-error">is &lt;required&gt;</span>`,
+		"-field-errors form name":  `<span class="field-error" id="signup-name-error">too short<br>no digits</span>`,
+		"-field-errors form zip":   "",
+		"-form-errors form": `<div class="form-errors" role="alert" tabindex="-1" aria-labelledby="signup-form-errors-title">` +
+			`<h2 id="signup-form-errors-title">Please correct the following errors</h2><ul>` +
+			`<li><a href="#signup-email">is &lt;required&gt;</a></li>` +
+			`<li><a href="#signup-name">too short</a></li>` +
+			`<li><a href="#signup-name">no digits</a></li></ul></div>`,
+		"-form-errors missing": "",
+	}
+
+	for input, exp := range tests {
+		if got := m.Map(input); got != exp {
+			t.Errorf("%#v: unexpected result: %#v, expected: %#v", input, got, exp)
+		}
+	}
+
+	empty := tpl.NewMapper(map[string]places.Mapper{"form": &FormMapper{}})
+	if got := empty.Map("-form-errors form"); got != "" {
+		t.Errorf("unexpected summary without errors: %#v", got)
+	}
+}
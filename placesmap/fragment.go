@@ -0,0 +1,182 @@
+package placesmap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/metakeule/places"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fragments caches the rendered includes whose templates declare a cache_ttl
+type fragments struct {
+	mx      sync.Mutex
+	stale   time.Duration // how long an expired fragment may be served while it is rendered again
+	entries map[string]*fragment
+	now     func() time.Time
+	mark    string // the random prefix of the markers that stand for the nonce, see serveFragment
+}
+
+type fragment struct {
+	name       string
+	val        string
+	expires    time.Time
+	ready      chan struct{}     // closed when the first render is done
+	refreshing bool              // a render in the background is running
+	compressed map[string][]byte // the compressed pages by content encoding, see EnableCompression
+}
+
+// EnableFragmentCache enables the cache for includes whose templates declare a cache_ttl in their
+// front matter (see Meta). Within the TTL the rendered fragment is served from the cache.
+// Afterwards, for the given stale duration, the expired fragment is still served immediately while
+// it is rendered again in the background, so that heavy fragments like sidebars never block
+// a request. Only one render per fragment runs at a time, concurrent requests for a fragment
+// that is not cached yet wait for it. Fragments that are older than the TTL plus the stale
+// duration are rendered again before they are served.
+//
+// A fragment is cached per template, variant, theme, locale and print mode. It must not depend
+// on other values of the request (i.e. no user specific values) and is only cached, if it is not
+// included within a loop. The nonce of the Content-Security-Policy (see SetCSP) is inserted
+// when the fragment is served, so that every response gets its own. Renders in the background use the mappers of the request that
+// triggered them, but are not canceled with its context. Failed renders are not cached.
+func (h *HTMLTemplate) EnableFragmentCache(stale time.Duration) {
+	h.Lock()
+	h.fragments = newFragments(stale)
+	h.Unlock()
+}
+
+// InvalidateFragments removes the cached fragments of the templates with the given names,
+// all cached fragments if no name is given
+func (h *HTMLTemplate) InvalidateFragments(names ...string) {
+	h.RLock()
+	f := h.fragments
+	h.RUnlock()
+	if f == nil {
+		return
+	}
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if len(names) == 0 {
+		f.entries = map[string]*fragment{}
+		return
+	}
+	remove := map[string]bool{}
+	for _, name := range names {
+		remove[name] = true
+	}
+	for key, e := range f.entries {
+		if remove[e.name] {
+			delete(f.entries, key)
+		}
+	}
+}
+
+func newFragments(stale time.Duration) *fragments {
+	b := make([]byte, 16)
+	// the marker must not be predictable for the values of the mappers
+	rand.Read(b)
+	return &fragments{stale: stale, entries: map[string]*fragment{}, now: time.Now, mark: "\x00nonce-" + hex.EncodeToString(b)}
+}
+
+// serveFragment returns the given cached fragment with the markers of the nonce replaced by
+// the nonce of the render (see nonceAttr and nonceValue). Renders for the cache keep the markers.
+func (r *RenderContext) serveFragment(val string) string {
+	mark := r.fragments.mark
+	if r.caching > 0 || !strings.Contains(val, mark) {
+		return val
+	}
+	return strings.NewReplacer(mark+"a", r.nonceAttr(), mark+"v", r.nonceValue()).Replace(val)
+}
+
+// cachedInclude returns the fragment for the template with the given name and variant from
+// the cache and renders it, if needed. ok is false, if the include is not cached.
+func (r *RenderContext) cachedInclude(name, variant string, t *places.Template, m places.Mapper) (res string, ok bool) {
+	if r.fragments == nil || r.preferred != nil || m != places.Mapper(r) {
+		return "", false
+	}
+	meta, _ := r.hm.HTMLTemplate.Meta(name)
+	if meta.CacheTTL <= 0 {
+		return "", false
+	}
+	// includes within a cycle are not cached, since the render would wait for its own fragment,
+	// the stack covers cycles via variants and themes that the dependency graph does not know
+	for _, inc := range r.includes {
+		if inc == name {
+			return "", false
+		}
+	}
+	if r.hm.HTMLTemplate.cyclic(name) {
+		return "", false
+	}
+
+	f := r.fragments
+	key := strings.Join([]string{name, variant, r.theme, r.hm.locale, strconv.FormatBool(r.hm.print)}, "\x00")
+	f.mx.Lock()
+	e := f.entries[key]
+	if len(r.includes) == 0 {
+		defer func() { r.page = e }()
+	}
+	if e != nil {
+		select {
+		case <-e.ready:
+		default:
+			// the first render is running
+			f.mx.Unlock()
+			<-e.ready
+			f.mx.Lock()
+			defer f.mx.Unlock()
+			return r.serveFragment(e.val), true
+		}
+		now := f.now()
+		if now.Before(e.expires.Add(f.stale)) {
+			if !now.Before(e.expires) && !e.refreshing {
+				e.refreshing = true
+				go r.refresh(e, t, meta.CacheTTL)
+			}
+			val := e.val
+			f.mx.Unlock()
+			return r.serveFragment(val), true
+		}
+	}
+	e = &fragment{name: name, ready: make(chan struct{})}
+	f.entries[key] = e
+	f.mx.Unlock()
+
+	// ready is closed even if the render panics, so that other renders don't wait forever
+	done := false
+	defer func() {
+		f.mx.Lock()
+		e.val, e.expires = res, f.now().Add(meta.CacheTTL)
+		res = r.serveFragment(res)
+		// the error might stem from an earlier part of the render, but is not worth the risk
+		if (!done || r.err != nil) && f.entries[key] == e {
+			delete(f.entries, key)
+		}
+		close(e.ready)
+		f.mx.Unlock()
+	}()
+	r.caching++
+	res = r.include(name, t, m)
+	r.caching--
+	done = true
+	return res, true
+}
+
+// refresh renders the cached fragment e again in the background
+func (r *RenderContext) refresh(e *fragment, t *places.Template, ttl time.Duration) {
+	c := r.hm.newRender()
+	c.ctx = context.Background()
+	c.theme, c.caching = r.theme, 1
+	val := c.include(e.name, t, c)
+
+	f := r.fragments
+	f.mx.Lock()
+	if c.err == nil {
+		e.val, e.expires, e.compressed = val, f.now().Add(ttl), nil
+	}
+	e.refreshing = false
+	f.mx.Unlock()
+}
@@ -0,0 +1,140 @@
+package placesmap
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFragmentCache(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@-require sidebar.html@>",
+		"sidebar.html", "---\ncache_ttl: 1m\n---\nsidebar <@trending@>",
+	)
+	tpl.EnableFragmentCache(time.Minute)
+	now := time.Now()
+	var mx sync.Mutex
+	tpl.fragments.now = func() time.Time {
+		mx.Lock()
+		defer mx.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mx.Lock()
+		now = now.Add(d)
+		mx.Unlock()
+	}
+
+	var calls int32
+	hm := tpl.NewMapper(map[string]places.Mapper{"trending": MapFunc(func(string) string {
+		return fmt.Sprintf("v%d", atomic.AddInt32(&calls, 1))
+	})})
+	render := func(exp string) {
+		t.Helper()
+		if got := hm.Map("-require page.html"); got != exp {
+			t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+		}
+	}
+	refreshed := func() {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			tpl.fragments.mx.Lock()
+			done := true
+			for _, e := range tpl.fragments.entries {
+				done = done && !e.refreshing
+			}
+			tpl.fragments.mx.Unlock()
+			if done {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("fragment was not rendered in the background")
+	}
+
+	render("sidebar v1")
+	render("sidebar v1")
+
+	// stale: served immediately, rendered in the background
+	advance(90 * time.Second)
+	render("sidebar v1")
+	refreshed()
+	render("sidebar v2")
+
+	// too stale: rendered before it is served
+	advance(3 * time.Minute)
+	render("sidebar v3")
+
+	tpl.InvalidateFragments("sidebar.html")
+	render("sidebar v4")
+
+	// within loops, fragments are not cached
+	hm.m["items"] = list{keyed("a"), keyed("b")}
+	if got := hm.Map("-each items sidebar.html"); got != "sidebar v5sidebar v6" {
+		t.Errorf("unexpected result within loop: %#v", got)
+	}
+}
+
+func TestFragmentCacheCycle(t *testing.T) {
+	tpl := newTemplate(
+		"a.html", "---\ncache_ttl: 1m\n---\na<@-require b.html@>",
+		"b.html", "b<@-require a.html@>",
+	)
+	tpl.EnableFragmentCache(time.Minute)
+
+	done := make(chan string, 1)
+	go func() { done <- tpl.NewMapper(nil).Map("-require a.html") }()
+	select {
+	case got := <-done:
+		if !strings.HasPrefix(got, "abab") {
+			t.Errorf("unexpected result: %#v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the render of a cached template within a cycle must not hang")
+	}
+	if !tpl.cyclic("b.html") || tpl.cyclic("missing.html") {
+		t.Errorf("unexpected result of cyclic")
+	}
+}
+
+func TestFragmentCacheRequestValues(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@-require side.html@>",
+		"side.html", "---\ncache_ttl: 1m\n---\n<script>a</script><@-nonce@><@-if printmode print.html@>",
+		"side.b.html", "<script>b</script>",
+		"print.html", " print",
+	)
+	tpl.EnableFragmentCache(time.Minute)
+	render := func(nonce string, hm *HTMLTemplateMapper) string {
+		if nonce != "" {
+			hm = hm.WithNonce(&NonceMapper{nonce: nonce})
+		}
+		return hm.Map("-require page.html")
+	}
+	b := &VariantMapper{Bucket: func(string) string { return "b" }}
+
+	tests := []struct {
+		nonce string
+		hm    *HTMLTemplateMapper
+		exp   string
+	}{
+		{"n1", tpl.NewMapper(nil), `<script nonce="n1">a</script>n1`},
+		{"n2", tpl.NewMapper(nil), `<script nonce="n2">a</script>n2`},
+		{"", tpl.NewMapper(nil), `<script>a</script>`},
+		{"n3", tpl.NewMapper(nil).WithVariants(b), `<script nonce="n3">b</script>`},
+		{"", tpl.NewMapper(nil).WithPrintMode(), `<script>a</script> print`},
+		{"n4", tpl.NewMapper(nil), `<script nonce="n4">a</script>n4`},
+	}
+	for i, test := range tests {
+		if got := render(test.nonce, test.hm); got != test.exp {
+			t.Errorf("%d: unexpected result: %#v, expected: %#v", i, got, test.exp)
+		}
+	}
+	if n := len(tpl.fragments.entries); n != 3 {
+		t.Errorf("expected 3 cached fragments, got %d", n)
+	}
+}
@@ -0,0 +1,196 @@
+package placesmap
+
+import (
+	"bytes"
+	"github.com/metakeule/places"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Meta is the metadata of a template, declared in the optional front matter
+// at the top of the template, either in YAML style
+//
+//	---
+//	title: Welcome
+//	layout: layout.html
+//	cache_ttl: 5m
+//	required: [user, title]
+//	---
+//
+// or in TOML style
+//
+//	+++
+//	title = "Welcome"
+//	required = ["user", "title"]
+//	+++
+//
+// Only flat key value pairs and lists of strings are supported.
+// The front matter is removed from the template. A block is only taken as front matter, if it has
+// at least one key and every line of it is a key value pair, a list item or a comment, so that
+// templates that merely start with --- or +++ (e.g. a horizontal rule) are kept as they are.
+type Meta struct {
+	Title    string
+	Layout   string
+	CacheTTL time.Duration // a duration like 5m or a number of seconds, see EnableFragmentCache
+	Required []string      // required placeholders
+
+	// Params contains all key value pairs; list values are joined with a comma
+	Params map[string]string
+}
+
+// Missing returns the required placeholders that have no mapper within the given mappers
+func (m Meta) Missing(mappers map[string]places.Mapper) (missing []string) {
+	for _, r := range m.Required {
+		if _, has := mappers[r]; !has {
+			missing = append(missing, r)
+		}
+	}
+	return
+}
+
+// Meta returns the metadata of the template with the given name. If the template has no
+// front matter, false is returned.
+func (h *HTMLTemplate) Meta(name string) (m Meta, has bool) {
+	h.RLock()
+	m, has = h.meta[name]
+	h.RUnlock()
+	return
+}
+
+// splitFrontMatter splits the front matter from the template.
+// If the template has no complete front matter, the delimiter is empty.
+func splitFrontMatter(tpl []byte) (delim string, front []byte, body []byte) {
+	for _, d := range []string{"---", "+++"} {
+		for _, nl := range []string{"\n", "\r\n"} {
+			start := d + nl
+			if !bytes.HasPrefix(tpl, []byte(start)) {
+				continue
+			}
+			rest := tpl[len(start):]
+			end := bytes.Index(rest, []byte(nl+d))
+			if end == -1 {
+				return "", nil, tpl
+			}
+			body = rest[end+len(nl)+len(d):]
+			// strip the line break after the closing delimiter
+			body = bytes.TrimPrefix(body, []byte(nl))
+			return d, rest[:end], body
+		}
+	}
+	return "", nil, tpl
+}
+
+// unquote removes surrounding quotes
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		if s[0] == '"' {
+			if u, err := strconv.Unquote(s); err == nil {
+				return u
+			}
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseList parses a list like [a, "b"]
+func parseList(s string) []string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = unquote(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// validKey returns whether the given key of the front matter consists of letters, digits,
+// underscores and hyphens
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFrontMatter parses the front matter of the given template and returns the template without it.
+func parseFrontMatter(tpl []byte) (meta Meta, body []byte, has bool) {
+	delim, front, body := splitFrontMatter(tpl)
+	if delim == "" {
+		return meta, tpl, false
+	}
+
+	sep := ":"
+	if delim == "+++" {
+		sep = "="
+	}
+
+	meta.Params = map[string]string{}
+	lists := map[string][]string{}
+	var lastKey string
+
+	for _, line := range strings.Split(string(front), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' {
+			continue
+		}
+
+		// YAML style list items following a key without value
+		if sep == ":" && strings.HasPrefix(trimmed, "- ") && lastKey != "" {
+			lists[lastKey] = append(lists[lastKey], unquote(trimmed[2:]))
+			continue
+		}
+
+		idx := strings.Index(line, sep)
+		if idx == -1 {
+			return Meta{}, tpl, false
+		}
+		key := strings.TrimSpace(line[:idx])
+		if !validKey(key) {
+			return Meta{}, tpl, false
+		}
+		val := strings.TrimSpace(line[idx+1:])
+		lastKey = key
+
+		if strings.HasPrefix(val, "[") {
+			lists[key] = parseList(val)
+			continue
+		}
+		meta.Params[key] = unquote(val)
+	}
+
+	for k, l := range lists {
+		meta.Params[k] = strings.Join(l, ",")
+	}
+	if len(meta.Params) == 0 {
+		return Meta{}, tpl, false
+	}
+
+	meta.Title = meta.Params["title"]
+	meta.Layout = meta.Params["layout"]
+	meta.Required = lists["required"]
+	if meta.Required == nil && meta.Params["required"] != "" {
+		meta.Required = parseList(meta.Params["required"])
+	}
+
+	if ttl := meta.Params["cache_ttl"]; ttl != "" {
+		if secs, err := strconv.Atoi(ttl); err == nil {
+			meta.CacheTTL = time.Duration(secs) * time.Second
+		} else if d, err := time.ParseDuration(ttl); err == nil {
+			meta.CacheTTL = d
+		}
+	}
+
+	return meta, body, true
+}
@@ -0,0 +1,49 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+	"time"
+)
+
+func TestFrontMatter(t *testing.T) {
+	tpl := newTemplate(
+		"yaml.html", "---\ntitle: Welcome\nlayout: \"layout.html\"\ncache_ttl: 5m\nrequired:\n  - user\n  - title\n---\nhello <@user@>",
+		"toml.html", "+++\ntitle = \"Start\"\ncache_ttl = 30\nrequired = [\"user\"]\n+++\nhi",
+		"plain.html", "--- no front matter",
+		"rule.html", "---\n<p>a: b</p>\n---\n<@user@>",
+		"empty.html", "+++\n\n+++\nx",
+	)
+
+	m, has := tpl.Meta("yaml.html")
+	if !has || m.Title != "Welcome" || m.Layout != "layout.html" || m.CacheTTL != 5*time.Minute {
+		t.Errorf("unexpected meta: %#v", m)
+	}
+
+	if missing := m.Missing(map[string]places.Mapper{"user": String("u")}); len(missing) != 1 || missing[0] != "title" {
+		t.Errorf("unexpected missing placeholders: %#v", missing)
+	}
+
+	if got := tpl.NewMapper(map[string]places.Mapper{"user": String("u")}).Map("-require yaml.html"); got != "hello u" {
+		t.Errorf("front matter not removed: %#v", got)
+	}
+
+	m, has = tpl.Meta("toml.html")
+	if !has || m.Title != "Start" || m.CacheTTL != 30*time.Second || len(m.Required) != 1 {
+		t.Errorf("unexpected meta: %#v", m)
+	}
+
+	if _, has = tpl.Meta("plain.html"); has {
+		t.Errorf("plain.html has no front matter")
+	}
+
+	// blocks that are no key value pairs are kept
+	for name, exp := range map[string]string{"rule.html": "---\n<p>a: b</p>\n---\nu", "empty.html": "+++\n\n+++\nx"} {
+		if _, has = tpl.Meta(name); has {
+			t.Errorf("%s has no front matter", name)
+		}
+		if got := tpl.NewMapper(map[string]places.Mapper{"user": String("u")}).Map("-require " + name); got != exp {
+			t.Errorf("unexpected result of %s: %#v, expected: %#v", name, got, exp)
+		}
+	}
+}
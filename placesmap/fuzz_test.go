@@ -0,0 +1,72 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+)
+
+func FuzzSplit(f *testing.F) {
+	for _, s := range []string{"", "-", "- ", "-each", "-each items row.html", "name", "-if  a  b else c", "--x"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		prefix, rest := split(input)
+		if strings.ContainsRune(prefix, ' ') {
+			t.Fatalf("prefix %#v contains whitespace", prefix)
+		}
+		if len(prefix)+len(rest) > len(input) {
+			t.Fatalf("split(%#v) = %#v, %#v", input, prefix, rest)
+		}
+	})
+}
+
+func FuzzEachArgs(f *testing.F) {
+	for _, s := range []string{"", "x", "x ", " x", "items row.html", "items row.html else empty.html", "items.a.b row.html", "items else"} {
+		f.Add(s)
+	}
+
+	tpl := newTemplate("row.html", "[<@name@>]", "empty.html", "empty")
+	m := map[string]places.Mapper{
+		"items": list{keyed("a"), keyed("b")},
+		"empty": list{},
+		"x":     String("x"),
+	}
+
+	f.Fuzz(func(t *testing.T, args string) {
+		tpl.NewMapper(m).Map("-each " + args)
+	})
+}
+
+// FuzzRender renders arbitrary templates, that may include each other
+func FuzzRender(f *testing.F) {
+	for _, s := range []string{
+		"<@-each items row.html@>",
+		"<@-each items@>",
+		"<@-each items.a.b page.html@>",
+		"<@-require page.html@>",
+		"<@-if x page.html else row.html@>",
+		"<@-tree tree page.html@><@-children@><@-depth@>",
+		"<@-calc (1 + x) / 0@>",
+		"<@-island a x page.html@>",
+		"<@-js x@><@-url x@><@-attr x@><@-attrs x@><@-include inc@>",
+		"<@-@><@ @><@-@@>",
+	} {
+		f.Add(s)
+	}
+
+	// templates including each other take exponential time up to MaxIncludes
+	defer func(max int) { MaxIncludes = max }(MaxIncludes)
+	MaxIncludes = 1000
+
+	f.Fuzz(func(t *testing.T, page string) {
+		tpl := newTemplate("page.html", page, "row.html", "<@name@><@-require page.html@>")
+		tpl.NewMapper(map[string]places.Mapper{
+			"items": list{keyed("a"), list{keyed("b")}},
+			"tree":  nodes{{text: "a", children: []node{{text: "b"}}}},
+			"x":     String("1"),
+			"inc":   String("page.html"),
+		}).Map("-require page.html")
+	})
+}
@@ -0,0 +1,84 @@
+package placesmap
+
+import (
+	"fmt"
+	"log"
+	"unicode/utf8"
+)
+
+// LengthPolicy decides what happens to values that exceed the maximum length of a LengthGuard
+type LengthPolicy int
+
+const (
+	// LengthTruncate truncates the value to the maximum length
+	LengthTruncate LengthPolicy = iota
+
+	// LengthError renders the empty string instead of the value and reports a ValueTooLongError
+	// to MapStrict
+	LengthError
+
+	// LengthLog keeps the value and logs a ValueTooLongError via log.Print
+	LengthLog
+)
+
+type ValueTooLongError struct {
+	Key    string
+	Prefix string
+	Length int
+	Max    int
+}
+
+func (v ValueTooLongError) Error() string {
+	return fmt.Sprintf("value of %#v for prefix %#v has %d bytes, more than the maximum of %d", v.Key, v.Prefix, v.Length, v.Max)
+}
+
+// LengthGuard limits the length of the values of mappers, e.g. to not dump a misrouted file body
+// into a page
+type LengthGuard struct {
+	// Max is the maximum length of values in bytes, 0 means no limit
+	Max int
+
+	// Prefixes are the maximum lengths for the values used by placeholders with the given prefixes
+	// (the empty string for placeholders without prefix), overriding Max. 0 means no limit.
+	Prefixes map[string]int
+
+	Policy LengthPolicy
+}
+
+// SetLengthGuard sets the LengthGuard for all renders that are started afterwards.
+// The Prefixes of the guard must not be changed afterwards.
+func (h *HTMLTemplate) SetLengthGuard(g LengthGuard) {
+	h.Lock()
+	h.guard = g
+	h.Unlock()
+}
+
+// max returns the maximum length for values used by placeholders with the given prefix
+func (g LengthGuard) max(prefix string) int {
+	if m, has := g.Prefixes[prefix]; has {
+		return m
+	}
+	return g.Max
+}
+
+// guardLength applies the LengthGuard to the value of the mapper for the given key
+func (r *RenderContext) guardLength(key, val string) string {
+	max := r.guard.max(r.prefix)
+	if max <= 0 || len(val) <= max {
+		return val
+	}
+	err := ValueTooLongError{Key: key, Prefix: r.prefix, Length: len(val), Max: max}
+	switch r.guard.Policy {
+	case LengthError:
+		r.fail(err)
+		return ""
+	case LengthLog:
+		log.Print(err)
+		return val
+	}
+	// don't cut runes
+	for max > 0 && !utf8.RuneStart(val[max]) {
+		max--
+	}
+	return val[:max]
+}
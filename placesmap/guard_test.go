@@ -0,0 +1,29 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestLengthGuard(t *testing.T) {
+	tpl := newTemplate("page.html", "<@body@>|<@-html body@>|<@-raw name@>")
+	hm := tpl.NewMapper(map[string]places.Mapper{"body": String("äöüäöü"), "name": String("Donald")})
+
+	tests := []struct {
+		guard LengthGuard
+		exp   string
+		err   error
+	}{
+		{LengthGuard{}, "äöüäöü|äöüäöü|Donald", nil},
+		{LengthGuard{Max: 5}, "äö|äö|Donal", nil},
+		{LengthGuard{Max: 5, Prefixes: map[string]int{"html": 0, "raw": 20}}, "äö|äöüäöü|Donald", nil},
+		{LengthGuard{Max: 6, Policy: LengthError}, "||Donald", ValueTooLongError{Key: "body", Prefix: "", Length: 12, Max: 6}},
+	}
+	for _, test := range tests {
+		tpl.SetLengthGuard(test.guard)
+		got, err := hm.MapStrict("-require page.html")
+		if got != test.exp || err != test.err {
+			t.Errorf("%+v: unexpected result: %#v (%v), expected: %#v (%v)", test.guard, got, err, test.exp, test.err)
+		}
+	}
+}
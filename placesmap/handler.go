@@ -0,0 +1,263 @@
+package placesmap
+
+import (
+	"context"
+	"fmt"
+	"github.com/metakeule/places"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// PrefixHandler handles the placeholders with a prefix, i.e. <@-prefix rest@>.
+// It returns the replacement for the placeholder, which is inserted as it is,
+// so the handler is responsible for proper escaping.
+type PrefixHandler func(c *RenderContext, rest string) string
+
+// Middleware wraps the handler for the given prefix, e.g. to log, measure or restrict
+// the use of directives. It is called for every placeholder with a prefix, including
+// the built-in ones and placeholders without prefix (prefix is the empty string then).
+type Middleware func(prefix string, next PrefixHandler) PrefixHandler
+
+type InvalidPrefixError string
+
+func (i InvalidPrefixError) Error() string {
+	return fmt.Sprintf("invalid prefix %#v", i)
+}
+
+// builtins are the built-in directives and escaping prefixes.
+// The handler for the empty prefix handles placeholders without or with unknown prefix.
+var builtins map[string]PrefixHandler
+
+func init() {
+	builtins = map[string]PrefixHandler{
+		"require": func(r *RenderContext, rest string) string {
+			return r.require(rest, r)
+		},
+		"each": (*RenderContext).each,
+		"if":   (*RenderContext).cond,
+		"with": (*RenderContext).with,
+		"flag": (*RenderContext).flag,
+		"calc": (*RenderContext).calc,
+		"eq":   compare(func(c int) bool { return c == 0 }),
+		"ne":   compare(func(c int) bool { return c != 0 }),
+		"gt":   compare(func(c int) bool { return c > 0 }),
+		"ge":   compare(func(c int) bool { return c >= 0 }),
+		"lt":   compare(func(c int) bool { return c < 0 }),
+		"le":   compare(func(c int) bool { return c <= 0 }),
+		"tree": (*RenderContext).tree,
+		"children": func(r *RenderContext, rest string) string {
+			return r.children()
+		},
+		"depth": func(r *RenderContext, rest string) string {
+			return r.treeDepth()
+		},
+		"island":  (*RenderContext).island,
+		"json-ld": (*RenderContext).jsonLD,
+		"time":    (*RenderContext).time,
+		"sri":     (*RenderContext).sri,
+		"variant": func(r *RenderContext, rest string) string {
+			if r.hm.variants == nil {
+				return ""
+			}
+			return html.EscapeString(r.hm.variants.Map(rest))
+		},
+		"nonce": func(r *RenderContext, rest string) string {
+			return r.nonceValue()
+		},
+		"nonce-attr": func(r *RenderContext, rest string) string {
+			return r.nonceAttr()
+		},
+		"slot":  (*RenderContext).slot,
+		"bool":  (*RenderContext).boolAttr,
+		"link":  (*RenderContext).link,
+		"set":   (*RenderContext).set,
+		"table": (*RenderContext).table,
+		"image": (*RenderContext).image,
+		"meta":  (*RenderContext).meta,
+		"attrs": func(r *RenderContext, rest string) string {
+			mp, ok := r.mapper(rest)
+			if !ok {
+				return ""
+			}
+			// only an AttrMapper guarantees proper escaping of names and values
+			if a, is := mp.(AttrMapper); is {
+				return a.Map(rest)
+			}
+			return ""
+		},
+		"form-errors": func(r *RenderContext, rest string) string {
+			if f, _, ok := r.formMapper(rest); ok {
+				return f.Summary()
+			}
+			return ""
+		},
+		"aria": func(r *RenderContext, rest string) string {
+			if f, field, ok := r.formMapper(rest); ok {
+				return f.Aria(field)
+			}
+			return ""
+		},
+		"field-errors": func(r *RenderContext, rest string) string {
+			if f, field, ok := r.formMapper(rest); ok {
+				return f.FieldErrors(field)
+			}
+			return ""
+		},
+		"js": filter(func(r *RenderContext, val string) string {
+			return fmt.Sprintf("%#v", val)
+		}),
+		"raw": filter(func(r *RenderContext, val string) string {
+			return val
+		}),
+		"html": filter(func(r *RenderContext, val string) string {
+			return r.norm.escapeASCII(val)
+		}),
+		"html-name": (*RenderContext).htmlName,
+		"trunc":     truncate(false),
+		"excerpt":   truncate(true),
+		"url": filter(func(r *RenderContext, val string) string {
+			return url.QueryEscape(val)
+		}),
+		"attr": filter(func(r *RenderContext, val string) string {
+			return r.norm.escapeASCII(AttrEscape(val))
+		}),
+		"include": filter(func(r *RenderContext, val string) string {
+			if val != "" {
+				return r.require(val, r)
+			}
+			return ""
+		}),
+		"": filter(func(r *RenderContext, val string) string {
+			return r.escape(val)
+		}),
+	}
+}
+
+// filter returns a handler that passes the value of the mapper named by rest to fn
+func filter(fn func(r *RenderContext, val string) string) PrefixHandler {
+	return func(r *RenderContext, rest string) string {
+		mp, ok := r.mapper(rest)
+		if !ok {
+			return ""
+		}
+		return fn(r, r.mapValue(mp, rest))
+	}
+}
+
+// Handle registers the handler for the given prefix, so that <@-prefix rest@> is replaced by
+// the result of fn. A handler for the prefix of a built-in directive (e.g. "each") replaces the
+// built-in one. If fn is nil, the handler for the prefix is removed.
+// The prefix must not be empty and contain no whitespace.
+func (h *HTMLTemplate) Handle(prefix string, fn PrefixHandler) error {
+	if prefix == "" || strings.HasPrefix(prefix, "-") || strings.ContainsAny(prefix, " \t\r\n") {
+		return InvalidPrefixError(prefix)
+	}
+	h.Lock()
+	defer h.Unlock()
+	// the map is copied, since running renders keep using the previous one
+	handlers := make(map[string]PrefixHandler, len(h.handlers)+1)
+	for k, v := range h.handlers {
+		handlers[k] = v
+	}
+	if fn == nil {
+		delete(handlers, prefix)
+	} else {
+		handlers[prefix] = fn
+	}
+	h.handlers = handlers
+	return nil
+}
+
+// Use adds middlewares that wrap all prefix handlers of renders that are started afterwards.
+// The middleware added first is the outermost.
+func (h *HTMLTemplate) Use(mw ...Middleware) {
+	h.Lock()
+	h.middlewares = append(h.middlewares, mw...)
+	h.Unlock()
+}
+
+// handler returns the handler for the given prefix, wrapped by the middlewares
+func (r *RenderContext) handler(prefix string) PrefixHandler {
+	fn, ok := r.handlers[prefix]
+	if !ok {
+		fn, ok = builtins[prefix]
+	}
+	if !ok {
+		fn = builtins[""]
+	}
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		fn = r.middlewares[i](prefix, fn)
+	}
+	return fn
+}
+
+// Template returns the HTMLTemplate that is rendered
+func (r *RenderContext) Template() *HTMLTemplate {
+	return r.hm.HTMLTemplate
+}
+
+// Context returns the context of the render (see HTMLTemplate.NewMapperContext)
+func (r *RenderContext) Context() context.Context {
+	return r.ctx
+}
+
+// Locale returns the locale of the render (see HTMLTemplateMapper.WithLocale)
+func (r *RenderContext) Locale() string {
+	return r.hm.locale
+}
+
+// Mapper returns the mapper that is registered for the given name
+func (r *RenderContext) Mapper(name string) (places.Mapper, bool) {
+	return r.mapper(name)
+}
+
+// Value returns the unescaped value for the given name. The current loop scope is consulted
+// before the registered mappers.
+func (r *RenderContext) Value(name string) string {
+	if r.preferred != nil {
+		if v := r.preferred.Map(name); v != "" {
+			return v
+		}
+	}
+	mp, ok := r.mapper(name)
+	if !ok {
+		return ""
+	}
+	return r.mapValue(mp, name)
+}
+
+// Scope returns the current element of the innermost loop, nil outside of loops
+func (r *RenderContext) Scope() places.Mapper {
+	return r.preferred
+}
+
+// Scopes returns the current elements of all loops, the outermost first
+func (r *RenderContext) Scopes() []places.Mapper {
+	return append([]places.Mapper(nil), r.scopes...)
+}
+
+// Includes returns the names of the currently rendered includes, the outermost first
+func (r *RenderContext) Includes() []string {
+	return append([]string(nil), r.includes...)
+}
+
+// Require renders the template with the given name within the current scope
+func (r *RenderContext) Require(name string) string {
+	return r.require(name, r)
+}
+
+// RequireWith renders the template with the given name with scope as the current loop element
+func (r *RenderContext) RequireWith(name string, scope places.Mapper) string {
+	t, ok := r.lookup(name)
+	if !ok {
+		return ""
+	}
+	if !r.push(name) {
+		return ""
+	}
+	var bf strings.Builder
+	r.renderWith(&bf, t, scope)
+	r.pop(bf.Len())
+	return bf.String()
+}
@@ -0,0 +1,47 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+)
+
+func TestHandle(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", `<@-each items row.html@>|<@-upper title@>`,
+		"row.html", `<@-info name@>;`,
+	)
+
+	tpl.Handle("upper", func(c *RenderContext, rest string) string {
+		return strings.ToUpper(c.Value(rest)) + "/" + c.Locale()
+	})
+	tpl.Handle("info", func(c *RenderContext, rest string) string {
+		return c.Value(rest) + ":" + strings.Join(c.Includes(), ",")
+	})
+
+	var prefixes []string
+	tpl.Use(func(prefix string, next PrefixHandler) PrefixHandler {
+		return func(c *RenderContext, rest string) string {
+			prefixes = append(prefixes, prefix)
+			return next(c, rest)
+		}
+	})
+
+	got := tpl.NewMapper(map[string]places.Mapper{
+		"title": String("hi"),
+		"items": list{keyed("a"), keyed("b")},
+	}).WithLocale("de").Map("-require page.html")
+
+	exp := `a:page.html,row.html;b:page.html,row.html;|HI/de`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	if strings.Join(prefixes, ",") != "require,each,info,info,upper" {
+		t.Errorf("unexpected prefixes: %#v", prefixes)
+	}
+
+	if err := tpl.Handle("a b", nil); err == nil {
+		t.Errorf("expected error for invalid prefix")
+	}
+}
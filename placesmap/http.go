@@ -0,0 +1,283 @@
+package placesmap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/metakeule/places"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type TemplateNotFoundError string
+
+func (t TemplateNotFoundError) Error() string {
+	return fmt.Sprintf("template %#v not found", t)
+}
+
+// Keyed is implemented by mappers that know the keys they can map.
+// They are serialized as JSON objects by Negotiate and MarshalMappers.
+type Keyed interface {
+	places.Mapper
+	Keys() []string
+}
+
+// jsonValue returns the JSON representation of the given mapper
+func jsonValue(key string, mp places.Mapper) interface{} {
+	switch v := mp.(type) {
+	case json.Marshaler:
+		return v
+	case Keyed:
+		obj := map[string]interface{}{}
+		for _, k := range v.Keys() {
+			obj[k] = v.Map(k)
+		}
+		return obj
+	case NMapper:
+		l := v.Len()
+		arr := make([]interface{}, l)
+		for i := 0; i < l; i++ {
+			arr[i] = jsonValue("", v.NMap(i, ""))
+		}
+		return arr
+	case Iterator:
+		arr := []interface{}{}
+		for {
+			m, ok := v.Next()
+			if !ok {
+				break
+			}
+			arr = append(arr, jsonValue("", m))
+		}
+		return arr
+	default:
+		return mp.Map(key)
+	}
+}
+
+// MarshalMappers returns the data of the given mappers as JSON object. Every mapper is
+// serialized by the following rules:
+//   - mappers implementing json.Marshaler are marshalled
+//   - Keyed mappers become objects with the values of their keys
+//   - NMappers and Iterators become arrays of their elements
+//   - all other mappers become the string they return for their key
+func MarshalMappers(m map[string]places.Mapper) ([]byte, error) {
+	obj := make(map[string]interface{}, len(m))
+	for k, mp := range m {
+		obj[k] = jsonValue(k, mp)
+	}
+	return json.Marshal(obj)
+}
+
+// Render renders the template with the given name and the given mappers as text/html to the
+// ResponseWriter, encoded in the charset of the template (see HTMLTemplate.SetCharset).
+// If a Content-Security-Policy is set (see HTMLTemplate.SetCSP), it is sent along with a new nonce.
+// If a checksum header is set (see HTMLTemplate.SetChecksumHeader), it is sent with the checksum of the page.
+// The templates that composed the page are recorded according to HTMLTemplate.SetAccessLog.
+// If the render fails (see MapStrict), nothing of the page is sent, but a 500 Internal Server Error,
+// and the first error of the render is returned.
+// If the template does not exist, TemplateNotFoundError is returned and nothing is written.
+// Requests for a DebugDump get the dump instead of the page, see Debug.
+func Render(wr http.ResponseWriter, t *HTMLTemplate, name string, m map[string]places.Mapper) error {
+	return serve(wr, nil, t, name, m, 0)
+}
+
+// serve writes the page for Render, Negotiate and Stream. If the request is not nil, cached pages
+// are served compressed according to its Accept-Encoding header (see EnableCompression).
+// The page is streamed with the given threshold (see Stream), if it is > 0.
+func serve(wr http.ResponseWriter, rq *http.Request, t *HTMLTemplate, name string, m map[string]places.Mapper, threshold int) error {
+	if !t.Has(name) {
+		return TemplateNotFoundError(name)
+	}
+	if _, is := wr.(*debugWriter); is {
+		return writeDump(wr, t, name, m)
+	}
+	p, err := newResponse(wr, rq, t, name, m)
+	if err != nil {
+		return err
+	}
+	if threshold > 0 {
+		return p.stream(threshold)
+	}
+	return p.write(p.r.limited("-require " + name))
+}
+
+// response is the response of a page, shared by Render and Stream, so that both send the same
+// headers and record the page the same way
+type response struct {
+	wr          http.ResponseWriter
+	rq          *http.Request // nil, if the page is not compressed
+	name        string
+	r           *RenderContext
+	policy      string // the Content-Security-Policy
+	cs          Charset
+	checksum    string // the checksum header, see SetChecksumHeader
+	compressors []Compressor
+	access      AccessLog
+}
+
+// newResponse prepares the render of the page with the given name
+func newResponse(wr http.ResponseWriter, rq *http.Request, t *HTMLTemplate, name string, m map[string]places.Mapper) (*response, error) {
+	hm, policy, err := t.cspMapper(m)
+	if err != nil {
+		return nil, err
+	}
+	if rq != nil {
+		hm.ctx = WithRequest(context.Background(), rq)
+	}
+	p := &response{wr: wr, rq: rq, name: name, policy: policy, cs: t.Charset()}
+	t.RLock()
+	p.checksum, p.compressors, p.access = t.checksum, t.compressors, t.access
+	t.RUnlock()
+	p.r = hm.newRender()
+	if p.access.Header != "" || p.access.Record != nil {
+		p.r.composed = &composition{seen: map[string]bool{}}
+	}
+	return p, nil
+}
+
+// header sets the headers that are known before the page is rendered
+func (p *response) header() {
+	if p.policy != "" {
+		p.wr.Header().Set("Content-Security-Policy", p.policy)
+	}
+	p.wr.Header().Set("Content-Type", p.cs.contentType())
+	if p.rq != nil && len(p.compressors) > 0 {
+		p.wr.Header().Add("Vary", "Accept-Encoding")
+	}
+}
+
+// record sets the checksum header and the access log header to the given checksum and the
+// composition of the page and passes the composition to AccessLog.Record.
+// If h is nil, no headers are set.
+func (p *response) record(h http.Header, sum string) {
+	if h != nil && p.checksum != "" {
+		h.Set(p.checksum, sum)
+	}
+	if p.r.composed == nil {
+		return
+	}
+	c := p.r.composed.result(p.name)
+	if h != nil && p.access.Header != "" {
+		h.Set(p.access.Header, c.String())
+	}
+	if p.access.Record != nil {
+		p.access.Record(c)
+	}
+}
+
+// fail responds with a 500 Internal Server Error and returns the error of the render
+func (p *response) fail() error {
+	p.record(nil, "")
+	http.Error(p.wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	return p.r.err
+}
+
+// write writes the rendered page
+func (p *response) write(page string) error {
+	if p.r.err != nil {
+		return p.fail()
+	}
+	p.header()
+	p.record(p.wr.Header(), checksum(page))
+	if p.rq != nil && len(p.compressors) > 0 {
+		if c := acceptedEncoding(p.rq.Header.Get("Accept-Encoding"), p.compressors); c != nil && p.r.page != nil {
+			return writeCompressed(p.wr, p.r, page, p.cs, c)
+		}
+	}
+	_, err := io.WriteString(p.cs.encoder(p.wr), page)
+	return err
+}
+
+// writeCompressed writes the cached page compressed by c
+func writeCompressed(wr http.ResponseWriter, r *RenderContext, page string, cs Charset, c Compressor) error {
+	var encoded bytes.Buffer
+	if _, err := io.WriteString(cs.encoder(&encoded), page); err != nil {
+		return err
+	}
+	b, err := r.page.compress(r.fragments, page, encoded.Bytes(), c)
+	if err != nil {
+		return err
+	}
+	wr.Header().Set("Content-Encoding", c.Encoding())
+	wr.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	_, err = wr.Write(b)
+	return err
+}
+
+// Negotiate renders the template with the given name like Render, unless the client prefers
+// application/json over text/html (via the Accept header). In that case the mappers are
+// serialized via MarshalMappers and sent as JSON. Cached pages may be sent compressed,
+// see HTMLTemplate.EnableCompression.
+// This way API and web endpoints can share the same handlers and data assembly.
+func Negotiate(wr http.ResponseWriter, rq *http.Request, t *HTMLTemplate, name string, m map[string]places.Mapper) error {
+	wr.Header().Add("Vary", "Accept")
+	if _, is := wr.(*debugWriter); is || !prefersJSON(rq.Header.Get("Accept")) {
+		return serve(wr, rq, t, name, m, 0)
+	}
+
+	b, err := MarshalMappers(m)
+	if err != nil {
+		return err
+	}
+	wr.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, err = wr.Write(b)
+	return err
+}
+
+// quality returns the quality of the media type within the given accept header, -1 if it is not accepted
+// The quality is the one of the most specific media range that matches, so that e.g. text/html;q=0
+// is not accepted with */*.
+func quality(accept string, mediaType string) float64 {
+	best, specificity := -1.0, -1
+	major := strings.SplitN(mediaType, "/", 2)[0]
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mt := strings.ToLower(strings.TrimSpace(fields[0]))
+		var s int
+		switch mt {
+		case mediaType:
+			s = 2
+		case major + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s < specificity {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		// exact matches are more specific than wildcards
+		if s == 2 && q > 0 {
+			q += 0.0001
+		}
+		if s > specificity || q > best {
+			best, specificity = q, s
+		}
+	}
+	if best <= 0 {
+		return -1
+	}
+	return best
+}
+
+// prefersJSON returns whether the given Accept header prefers application/json over text/html
+func prefersJSON(accept string) bool {
+	if accept == "" {
+		return false
+	}
+	return quality(accept, "application/json") > quality(accept, "text/html")
+}
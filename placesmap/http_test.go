@@ -0,0 +1,69 @@
+package placesmap
+
+import (
+	"encoding/json"
+	"github.com/metakeule/places"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tpl := newTemplate("user.html", "<b><@name@></b>")
+	m := map[string]places.Mapper{
+		"name": String("Donald"),
+		"tags": list(names("a", "b")),
+	}
+
+	tests := []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"text/html,application/xhtml+xml,*/*;q=0.8", "text/html; charset=utf-8", "<b>Donald</b>"},
+		{"", "text/html; charset=utf-8", "<b>Donald</b>"},
+		{"application/json", "application/json; charset=utf-8", `{"name":"Donald","tags":["a","b"]}`},
+		{"application/json;q=0.9, text/html;q=0.5", "application/json; charset=utf-8", `{"name":"Donald","tags":["a","b"]}`},
+		{"application/json;q=0, */*", "text/html; charset=utf-8", "<b>Donald</b>"},
+		{"text/html;q=0, */*", "application/json; charset=utf-8", `{"name":"Donald","tags":["a","b"]}`},
+		{"*/*;q=0.9, text/*;q=0.1, application/*;q=0.5", "application/json; charset=utf-8", `{"name":"Donald","tags":["a","b"]}`},
+	}
+
+	for _, test := range tests {
+		rq := httptest.NewRequest("GET", "/", nil)
+		rq.Header.Set("Accept", test.accept)
+		rec := httptest.NewRecorder()
+
+		if err := Negotiate(rec, rq, tpl, "user.html", m); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := rec.Header().Get("Content-Type"); got != test.contentType {
+			t.Errorf("%#v: unexpected content type: %#v, expected: %#v", test.accept, got, test.contentType)
+		}
+
+		if got := rec.Body.String(); got != test.body {
+			t.Errorf("%#v: unexpected body: %#v, expected: %#v", test.accept, got, test.body)
+		}
+	}
+}
+
+func TestRenderNotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := Render(rec, newTemplate(), "missing.html", nil); err != TemplateNotFoundError("missing.html") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMarshalMappers(t *testing.T) {
+	b, err := MarshalMappers(map[string]places.Mapper{"attrs": AttrMapper{"id": "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res map[string]interface{}
+	if err := json.Unmarshal(b, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res["attrs"] != ` id="x"` {
+		t.Errorf("unexpected result: %s", b)
+	}
+}
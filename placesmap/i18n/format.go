@@ -0,0 +1,161 @@
+package i18n
+
+import (
+	"errors"
+	"github.com/metakeule/places/placesmap"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format describes how dates and numbers are formatted within a locale
+type Format struct {
+	Date     string // the layout of dates, see the time package
+	Time     string // the layout of times of day
+	DateTime string // the layout of dates with time of day
+	Decimal  string // the decimal separator
+	Group    string // the separator of groups of thousands
+}
+
+// Formats are the formats by locale. The formats of locales with a region fall back to the
+// language (see Catalogs.Catalog), unknown locales use the format of "en".
+// Formats may be changed or added before rendering starts.
+var Formats = map[string]Format{
+	"en":    {Date: "1/2/2006", Time: "3:04 PM", DateTime: "1/2/2006 3:04 PM", Decimal: ".", Group: ","},
+	"en_GB": {Date: "02/01/2006", Time: "15:04", DateTime: "02/01/2006 15:04", Decimal: ".", Group: ","},
+	"de":    {Date: "02.01.2006", Time: "15:04", DateTime: "02.01.2006 15:04", Decimal: ",", Group: "."},
+	"de_CH": {Date: "02.01.2006", Time: "15:04", DateTime: "02.01.2006 15:04", Decimal: ".", Group: "\u2019"},
+	"fr":    {Date: "02/01/2006", Time: "15:04", DateTime: "02/01/2006 15:04", Decimal: ",", Group: "\u202f"},
+	"es":    {Date: "2/1/2006", Time: "15:04", DateTime: "2/1/2006 15:04", Decimal: ",", Group: "."},
+	"it":    {Date: "02/01/2006", Time: "15:04", DateTime: "02/01/2006 15:04", Decimal: ",", Group: "."},
+	"nl":    {Date: "2-1-2006", Time: "15:04", DateTime: "2-1-2006 15:04", Decimal: ",", Group: "."},
+	"pt":    {Date: "02/01/2006", Time: "15:04", DateTime: "02/01/2006 15:04", Decimal: ",", Group: "\u00a0"},
+	"pt_BR": {Date: "02/01/2006", Time: "15:04", DateTime: "02/01/2006 15:04", Decimal: ",", Group: "."},
+	"pl":    {Date: "2.01.2006", Time: "15:04", DateTime: "2.01.2006 15:04", Decimal: ",", Group: "\u00a0"},
+	"ru":    {Date: "02.01.2006", Time: "15:04", DateTime: "02.01.2006 15:04", Decimal: ",", Group: "\u00a0"},
+	"sv":    {Date: "2006-01-02", Time: "15:04", DateTime: "2006-01-02 15:04", Decimal: ",", Group: "\u00a0"},
+	"ja":    {Date: "2006/01/02", Time: "15:04", DateTime: "2006/01/02 15:04", Decimal: ".", Group: ","},
+	"zh":    {Date: "2006/1/2", Time: "15:04", DateTime: "2006/1/2 15:04", Decimal: ".", Group: ","},
+}
+
+// FormatOf returns the format for the given locale
+func FormatOf(locale string) Format {
+	locale = strings.Replace(locale, "-", "_", 1)
+	if f, has := Formats[locale]; has {
+		return f
+	}
+	if idx := strings.IndexByte(locale, '_'); idx != -1 {
+		if f, has := Formats[locale[:idx]]; has {
+			return f
+		}
+	}
+	return Formats["en"]
+}
+
+// localeArgs splits the arguments of a directive into the locale override (locale=de) and the rest
+func localeArgs(r *placesmap.RenderContext, rest string) (locale string, args []string) {
+	locale = r.Locale()
+	for _, f := range strings.Fields(rest) {
+		if strings.HasPrefix(f, "locale=") {
+			locale = f[len("locale="):]
+			continue
+		}
+		args = append(args, f)
+	}
+	return locale, args
+}
+
+// date handles "-date name style locale=xx"
+func date(r *placesmap.RenderContext, rest string) string {
+	locale, args := localeArgs(r, rest)
+	if len(args) == 0 {
+		return ""
+	}
+	var t time.Time
+	if args[0] == "now" {
+		t = r.Now()
+	} else {
+		var err error
+		if t, err = placesmap.Time(r, args[0]); err != nil {
+			fail(r, err)
+			return ""
+		}
+	}
+	// the time zone of the user, see placesmap.ClockMapper
+	if mp, ok := r.Mapper(placesmap.ClockKey); ok {
+		if c, is := mp.(*placesmap.ClockMapper); is && c.Location != nil {
+			t = t.In(c.Location)
+		}
+	}
+
+	f := FormatOf(locale)
+	layout := f.Date
+	if len(args) > 1 {
+		switch args[1] {
+		case "time":
+			layout = f.Time
+		case "datetime":
+			layout = f.DateTime
+		}
+	}
+	return html.EscapeString(t.Format(layout))
+}
+
+// fail reports errors of values that can't be converted to MapStrict, missing values are no errors
+func fail(r *placesmap.RenderContext, err error) {
+	if !errors.Is(err, placesmap.ErrNoValue) {
+		r.Fail(err)
+	}
+}
+
+// number handles "-number name decimals locale=xx"
+func number(r *placesmap.RenderContext, rest string) string {
+	locale, args := localeArgs(r, rest)
+	if len(args) == 0 {
+		return ""
+	}
+	n, err := placesmap.Float(r, args[0])
+	if err != nil {
+		fail(r, err)
+		return ""
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return ""
+	}
+	decimals := -1
+	if len(args) > 1 {
+		d, err := strconv.Atoi(args[1])
+		if err != nil || d < 0 {
+			return ""
+		}
+		decimals = d
+	}
+	return html.EscapeString(formatNumber(n, decimals, FormatOf(locale)))
+}
+
+// formatNumber formats n with the given number of decimals (-1 for as many as needed)
+// and the separators of the format
+func formatNumber(n float64, decimals int, f Format) string {
+	s := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	intPart, frac := s, ""
+	if idx := strings.IndexByte(s, '.'); idx != -1 {
+		intPart, frac = s[:idx], s[idx+1:]
+	}
+	var bf strings.Builder
+	if n < 0 && strings.Trim(s, "0.") != "" {
+		bf.WriteString("-")
+	}
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			bf.WriteString(f.Group)
+		}
+		bf.WriteRune(c)
+	}
+	if frac != "" {
+		bf.WriteString(f.Decimal)
+		bf.WriteString(frac)
+	}
+	return bf.String()
+}
@@ -0,0 +1,199 @@
+/*
+package i18n provides translations for the "-t" directive from gettext catalogs (.po and .mo files).
+
+Registered via Catalogs.Register, the directive
+
+	-t Hello world
+	-t ctx=menu Open
+	-t n=count One item|%d items
+
+renders the translation of the msgid "Hello world" for the locale of the render
+(see placesmap.HTMLTemplateMapper.WithLocale), the translation of "Open" within the message context
+"menu" and the plural form of "One item" for the number given by the value of count. Within the
+translation, %d is replaced by the number. Untranslated messages render the msgid (or msgid_plural).
+The result is HTML escaped.
+
+Dates and numbers are formatted for the same locale (see Formats) by
+
+	-date created                 the date of the time value of created, e.g. 24.12.2020 for de
+	-date created datetime        the date with the time of day, "time" for the time of day only
+	-date now                     the current date (see placesmap.ClockMapper)
+	-number price 2               the value of price with 2 decimals, e.g. 1.234,50 for de
+	-number count locale=en       the value of count formatted for the locale en instead
+
+The time values are converted like for "-time" and to the time zone of the ClockMapper, if registered.
+*/
+package i18n
+
+import (
+	"github.com/metakeule/places/placesmap"
+	"html"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// key identifies a message by its context and msgid
+type key struct {
+	ctx string
+	id  string
+}
+
+// Catalog holds the translations of one locale
+type Catalog struct {
+	messages map[key][]string
+	header   map[string]string
+	plural   pluralFunc
+	nplurals int
+}
+
+// NewCatalog returns an empty Catalog with the plural rule of english
+func NewCatalog() *Catalog {
+	return &Catalog{messages: map[key][]string{}, header: map[string]string{}, plural: germanic, nplurals: 2}
+}
+
+// Set sets the translations for the message with the given context and msgid, the plural forms
+// in the order of the plural rule
+func (c *Catalog) Set(ctx, id string, translations ...string) {
+	c.messages[key{ctx, id}] = translations
+}
+
+// Header returns the value of the given field of the header of the catalog, e.g. "Language"
+func (c *Catalog) Header(field string) string {
+	return c.header[field]
+}
+
+// setHeader parses the header of a catalog, i.e. the translation of the empty msgid
+func (c *Catalog) setHeader(h string) error {
+	for _, line := range strings.Split(h, "\n") {
+		idx := strings.IndexByte(line, ':')
+		if idx == -1 {
+			continue
+		}
+		c.header[strings.TrimSpace(line[:idx])] = strings.TrimSpace(line[idx+1:])
+	}
+
+	pf, has := c.header["Plural-Forms"]
+	if !has {
+		return nil
+	}
+	n, fn, err := parsePluralForms(pf)
+	if err != nil {
+		return err
+	}
+	c.nplurals, c.plural = n, fn
+	return nil
+}
+
+// Get returns the translation of the message with the given context and msgid,
+// the msgid if there is none
+func (c *Catalog) Get(ctx, id string) string {
+	if t := c.messages[key{ctx, id}]; len(t) > 0 && t[0] != "" {
+		return t[0]
+	}
+	return id
+}
+
+// GetN returns the plural form for n of the message with the given context and msgid,
+// the msgid or the plural (following the rule of english) if there is none
+func (c *Catalog) GetN(ctx, id, plural string, n int) string {
+	if t := c.messages[key{ctx, id}]; len(t) > 0 {
+		idx := c.plural(n)
+		if idx >= 0 && idx < len(t) && t[idx] != "" {
+			return t[idx]
+		}
+	}
+	if n == 1 {
+		return id
+	}
+	return plural
+}
+
+// Catalogs are the catalogs by locale, e.g. "de" or "pt_BR"
+type Catalogs map[string]*Catalog
+
+// Catalog returns the catalog for the given locale, falling back to the language for locales
+// with a region (e.g. "de" for "de_AT" or "de-AT"), nil if there is none
+func (c Catalogs) Catalog(locale string) *Catalog {
+	if cat, has := c[locale]; has {
+		return cat
+	}
+	if idx := strings.IndexAny(locale, "_-"); idx != -1 {
+		return c[locale[:idx]]
+	}
+	return nil
+}
+
+// LoadFile loads the catalog for the given locale from the .po or .mo file with the given path
+func (c Catalogs) LoadFile(locale, path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cat *Catalog
+	if filepath.Ext(path) == ".mo" {
+		cat, err = ParseMO(b)
+	} else {
+		cat, err = ParsePO(b)
+	}
+	if err != nil {
+		return err
+	}
+	c[locale] = cat
+	return nil
+}
+
+// Register registers the directives "-t", "-date" and "-number" for the given HTMLTemplate
+func (c Catalogs) Register(t *placesmap.HTMLTemplate) error {
+	if err := t.Handle("t", c.translate); err != nil {
+		return err
+	}
+	if err := t.Handle("date", date); err != nil {
+		return err
+	}
+	return t.Handle("number", number)
+}
+
+// translate handles "-t ctx=context n=name msgid|plural"
+func (c Catalogs) translate(r *placesmap.RenderContext, rest string) string {
+	var ctx, count string
+	rest = strings.TrimSpace(rest)
+	for {
+		switch {
+		case strings.HasPrefix(rest, "ctx="):
+			ctx, rest = option(rest[4:])
+			continue
+		case strings.HasPrefix(rest, "n="):
+			count, rest = option(rest[2:])
+			continue
+		}
+		break
+	}
+
+	cat := c.Catalog(r.Locale())
+	if cat == nil {
+		cat = NewCatalog()
+	}
+
+	if count == "" {
+		return html.EscapeString(cat.Get(ctx, rest))
+	}
+
+	id, plural := rest, rest
+	if idx := strings.Index(rest, "|"); idx != -1 {
+		id, plural = rest[:idx], rest[idx+1:]
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(r.Value(count)))
+	s := cat.GetN(ctx, id, plural, n)
+	return html.EscapeString(strings.Replace(s, "%d", strconv.Itoa(n), -1))
+}
+
+// option returns the value of an option and the rest after it
+func option(s string) (val, rest string) {
+	idx := strings.IndexByte(s, ' ')
+	if idx == -1 {
+		return s, ""
+	}
+	return s[:idx], strings.TrimLeft(s[idx+1:], " ")
+}
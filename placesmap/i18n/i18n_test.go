@@ -0,0 +1,189 @@
+package i18n
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"strings"
+	"testing"
+	"time"
+)
+
+var po = `# Polish translation
+msgid ""
+msgstr ""
+"Language: pl\n"
+"Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+msgid "Hello"
+msgstr "Cześć"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Otwórz"
+
+#, fuzzy
+msgid "Close"
+msgstr "Zamknij"
+
+msgid "Save"
+msgstr ""
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "%d plik"
+msgstr[1] "%d pliki"
+msgstr[2] ""
+"%d plików"
+`
+
+func TestParsePO(t *testing.T) {
+	c, err := ParsePO([]byte(po))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Header("Language"); got != "pl" {
+		t.Errorf("unexpected language: %#v", got)
+	}
+
+	tests := [][2]string{
+		{c.Get("", "Hello"), "Cześć"},
+		{c.Get("menu", "Open"), "Otwórz"},
+		{c.Get("", "Open"), "Open"},
+		{c.Get("", "Close"), "Close"},
+		{c.Get("", "Save"), "Save"},
+		{c.GetN("", "One file", "%d files", 1), "%d plik"},
+		{c.GetN("", "One file", "%d files", 3), "%d pliki"},
+		{c.GetN("", "One file", "%d files", 12), "%d plików"},
+		{c.GetN("", "One file", "%d files", 22), "%d pliki"},
+		{c.GetN("", "One dir", "%d dirs", 2), "%d dirs"},
+	}
+	for _, tt := range tests {
+		if tt[0] != tt[1] {
+			t.Errorf("unexpected translation: %#v, expected: %#v", tt[0], tt[1])
+		}
+	}
+
+	for _, invalid := range []string{`msgid "a`, `"a"`, `msgfoo "a"`, "msgid \"\"\nmsgstr \"Plural-Forms: nplurals=2; plural=n >;\"\n"} {
+		if _, err := ParsePO([]byte(invalid)); err == nil {
+			t.Errorf("%#v: expected error", invalid)
+		}
+	}
+}
+
+// mo returns a little endian .mo file for the given pairs of msgids and translations
+func mo(pairs ...string) []byte {
+	n := len(pairs) / 2
+	var strs bytes.Buffer
+	origs := make([]uint32, 0, n*2)
+	trans := make([]uint32, 0, n*2)
+	start := uint32(28 + n*16)
+	for i := 0; i < len(pairs); i += 2 {
+		origs = append(origs, uint32(len(pairs[i])), start+uint32(strs.Len()))
+		strs.WriteString(pairs[i])
+		trans = append(trans, uint32(len(pairs[i+1])), start+uint32(strs.Len()))
+		strs.WriteString(pairs[i+1])
+	}
+
+	var bf bytes.Buffer
+	for _, v := range []uint32{0x950412de, 0, uint32(n), 28, uint32(28 + n*8), 0, 0} {
+		binary.Write(&bf, binary.LittleEndian, v)
+	}
+	binary.Write(&bf, binary.LittleEndian, origs)
+	binary.Write(&bf, binary.LittleEndian, trans)
+	bf.Write(strs.Bytes())
+	return bf.Bytes()
+}
+
+func TestParseMO(t *testing.T) {
+	c, err := ParseMO(mo(
+		"", "Plural-Forms: nplurals=2; plural=(n > 1);\n",
+		"Hello", "Bonjour",
+		"menu\x04Open", "Ouvrir",
+		"One file\x00%d files", "%d fichier\x00%d fichiers",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := [][2]string{
+		{c.Get("", "Hello"), "Bonjour"},
+		{c.Get("menu", "Open"), "Ouvrir"},
+		{c.GetN("", "One file", "%d files", 0), "%d fichier"},
+		{c.GetN("", "One file", "%d files", 2), "%d fichiers"},
+	}
+	for _, tt := range tests {
+		if tt[0] != tt[1] {
+			t.Errorf("unexpected translation: %#v, expected: %#v", tt[0], tt[1])
+		}
+	}
+
+	if _, err := ParseMO(mo("Hello", "Bonjour")[:40]); err != ErrInvalidMO {
+		t.Errorf("expected ErrInvalidMO, got %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	pl, err := ParsePO([]byte(po))
+	if err != nil {
+		t.Fatal(err)
+	}
+	de := NewCatalog()
+	de.Set("", "Hello", "Hallo <Welt>")
+
+	rs := placesmap.NewReadSeekerMap()
+	rs.Add("page.html", strings.NewReader(`<@-t Hello@>|<@-t ctx=menu Open@>|<@-t n=count One file|%d files@>`))
+	tpl := placesmap.NewHTMLTemplate(rs)
+	if err := (Catalogs{"pl": pl, "de": de}).Register(tpl); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"pl_PL": "Cześć|Otwórz|5 plików",
+		"de-AT": "Hallo &lt;Welt&gt;|Open|5 files",
+		"fr":    "Hello|Open|5 files",
+	}
+	m := tpl.NewMapper(map[string]places.Mapper{"count": placesmap.String("5")})
+	for locale, exp := range tests {
+		if got := m.WithLocale(locale).Map("-require page.html"); got != exp {
+			t.Errorf("%s: unexpected result: %#v, expected: %#v", locale, got, exp)
+		}
+	}
+}
+
+// typed is a TypedMapper for a value
+type typed struct{ v interface{} }
+
+func (t typed) Map(string) string        { return fmt.Sprint(t.v) }
+func (t typed) Value(string) interface{} { return t.v }
+
+func TestFormats(t *testing.T) {
+	rs := placesmap.NewReadSeekerMap()
+	rs.Add("page.html", strings.NewReader(`<@-date created@>|<@-date created datetime@>|<@-number price 2@>|<@-number count@>|<@-number count locale=en@>|<@-date created locale=de@>`))
+	tpl := placesmap.NewHTMLTemplate(rs)
+	if err := (Catalogs{}).Register(tpl); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2020, 12, 24, 18, 30, 0, 0, time.UTC)
+	m := tpl.NewMapper(map[string]places.Mapper{
+		"created": typed{created},
+		"price":   placesmap.String("-1234567.5"),
+		"count":   typed{12345},
+		"clock":   &placesmap.ClockMapper{Location: time.FixedZone("CET", 3600)},
+	})
+
+	tests := map[string]string{
+		"de_AT": "24.12.2020|24.12.2020 19:30|-1.234.567,50|12.345|12,345|24.12.2020",
+		"en-US": "12/24/2020|12/24/2020 7:30 PM|-1,234,567.50|12,345|12,345|24.12.2020",
+		"fr":    "24/12/2020|24/12/2020 19:30|-1 234 567,50|12 345|12,345|24.12.2020",
+		"xx":    "12/24/2020|12/24/2020 7:30 PM|-1,234,567.50|12,345|12,345|24.12.2020",
+	}
+	for locale, exp := range tests {
+		if got := m.WithLocale(locale).Map("-require page.html"); got != exp {
+			t.Errorf("%s: unexpected result: %#v, expected: %#v", locale, got, exp)
+		}
+	}
+}
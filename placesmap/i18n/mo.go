@@ -0,0 +1,74 @@
+package i18n
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidMO = errors.New("invalid .mo file")
+
+// ParseMO parses a catalog from the content of a .mo file
+func ParseMO(b []byte) (*Catalog, error) {
+	if len(b) < 28 {
+		return nil, ErrInvalidMO
+	}
+
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(b) {
+	case 0x950412de:
+		order = binary.LittleEndian
+	case 0xde120495:
+		order = binary.BigEndian
+	default:
+		return nil, ErrInvalidMO
+	}
+
+	n := order.Uint32(b[8:])
+	origs := order.Uint32(b[12:])
+	trans := order.Uint32(b[16:])
+
+	// str returns the string of the table entry at the given offset
+	str := func(table uint32, i uint32) (string, bool) {
+		pos := uint64(table) + uint64(i)*8
+		if pos+8 > uint64(len(b)) {
+			return "", false
+		}
+		length := uint64(order.Uint32(b[pos:]))
+		offset := uint64(order.Uint32(b[pos+4:]))
+		if offset+length > uint64(len(b)) {
+			return "", false
+		}
+		return string(b[offset : offset+length]), true
+	}
+
+	c := NewCatalog()
+	for i := uint32(0); i < n; i++ {
+		orig, ok := str(origs, i)
+		if !ok {
+			return nil, ErrInvalidMO
+		}
+		tr, ok := str(trans, i)
+		if !ok {
+			return nil, ErrInvalidMO
+		}
+
+		if orig == "" {
+			if err := c.setHeader(tr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var ctx string
+		// the context is separated by EOT, the plural by NUL
+		if idx := strings.IndexByte(orig, '\x04'); idx != -1 {
+			ctx, orig = orig[:idx], orig[idx+1:]
+		}
+		if idx := strings.IndexByte(orig, 0); idx != -1 {
+			orig = orig[:idx]
+		}
+		c.Set(ctx, orig, strings.Split(tr, "\x00")...)
+	}
+	return c, nil
+}
@@ -0,0 +1,221 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pluralFunc returns the index of the plural form for n
+type pluralFunc func(n int) int
+
+// germanic is the plural rule of english and german
+func germanic(n int) int {
+	if n == 1 {
+		return 0
+	}
+	return 1
+}
+
+type PluralError string
+
+func (p PluralError) Error() string {
+	return fmt.Sprintf("invalid plural forms %#v", p)
+}
+
+// parsePluralForms parses the Plural-Forms header, e.g. "nplurals=2; plural=(n != 1);"
+func parsePluralForms(s string) (int, pluralFunc, error) {
+	var (
+		n    int
+		expr string
+		err  error
+	)
+	for _, part := range strings.Split(s, ";") {
+		idx := strings.IndexByte(part, '=')
+		if idx == -1 {
+			continue
+		}
+		switch strings.TrimSpace(part[:idx]) {
+		case "nplurals":
+			if n, err = strconv.Atoi(strings.TrimSpace(part[idx+1:])); err != nil || n < 1 {
+				return 0, nil, PluralError(s)
+			}
+		case "plural":
+			expr = part[idx+1:]
+		}
+	}
+	if n == 0 || expr == "" {
+		return 0, nil, PluralError(s)
+	}
+
+	p := &pluralParser{s: expr}
+	fn, ok := p.ternary()
+	p.skip()
+	if !ok || p.pos != len(p.s) {
+		return 0, nil, PluralError(s)
+	}
+	return n, pluralFunc(fn), nil
+}
+
+// pluralParser parses the C expression of a plural rule, with n as the only variable
+type pluralParser struct {
+	s   string
+	pos int
+}
+
+type expr func(n int) int
+
+func (p *pluralParser) skip() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+// consume consumes the given operator, if it is next
+func (p *pluralParser) consume(op string) bool {
+	p.skip()
+	if !strings.HasPrefix(p.s[p.pos:], op) {
+		return false
+	}
+	// don't mistake < for <= or ! for !=
+	if len(op) == 1 && p.pos+1 < len(p.s) && p.s[p.pos+1] == '=' && strings.IndexByte("<>!=", op[0]) != -1 {
+		return false
+	}
+	p.pos += len(op)
+	return true
+}
+
+func bool2int(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (p *pluralParser) ternary() (expr, bool) {
+	cond, ok := p.binary(0)
+	if !ok || !p.consume("?") {
+		return cond, ok
+	}
+	a, ok := p.ternary()
+	if !ok || !p.consume(":") {
+		return nil, false
+	}
+	b, ok := p.ternary()
+	if !ok {
+		return nil, false
+	}
+	return func(n int) int {
+		if cond(n) != 0 {
+			return a(n)
+		}
+		return b(n)
+	}, true
+}
+
+// levels are the binary operators by precedence, lowest first
+var levels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func apply(op string, a, b int) int {
+	switch op {
+	case "||":
+		return bool2int(a != 0 || b != 0)
+	case "&&":
+		return bool2int(a != 0 && b != 0)
+	case "==":
+		return bool2int(a == b)
+	case "!=":
+		return bool2int(a != b)
+	case "<=":
+		return bool2int(a <= b)
+	case ">=":
+		return bool2int(a >= b)
+	case "<":
+		return bool2int(a < b)
+	case ">":
+		return bool2int(a > b)
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	default:
+		if b == 0 {
+			return 0
+		}
+		return a % b
+	}
+}
+
+func (p *pluralParser) binary(level int) (expr, bool) {
+	if level == len(levels) {
+		return p.unary()
+	}
+	left, ok := p.binary(level + 1)
+	if !ok {
+		return nil, false
+	}
+outer:
+	for {
+		for _, op := range levels[level] {
+			if !p.consume(op) {
+				continue
+			}
+			right, ok := p.binary(level + 1)
+			if !ok {
+				return nil, false
+			}
+			l, op := left, op
+			left = func(n int) int { return apply(op, l(n), right(n)) }
+			continue outer
+		}
+		return left, true
+	}
+}
+
+func (p *pluralParser) unary() (expr, bool) {
+	if p.consume("!") {
+		e, ok := p.unary()
+		if !ok {
+			return nil, false
+		}
+		return func(n int) int { return bool2int(e(n) == 0) }, true
+	}
+	if p.consume("(") {
+		e, ok := p.ternary()
+		if !ok || !p.consume(")") {
+			return nil, false
+		}
+		return e, true
+	}
+	if p.consume("n") {
+		return func(n int) int { return n }, true
+	}
+
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, false
+	}
+	v, err := strconv.Atoi(p.s[start:p.pos])
+	if err != nil {
+		return nil, false
+	}
+	return func(int) int { return v }, true
+}
@@ -0,0 +1,125 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type ParseError struct {
+	Line   int
+	Reason string
+}
+
+func (p ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Reason)
+}
+
+// poEntry is an entry of a .po file
+type poEntry struct {
+	ctx, id, plural string
+	strs            []string
+	fuzzy           bool
+	started         bool
+}
+
+// ParsePO parses a catalog from the source of a .po file. Fuzzy and untranslated entries are skipped.
+func ParsePO(src []byte) (*Catalog, error) {
+	c := NewCatalog()
+	var (
+		e    poEntry
+		last *string // the string that continuation lines are appended to
+	)
+
+	flush := func() error {
+		if e.started && !e.fuzzy && len(e.strs) > 0 {
+			if e.id == "" && e.ctx == "" {
+				if err := c.setHeader(e.strs[0]); err != nil {
+					return err
+				}
+			} else {
+				c.Set(e.ctx, e.id, e.strs...)
+			}
+		}
+		e, last = poEntry{}, nil
+		return nil
+	}
+
+	for no, line := range strings.Split(string(src), "\n") {
+		no++
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			// comments belong to the next entry
+			if len(e.strs) > 0 {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				e.fuzzy = true
+			}
+			continue
+		case strings.HasPrefix(line, `"`):
+			if last == nil {
+				return nil, ParseError{no, "unexpected string"}
+			}
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, ParseError{no, "invalid string"}
+			}
+			*last += s
+			continue
+		}
+
+		idx := strings.IndexByte(line, ' ')
+		if idx == -1 {
+			return nil, ParseError{no, "expected keyword and string"}
+		}
+		keyword, value := line[:idx], strings.TrimSpace(line[idx+1:])
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, ParseError{no, "invalid string"}
+		}
+
+		switch {
+		case keyword == "msgctxt" || keyword == "msgid":
+			// a new entry starts, if the previous one is complete
+			if len(e.strs) > 0 {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			e.started = true
+			if keyword == "msgctxt" {
+				e.ctx = s
+				last = &e.ctx
+				continue
+			}
+			e.id = s
+			last = &e.id
+		case keyword == "msgid_plural":
+			e.plural = s
+			last = &e.plural
+		case keyword == "msgstr":
+			e.strs = []string{s}
+			last = &e.strs[0]
+		case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]"):
+			n, err := strconv.Atoi(keyword[7 : len(keyword)-1])
+			if err != nil || n != len(e.strs) {
+				return nil, ParseError{no, "invalid plural index"}
+			}
+			e.strs = append(e.strs, s)
+			last = &e.strs[n]
+		default:
+			return nil, ParseError{no, fmt.Sprintf("unknown keyword %#v", keyword)}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,151 @@
+package placesmap
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ImageVariants are the widths and formats an image is available in
+type ImageVariants struct {
+	Widths  []int    // in pixels
+	Formats []string // e.g. "avif", "webp", "jpeg"; the last one is the fallback for browsers without <picture>
+}
+
+// ImageMapper renders responsive images via the directive
+//
+//	-image name image alt
+//
+// where name is the name under which the ImageMapper is registered, image the logical name of the image
+// and alt the alternative text. image and alt are literals (without whitespace), if they don't
+// start with $, otherwise the values of the given names, e.g.
+//
+//	-image images hero.jpg $title
+//	-image images $product.image $product.name
+//
+// If the image has one format, an <img> with srcset is rendered, otherwise a <picture> with a <source>
+// for every format but the last. The src of the <img> is the largest width of the last format.
+type ImageMapper struct {
+	ImageVariants                          // the variants of all images that are not in the Catalog
+	Catalog       map[string]ImageVariants // the variants of specific images by logical name
+
+	// Pattern is the URL of a variant, where {name}, {width} and {format} are replaced,
+	// e.g. "/img/{name}-{width}.{format}". The name is path escaped, slashes are kept.
+	Pattern string
+
+	// URL returns the URL of a variant, overriding Pattern
+	URL func(name string, width int, format string) string
+
+	Sizes string // the sizes attribute, "" means none
+	Lazy  bool   // adds loading="lazy" and decoding="async"
+}
+
+// Map returns the empty string, the ImageMapper is used via its directive
+func (i *ImageMapper) Map(string) string { return "" }
+
+// variants returns the variants of the image with the given name
+func (i *ImageMapper) variants(name string) ImageVariants {
+	if v, has := i.Catalog[name]; has {
+		return v
+	}
+	return i.ImageVariants
+}
+
+// url returns the URL of the given variant
+func (i *ImageMapper) url(name string, width int, format string) string {
+	if i.URL != nil {
+		return i.URL(name, width, format)
+	}
+	segments := strings.Split(name, "/")
+	for k, s := range segments {
+		segments[k] = url.PathEscape(s)
+	}
+	return strings.NewReplacer(
+		"{name}", strings.Join(segments, "/"),
+		"{width}", strconv.Itoa(width),
+		"{format}", url.PathEscape(format),
+	).Replace(i.Pattern)
+}
+
+// srcset returns the srcset of the given format
+func (i *ImageMapper) srcset(name string, widths []int, format string) string {
+	parts := make([]string, len(widths))
+	for k, w := range widths {
+		parts[k] = i.url(name, w, format) + " " + strconv.Itoa(w) + "w"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mimeTypes are the types of the image formats that are no image/ followed by the format
+var mimeTypes = map[string]string{"jpg": "image/jpeg", "svg": "image/svg+xml"}
+
+// Image returns the markup of the image with the given logical name and alternative text
+func (i *ImageMapper) Image(name, alt string) string {
+	v := i.variants(name)
+	if len(v.Widths) == 0 || len(v.Formats) == 0 {
+		return ""
+	}
+	largest := v.Widths[0]
+	for _, w := range v.Widths {
+		if w > largest {
+			largest = w
+		}
+	}
+
+	var bf bytes.Buffer
+	sizes := ""
+	if i.Sizes != "" {
+		sizes = ` sizes="` + html.EscapeString(i.Sizes) + `"`
+	}
+	last := len(v.Formats) - 1
+	if last > 0 {
+		bf.WriteString("<picture>")
+		for _, f := range v.Formats[:last] {
+			typ, has := mimeTypes[f]
+			if !has {
+				typ = "image/" + f
+			}
+			bf.WriteString(`<source type="` + html.EscapeString(typ) + `" srcset="` + html.EscapeString(i.srcset(name, v.Widths, f)) + `"` + sizes + ">")
+		}
+	}
+	f := v.Formats[last]
+	bf.WriteString(`<img src="` + html.EscapeString(i.url(name, largest, f)) + `" srcset="` + html.EscapeString(i.srcset(name, v.Widths, f)) + `"` + sizes)
+	bf.WriteString(` alt="` + html.EscapeString(alt) + `"`)
+	if i.Lazy {
+		bf.WriteString(` loading="lazy" decoding="async"`)
+	}
+	bf.WriteString(">")
+	if last > 0 {
+		bf.WriteString("</picture>")
+	}
+	return bf.String()
+}
+
+// image handles "-image name image alt"
+func (r *RenderContext) image(rest string) string {
+	args := strings.Fields(rest)
+	if len(args) < 2 {
+		return ""
+	}
+	mp, ok := r.mapper(args[0])
+	if !ok {
+		return ""
+	}
+	i, is := mp.(*ImageMapper)
+	if !is {
+		return ""
+	}
+	arg := func(s string) string {
+		if strings.HasPrefix(s, "$") {
+			return r.Value(s[1:])
+		}
+		return s
+	}
+	var alt string
+	if len(args) > 2 {
+		alt = arg(args[2])
+	}
+	return i.Image(arg(args[1]), alt)
+}
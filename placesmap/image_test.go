@@ -0,0 +1,33 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strconv"
+	"testing"
+)
+
+func TestImage(t *testing.T) {
+	tpl := newTemplate("page.html", `<@-image images hero.jpg $alt@>|<@-image images my logo $alt@>|<@-image alt hero.jpg@>`)
+	images := &ImageMapper{
+		ImageVariants: ImageVariants{Widths: []int{320, 640}, Formats: []string{"webp", "jpg"}},
+		Catalog:       map[string]ImageVariants{"my": {Widths: []int{100}, Formats: []string{"png"}}},
+		Pattern:       "/img/{name}-{width}.{format}",
+		Sizes:         "(min-width: 40em) 50vw, 100vw",
+		Lazy:          true,
+	}
+	got := tpl.NewMapper(map[string]places.Mapper{"images": images, "alt": String(`"Hero" & co`)}).Map("-require page.html")
+	exp := `<picture><source type="image/webp" srcset="/img/hero.jpg-320.webp 320w, /img/hero.jpg-640.webp 640w" sizes="(min-width: 40em) 50vw, 100vw">` +
+		`<img src="/img/hero.jpg-640.jpg" srcset="/img/hero.jpg-320.jpg 320w, /img/hero.jpg-640.jpg 640w" sizes="(min-width: 40em) 50vw, 100vw" alt="&#34;Hero&#34; &amp; co" loading="lazy" decoding="async"></picture>|` +
+		`<img src="/img/my-100.png" srcset="/img/my-100.png 100w" sizes="(min-width: 40em) 50vw, 100vw" alt="logo" loading="lazy" decoding="async">|`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	images.Formats = []string{"jpg"}
+	images.URL = func(name string, width int, format string) string {
+		return "/i?n=" + name + "&w=" + strconv.Itoa(width)
+	}
+	if got, exp := images.Image("a b", ""), `<img src="/i?n=a b&amp;w=640" srcset="/i?n=a b&amp;w=320 320w, /i?n=a b&amp;w=640 640w" sizes="(min-width: 40em) 50vw, 100vw" alt="" loading="lazy" decoding="async">`; got != exp {
+		t.Errorf("unexpected result with URL: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,68 @@
+package placesmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// IslandTag is the tag of the element that wraps an island
+var IslandTag = "div"
+
+// island handles "-island component props fallback.html"
+// It renders the fallback include (server side rendering of the component) wrapped
+// into an element with the data attributes data-island (name of the component) and
+// data-props (the props as JSON), so that a client side runtime can hydrate the component.
+// The props are taken from the mapper registered for props: if it implements json.Marshaler,
+// it is marshalled, otherwise its value is used, if it is valid JSON and encoded as JSON string if not.
+// props and fallback.html are optional.
+func (r *RenderContext) island(rest string) string {
+	args := strings.Fields(rest)
+	if len(args) == 0 {
+		return ""
+	}
+
+	var bf bytes.Buffer
+	bf.WriteString("<" + IslandTag + ` data-island="`)
+	bf.WriteString(AttrEscape(args[0]))
+	bf.WriteString(`"`)
+
+	if len(args) > 1 {
+		bf.WriteString(` data-props="`)
+		bf.WriteString(AttrEscape(r.props(args[1])))
+		bf.WriteString(`"`)
+	}
+
+	bf.WriteString(">")
+
+	if len(args) > 2 {
+		bf.WriteString(r.require(args[2], r))
+	}
+
+	bf.WriteString("</" + IslandTag + ">")
+	return bf.String()
+}
+
+// props returns the JSON for the mapper with the given name
+func (r *RenderContext) props(name string) string {
+	mp, ok := r.mapper(name)
+	if !ok {
+		return "null"
+	}
+
+	if jm, is := mp.(json.Marshaler); is {
+		b, err := jm.MarshalJSON()
+		if err != nil {
+			return "null"
+		}
+		return string(b)
+	}
+
+	val := mp.Map(name)
+	if json.Valid([]byte(val)) {
+		return val
+	}
+
+	b, _ := json.Marshal(val)
+	return string(b)
+}
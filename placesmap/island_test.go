@@ -0,0 +1,25 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestIsland(t *testing.T) {
+	tpl := newTemplate("page.html", "<@-island Counter counter counter.html@>", "counter.html", "<span><@count@></span>")
+	got := tpl.NewMapper(map[string]places.Mapper{
+		"counter": String(`{"start":3}`),
+		"count":   String("3"),
+	}).Map("-require page.html")
+
+	exp := `<div data-island="Counter" data-props="{&#34;start&#34;:3}"><span>3</span></div>`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	got = tpl.NewMapper(map[string]places.Mapper{"counter": String("plain")}).Map("-island Counter counter")
+	exp = `<div data-island="Counter" data-props="&#34;plain&#34;"></div>`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
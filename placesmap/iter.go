@@ -0,0 +1,36 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+)
+
+// Iterator is a collection of unknown length that can be used with "each".
+// In contrast to NMapper the length does not have to be known in advance,
+// so that streaming data sources (cursors, message streams etc.) can be iterated.
+type Iterator interface {
+	places.Mapper
+
+	// Next returns the next element. If there are no more elements, ok is false.
+	Next() (m places.Mapper, ok bool)
+}
+
+// Iter is a func implementing Iterator
+type Iter func() (places.Mapper, bool)
+
+func (i Iter) Next() (places.Mapper, bool) {
+	return i()
+}
+
+// Map always returns the empty string, since an Iterator is only meant to be used with "each"
+func (i Iter) Map(string) string {
+	return ""
+}
+
+// Chan returns an Iterator that iterates over the mappers received from the given
+// channel until the channel is closed
+func Chan(ch <-chan places.Mapper) Iter {
+	return func() (places.Mapper, bool) {
+		m, ok := <-ch
+		return m, ok
+	}
+}
@@ -0,0 +1,42 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestEachIter(t *testing.T) {
+	tpl := newTemplate("list.html", "<@-each items row.html@>", "row.html", "[<@name@>]")
+	ms := names("a", "b", "c")
+	i := 0
+	it := Iter(func() (places.Mapper, bool) {
+		if i >= len(ms) {
+			return nil, false
+		}
+		i++
+		return ms[i-1], true
+	})
+
+	got := tpl.NewMapper(map[string]places.Mapper{"items": it}).Map("-require list.html")
+
+	if exp := "[a][b][c]"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
+
+func TestEachChan(t *testing.T) {
+	tpl := newTemplate("list.html", "<@-each items row.html@>", "row.html", "[<@name@>]")
+	ch := make(chan places.Mapper)
+	go func() {
+		for _, m := range names("x", "y") {
+			ch <- m
+		}
+		close(ch)
+	}()
+
+	got := tpl.NewMapper(map[string]places.Mapper{"items": Chan(ch)}).Map("-require list.html")
+
+	if exp := "[x][y]"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
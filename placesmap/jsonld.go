@@ -0,0 +1,32 @@
+package placesmap
+
+import (
+	"encoding/json"
+)
+
+// jsonLD handles "-json-ld name"
+// The mapper registered for name is serialized like the mappers of MarshalMappers (e.g. Keyed
+// mappers become objects) and wrapped into a <script type="application/ld+json"> element.
+// Values that are valid JSON are used as they are. The characters <, > and & are escaped
+// within the JSON, so that the data can't end the script element.
+func (r *RenderContext) jsonLD(name string) string {
+	mp, ok := r.mapper(name)
+	if !ok {
+		return ""
+	}
+
+	v := jsonValue(name, mp)
+	if s, is := v.(string); is {
+		if !json.Valid([]byte(s)) {
+			return ""
+		}
+		v = json.RawMessage(s)
+	}
+
+	// json.Marshal escapes <, > and & and the line terminators U+2028 and U+2029
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return `<script type="application/ld+json">` + string(b) + `</script>`
+}
@@ -0,0 +1,33 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+// dict is a Keyed mapper
+type dict map[string]string
+
+func (d dict) Map(key string) string { return d[key] }
+func (d dict) Keys() []string {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestJSONLD(t *testing.T) {
+	tpl := newTemplate("page.html", "<@-json-ld product@><@-json-ld raw@><@-json-ld invalid@>")
+	got := tpl.NewMapper(map[string]places.Mapper{
+		"product": dict{"@type": "Product", "name": "</script><script>alert(1)</script>"},
+		"raw":     String(`{"@type": "Thing"}`),
+		"invalid": String("no json"),
+	}).Map("-require page.html")
+
+	exp := `<script type="application/ld+json">{"@type":"Product","name":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e"}</script>` +
+		`<script type="application/ld+json">{"@type":"Thing"}</script>`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
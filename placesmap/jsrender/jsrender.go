@@ -0,0 +1,231 @@
+/*
+package jsrender generates JavaScript modules that render places templates client-side, so that
+the same fragments can be rendered after AJAX updates without duplicating markup.
+
+Generate writes an ES module for a selection of templates and the templates they include:
+
+	import { render } from "./templates.js";
+	list.innerHTML = render("row.html", {name: "Tom", tags: [{tag: "a"}]});
+
+The module supports the placeholder semantics that don't need Go mappers:
+
+	<@name@>, <@user.name@>                   HTML escaped value, dotted names are paths
+	<@-html name@>, <@-raw name@>             value as it is
+	<@-attr name@>                            escaped for attribute values like placesmap.AttrEscape
+	<@-url name@>                             escaped like url.QueryEscape
+	<@-require x.html@>                       include
+	<@-each items x.html else y.html@>        include per element of an array
+	<@-if name x.html else y.html@>           include, if the value is truthy
+	<@-with name x.html else y.html@>         include with the value as scope
+
+Inline bodies of -each, -if and -with are supported as well. Within -each and -with, names are
+looked up in the element or value first and in the outer scopes afterwards; elements that are no
+objects (e.g. strings) are the value of every name. Values are escaped according to their prefix,
+also within -each. null and undefined values and objects render as the empty string.
+Other directives and placeholders at the position of tag or attribute names make Generate fail
+with an UnsupportedError.
+*/
+package jsrender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"io"
+	"strings"
+)
+
+// UnsupportedError is returned by Generate for placeholders that can't be rendered client-side
+type UnsupportedError struct {
+	Template    string
+	Placeholder string
+}
+
+func (u UnsupportedError) Error() string {
+	return fmt.Sprintf("template %#v: placeholder %#v is not supported client-side", u.Template, u.Placeholder)
+}
+
+// filters are the ops of the escaping prefixes by prefix
+var filters = map[string]string{"": "html", "html": "raw", "raw": "raw", "attr": "attr", "url": "url"}
+
+// op returns the op of the placeholder with the given prefix and rest, i.e. the name of the op
+// followed by its arguments, along with the names of the included templates
+func op(prefix, rest string) (o []string, includes []string, ok bool) {
+	rest = strings.TrimSpace(rest)
+	if f, has := filters[prefix]; has {
+		return []string{f, rest}, nil, true
+	}
+	switch prefix {
+	case "require":
+		return []string{"require", rest}, []string{rest}, true
+	case "each", "if", "with":
+		s := strings.SplitN(rest, " ", 2)
+		if len(s) != 2 {
+			return nil, nil, false
+		}
+		then, otherwise := strings.TrimSpace(s[1]), ""
+		if idx := strings.Index(then, " else "); idx != -1 {
+			then, otherwise = strings.TrimSpace(then[:idx]), strings.TrimSpace(then[idx+6:])
+		}
+		return []string{prefix, s[0], then, otherwise}, []string{then, otherwise}, true
+	}
+	return nil, nil, false
+}
+
+// compile returns the parts of the given template: strings for the literal parts and ops
+// for the placeholders
+func compile(t placesmap.ExportTemplate) (parts []interface{}, includes []string, err error) {
+	literals := places.NewTemplate([]byte(t.Source)).Literals()
+	for i, ph := range t.Placeholders {
+		if lit := literals[i]; len(lit) > 0 {
+			parts = append(parts, string(lit))
+		}
+
+		o, incs, ok := op(ph.Prefix, ph.Rest)
+		if !ok || ph.Name {
+			return nil, nil, UnsupportedError{Template: t.Name, Placeholder: ph.Placeholder}
+		}
+		parts = append(parts, o)
+		includes = append(includes, incs...)
+	}
+	if lit := literals[len(literals)-1]; len(lit) > 0 {
+		parts = append(parts, string(lit))
+	}
+	if parts == nil {
+		parts = []interface{}{}
+	}
+	return parts, includes, nil
+}
+
+// Generate writes an ES module that exports the function render(name, data), which renders the
+// templates with the given names and the templates they include client-side. A template that
+// doesn't exist returns a placesmap.TemplateNotFoundError, a template with placeholders that
+// have no client-side equivalent an UnsupportedError.
+func Generate(w io.Writer, h *placesmap.HTMLTemplate, names ...string) error {
+	all := map[string]placesmap.ExportTemplate{}
+	for _, t := range h.Export().Templates {
+		all[t.Name] = t
+	}
+
+	templates := map[string][]interface{}{}
+	queue := append([]string{}, names...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, done := templates[name]; done || name == "" {
+			continue
+		}
+		t, has := all[name]
+		if !has {
+			return placesmap.TemplateNotFoundError(name)
+		}
+		parts, includes, err := compile(t)
+		if err != nil {
+			return err
+		}
+		templates[name] = parts
+		queue = append(queue, includes...)
+	}
+
+	// json.Marshal sorts the keys, so the module is stable
+	js, err := json.Marshal(templates)
+	if err != nil {
+		return err
+	}
+	var bf bytes.Buffer
+	bf.WriteString("// Code generated by places js. DO NOT EDIT.\n\nconst templates = ")
+	bf.Write(js)
+	bf.WriteString(";\n")
+	bf.WriteString(runtime)
+	_, err = w.Write(bf.Bytes())
+	return err
+}
+
+// runtime is the JavaScript that renders the templates
+const runtime = `
+const htmlEscapes = {"&": "&amp;", "'": "&#39;", "<": "&lt;", ">": "&gt;", "\"": "&#34;"};
+
+function str(v) {
+  return v === null || v === undefined || typeof v === "object" ? "" : String(v);
+}
+
+function truthy(v) {
+  const s = str(v);
+  return s !== "" && s !== "0" && s !== "false";
+}
+
+const filters = {
+  html: s => s.replace(/[&'<>"]/g, c => htmlEscapes[c]),
+  raw: s => s,
+  attr: s => s.replace(/[&<>"'` + "`" + `=\u0000- \u007f]/g, c => htmlEscapes[c] || "&#" + c.charCodeAt(0) + ";"),
+  url: s => encodeURIComponent(s).replace(/[!'()*]/g, c => "%" + c.charCodeAt(0).toString(16).toUpperCase()).replace(/%20/g, "+"),
+};
+
+function lookup(scopes, name) {
+  for (const scope of scopes) {
+    if (scope === null || typeof scope !== "object") {
+      return scope;
+    }
+    let v = scope;
+    for (const key of name.split(".")) {
+      if (v === null || typeof v !== "object" || !(key in v)) {
+        v = undefined;
+        break;
+      }
+      v = v[key];
+    }
+    if (v !== undefined) {
+      return v;
+    }
+  }
+  return undefined;
+}
+
+function run(name, scopes) {
+  const parts = templates[name];
+  if (parts === undefined) {
+    return "";
+  }
+  let out = "";
+  for (const part of parts) {
+    if (typeof part === "string") {
+      out += part;
+      continue;
+    }
+    const [op, arg, then, otherwise] = part;
+    if (filters[op]) {
+      out += filters[op](str(lookup(scopes, arg)));
+      continue;
+    }
+    const v = op === "require" ? undefined : lookup(scopes, arg);
+    switch (op) {
+    case "require":
+      out += run(arg, scopes);
+      break;
+    case "if":
+      out += run(truthy(v) ? then : otherwise, scopes);
+      break;
+    case "with":
+      out += v === null || v === undefined ? run(otherwise, scopes) : run(then, [v, ...scopes]);
+      break;
+    case "each":
+      if (!Array.isArray(v) || v.length === 0) {
+        out += run(otherwise, scopes);
+        break;
+      }
+      for (const el of v) {
+        out += run(then, [el, ...scopes]);
+      }
+      break;
+    }
+  }
+  return out;
+}
+
+// render renders the template with the given name with the given data
+export function render(name, data) {
+  return run(name, [data]);
+}
+`
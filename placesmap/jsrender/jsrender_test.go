@@ -0,0 +1,98 @@
+package jsrender
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTemplate(pairs ...string) *placesmap.HTMLTemplate {
+	rs := placesmap.NewReadSeekerMap()
+	for i := 0; i < len(pairs); i += 2 {
+		rs.Add(pairs[i], strings.NewReader(pairs[i+1]))
+	}
+	return placesmap.NewHTMLTemplate(rs)
+}
+
+// list is a NMapper for the tests
+type list []places.Mapper
+
+func (l list) Map(string) string                  { return "" }
+func (l list) Len() int                           { return len(l) }
+func (l list) NMap(n int, _ string) places.Mapper { return l[n] }
+
+func TestGenerate(t *testing.T) {
+	tpl := newTemplate(
+		"list.html", `<ul class="<@-attr class@>"><@-each items row.html else empty.html@></ul><@-if admin@><a href="/?q=<@-url q@>">edit</a><@-end@>`+
+			`<@-with user@><p><@city@>, <@-html badge@></p><@-end@><@-require empty.html@>`,
+		"row.html", "<li><@name@></li>",
+		"empty.html", "no items",
+		"other.html", "<@-calc a + 1@>",
+	)
+
+	var bf bytes.Buffer
+	if err := Generate(&bf, tpl, "list.html"); err != nil {
+		t.Fatal(err)
+	}
+	js := bf.String()
+	for _, exp := range []string{`"empty.html":["no items"]`, `["each","items","row.html","empty.html"]`, `["with","user","list.html#2",""]`, "export function render("} {
+		if !strings.Contains(js, exp) {
+			t.Errorf("expected %s within module: %s", exp, js)
+		}
+	}
+	if strings.Contains(js, "other.html") {
+		t.Errorf("unexpected template other.html within module")
+	}
+
+	if err := Generate(&bf, tpl, "other.html"); err != (UnsupportedError{Template: "other.html", Placeholder: "-calc a + 1"}) {
+		t.Errorf("expected UnsupportedError, got %v", err)
+	}
+	if err := Generate(&bf, tpl, "missing.html"); err != placesmap.TemplateNotFoundError("missing.html") {
+		t.Errorf("expected TemplateNotFoundError, got %v", err)
+	}
+
+	// the module renders like the server, if node is available
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not found")
+	}
+	dir, err := ioutil.TempDir("", "jsrender")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "templates.mjs"), []byte(js), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]interface{}{
+		"class": `a b"`, "q": "x y&z!", "admin": true, "badge": "<b>1</b>",
+		"items": []interface{}{map[string]interface{}{"name": "Tom"}, "Ann"},
+		"user":  map[string]interface{}{"city": "<Berlin>"},
+	}
+	b, _ := json.Marshal(data)
+	script := `import { render } from "./templates.mjs"; process.stdout.write(render("list.html", ` + string(b) + `));`
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.mjs"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(node, filepath.Join(dir, "main.mjs")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+
+	server := tpl.NewMapper(map[string]places.Mapper{
+		"class": placesmap.String(`a b"`), "q": placesmap.String("x y&z!"), "admin": placesmap.String("true"), "badge": placesmap.String("<b>1</b>"),
+		"items": list{placesmap.Nested{"name": "Tom"}, placesmap.String("Ann")},
+		"user":  placesmap.Nested{"city": "<Berlin>"},
+	}).Map("-require list.html")
+	if string(out) != server {
+		t.Errorf("client-side result %#v differs from server-side result %#v", string(out), server)
+	}
+}
@@ -0,0 +1,189 @@
+package placesmap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LazyFileSource is a Source for the templates with the given extension within the root directory.
+// Used with NewHTMLTemplateLazy, templates are read on their first use and read again when their
+// modification time changes, so that large template trees with rarely used templates are not
+// read into memory at startup.
+type LazyFileSource struct {
+	Root       string
+	Extension  string
+	IgnoreDirs *regexp.Regexp // directories to be ignored by List
+
+	// CheckInterval is the minimum time between two checks of the modification time of a template,
+	// 0 means that it is checked on every use
+	CheckInterval time.Duration
+}
+
+// path returns the path of the template with the given name
+func (l *LazyFileSource) path(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if name == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) ||
+		filepath.Ext(clean) != l.Extension {
+		return "", &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return filepath.Join(l.Root, clean), nil
+}
+
+// List returns the names of all templates within the root directory
+func (l *LazyFileSource) List() (names []string, err error) {
+	err = filepath.Walk(l.Root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if l.IgnoreDirs != nil && path != l.Root && l.IgnoreDirs.MatchString(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != l.Extension {
+			return nil
+		}
+		rel, err := filepath.Rel(l.Root, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(names)
+	return
+}
+
+// Get returns the content of the template with the given name
+func (l *LazyFileSource) Get(name string) ([]byte, error) {
+	p, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(p)
+}
+
+// ModTime returns the modification time of the template with the given name
+func (l *LazyFileSource) ModTime(name string) (time.Time, error) {
+	p, err := l.path(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// lazy loads the templates of a LazyFileSource on demand
+type lazy struct {
+	src        *LazyFileSource
+	validation Validation
+	mx         sync.RWMutex // guards entries
+	entries    map[string]*lazyEntry
+}
+
+type lazyEntry struct {
+	mx      sync.Mutex // held while the template is checked
+	mod     time.Time  // the modification time of the loaded version
+	checked int64      // the time of the last check in nanoseconds, accessed atomically
+	loaded  int32      // 1, if the template is loaded, accessed atomically
+}
+
+// fresh returns whether the entry has been checked within the given interval
+func (e *lazyEntry) fresh(now time.Time, interval time.Duration) bool {
+	checked := atomic.LoadInt64(&e.checked)
+	return checked != 0 && interval > 0 && now.Sub(time.Unix(0, checked)) < interval
+}
+
+func (e *lazyEntry) isLoaded() bool {
+	return atomic.LoadInt32(&e.loaded) == 1
+}
+
+func (e *lazyEntry) setLoaded(loaded bool) {
+	var v int32
+	if loaded {
+		v = 1
+	}
+	atomic.StoreInt32(&e.loaded, v)
+}
+
+// NewHTMLTemplateLazy returns a HTMLTemplate that reads the templates of the given LazyFileSource
+// on their first use. Templates that don't pass the given validation are treated as missing;
+// if a changed template is invalid, the previous version is kept.
+func NewHTMLTemplateLazy(l *LazyFileSource, v Validation) *HTMLTemplate {
+	h := newHTMLTemplate(NewReadSeekerMap())
+	h.lazy = &lazy{src: l, validation: v, entries: map[string]*lazyEntry{}}
+	return h
+}
+
+// load loads the template with the given name into h, if it is not loaded or has been changed,
+// and returns whether it is loaded. Only renders of the same template wait for each other and
+// only, if it has not been loaded before.
+func (lz *lazy) load(h *HTMLTemplate, name string) bool {
+	lz.mx.RLock()
+	e := lz.entries[name]
+	lz.mx.RUnlock()
+	if e == nil {
+		lz.mx.Lock()
+		if e = lz.entries[name]; e == nil {
+			e = &lazyEntry{}
+			lz.entries[name] = e
+		}
+		lz.mx.Unlock()
+	}
+
+	if e.fresh(time.Now(), lz.src.CheckInterval) {
+		return e.isLoaded()
+	}
+	// a loaded template is used, while it is checked by another render
+	if !e.mx.TryLock() {
+		if e.isLoaded() {
+			return true
+		}
+		e.mx.Lock()
+	}
+	defer e.mx.Unlock()
+
+	now := time.Now()
+	if e.fresh(now, lz.src.CheckInterval) {
+		return e.isLoaded()
+	}
+	atomic.StoreInt64(&e.checked, now.UnixNano())
+
+	mod, err := lz.src.ModTime(name)
+	if err != nil {
+		if e.isLoaded() {
+			h.Lock()
+			h.remove(name)
+			h.Unlock()
+		}
+		e.setLoaded(false)
+		return false
+	}
+	if e.isLoaded() && mod.Equal(e.mod) {
+		return true
+	}
+
+	b, err := lz.src.Get(name)
+	if err != nil || lz.validation.check(b) != "" {
+		return e.isLoaded()
+	}
+	// the previous version is removed along with the templates of its inline bodies
+	h.Lock()
+	h.remove(name)
+	h.add(name, b)
+	h.Unlock()
+	e.mod = mod
+	e.setLoaded(true)
+	return true
+}
@@ -0,0 +1,98 @@
+package placesmap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLazyFileSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placesmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "templates")
+	os.MkdirAll(filepath.Join(root, "parts"), 0755)
+	write := func(name, content string, mod time.Time) {
+		p := filepath.Join(root, name)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, mod, mod)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "secret.html"), []byte("secret"), 0644)
+
+	start := time.Now().Add(-time.Hour)
+	write("page.html", "v1 <@-require parts/part.html@>", start)
+	write("parts/part.html", "part", start)
+
+	src := &LazyFileSource{Root: root, Extension: ".html"}
+	tpl := NewHTMLTemplateLazy(src, Validation{})
+	render := func(name string) string { return tpl.NewMapper(nil).Map("-require " + name) }
+
+	if len(tpl.rsm) != 0 {
+		t.Errorf("templates loaded before their use: %d", len(tpl.rsm))
+	}
+	if got, exp := render("page.html"), "v1 part"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	write("page.html", "v2", start.Add(time.Minute))
+	if got, exp := render("page.html"), "v2"; got != exp {
+		t.Errorf("unexpected result after change: %#v, expected: %#v", got, exp)
+	}
+
+	src.CheckInterval = time.Hour
+	write("page.html", "v3", start.Add(2*time.Minute))
+	if got, exp := render("page.html"), "v2"; got != exp {
+		t.Errorf("unexpected result within check interval: %#v, expected: %#v", got, exp)
+	}
+	src.CheckInterval = 0
+
+	os.Remove(filepath.Join(root, "page.html"))
+	if tpl.Has("page.html") {
+		t.Errorf("removed template is still available")
+	}
+
+	if got := render("../secret.html"); got != "" {
+		t.Errorf("template outside the root rendered: %#v", got)
+	}
+	if names, _ := src.List(); len(names) != 1 || names[0] != "parts/part.html" {
+		t.Errorf("unexpected names: %#v", names)
+	}
+
+	// the templates of the inline bodies of the previous version are removed
+	write("blocks.html", "<@-if x@>a<@-else@>b<@-end@>", start)
+	render("blocks.html")
+	write("blocks.html", "none", start.Add(time.Minute))
+	if got := render("blocks.html"); got != "none" {
+		t.Errorf("unexpected result after change: %#v", got)
+	}
+	tpl.RLock()
+	for name := range tpl.rsm {
+		if strings.HasPrefix(name, "blocks.html#") {
+			t.Errorf("the inline body %s of the previous version is kept", name)
+		}
+	}
+	tpl.RUnlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if got := render("parts/part.html"); got != "part" {
+					t.Errorf("unexpected concurrent result: %#v", got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
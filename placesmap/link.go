@@ -0,0 +1,62 @@
+package placesmap
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// requestKey is the context key of the request, see WithRequest
+type requestKey struct{}
+
+// WithRequest returns a context with the given request for the renders of
+// HTMLTemplate.NewMapperContext, e.g. for "-link". Negotiate adds the request itself.
+func WithRequest(ctx context.Context, rq *http.Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, rq)
+}
+
+// Request returns the request of the render, nil if there is none, see WithRequest
+func (r *RenderContext) Request() *http.Request {
+	rq, _ := r.ctx.Value(requestKey{}).(*http.Request)
+	return rq
+}
+
+// link handles "-link path?page=2 keep=sort,filter", that renders the URL of the path with the given
+// query, preserving the query params sort and filter of the current request (see WithRequest).
+// The params of the path take precedence over the preserved ones, the path defaults to the path of
+// the request. The URL is HTML escaped, so that it can be used within attributes.
+func (r *RenderContext) link(rest string) string {
+	target, args := nextArg(rest)
+	u, err := url.Parse(target)
+	if err != nil {
+		r.fail(err)
+		return ""
+	}
+
+	q := url.Values{}
+	rq := r.Request()
+	if rq != nil {
+		if u.Path == "" {
+			u.Path = rq.URL.Path
+		}
+		current := rq.URL.Query()
+		for _, arg := range strings.Fields(args) {
+			if !strings.HasPrefix(arg, "keep=") {
+				continue
+			}
+			for _, k := range strings.Split(strings.TrimPrefix(arg, "keep="), ",") {
+				if vals, has := current[k]; has {
+					q[k] = vals
+				}
+			}
+		}
+	}
+	for k, vals := range u.Query() {
+		q[k] = vals
+	}
+	// Encode sorts by key
+	u.RawQuery = q.Encode()
+	return html.EscapeString(u.String())
+}
@@ -0,0 +1,22 @@
+package placesmap
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	tpl := newTemplate("page.html", `<a href="<@-link ?page=2 keep=sort,filter@>">|<@-link /users?sort=name keep=sort,q@>|<@-link /a%20b@>`)
+	rq := httptest.NewRequest("GET", "/list?sort=date&filter=a&filter=b&q=x&page=1", nil)
+
+	got := tpl.NewMapperContext(WithRequest(context.Background(), rq), nil).Map("-require page.html")
+	exp := `<a href="/list?filter=a&amp;filter=b&amp;page=2&amp;sort=date">|/users?q=x&amp;sort=name|/a%20b`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	if got, exp := tpl.NewMapper(nil).Map("-require page.html"), `<a href="?page=2">|/users?sort=name|/a%20b`; got != exp {
+		t.Errorf("unexpected result without request: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,185 @@
+package placesmap
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BrokenLink is an internal link or asset of a page that does not resolve within a static export
+type BrokenLink struct {
+	Page   string // the path of the page relative to the export, with slashes
+	Line   int
+	Attr   string // the attribute, e.g. href, src or srcset
+	URL    string
+	Reason string // "not found" or "missing fragment"
+}
+
+func (b BrokenLink) String() string {
+	return fmt.Sprintf("%s:%d: %s %#v: %s", b.Page, b.Line, b.Attr, b.URL, b.Reason)
+}
+
+// LinkReport is the result of CheckLinks
+type LinkReport struct {
+	Pages  int          // the number of checked pages
+	Broken []BrokenLink // sorted by page and line
+}
+
+// linkAttrs are the attributes with URLs by tag
+var linkAttrs = map[string][]string{
+	"a": {"href"}, "area": {"href"}, "link": {"href"},
+	"img": {"src", "srcset"}, "source": {"src", "srcset"}, "script": {"src"}, "iframe": {"src"},
+	"video": {"src", "poster"}, "audio": {"src"}, "embed": {"src"}, "track": {"src"},
+}
+
+// pageLink is a link of a page
+type pageLink struct {
+	line      int
+	attr, url string
+}
+
+// CheckLinks checks the pages (files ending with .html or .htm) of the static export within dir
+// for internal links and assets that don't resolve within the export, e.g. to fail a build.
+// URLs with a scheme (e.g. https: or mailto:) or a host are external and not checked, the query
+// is ignored. Absolute paths are resolved against dir, relative ones against the directory of the
+// page. A path resolves to a file, to the index.html of a directory or, without extension, to
+// the file with the extension .html. Fragments must be the id of an element of the target page
+// (or the name of an <a>), the empty fragment and #top are always valid.
+func CheckLinks(dir string) (LinkReport, error) {
+	var report LinkReport
+	ids := map[string]map[string]bool{} // the ids by page
+	links := map[string][]pageLink{}    // the links by page
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if info.IsDir() || ext != ".html" && ext != ".htm" {
+			return nil
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		page := filepath.ToSlash(rel)
+		ids[page], links[page] = scanPage(string(b))
+		report.Pages++
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for page, ls := range links {
+		for _, l := range ls {
+			if reason := checkLink(dir, page, l.url, ids); reason != "" {
+				report.Broken = append(report.Broken, BrokenLink{Page: page, Line: l.line, Attr: l.attr, URL: l.url, Reason: reason})
+			}
+		}
+	}
+	sort.SliceStable(report.Broken, func(a, b int) bool {
+		x, y := report.Broken[a], report.Broken[b]
+		if x.Page != y.Page {
+			return x.Page < y.Page
+		}
+		return x.Line < y.Line
+	})
+	return report, nil
+}
+
+// scanPage returns the ids and the links of the given page
+func scanPage(page string) (ids map[string]bool, links []pageLink) {
+	ids = map[string]bool{}
+	line, counted := 1, 0
+	for last := 0; ; {
+		t, ok := nextTag(page, last)
+		if !ok {
+			break
+		}
+		last = t.end
+		line += strings.Count(page[counted:t.offset], "\n")
+		counted = t.offset
+
+		if id := t.attrs["id"]; id != "" {
+			ids[id] = true
+		}
+		if name := t.attrs["name"]; t.name == "a" && name != "" {
+			ids[name] = true
+		}
+		for _, attr := range linkAttrs[t.name] {
+			val, has := t.attrs[attr]
+			if !has {
+				continue
+			}
+			val = html.UnescapeString(val)
+			if attr != "srcset" {
+				links = append(links, pageLink{line: line, attr: attr, url: strings.TrimSpace(val)})
+				continue
+			}
+			for _, candidate := range strings.Split(val, ",") {
+				if fields := strings.Fields(candidate); len(fields) > 0 {
+					links = append(links, pageLink{line: line, attr: attr, url: fields[0]})
+				}
+			}
+		}
+	}
+	return
+}
+
+// checkLink returns why the given URL of the page does not resolve, the empty string if it does
+func checkLink(dir, page, link string, ids map[string]map[string]bool) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "invalid URL"
+	}
+	if u.Scheme != "" || u.Host != "" || link == "" {
+		return ""
+	}
+
+	target := page
+	if u.Path != "" {
+		p := u.Path
+		if !strings.HasPrefix(p, "/") {
+			p = path.Join(path.Dir("/"+page), p)
+		}
+		var ok bool
+		if target, ok = resolve(dir, path.Clean(p)); !ok {
+			return "not found"
+		}
+	}
+
+	if u.Fragment == "" || u.Fragment == "top" {
+		return ""
+	}
+	// fragments of other files than pages are not checked
+	if pageIDs, isPage := ids[target]; isPage && !pageIDs[u.Fragment] {
+		return "missing fragment"
+	}
+	return ""
+}
+
+// resolve returns the path of the file relative to dir, that the given absolute path resolves to
+func resolve(dir, p string) (string, bool) {
+	candidates := []string{p, path.Join(p, "index.html")}
+	if path.Ext(p) == "" {
+		candidates = append(candidates, p+".html")
+	}
+	for _, c := range candidates {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(c)))
+		if err == nil && !info.IsDir() {
+			return strings.TrimPrefix(c, "/"), true
+		}
+	}
+	return "", false
+}
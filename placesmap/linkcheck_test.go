@@ -0,0 +1,56 @@
+package placesmap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckLinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placesmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"index.html": `<a href="/docs/">Docs</a><a href="about">About</a><a href="https://example.com/x">x</a>
+<img src="img/logo.png" srcset="img/logo.png 1x, img/logo@2x.png 2x" alt="">
+<a href="docs/intro.html#setup">Setup</a><a href="#main">Main</a><a href="?page=2">2</a><a href="mailto:a@b.c">mail</a>`,
+		"about.html":      `<main id="main"><script src="/js/app.js"></script><a href="/index.html#main">home</a></main>`,
+		"docs/index.html": `<a href="../about.html">About</a><a href="intro.html#install">Install</a>` + "\n" + `<link href="/css/site.css" rel="stylesheet">`,
+		"docs/intro.html": `<h2 id="setup">Setup</h2><a href="/docs/missing/">x</a>`,
+		"img/logo.png":    "png",
+		"js/app.js":       "js",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := CheckLinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, b := range report.Broken {
+		got = append(got, b.String())
+	}
+	exp := []string{
+		`about.html:1: href "/index.html#main": missing fragment`,
+		`docs/index.html:1: href "intro.html#install": missing fragment`,
+		`docs/index.html:2: href "/css/site.css": not found`,
+		`docs/intro.html:1: href "/docs/missing/": not found`,
+		`index.html:2: srcset "img/logo@2x.png": not found`,
+		`index.html:3: href "#main": missing fragment`,
+	}
+	if report.Pages != 4 || strings.Join(got, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("unexpected report for %d pages:\n%s\nexpected:\n%s", report.Pages, strings.Join(got, "\n"), strings.Join(exp, "\n"))
+	}
+}
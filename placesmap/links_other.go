@@ -0,0 +1,10 @@
+//go:build !unix
+
+package placesmap
+
+import "os"
+
+// linkCount returns the number of hard links of the file, that is not known on this system
+func linkCount(info os.FileInfo) int {
+	return 1
+}
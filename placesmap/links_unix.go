@@ -0,0 +1,16 @@
+//go:build unix
+
+package placesmap
+
+import (
+	"os"
+	"syscall"
+)
+
+// linkCount returns the number of hard links of the file
+func linkCount(info os.FileInfo) int {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(st.Nlink)
+	}
+	return 1
+}
@@ -0,0 +1,170 @@
+/*
+package live renders named includes of a placesmap.HTMLTemplate on demand and
+pushes the resulting HTML to browsers via Server-Sent Events or WebSocket.
+
+Every fragment is an include of the HTMLTemplate that is registered together with
+a MapperProvider. Clients subscribe to fragments, get the current rendering
+immediately and a fresh rendering whenever Push is called for the fragment.
+*/
+package live
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"net/http"
+	"sync"
+)
+
+// MapperProvider returns the mappers that are used to render a fragment for
+// the given request (the request of the subscribing client)
+type MapperProvider func(r *http.Request) map[string]places.Mapper
+
+// Message is a rendered fragment as it is sent to the clients
+type Message struct {
+	Fragment string `json:"fragment"`
+	HTML     string `json:"html"`
+}
+
+type UnknownFragmentError string
+
+func (u UnknownFragmentError) Error() string {
+	return fmt.Sprintf("fragment %#v is not registered", u)
+}
+
+type FragmentAlreadyExistsError string
+
+func (f FragmentAlreadyExistsError) Error() string {
+	return fmt.Sprintf("fragment %#v already exists", f)
+}
+
+type UnknownIncludeError string
+
+func (u UnknownIncludeError) Error() string {
+	return fmt.Sprintf("include %#v is not loaded", u)
+}
+
+// subscriber is a connected client
+type subscriber struct {
+	rq        *http.Request
+	fragments map[string]bool
+	messages  chan Message
+}
+
+// Renderer renders and pushes fragments. It is safe for concurrent use.
+type Renderer struct {
+	tpl       *placesmap.HTMLTemplate
+	mx        sync.RWMutex
+	providers map[string]MapperProvider
+	subs      map[*subscriber]struct{}
+
+	// BufferSize is the number of messages that are queued for a slow client
+	// before further messages will be dropped. It must be set before the first client connects.
+	BufferSize int
+
+	// CheckOrigin returns whether a WebSocket connection with the Origin header of the given
+	// request is allowed. If it is nil, only requests without Origin header and requests whose
+	// Origin has the host of the request are allowed, see SameOrigin.
+	CheckOrigin func(rq *http.Request) bool
+}
+
+// New returns a Renderer for the includes of the given template
+func New(tpl *placesmap.HTMLTemplate) *Renderer {
+	return &Renderer{
+		tpl:        tpl,
+		providers:  map[string]MapperProvider{},
+		subs:       map[*subscriber]struct{}{},
+		BufferSize: 16,
+	}
+}
+
+// RegisterFragment registers the include with the given name as fragment that
+// is rendered with the mappers returned by the given provider.
+// If the include is not part of the HTMLTemplate, UnknownIncludeError is returned.
+// If the fragment is already registered, FragmentAlreadyExistsError is returned.
+func (r *Renderer) RegisterFragment(name string, provider MapperProvider) error {
+	if !r.tpl.Has(name) {
+		return UnknownIncludeError(name)
+	}
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if _, has := r.providers[name]; has {
+		return FragmentAlreadyExistsError(name)
+	}
+	r.providers[name] = provider
+	return nil
+}
+
+// Render renders the fragment with the given name for the given request.
+// If the fragment is not registered, UnknownFragmentError is returned.
+func (r *Renderer) Render(name string, rq *http.Request) (string, error) {
+	r.mx.RLock()
+	provider, has := r.providers[name]
+	r.mx.RUnlock()
+	if !has {
+		return "", UnknownFragmentError(name)
+	}
+	return r.tpl.NewMapper(provider(rq)).Map("-require " + name), nil
+}
+
+// Push renders the given fragments for every client that subscribed to them
+// and sends them to the clients.
+// Messages for clients that do not keep up are dropped.
+func (r *Renderer) Push(fragments ...string) {
+	type target struct {
+		sub  *subscriber
+		name string
+	}
+
+	var targets []target
+	r.mx.RLock()
+	for sub := range r.subs {
+		for _, name := range fragments {
+			if sub.fragments[name] {
+				targets = append(targets, target{sub, name})
+			}
+		}
+	}
+	r.mx.RUnlock()
+
+	for _, t := range targets {
+		html, err := r.Render(t.name, t.sub.rq)
+		if err != nil {
+			continue
+		}
+		select {
+		case t.sub.messages <- Message{Fragment: t.name, HTML: html}:
+		default:
+		}
+	}
+}
+
+// subscribe registers a client for the given fragments and queues the current
+// rendering of the fragments
+func (r *Renderer) subscribe(rq *http.Request, fragments []string) (*subscriber, error) {
+	sub := &subscriber{
+		rq:        rq,
+		fragments: map[string]bool{},
+		messages:  make(chan Message, r.BufferSize+len(fragments)),
+	}
+
+	for _, name := range fragments {
+		html, err := r.Render(name, rq)
+		if err != nil {
+			return nil, err
+		}
+		sub.fragments[name] = true
+		sub.messages <- Message{Fragment: name, HTML: html}
+	}
+
+	r.mx.Lock()
+	r.subs[sub] = struct{}{}
+	r.mx.Unlock()
+	return sub, nil
+}
+
+func (r *Renderer) unsubscribe(sub *subscriber) {
+	r.mx.Lock()
+	delete(r.subs, sub)
+	r.mx.Unlock()
+}
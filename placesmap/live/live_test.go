@@ -0,0 +1,163 @@
+package live
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRenderer(t *testing.T, val *string) *Renderer {
+	rs := placesmap.NewReadSeekerMap()
+	rs.Add("counter.html", strings.NewReader("count: <@count@>"))
+	r := New(placesmap.NewHTMLTemplate(rs))
+	err := r.RegisterFragment("counter.html", func(*http.Request) map[string]places.Mapper {
+		return map[string]places.Mapper{"count": placesmap.String(*val)}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRegisterFragment(t *testing.T) {
+	val := "1"
+	r := newRenderer(t, &val)
+
+	if err := r.RegisterFragment("missing.html", nil); err != UnknownIncludeError("missing.html") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := r.RegisterFragment("counter.html", nil); err != FragmentAlreadyExistsError("counter.html") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := r.Render("missing.html", nil); err != UnknownFragmentError("missing.html") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSSE(t *testing.T) {
+	val := "1"
+	r := newRenderer(t, &val)
+	srv := httptest.NewServer(r.SSE())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "?fragment=counter.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	rd := bufio.NewReader(res.Body)
+	readEvent := func() Message {
+		var msg Message
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(line, "data: ") {
+				json.Unmarshal([]byte(line[6:]), &msg)
+				return msg
+			}
+		}
+	}
+
+	if msg := readEvent(); msg.HTML != "count: 1" {
+		t.Errorf("unexpected initial message: %#v", msg)
+	}
+
+	val = "2"
+	r.Push("counter.html")
+
+	if msg := readEvent(); msg.HTML != "count: 2" || msg.Fragment != "counter.html" {
+		t.Errorf("unexpected pushed message: %#v", msg)
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	val := "3"
+	r := newRenderer(t, &val)
+	srv := httptest.NewServer(r.WebSocket())
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("GET /?fragment=counter.html HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\n" +
+		"Connection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+
+	rd := bufio.NewReader(conn)
+	res, err := http.ReadResponse(rd, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status: %d", res.StatusCode)
+	}
+
+	if got, exp := res.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != exp {
+		t.Errorf("unexpected accept header: %#v, expected: %#v", got, exp)
+	}
+
+	op, payload, err := readFrame(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg Message
+	json.Unmarshal(payload, &msg)
+	if op != wsOpText || msg.HTML != "count: 3" {
+		t.Errorf("unexpected message: %#v", msg)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	val := "3"
+	r := newRenderer(t, &val)
+	status := func(origin string) int {
+		rq := httptest.NewRequest("GET", "http://example.com/?fragment=counter.html", nil)
+		rq.Header.Set("Upgrade", "websocket")
+		rq.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if origin != "" {
+			rq.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		r.WebSocket().ServeHTTP(rec, rq)
+		return rec.Code
+	}
+
+	// the recorder can't be hijacked, so allowed origins fail after the check
+	tests := []struct {
+		origin string
+		exp    int
+	}{
+		{"", http.StatusInternalServerError},
+		{"http://example.com", http.StatusInternalServerError},
+		{"https://evil.com", http.StatusForbidden},
+		{"http://example.com.evil.com", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		if got := status(test.origin); got != test.exp {
+			t.Errorf("origin %#v: unexpected status: %d, expected: %d", test.origin, got, test.exp)
+		}
+	}
+
+	r.CheckOrigin = func(rq *http.Request) bool { return rq.Header.Get("Origin") == "https://evil.com" }
+	if got := status("https://evil.com"); got != http.StatusInternalServerError {
+		t.Errorf("expected the origin allowed by CheckOrigin, got status %d", got)
+	}
+	if got := status("http://example.com"); got != http.StatusForbidden {
+		t.Errorf("expected the origin rejected by CheckOrigin, got status %d", got)
+	}
+}
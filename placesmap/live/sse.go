@@ -0,0 +1,52 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FragmentParam is the query parameter that lists the fragments a client subscribes to
+const FragmentParam = "fragment"
+
+// SSE returns an http.Handler that streams the fragments given via the query parameter
+// FragmentParam (may be repeated) as Server-Sent Events.
+// Every event has the type "fragment" and a Message as JSON encoded data.
+func (r *Renderer) SSE() http.Handler {
+	return http.HandlerFunc(r.serveSSE)
+}
+
+func (r *Renderer) serveSSE(wr http.ResponseWriter, rq *http.Request) {
+	flusher, ok := wr.(http.Flusher)
+	if !ok {
+		http.Error(wr, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, err := r.subscribe(rq, rq.URL.Query()[FragmentParam])
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer r.unsubscribe(sub)
+
+	wr.Header().Set("Content-Type", "text/event-stream")
+	wr.Header().Set("Cache-Control", "no-cache")
+	wr.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	done := rq.Context().Done()
+
+	for {
+		select {
+		case <-done:
+			return
+		case msg := <-sub.messages:
+			b, _ := json.Marshal(msg)
+			if _, err := fmt.Fprintf(wr, "event: fragment\ndata: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package live
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// the magic GUID of RFC 6455 to calculate Sec-WebSocket-Accept
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// maxClientFrame is the maximum payload we accept from a client
+const maxClientFrame = 4096
+
+var ErrFrameTooLarge = errors.New("websocket frame too large")
+
+// WebSocket returns an http.Handler that upgrades the connection to a WebSocket and
+// sends the fragments given via the query parameter FragmentParam as JSON encoded Message
+// text frames.
+// A client may send the name of a registered fragment as text frame to subscribe to it
+// and to get it rendered on demand.
+// Only the minimal part of RFC 6455 that is needed for pushing is implemented,
+// i.e. no extensions, no fragmented client messages.
+// Connections from other origins are rejected with 403 Forbidden, see Renderer.CheckOrigin.
+func (r *Renderer) WebSocket() http.Handler {
+	return http.HandlerFunc(r.serveWebSocket)
+}
+
+// SameOrigin returns whether the request has no Origin header or an Origin whose host equals the
+// host of the request. It protects against cross-site WebSocket hijacking, since browsers send
+// the cookies of the site with WebSocket connections from any origin.
+func SameOrigin(rq *http.Request) bool {
+	origin := rq.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, rq.Host)
+}
+
+func (r *Renderer) serveWebSocket(wr http.ResponseWriter, rq *http.Request) {
+	key := rq.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(rq.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(wr, "websocket upgrade required", http.StatusBadRequest)
+		return
+	}
+
+	check := r.CheckOrigin
+	if check == nil {
+		check = SameOrigin
+	}
+	if !check(rq) {
+		http.Error(wr, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	hj, ok := wr.(http.Hijacker)
+	if !ok {
+		http.Error(wr, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, err := r.subscribe(rq, rq.URL.Query()[FragmentParam])
+	if err != nil {
+		http.Error(wr, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer r.unsubscribe(sub)
+
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	h := sha1.New()
+	io.WriteString(h, key+wsGUID)
+	accept := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	brw.WriteString("Sec-WebSocket-Accept: " + accept + "\r\n\r\n")
+	if brw.Flush() != nil {
+		return
+	}
+
+	requests := make(chan string)
+	pongs := make(chan []byte)
+	done := make(chan struct{})
+	quit := make(chan struct{})
+	defer close(quit)
+	go r.readWebSocket(brw.Reader, requests, pongs, done, quit)
+
+	for {
+		var err error
+		select {
+		case <-done:
+			writeFrame(conn, wsOpClose, nil)
+			return
+		case payload := <-pongs:
+			err = writeFrame(conn, wsOpPong, payload)
+		case name := <-requests:
+			html, errRender := r.Render(name, rq)
+			if errRender != nil {
+				continue
+			}
+			r.mx.Lock()
+			sub.fragments[name] = true
+			r.mx.Unlock()
+			err = writeMessage(conn, Message{Fragment: name, HTML: html})
+		case msg := <-sub.messages:
+			err = writeMessage(conn, msg)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readWebSocket reads the frames sent by the client until the connection
+// is closed or an error occurs
+func (r *Renderer) readWebSocket(rd *bufio.Reader, requests chan<- string, pongs chan<- []byte, done, quit chan struct{}) {
+	defer close(done)
+	for {
+		op, payload, err := readFrame(rd)
+		if err != nil {
+			return
+		}
+		switch op {
+		case wsOpClose:
+			return
+		case wsOpPing:
+			select {
+			case pongs <- payload:
+			case <-quit:
+				return
+			}
+		case wsOpText:
+			select {
+			case requests <- strings.TrimSpace(string(payload)):
+			case <-quit:
+				return
+			}
+		}
+	}
+}
+
+func writeMessage(conn net.Conn, msg Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return writeFrame(conn, wsOpText, b)
+}
+
+// writeFrame writes an unmasked frame as required for server to client frames
+func writeFrame(wr io.Writer, op byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | op // FIN
+	l := len(payload)
+	switch {
+	case l < 126:
+		header[1] = byte(l)
+	case l <= 0xFFFF:
+		header[1] = 126
+		header = header[:4]
+		binary.BigEndian.PutUint16(header[2:], uint16(l))
+	default:
+		header[1] = 127
+		header = header[:10]
+		binary.BigEndian.PutUint64(header[2:], uint64(l))
+	}
+	if _, err := wr.Write(header); err != nil {
+		return err
+	}
+	_, err := wr.Write(payload)
+	return err
+}
+
+// readFrame reads a (masked) frame sent by the client
+func readFrame(rd io.Reader) (op byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(rd, header[:]); err != nil {
+		return
+	}
+	op = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	l := uint64(header[1] & 0x7F)
+
+	switch l {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(rd, ext[:]); err != nil {
+			return
+		}
+		l = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(rd, ext[:]); err != nil {
+			return
+		}
+		l = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if l > maxClientFrame {
+		err = ErrFrameTooLarge
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(rd, mask[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, l)
+	if _, err = io.ReadFull(rd, payload); err != nil {
+		return
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
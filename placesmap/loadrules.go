@@ -0,0 +1,314 @@
+package placesmap
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SymlinkPolicy decides how a TemplateLoader treats symbolic links
+type SymlinkPolicy int
+
+const (
+	// SymlinkFiles loads symbolic links to files, but does not follow symbolic links to directories
+	SymlinkFiles SymlinkPolicy = iota
+
+	// SymlinkSkip ignores symbolic links
+	SymlinkSkip
+
+	// SymlinkFollow loads symbolic links to files and follows symbolic links to directories.
+	// Directories that have already been walked (e.g. by cyclic links) are skipped.
+	SymlinkFollow
+)
+
+// ConfinePolicy decides how a TemplateLoader treats symbolic links that resolve to paths outside
+// of the root directory
+type ConfinePolicy int
+
+const (
+	// ConfineOff loads symbolic links regardless of their targets
+	ConfineOff ConfinePolicy = iota
+
+	// ConfineSkip ignores symbolic links that resolve outside of the root directory
+	ConfineSkip
+
+	// ConfineFail makes the loading fail with a SymlinkEscapeError
+	ConfineFail
+)
+
+// LoadRules scope the files of a TemplateLoader, see TemplateLoader.SetRules.
+// The patterns are matched against the paths relative to the root directory, separated by slashes.
+// They have the syntax of path.Match, in addition ** matches any number of directories
+// and {a,b} matches one of the alternatives, e.g. "pages/**/*.html" or "{partials,layouts}/*".
+type LoadRules struct {
+	// Include are the patterns of the files to load. If empty, all files are loaded.
+	Include []string
+
+	// Exclude are the patterns of the files and directories to skip. Excluded directories are not walked.
+	Exclude []string
+
+	Symlinks SymlinkPolicy
+
+	// Confine decides how symbolic links that resolve outside of the root directory are treated.
+	// Roots that are partially controlled by users should set it. The files are checked again
+	// after they have been opened, so that links that are replaced after the walk are caught, too.
+	Confine ConfinePolicy
+
+	// RefuseHardLinks makes the loading fail with a HardLinkError for files with more than one
+	// hard link, since they may be the same as files outside of the root directory.
+	// It is only supported on unix systems.
+	RefuseHardLinks bool
+
+	// MaxDepth is the maximum number of path elements of the loaded files, i.e. 1 loads
+	// only the files of the root directory. 0 means no limit.
+	MaxDepth int
+}
+
+// InvalidPatternError is the error for malformed patterns of LoadRules
+type InvalidPatternError string
+
+func (i InvalidPatternError) Error() string {
+	return fmt.Sprintf("invalid pattern %#v", string(i))
+}
+
+// SymlinkEscapeError is the error for symbolic links that resolve outside of the root directory,
+// see ConfineFail
+type SymlinkEscapeError struct {
+	Name   string // the path relative to the root directory
+	Target string // the resolved path
+}
+
+func (s SymlinkEscapeError) Error() string {
+	return fmt.Sprintf("symbolic link %#v resolves to %#v outside of the root", s.Name, s.Target)
+}
+
+// HardLinkError is the error for files with more than one hard link, see LoadRules.RefuseHardLinks
+type HardLinkError string
+
+func (h HardLinkError) Error() string {
+	return fmt.Sprintf("file %#v has more than one hard link", string(h))
+}
+
+// SetRules sets the rules that scope the files loaded by Load. They apply in addition to the
+// extension and the ignored directories of the TemplateLoader.
+// It returns an InvalidPatternError, if one of the patterns is malformed.
+func (l *TemplateLoader) SetRules(rules LoadRules) error {
+	for _, patterns := range [][]string{rules.Include, rules.Exclude} {
+		for _, p := range patterns {
+			if !validGlob(p) {
+				return InvalidPatternError(p)
+			}
+		}
+	}
+	l.rules = rules
+	return nil
+}
+
+// validGlob returns whether the given pattern is well-formed
+func validGlob(pattern string) bool {
+	for _, p := range expandBraces(pattern) {
+		for _, seg := range strings.Split(p, "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchAny returns whether the given relative path matches one of the patterns
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob returns whether the given slash separated path matches the pattern (see LoadRules)
+func matchGlob(pattern, name string) bool {
+	for _, p := range expandBraces(pattern) {
+		if matchSegments(strings.Split(p, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBraces returns the alternatives of the pattern, i.e. "{a,b}.html" becomes "a.html" and "b.html"
+func expandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start == -1 {
+		return []string{pattern}
+	}
+	end := strings.IndexByte(pattern[start:], '}')
+	if end == -1 {
+		return []string{pattern}
+	}
+	end += start
+
+	var res []string
+	for _, alt := range strings.Split(pattern[start+1:end], ",") {
+		res = append(res, expandBraces(pattern[:start]+alt+pattern[end+1:])...)
+	}
+	return res
+}
+
+// matchSegments matches the path elements of a name against the elements of a pattern
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+// walkDir collects the template files of the given directory, whose path relative to the
+// root is rel, into l.files. seen are the walked directories, if symbolic links are followed.
+func (l *TemplateLoader) walkDir(dir, rel string, seen map[string]bool) error {
+	if seen != nil {
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return err
+		}
+		if seen[real] {
+			return nil
+		}
+		seen[real] = true
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		var (
+			p    = filepath.Join(dir, info.Name())
+			name = filepath.Join(rel, info.Name())
+			slug = filepath.ToSlash(name)
+		)
+		if info.Mode()&os.ModeSymlink != 0 {
+			if l.rules.Symlinks == SymlinkSkip {
+				continue
+			}
+			if escapes, err := l.escapes(p, name); escapes || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			// without confinement, broken links are kept as files, so that reading them reports the error
+			if l.rules.Symlinks == SymlinkFollow {
+				if target, err := os.Stat(p); err == nil {
+					info = target
+				}
+			}
+		}
+		if matchAny(l.rules.Exclude, slug) {
+			continue
+		}
+
+		if info.IsDir() {
+			if l.ignoreDirs != nil && l.ignoreDirs.MatchString(info.Name()) {
+				continue
+			}
+			if l.rules.MaxDepth > 0 && strings.Count(slug, "/")+2 > l.rules.MaxDepth {
+				continue
+			}
+			if err = l.walkDir(p, name, seen); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if filepath.Ext(p) != l.extension {
+			continue
+		}
+		if len(l.rules.Include) > 0 && !matchAny(l.rules.Include, slug) {
+			continue
+		}
+		if l.rules.RefuseHardLinks {
+			if target, err := os.Stat(p); err == nil && linkCount(target) > 1 {
+				return HardLinkError(name)
+			}
+		}
+		l.files = append(l.files, loadFile{name, p})
+	}
+	return nil
+}
+
+// escapes returns whether the symbolic link at p with the given relative name resolves outside
+// of the root directory and has to be skipped (see LoadRules.Confine). Links that can't be
+// resolved (e.g. broken links) are treated as escaping, since their target is unknown.
+func (l *TemplateLoader) escapes(p, name string) (bool, error) {
+	if l.rules.Confine == ConfineOff {
+		return false, nil
+	}
+	target, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		// the unresolved target, as far as it is known
+		target, _ = os.Readlink(p)
+	} else if l.within(target) {
+		return false, nil
+	}
+	if l.rules.Confine == ConfineFail {
+		return true, SymlinkEscapeError{Name: name, Target: target}
+	}
+	return true, nil
+}
+
+// within returns whether the given resolved path is within the root directory
+func (l *TemplateLoader) within(target string) bool {
+	rel, err := filepath.Rel(l.realRoot, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// errSkipFile is returned by checkOpened for files that are skipped, see ConfineSkip
+var errSkipFile = errors.New("file skipped")
+
+// checkOpened checks the opened template file again, since it might have been replaced after the
+// walk, e.g. by a symbolic link: the file must still be the file at its real path within the root
+// directory (see LoadRules.Confine) and must have no other hard links (see LoadRules.RefuseHardLinks).
+func (l *TemplateLoader) checkOpened(file *os.File, f loadFile) error {
+	if l.rules.Confine == ConfineOff && !l.rules.RefuseHardLinks {
+		return nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if l.rules.RefuseHardLinks && linkCount(info) > 1 {
+		return HardLinkError(f.name)
+	}
+	if l.rules.Confine == ConfineOff {
+		return nil
+	}
+	target, err := filepath.EvalSymlinks(f.path)
+	if err == nil && l.within(target) {
+		if real, err := os.Stat(target); err == nil && os.SameFile(info, real) {
+			return nil
+		}
+	}
+	if l.rules.Confine == ConfineFail {
+		return SymlinkEscapeError{Name: f.name, Target: target}
+	}
+	return errSkipFile
+}
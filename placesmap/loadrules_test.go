@@ -0,0 +1,152 @@
+package placesmap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTemplateLoaderRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placesmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root, other := filepath.Join(dir, "root"), filepath.Join(dir, "other")
+	for _, name := range []string{"root/page.html", "root/a/x.html", "root/a/b/y.html", "root/a/skip.html", "root/vendor/v.html", "other/z.html"} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{"ln.html": filepath.Join(root, "page.html"), "link": filepath.Join(root, "a"), "ext": other} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	load := func(rules LoadRules) string {
+		l := NewTemplateLoader(root, ".html", nil)
+		if err := l.SetRules(rules); err != nil {
+			t.Fatal(err)
+		}
+		rs, err := l.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for name := range rs.m {
+			names = append(names, filepath.ToSlash(name))
+		}
+		sort.Strings(names)
+		return strings.Join(names, " ")
+	}
+
+	tests := []struct {
+		rules LoadRules
+		exp   string
+	}{
+		{LoadRules{}, "a/b/y.html a/skip.html a/x.html ln.html page.html vendor/v.html"},
+		{LoadRules{Include: []string{"a/**/*.html"}, Exclude: []string{"**/skip.html"}}, "a/b/y.html a/x.html"},
+		{LoadRules{Include: []string{"{page,vendor/*}.html"}}, "page.html vendor/v.html"},
+		{LoadRules{Exclude: []string{"vendor/**", "a/b"}}, "a/skip.html a/x.html ln.html page.html"},
+		{LoadRules{MaxDepth: 1}, "ln.html page.html"},
+		{LoadRules{MaxDepth: 2, Symlinks: SymlinkSkip}, "a/skip.html a/x.html page.html vendor/v.html"},
+		{LoadRules{Symlinks: SymlinkFollow, Exclude: []string{"a"}}, "ext/z.html link/b/y.html link/skip.html link/x.html ln.html page.html vendor/v.html"},
+		{LoadRules{Symlinks: SymlinkFollow, Include: []string{"ext/*", "link/*"}}, "ext/z.html"},
+		{LoadRules{Symlinks: SymlinkFollow, Confine: ConfineSkip, Exclude: []string{"a"}}, "link/b/y.html link/skip.html link/x.html ln.html page.html vendor/v.html"},
+	}
+	for _, test := range tests {
+		if got := load(test.rules); got != test.exp {
+			t.Errorf("%+v: unexpected templates: %#v, expected: %#v", test.rules, got, test.exp)
+		}
+	}
+
+	l := NewTemplateLoader(root, ".html", nil)
+	l.SetRules(LoadRules{Confine: ConfineFail})
+	target, _ := filepath.EvalSymlinks(other)
+	if _, err := l.Load(); err != (SymlinkEscapeError{Name: "ext", Target: target}) {
+		t.Errorf("expected SymlinkEscapeError, got %v", err)
+	}
+
+	dangling := filepath.Join(dir, "missing.html")
+	if err := os.Symlink(dangling, filepath.Join(root, "dangling.html")); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := load(LoadRules{Confine: ConfineSkip, Exclude: []string{"a", "link"}}), "ln.html page.html vendor/v.html"; got != exp {
+		t.Errorf("unexpected templates with a dangling link: %#v, expected: %#v", got, exp)
+	}
+	if _, err := l.Load(); err != (SymlinkEscapeError{Name: "dangling.html", Target: dangling}) {
+		t.Errorf("expected SymlinkEscapeError for the dangling link, got %v", err)
+	}
+	if err := os.Remove(filepath.Join(root, "dangling.html")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Link(filepath.Join(other, "z.html"), filepath.Join(root, "a", "hard.html")); err != nil {
+		t.Fatal(err)
+	}
+	l.SetRules(LoadRules{RefuseHardLinks: true})
+	if _, err := l.Load(); err != HardLinkError(filepath.Join("a", "hard.html")) {
+		t.Errorf("expected HardLinkError, got %v", err)
+	}
+
+	if err := NewTemplateLoader(root, ".html", nil).SetRules(LoadRules{Exclude: []string{"a/["}}); err != InvalidPatternError("a/[") {
+		t.Errorf("expected InvalidPatternError, got %v", err)
+	}
+}
+
+func TestTemplateLoaderChecksOpenedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placesmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	os.MkdirAll(root, 0755)
+	outside := filepath.Join(dir, "secret.html")
+	ioutil.WriteFile(outside, []byte("secret"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "page.html"), []byte("page"), 0644)
+
+	// the page is replaced by a link to the outside between the walk and the read
+	read := func(confine ConfinePolicy) (*ReadSeekerMap, error) {
+		l := NewTemplateLoader(root, ".html", nil)
+		l.SetRules(LoadRules{Confine: confine})
+		l.realRoot, _ = filepath.EvalSymlinks(root)
+		l.ReadSeekerMap = NewReadSeekerMap()
+		if err := l.walkDir(root, "", nil); err != nil {
+			t.Fatal(err)
+		}
+		p := filepath.Join(root, "page.html")
+		os.Remove(p)
+		if err := os.Symlink(outside, p); err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			os.Remove(p)
+			ioutil.WriteFile(p, []byte("page"), 0644)
+		}()
+		return l.ReadSeekerMap, l.readFiles()
+	}
+
+	target, _ := filepath.EvalSymlinks(outside)
+	if _, err := read(ConfineFail); err != (SymlinkEscapeError{Name: "page.html", Target: target}) {
+		t.Errorf("expected SymlinkEscapeError, got %v", err)
+	}
+	rs, err := read(ConfineSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, has := rs.m["page.html"]; has {
+		t.Errorf("the replaced file must be skipped")
+	}
+	if rs, err = read(ConfineOff); err != nil || rs.Map("page.html") != "secret" {
+		t.Errorf("without confinement, the link is followed: %v", err)
+	}
+}
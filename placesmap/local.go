@@ -0,0 +1,46 @@
+package placesmap
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+)
+
+// InvalidLocalError is the error for "-set" with an invalid name
+type InvalidLocalError string
+
+func (i InvalidLocalError) Error() string {
+	return fmt.Sprintf("invalid name %#v for -set", string(i))
+}
+
+// local is the value of a local name, see set
+type local struct {
+	v interface{}
+}
+
+func (l local) Map(string) string { return formatCalc(l.v) }
+
+// Value returns the typed value, so that local is a TypedMapper
+func (l local) Value(string) interface{} { return l.v }
+
+// set handles "-set name expression", that binds the result of the expression to name
+// for the remainder of the render and renders nothing. The expression is evaluated like
+// the one of "-calc", e.g. "-set fullTitle title + ' - ' + site.name". Local names take
+// precedence over the registered mappers, but not over the current element of a loop.
+func (r *RenderContext) set(rest string) string {
+	name, expr := nextArg(rest)
+	if !validAttrName(name) {
+		r.fail(InvalidLocalError(name))
+		return ""
+	}
+	v, err := evalCalc(expr, r.typedValue)
+	if err != nil {
+		r.fail(err)
+		return ""
+	}
+	if r.locals == nil {
+		r.locals = map[string]places.Mapper{}
+	}
+	r.locals[name] = local{v}
+	delete(r.memo, name)
+	return ""
+}
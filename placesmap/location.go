@@ -0,0 +1,164 @@
+package placesmap
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is the position of a placeholder within the source of a template
+type Position struct {
+	Template string // the name of the template, for inline bodies the name of the enclosing template
+	Line     int    // the line, starting with 1, 0 if the position is unknown
+	Column   int    // the column in characters, starting with 1
+}
+
+// String returns the position as name:line:column
+func (p Position) String() string {
+	if p.Line == 0 {
+		return p.Template
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Template, p.Line, p.Column)
+}
+
+// sourcePositions returns the positions of the placeholders of t within src. t has been parsed from
+// tpl, whose placeholders are found at the given offsets within src[base:]. Placeholders that
+// have been inserted into t (e.g. "-nonce-attr") have no known position.
+func sourcePositions(name string, src []byte, base int, tpl []byte, offsets []int, t *places.Template) []Position {
+	var (
+		before = places.Find(tpl)
+		res    = make([]Position, 0, len(offsets))
+		j      int
+	)
+	for _, ph := range t.Placeholders() {
+		p := Position{Template: name}
+		if j*2 < len(before) && j < len(offsets) {
+			orig := string(tpl[before[j*2]+2 : before[j*2+1]])
+			if ph == orig || ph == "-html-name "+orig {
+				p.Line, p.Column = lineColumn(src, base+offsets[j])
+				j++
+			}
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+// lineColumn returns the line and column of the given offset within src
+func lineColumn(src []byte, offset int) (line, column int) {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	before := src[:offset]
+	start := strings.LastIndexByte(string(before), '\n') + 1
+	return strings.Count(string(before), "\n") + 1, utf8.RuneCount(before[start:]) + 1
+}
+
+// RenderError is an error of a render (see WithAllErrors) along with the placeholder that caused it
+type RenderError struct {
+	Position
+	Placeholder string // the placeholder of the template, that was rendered
+	Err         error
+}
+
+func (r RenderError) Error() string {
+	if r.Template == "" {
+		return r.Err.Error()
+	}
+	return fmt.Sprintf("%s: <@%s@>: %v", r.Position, r.Placeholder, r.Err)
+}
+
+// Unwrap returns the error of the prefix handler
+func (r RenderError) Unwrap() error {
+	return r.Err
+}
+
+// RenderErrors are all errors of a render, see WithAllErrors
+type RenderErrors []RenderError
+
+func (r RenderErrors) Error() string {
+	msgs := make([]string, len(r))
+	for i, e := range r {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// WithAllErrors returns a HTMLTemplateMapper with the same mappers, whose MapStrict returns all errors
+// of the render as RenderErrors instead of the first one, along with the positions of the
+// placeholders that caused them, so that all problems of the templates can be fixed in one pass.
+// Repeated errors of the same placeholder (e.g. within loops) are reported once.
+func (h *HTMLTemplateMapper) WithAllErrors() *HTMLTemplateMapper {
+	c := h.copy()
+	c.all = true
+	return c
+}
+
+// collect records the given error with the position of the current placeholder
+func (r *RenderContext) collect(err error) {
+	e := RenderError{Position: r.at.Position, Placeholder: r.at.placeholder, Err: err}
+	for _, o := range r.errs {
+		if o.Position == e.Position && o.Placeholder == e.Placeholder && o.Err.Error() == err.Error() {
+			return
+		}
+	}
+	r.errs = append(r.errs, e)
+}
+
+// location is a placeholder of a template that is being rendered
+type location struct {
+	Position
+	placeholder string
+}
+
+// locator keeps track of the placeholder of a template that is being rendered, see WithAllErrors
+type locator struct {
+	r         *RenderContext
+	m         places.Mapper
+	positions []Position
+	owner     string
+	n         int // the index of the next placeholder
+}
+
+func (l *locator) Map(input string) string {
+	prev := l.r.at
+	l.r.at = location{Position: Position{Template: l.owner}, placeholder: input}
+	if l.n < len(l.positions) {
+		l.r.at.Position = l.positions[l.n]
+	}
+	l.n++
+	res := l.m.Map(input)
+	l.r.at = prev
+	return res
+}
+
+// locate returns the mapper for rendering t with m, that keeps track of the placeholders,
+// if all errors are collected
+func (r *RenderContext) locate(t *places.Template, m places.Mapper) places.Mapper {
+	if !r.hm.all {
+		return m
+	}
+	l := &locator{r: r, m: m}
+	if len(r.includes) > 0 {
+		l.owner = blockOwner(r.includes[len(r.includes)-1])
+	}
+	for h := r.hm.HTMLTemplate; h != nil; {
+		if p, has := h.positions.Load(t); has {
+			l.positions = p.([]Position)
+			break
+		}
+		h.RLock()
+		fb := h.fallback
+		h.RUnlock()
+		h = fb
+	}
+	return l
+}
+
+// storePositions stores the positions of the placeholders of the given templates
+func (h *HTMLTemplate) storePositions(positions map[*places.Template][]Position) {
+	for t, p := range positions {
+		h.positions.Store(t, p)
+	}
+}
@@ -0,0 +1,86 @@
+package placesmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/metakeule/places"
+	"sync"
+	"testing"
+)
+
+func TestReplacedTemplatesAreForgotten(t *testing.T) {
+	tpl := newTemplate("app.js", "a", "page.html", "<@x@><@-if x@>b<@-end@>")
+	count := func(m *sync.Map) (n int) {
+		m.Range(func(interface{}, interface{}) bool {
+			n++
+			return true
+		})
+		return
+	}
+	touch := func(name string) {
+		t, _ := tpl.template(name)
+		tpl.version(t)
+		tpl.integrity(name, t)
+	}
+
+	for i := 0; i < 100; i++ {
+		tpl.Override("app.js", []byte(fmt.Sprint("o", i)))
+		touch("app.js")
+		tpl.Override("app.js", []byte(fmt.Sprint("p", i)))
+		touch("app.js")
+		tpl.RemoveOverride("app.js")
+
+		// like Refresh
+		tpl.Lock()
+		tpl.remove("page.html")
+		tpl.add("page.html", []byte(fmt.Sprint(i, "<@x@><@-if x@>b<@-end@>")))
+		tpl.Unlock()
+		touch("page.html")
+	}
+	touch("app.js")
+
+	// app.js, page.html and its inline body
+	if n := count(&tpl.positions); n != 3 {
+		t.Errorf("expected the positions of 3 templates, got %d", n)
+	}
+	if n := count(&tpl.versions); n != 2 {
+		t.Errorf("expected 2 versions, got %d", n)
+	}
+	if n := count(&tpl.sri); n != 1 {
+		t.Errorf("expected 1 integrity hash, got %d", n)
+	}
+}
+
+func TestWithAllErrors(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<h1>\n<@-require missing.html@>\n  <@-each items@><@-require gone.html@><@-end@><@-require row.html@>",
+		"row.html", "ä<@-each x@>",
+	)
+	m := map[string]places.Mapper{"items": list{keyed("a"), keyed("b")}}
+
+	if _, err := tpl.NewMapper(m).MapStrict("-require page.html"); err != TemplateNotFoundError("missing.html") {
+		t.Errorf("expected the first error, got %v", err)
+	}
+
+	_, err := tpl.NewMapper(m).WithAllErrors().MapStrict("-require page.html")
+	errs, ok := err.(RenderErrors)
+	if !ok {
+		t.Fatalf("expected RenderErrors, got %T %v", err, err)
+	}
+	exp := []string{
+		`page.html:2:1: <@-require missing.html@>: template "missing.html" not found`,
+		`page.html:3:18: <@-require gone.html@>: template "gone.html" not found`,
+		`row.html:1:2: <@-each x@>: directive "each" has no template`,
+	}
+	if len(errs) != len(exp) {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	for i, e := range exp {
+		if errs[i].Error() != e {
+			t.Errorf("unexpected error %d: %#v, expected: %#v", i, errs[i].Error(), e)
+		}
+	}
+	if !errors.Is(errs[0], TemplateNotFoundError("missing.html")) {
+		t.Errorf("expected RenderError to unwrap to TemplateNotFoundError")
+	}
+}
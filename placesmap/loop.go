@@ -0,0 +1,88 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+)
+
+// LoopScope is a loop that is currently rendered, e.g. by "-each"
+type LoopScope struct {
+	// Collection is the collection of the loop, nil for an Iterator
+	Collection NMapper
+
+	// Index is the index of the current element
+	Index int
+
+	// Element is the current element
+	Element places.Mapper
+}
+
+// Loop returns the loop at the given depth, the outermost loop having the depth 1.
+// A nested collection within a collection is a loop of its own.
+// If there is no loop at the given depth, ok is false.
+func (r *RenderContext) Loop(depth int) (LoopScope, bool) {
+	if depth < 1 || depth > len(r.loops) {
+		return LoopScope{}, false
+	}
+	return r.loops[depth-1], true
+}
+
+// LoopDepth returns the number of the currently rendered loops, 0 outside of loops
+func (r *RenderContext) LoopDepth() int {
+	return len(r.loops)
+}
+
+// RenderLoop renders the template with the given name for every element of nm like "-each",
+// so that custom directives can render loops. While an element is rendered, it is the current
+// scope (see Scope) and its loop is reported by Loop.
+func (r *RenderContext) RenderLoop(nm NMapper, name string) (res string) {
+	t, ok := r.lookup(name)
+	if !ok {
+		r.fail(TemplateNotFoundError(name))
+		return ""
+	}
+	if !r.push(name) {
+		return ""
+	}
+	defer func() { r.pop(len(res)) }()
+
+	var bf strings.Builder
+	r.loop(&bf, t, nm, "")
+	return bf.String()
+}
+
+// loop renders t for every element of nm. sub is the path within the collection that is passed
+// to NMap. Every nested collection consumes the first part of the path.
+// It returns false, if the path is deeper than the nested collections.
+func (r *RenderContext) loop(bf places.Buffer, t *places.Template, nm NMapper, sub string) bool {
+	r.loops = append(r.loops, LoopScope{Collection: nm})
+	level := len(r.loops) - 1
+	defer func() { r.loops = r.loops[:level] }()
+	for i, l := 0, nm.Len(); i < l; i++ {
+		m := nm.NMap(i, sub)
+		r.loops[level].Index, r.loops[level].Element = i, m
+		if !r.element(bf, t, m, sub) {
+			return false
+		}
+	}
+	return true
+}
+
+// element renders t for the element m of a loop, or loops over m, if it is a nested collection.
+// It returns false, if the path is deeper than the nested collections.
+func (r *RenderContext) element(bf places.Buffer, t *places.Template, m places.Mapper, sub string) bool {
+	if nm, isNM := m.(NMapper); isNM {
+		if idx := strings.IndexByte(sub, '.'); idx != -1 {
+			sub = sub[idx+1:]
+		} else {
+			sub = ""
+		}
+		return r.loop(bf, t, nm, sub)
+	}
+	if strings.ContainsRune(sub, '.') {
+		r.fail(TooDeepPathError{Path: sub})
+		return false
+	}
+	r.renderWith(bf, t, m)
+	return true
+}
@@ -0,0 +1,47 @@
+package placesmap
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+)
+
+func TestLoopScope(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@-each items row.html@>|<@-pos@>|<@-rows items@>|<@-each items.x row.html@>|<@-each items.x.y row.html@>",
+		"row.html", "<@name@><@-pos@>,",
+	)
+	tpl.Handle("pos", func(c *RenderContext, rest string) string {
+		var s []string
+		for d := 1; d <= c.LoopDepth(); d++ {
+			l, _ := c.Loop(d)
+			s = append(s, fmt.Sprintf("%d/%d", l.Index, l.Collection.Len()))
+		}
+		if _, ok := c.Loop(0); ok {
+			s = append(s, "loop 0")
+		}
+		if _, ok := c.Loop(c.LoopDepth() + 1); ok {
+			s = append(s, "loop too deep")
+		}
+		if l, ok := c.Loop(c.LoopDepth()); ok && l.Element.Map("name") != c.Scope().Map("name") {
+			s = append(s, "element is not the scope")
+		}
+		return strings.Join(s, " ")
+	})
+	tpl.Handle("rows", func(c *RenderContext, rest string) string {
+		mp, _ := c.Mapper(rest)
+		return c.RenderLoop(mp.(NMapper), "row.html")
+	})
+
+	items := list{list{keyed("a"), keyed("b")}, keyed("c")}
+	got, err := tpl.NewMapper(map[string]places.Mapper{"items": items}).MapStrict("-require page.html")
+
+	exp := "a0/2 0/2,b0/2 1/2,c1/2,||a0/2 0/2,b0/2 1/2,c1/2,|a0/2 0/2,b0/2 1/2,c1/2,|a0/2 0/2,b0/2 1/2,"
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if err != (TooDeepPathError{Path: "x.y"}) {
+		t.Errorf("expected TooDeepPathError for x.y, got %v", err)
+	}
+}
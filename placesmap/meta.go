@@ -0,0 +1,108 @@
+package placesmap
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// MetaKey is the name under which the MetaMapper of a page is registered
+const MetaKey = "meta"
+
+// PageMeta are the meta data of a page
+type PageMeta struct {
+	Title       string
+	Description string
+	Image       string // the URL of the preview image, relative URLs are resolved against Canonical
+	Canonical   string // the canonical URL of the page
+	Type        string // the og:type, "" means "website"
+}
+
+// MetaMapper renders the meta data of a page for the head via the directive
+//
+//	-meta
+//
+// or "-meta name", if it is not registered as MetaKey. It renders the <title>, the description,
+// the canonical link and the OpenGraph and Twitter tags; tags of empty fields are left out.
+type MetaMapper struct {
+	PageMeta
+	SiteName    string // the og:site_name, "" means none
+	TwitterSite string // the twitter:site, e.g. "@metakeule", "" means none
+}
+
+// Map returns the empty string, the MetaMapper is used via its directive
+func (m *MetaMapper) Map(string) string { return "" }
+
+// image returns the URL of the image, resolved against the canonical URL
+func (m *MetaMapper) image() string {
+	if m.Image == "" || m.Canonical == "" {
+		return m.Image
+	}
+	base, err := url.Parse(m.Canonical)
+	if err != nil {
+		return m.Image
+	}
+	img, err := url.Parse(m.Image)
+	if err != nil {
+		return m.Image
+	}
+	return base.ResolveReference(img).String()
+}
+
+// Tags returns the markup of the meta tags
+func (m *MetaMapper) Tags() string {
+	var bf bytes.Buffer
+	tag := func(attr, key, content string) {
+		if content != "" {
+			bf.WriteString(`<meta ` + attr + `="` + key + `" content="` + html.EscapeString(content) + `">`)
+		}
+	}
+
+	if m.Title != "" {
+		bf.WriteString("<title>" + html.EscapeString(m.Title) + "</title>")
+	}
+	tag("name", "description", m.Description)
+	if m.Canonical != "" {
+		bf.WriteString(`<link rel="canonical" href="` + html.EscapeString(m.Canonical) + `">`)
+	}
+
+	image := m.image()
+	typ := m.Type
+	if typ == "" {
+		typ = "website"
+	}
+	tag("property", "og:title", m.Title)
+	tag("property", "og:description", m.Description)
+	tag("property", "og:image", image)
+	tag("property", "og:url", m.Canonical)
+	tag("property", "og:type", typ)
+	tag("property", "og:site_name", m.SiteName)
+
+	card := "summary"
+	if image != "" {
+		card = "summary_large_image"
+	}
+	tag("name", "twitter:card", card)
+	tag("name", "twitter:title", m.Title)
+	tag("name", "twitter:description", m.Description)
+	tag("name", "twitter:image", image)
+	tag("name", "twitter:site", m.TwitterSite)
+	return bf.String()
+}
+
+// meta handles "-meta name"
+func (r *RenderContext) meta(rest string) string {
+	name := strings.TrimSpace(rest)
+	if name == "" {
+		name = MetaKey
+	}
+	mp, ok := r.mapper(name)
+	if !ok {
+		return ""
+	}
+	if m, is := mp.(*MetaMapper); is {
+		return m.Tags()
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestMeta(t *testing.T) {
+	tpl := newTemplate("page.html", "<head><@-meta@></head>", "other.html", "<@-meta page@>")
+	meta := &MetaMapper{
+		PageMeta: PageMeta{Title: `Tom & "Jerry"`, Description: "<b>cartoon</b>", Image: "/img/tom.jpg", Canonical: "https://example.com/shows/tom?id=1&x=2"},
+		SiteName: "Shows",
+	}
+	got := tpl.NewMapper(map[string]places.Mapper{MetaKey: meta}).Map("-require page.html")
+	exp := `<head><title>Tom &amp; &#34;Jerry&#34;</title><meta name="description" content="&lt;b&gt;cartoon&lt;/b&gt;">` +
+		`<link rel="canonical" href="https://example.com/shows/tom?id=1&amp;x=2">` +
+		`<meta property="og:title" content="Tom &amp; &#34;Jerry&#34;"><meta property="og:description" content="&lt;b&gt;cartoon&lt;/b&gt;">` +
+		`<meta property="og:image" content="https://example.com/img/tom.jpg"><meta property="og:url" content="https://example.com/shows/tom?id=1&amp;x=2">` +
+		`<meta property="og:type" content="website"><meta property="og:site_name" content="Shows">` +
+		`<meta name="twitter:card" content="summary_large_image"><meta name="twitter:title" content="Tom &amp; &#34;Jerry&#34;">` +
+		`<meta name="twitter:description" content="&lt;b&gt;cartoon&lt;/b&gt;"><meta name="twitter:image" content="https://example.com/img/tom.jpg"></head>`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	page := &MetaMapper{PageMeta: PageMeta{Title: "About", Type: "article"}}
+	got = tpl.NewMapper(map[string]places.Mapper{"page": page, MetaKey: String("x")}).Map("-require other.html")
+	exp = `<title>About</title><meta property="og:title" content="About"><meta property="og:type" content="article">` +
+		`<meta name="twitter:card" content="summary"><meta name="twitter:title" content="About">`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
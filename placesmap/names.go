@@ -0,0 +1,240 @@
+package placesmap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/metakeule/places"
+	"strings"
+)
+
+// UnsafeNameError is reported to MapStrict for values of placeholders in tag or attribute name
+// positions that are no valid names
+type UnsafeNameError string
+
+func (u UnsafeNameError) Error() string {
+	return fmt.Sprintf("%#v is no valid tag or attribute name", u)
+}
+
+// attrListDirectives render lists of attributes that are escaped by their handlers
+// and may be placed where attribute names are expected
+var attrListDirectives = map[string]bool{"attrs": true, "nonce-attr": true, "aria": true, "bool": true}
+
+// namePositions returns for every placeholder at the given places whether it is at the position
+// of a tag name or an attribute name, i.e. within a tag but not within an attribute value.
+func namePositions(tpl []byte, pos []int) []bool {
+	ctxs := placeholderContexts(tpl, pos)
+	res := make([]bool, len(ctxs))
+	for i, c := range ctxs {
+		res[i] = c == ctxName
+	}
+	return res
+}
+
+// htmlContext is the HTML context of a placeholder, see placeholderContexts
+type htmlContext int
+
+const (
+	ctxText     htmlContext = iota // text content
+	ctxName                        // a tag or attribute name
+	ctxQuoted                      // a quoted attribute value
+	ctxUnquoted                    // an unquoted attribute value
+	ctxComment                     // a comment
+	ctxRawText                     // the content of a script or style element
+)
+
+// placeholderContexts returns the HTML context of every placeholder at the given places
+func placeholderContexts(tpl []byte, pos []int) []htmlContext {
+	const (
+		text = iota
+		tagOpen
+		tagName
+		inTag
+		attrName
+		afterAttrName
+		beforeValue
+		quoted
+		unquoted
+		comment
+		rawText
+	)
+
+	var (
+		state = text
+		quote byte
+		name  []byte // the name of the current tag
+		raw   string // the name of the current raw text element (script or style)
+		res   = make([]htmlContext, len(pos)/2)
+		next  = 0 // the index of the next placeholder within pos
+	)
+
+	for i := 0; i < len(tpl); i++ {
+		if next < len(pos) && i == pos[next] {
+			// a placeholder: the state before it decides, the state after it is kept as far as possible
+			switch state {
+			case quoted:
+				res[next/2] = ctxQuoted
+			case unquoted:
+				res[next/2] = ctxUnquoted
+			case comment:
+				res[next/2] = ctxComment
+			case rawText:
+				res[next/2] = ctxRawText
+			case tagOpen, tagName, inTag, attrName, afterAttrName:
+				res[next/2] = ctxName
+				prefix, _ := split(string(tpl[pos[next]+2 : pos[next+1]]))
+				switch {
+				case state == tagOpen:
+					state, name = tagName, nil
+				case attrListDirectives[prefix]:
+					state = inTag
+				case state != tagName:
+					state = attrName
+				}
+			case beforeValue:
+				res[next/2], state = ctxUnquoted, unquoted
+			}
+			i = pos[next+1] + 1
+			next += 2
+			continue
+		}
+
+		c := tpl[i]
+		space := c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+		switch state {
+		case text:
+			switch {
+			case bytes.HasPrefix(tpl[i:], []byte("<!--")):
+				state = comment
+			case c == '<':
+				state = tagOpen
+			}
+		case tagOpen:
+			switch {
+			case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '/':
+				state, name = tagName, []byte{c}
+			default:
+				state = text
+			}
+		case tagName:
+			switch {
+			case space:
+				state = inTag
+			case c == '>':
+				state = text
+			default:
+				name = append(name, c)
+			}
+		case inTag, afterAttrName:
+			switch {
+			case space, c == '/':
+			case c == '=' && state == afterAttrName:
+				state = beforeValue
+			case c == '>':
+				state = text
+			default:
+				state = attrName
+			}
+		case attrName:
+			switch {
+			case space:
+				state = afterAttrName
+			case c == '=':
+				state = beforeValue
+			case c == '>':
+				state = text
+			}
+		case beforeValue:
+			switch {
+			case space:
+			case c == '"' || c == '\'':
+				state, quote = quoted, c
+			case c == '>':
+				state = text
+			default:
+				state = unquoted
+			}
+		case quoted:
+			if c == quote {
+				state = inTag
+			}
+		case unquoted:
+			switch {
+			case space:
+				state = inTag
+			case c == '>':
+				state = text
+			}
+		case comment:
+			if bytes.HasPrefix(tpl[i:], []byte("-->")) {
+				state = text
+				i += 2
+			}
+		case rawText:
+			if end := "</" + raw; c == '<' && len(tpl)-i >= len(end) && bytes.EqualFold(tpl[i:i+len(end)], []byte(end)) {
+				state = tagOpen
+			}
+		}
+
+		if c == '>' && state == text && name != nil {
+			if n := strings.ToLower(string(name)); n == "script" || n == "style" {
+				state, raw = rawText, n
+			}
+			name = nil
+		}
+	}
+	return res
+}
+
+// guardNames wraps the placeholders at tag and attribute name positions (except for directives that
+// render attribute lists, like -attrs) into the "-html-name" directive, that only renders valid names
+func guardNames(tpl []byte) []byte {
+	pos := places.Find(tpl)
+	names := namePositions(tpl, pos)
+
+	var (
+		bf   bytes.Buffer
+		last int
+	)
+	for i, isName := range names {
+		start, end := pos[i*2], pos[i*2+1]
+		placeholder := string(tpl[start+2 : end])
+		if prefix, _ := split(placeholder); !isName || attrListDirectives[prefix] {
+			continue
+		}
+		bf.Write(tpl[last:start])
+		bf.WriteString("<@-html-name " + placeholder + "@>")
+		last = end + 2
+	}
+	if last == 0 {
+		return tpl
+	}
+	bf.Write(tpl[last:])
+	return bf.Bytes()
+}
+
+// hasNamePlaceholders returns whether the template has placeholders at tag or attribute name
+// positions, that are not attribute list directives
+func hasNamePlaceholders(tpl []byte) bool {
+	pos := places.Find(tpl)
+	for i, isName := range namePositions(tpl, pos) {
+		if prefix, _ := split(string(tpl[pos[i*2]+2 : pos[i*2+1]])); isName && !attrListDirectives[prefix] {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlName handles "-html-name placeholder", that guards placeholders at tag and attribute name
+// positions (see guardNames). It renders the placeholder, if its value consists only of letters, digits
+// and the characters _ : . - (since it may continue a name, it may start with a digit, e.g. for h<@level@>).
+func (r *RenderContext) htmlName(rest string) string {
+	val := r.Map(rest)
+	if val == "" {
+		return ""
+	}
+	if !validAttrName("x" + val) {
+		r.fail(UnsafeNameError(val))
+		return ""
+	}
+	return val
+}
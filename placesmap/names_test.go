@@ -0,0 +1,56 @@
+package placesmap
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+)
+
+func TestNamePositions(t *testing.T) {
+	tests := map[string]string{
+		`<p><@a@></p>`:                                 "[false]",
+		`<<@a@>>x</<@a@>>`:                             "[true true]",
+		`<h<@a@> class="x">`:                           "[true]",
+		`<p <@a@>="1" x=<@b@> y="<@c@>" <@d@>>`:        "[true false false true]",
+		`<p <@-attrs a@> <@b@>>`:                       "[true true]",
+		`<!-- <p <@a@>> --><@b@>`:                      "[false false]",
+		`<script>if (a<b) {<@a@>}</script><p <@b@>>`:   "[false true]",
+		`<input value='<@a@>' <@b@>=x disabled <@c@>>`: "[false true true]",
+		`a < <@a@>`:                                    "[false]",
+		`<STYLE>a<b</Style><p <@a@>>`:                  "[true]",
+		`<script>a<b</scrip<@a@>`:                      "[false]",
+		`<script><@a@></script`:                        "[false]",
+	}
+
+	for tpl, exp := range tests {
+		if got := fmt.Sprint(namePositions([]byte(tpl), places.Find([]byte(tpl)))); got != exp {
+			t.Errorf("%#v: unexpected result: %s, expected: %s", tpl, got, exp)
+		}
+	}
+}
+
+func TestGuardNames(t *testing.T) {
+	tpl := newTemplate("page.html", `<h<@level@> <@attr@>="1" <@-attrs attrs@>><@attr@></h<@level@>>`)
+	m := map[string]places.Mapper{"attrs": AttrMapper{"id": "x"}, "level": String("1")}
+
+	m["attr"] = String("data-x")
+	got, err := tpl.NewMapper(m).MapStrict("-require page.html")
+	if exp := `<h1 data-x="1"  id="x">data-x</h1>`; got != exp || err != nil {
+		t.Errorf("unexpected result: %#v, %v, expected: %#v", got, err, exp)
+	}
+
+	m["attr"] = String(`onclick=alert(1) x`)
+	got, err = tpl.NewMapper(m).MapStrict("-require page.html")
+	if exp := `<h1 ="1"  id="x">onclick=alert(1) x</h1>`; got != exp || err != UnsafeNameError("onclick=alert(1) x") {
+		t.Errorf("unexpected result: %#v, %v, expected: %#v", got, err, exp)
+	}
+
+	rs := NewReadSeekerMap()
+	rs.Add("page.html", strings.NewReader(`<p <@attr@>>`))
+	rs.Add("ok.html", strings.NewReader(`<p <@-attrs attrs@> class="<@class@>">`))
+	_, report, _ := NewHTMLTemplateValidated(rs, Validation{StrictNames: true})
+	if len(report.Skipped) != 1 || report.Skipped[0].Name != "page.html" {
+		t.Errorf("unexpected skipped templates: %#v", report.Skipped)
+	}
+}
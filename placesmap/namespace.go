@@ -0,0 +1,103 @@
+package placesmap
+
+import (
+	"context"
+	"github.com/metakeule/places"
+	"strings"
+)
+
+// Namespace groups mappers under a common name. Registered as "shop", the mapper registered
+// as "count" within the Namespace "cart" of the Namespace is found for "shop.cart.count".
+// Namespaces may be nested; the mappers are called with the full name (e.g. "shop.cart.count").
+// Mappers that are registered with dotted names take precedence over namespaces.
+type Namespace map[string]places.Mapper
+
+// Map returns the empty string, the Namespace is a container for mappers
+func (n Namespace) Map(string) string { return "" }
+
+// Lookup returns the mapper for the given name within the Namespace
+func (n Namespace) Lookup(name string) (places.Mapper, bool) {
+	return lookupName(n, name)
+}
+
+// NamespaceMapper is a mapper that provides the mappers for the names within the namespace
+// it is registered for, like Namespace. name is the name without the namespace.
+type NamespaceMapper interface {
+	places.Mapper
+	Lookup(name string) (places.Mapper, bool)
+}
+
+// SubMapper is a mapper for nested data. Registered as "user", the name "user.address.city"
+// is resolved by Sub("address") of the SubMapper and mapped by the returned mapper via Map("city").
+// All mappers on the path except the last must be SubMappers.
+type SubMapper interface {
+	places.Mapper
+	Sub(key string) (places.Mapper, bool)
+}
+
+// subKey maps the last key of a path of SubMappers, whatever name it is called with
+type subKey struct {
+	mp  places.Mapper
+	key string
+}
+
+func (s subKey) Map(string) string { return s.mp.Map(s.key) }
+
+func (s subKey) MapContext(ctx context.Context, _ string) string {
+	if cm, is := s.mp.(ContextMapper); is {
+		return cm.MapContext(ctx, s.key)
+	}
+	return s.mp.Map(s.key)
+}
+
+func (s subKey) Value(string) interface{} {
+	if tm, is := s.mp.(TypedMapper); is {
+		return tm.Value(s.key)
+	}
+	return s.mp.Map(s.key)
+}
+
+// walk resolves the given path within the SubMapper
+func walk(sm SubMapper, path string) (places.Mapper, bool) {
+	keys := strings.Split(path, ".")
+	var mp places.Mapper = sm
+	for _, k := range keys[:len(keys)-1] {
+		s, is := mp.(SubMapper)
+		if !is {
+			return nil, false
+		}
+		if mp, is = s.Sub(k); !is {
+			return nil, false
+		}
+	}
+	return subKey{mp, keys[len(keys)-1]}, true
+}
+
+// lookupName returns the mapper for the given name within m. For dotted names, the
+// namespaces and SubMappers are consulted, the longest first.
+func lookupName(m map[string]places.Mapper, name string) (places.Mapper, bool) {
+	if mp, has := m[name]; has {
+		return mp, true
+	}
+	for idx := strings.LastIndexByte(name, '.'); idx > 0; idx = strings.LastIndexByte(name[:idx], '.') {
+		if ns, is := m[name[:idx]].(NamespaceMapper); is {
+			if mp, has := ns.Lookup(name[idx+1:]); has {
+				return mp, true
+			}
+		}
+		if sm, is := m[name[:idx]].(SubMapper); is {
+			return walk(sm, name[idx+1:])
+		}
+	}
+	return nil, false
+}
+
+// WithFallbacks returns a HTMLTemplateMapper with the same mappers that consults the given
+// maps in order for names that are not registered, e.g. the mappers of the session and the
+// defaults of the app as fallbacks for the mappers of the request. The fallbacks must not be
+// changed while they are in use.
+func (h *HTMLTemplateMapper) WithFallbacks(fallbacks ...map[string]places.Mapper) *HTMLTemplateMapper {
+	c := h.copy()
+	c.fallbacks = append(append([]map[string]places.Mapper(nil), h.fallbacks...), fallbacks...)
+	return c
+}
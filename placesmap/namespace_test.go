@@ -0,0 +1,48 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestNamespace(t *testing.T) {
+	tpl := newTemplate("page.html", "<@shop.cart.count@>|<@shop.title@>|<@user.name@>|<@site.name@>|<@-each shop.items row.html@>", "row.html", "<@name@>,")
+
+	request := map[string]places.Mapper{
+		"shop": Namespace{
+			"cart":  Namespace{"count": String("3")},
+			"title": String("Shop"),
+			"items": list{keyed("a"), keyed("b")},
+		},
+		"shop.title": String("Dotted"),
+	}
+	session := map[string]places.Mapper{"user": Namespace{"name": String("<Tom>")}, "site.name": String("session")}
+	defaults := map[string]places.Mapper{"site.name": String("default"), "user.name": String("nobody")}
+
+	got := tpl.NewMapper(request).WithFallbacks(session, defaults).Map("-require page.html")
+	if exp := "3|Dotted|&lt;Tom&gt;|session|a,b,"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	got = tpl.NewMapper(request).WithFallbacks(defaults).Map("-require page.html")
+	if exp := "3|Dotted|nobody|default|a,b,"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
+
+func TestSubMapper(t *testing.T) {
+	tpl := newTemplate("page.html", "<@user.address.city@>|<@user.name@>|<@user.address.zip.code@>|<@user.tags.a@>|<@-calc user.age + 1@>|<@user.address@>|<@user.missing.x@>")
+	user := Nested{
+		"name": "<Tom>",
+		"age":  41,
+		"address": map[string]interface{}{
+			"city": "Berlin & Co",
+		},
+		"tags": map[string]string{"a": "b"},
+	}
+
+	got := tpl.NewMapper(map[string]places.Mapper{"user": user, "user.name": String("dotted")}).Map("-require page.html")
+	if exp := "Berlin &amp; Co|dotted||b|42||"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,241 @@
+/*
+package nav provides mappers for breadcrumbs, menus and prev/next links of a site.
+
+The site tree is defined once, in code or as YAML (see Parse), and consists of named menus.
+For every request the mappers are created for the current path via Site.Mappers:
+
+	nav.title, nav.path                 the current item
+	nav.prev.title, nav.prev.path       the previous item of the menu of the current item
+	nav.next.title, nav.next.path       the next item
+	breadcrumbs                         the items from the top level to the current item, e.g. for "-each"
+	nav                                 the menus for the "-nav" directive (see Register)
+
+Within loops and menus every item maps "title", "path", "active" (the item is the current one
+or one of its ancestors) and "current" (the item is the current one), "active" and "current" being
+"true" or the empty string.
+*/
+package nav
+
+import (
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"strings"
+)
+
+// Item is an entry of a menu
+type Item struct {
+	Title    string
+	Path     string
+	Children []*Item
+}
+
+// Site is a set of named menus, e.g. "main" and "footer"
+type Site struct {
+	menus map[string][]*Item
+	order []string // the names of the menus in the order they were added
+}
+
+// New returns an empty Site
+func New() *Site {
+	return &Site{menus: map[string][]*Item{}}
+}
+
+// Add adds the given items to the menu with the given name
+func (s *Site) Add(menu string, items ...*Item) {
+	if _, has := s.menus[menu]; !has {
+		s.order = append(s.order, menu)
+	}
+	s.menus[menu] = append(s.menus[menu], items...)
+}
+
+// Menu returns the items of the menu with the given name
+func (s *Site) Menu(name string) []*Item {
+	return s.menus[name]
+}
+
+// cleanPath removes trailing slashes, except for the root
+func cleanPath(p string) string {
+	if p == "/" {
+		return p
+	}
+	return strings.TrimRight(p, "/")
+}
+
+// trail returns the items from the top level to the item with the given path
+func trail(items []*Item, path string) []*Item {
+	for _, it := range items {
+		if cleanPath(it.Path) == path {
+			return []*Item{it}
+		}
+		if t := trail(it.Children, path); t != nil {
+			return append([]*Item{it}, t...)
+		}
+	}
+	return nil
+}
+
+// flatten returns the items in depth first order
+func flatten(items []*Item) (res []*Item) {
+	for _, it := range items {
+		res = append(res, it)
+		res = append(res, flatten(it.Children)...)
+	}
+	return
+}
+
+// Current is the navigation state for a request path
+type Current struct {
+	site   *Site
+	path   string
+	trail  []*Item // the current item and its ancestors, top level first
+	active map[*Item]bool
+}
+
+// Current returns the navigation state for the given request path. The current item is
+// the first item with the path within the menus, in the order they were added.
+func (s *Site) Current(path string) *Current {
+	c := &Current{site: s, path: cleanPath(path), active: map[*Item]bool{}}
+	for _, name := range s.order {
+		if c.trail = trail(s.menus[name], c.path); c.trail != nil {
+			break
+		}
+	}
+	for _, it := range c.trail {
+		c.active[it] = true
+	}
+	return c
+}
+
+// Item returns the current item, nil if the path is not within the site
+func (c *Current) Item() *Item {
+	if len(c.trail) == 0 {
+		return nil
+	}
+	return c.trail[len(c.trail)-1]
+}
+
+// Map returns the empty string, the Current is a container for the menus, see Menu
+func (c *Current) Map(string) string { return "" }
+
+// Menu returns the items of the menu with the given name, for "-each" and "-tree"
+func (c *Current) Menu(name string) placesmap.TreeNMapper {
+	return items{c, c.site.menus[name]}
+}
+
+// Breadcrumbs returns the items from the top level to the current item
+func (c *Current) Breadcrumbs() placesmap.NMapper {
+	return items{c, c.trail}
+}
+
+// neighbours returns the previous and next item of the current item within its menu
+func (c *Current) neighbours() (prev, next *Item) {
+	cur := c.Item()
+	if cur == nil {
+		return
+	}
+	for _, name := range c.site.order {
+		all := flatten(c.site.menus[name])
+		for i, it := range all {
+			if it != cur {
+				continue
+			}
+			if i > 0 {
+				prev = all[i-1]
+			}
+			if i < len(all)-1 {
+				next = all[i+1]
+			}
+			return
+		}
+	}
+	return
+}
+
+// Mappers returns the mappers for the given request path, see the package documentation
+func (s *Site) Mappers(path string) map[string]places.Mapper {
+	c := s.Current(path)
+	m := map[string]places.Mapper{
+		"nav":         c,
+		"breadcrumbs": c.Breadcrumbs(),
+	}
+
+	set := func(prefix string, it *Item) {
+		if it == nil {
+			it = &Item{}
+		}
+		m[prefix+"title"] = placesmap.String(it.Title)
+		m[prefix+"path"] = placesmap.String(it.Path)
+	}
+
+	set("nav.", c.Item())
+	prev, next := c.neighbours()
+	set("nav.prev.", prev)
+	set("nav.next.", next)
+	return m
+}
+
+// items is a list of items, rendered for the current path
+type items struct {
+	c     *Current
+	items []*Item
+}
+
+func (i items) Map(string) string { return "" }
+func (i items) Len() int          { return len(i.items) }
+
+func (i items) NMap(n int, _ string) places.Mapper {
+	return item{i.c, i.items[n]}
+}
+
+func (i items) Children(n int) placesmap.NMapper {
+	if len(i.items[n].Children) == 0 {
+		return nil
+	}
+	return items{i.c, i.items[n].Children}
+}
+
+// item maps the properties of an item
+type item struct {
+	c  *Current
+	it *Item
+}
+
+func (i item) Map(key string) string {
+	switch key {
+	case "title":
+		return i.it.Title
+	case "path":
+		return i.it.Path
+	case "active":
+		if i.c.active[i.it] {
+			return "true"
+		}
+	case "current":
+		if i.c.Item() == i.it {
+			return "true"
+		}
+	}
+	return ""
+}
+
+// Register registers the directive "-nav menu node.html" for the given HTMLTemplate.
+// It renders node.html for the items of the menu like "-tree", so that "-children"
+// renders the submenu of an item. The menus are taken from the mapper registered as "nav"
+// (see Site.Mappers).
+func Register(t *placesmap.HTMLTemplate) error {
+	return t.Handle("nav", func(c *placesmap.RenderContext, rest string) string {
+		args := strings.Fields(rest)
+		if len(args) != 2 {
+			return ""
+		}
+		mp, ok := c.Mapper("nav")
+		if !ok {
+			return ""
+		}
+		cur, is := mp.(*Current)
+		if !is {
+			return ""
+		}
+		return c.RenderTree(cur.Menu(args[0]), args[1])
+	})
+}
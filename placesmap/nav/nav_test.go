@@ -0,0 +1,78 @@
+package nav
+
+import (
+	"github.com/metakeule/places/placesmap"
+	"strings"
+	"testing"
+)
+
+var siteYAML = `
+# the main menu
+main:
+  - title: Home
+    path: /
+  - title: Docs
+    path: /docs
+    children:
+      - title: Intro
+        path: /docs/intro
+      - title: "API & more"
+        path: /docs/api
+  - title: Blog
+    path: /blog
+footer:
+  - title: Imprint
+    path: /imprint
+`
+
+func newTemplate(pairs ...string) *placesmap.HTMLTemplate {
+	rs := placesmap.NewReadSeekerMap()
+	for i := 0; i < len(pairs); i += 2 {
+		rs.Add(pairs[i], strings.NewReader(pairs[i+1]))
+	}
+	return placesmap.NewHTMLTemplate(rs)
+}
+
+func TestNav(t *testing.T) {
+	site, err := Parse([]byte(siteYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := newTemplate(
+		"page.html", `<@-nav main menu.html@>|<@-each breadcrumbs crumb.html@>|<@nav.prev.path@> <@nav.next.path@>`,
+		"menu.html", `<a class="<@-if active active.html@>" href="<@path@>"><@title@></a>(<@-children@>)`,
+		"active.html", `active`,
+		"crumb.html", `/<@title@>`,
+	)
+	if err := Register(tpl); err != nil {
+		t.Fatal(err)
+	}
+
+	got := tpl.NewMapper(site.Mappers("/docs/intro/")).Map("-require page.html")
+	exp := `<a class="" href="/">Home</a>()` +
+		`<a class="active" href="/docs">Docs</a>(<a class="active" href="/docs/intro">Intro</a>()<a class="" href="/docs/api">API &amp; more</a>())` +
+		`<a class="" href="/blog">Blog</a>()` +
+		`|/Docs/Intro|/docs /docs/api`
+	if got != exp {
+		t.Errorf("unexpected result:\n%s\nexpected:\n%s", got, exp)
+	}
+
+	// unknown paths have no active items
+	got = tpl.NewMapper(site.Mappers("/unknown")).Map("-each breadcrumbs crumb.html")
+	if got != "" {
+		t.Errorf("unexpected breadcrumbs: %#v", got)
+	}
+
+	if cur := site.Current("/imprint").Item(); cur == nil || cur.Title != "Imprint" {
+		t.Errorf("unexpected current item: %#v", cur)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, src := range []string{"main:\n  - titel: x", "  main:", "main:\n  - title: x\n\tpath: y", "main:\n  - title: x\n      path: y"} {
+		if _, err := Parse([]byte(src)); err == nil {
+			t.Errorf("expected error for %#v", src)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package nav
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type ParseError struct {
+	Line   int
+	Reason string
+}
+
+func (p ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Reason)
+}
+
+// line is a non empty line of the YAML source
+type line struct {
+	no     int
+	indent int
+	dash   bool // the line starts a list item
+	key    string
+	value  string
+}
+
+// Parse parses a site tree from a subset of YAML. The top level keys are the names of the menus,
+// every menu is a list of items with the keys title, path and children, e.g.
+//
+//	main:
+//	  - title: Home
+//	    path: /
+//	  - title: Docs
+//	    path: /docs
+//	    children:
+//	      - title: Intro
+//	        path: /docs/intro
+func Parse(src []byte) (*Site, error) {
+	lines, err := splitLines(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	s := New()
+	for i := 0; i < len(lines); {
+		l := lines[i]
+		if l.indent != 0 || l.dash || l.value != "" {
+			return nil, ParseError{l.no, "expected the name of a menu"}
+		}
+		i++
+		if i == len(lines) || !lines[i].dash {
+			s.Add(l.key)
+			continue
+		}
+		var its []*Item
+		its, i, err = parseItems(lines, i, lines[i].indent)
+		if err != nil {
+			return nil, err
+		}
+		s.Add(l.key, its...)
+	}
+	return s, nil
+}
+
+// parseItems parses the list items at the given indentation, starting with lines[i]
+func parseItems(lines []line, i int, indent int) (its []*Item, next int, err error) {
+	for i < len(lines) && lines[i].indent == indent && lines[i].dash {
+		it := &Item{}
+		// the keys of an item are aligned with the first key after the dash
+		keyIndent := -1
+		first := true
+		for i < len(lines) {
+			l := lines[i]
+			if !first && (l.dash || l.indent != keyIndent) {
+				break
+			}
+			if first {
+				keyIndent = l.indent + 2
+				first = false
+			}
+			i++
+
+			switch l.key {
+			case "title":
+				it.Title = l.value
+			case "path":
+				it.Path = l.value
+			case "children":
+				if l.value != "" {
+					return nil, 0, ParseError{l.no, "children must be a list"}
+				}
+				if i < len(lines) && lines[i].dash && lines[i].indent >= keyIndent {
+					it.Children, i, err = parseItems(lines, i, lines[i].indent)
+					if err != nil {
+						return nil, 0, err
+					}
+				}
+			default:
+				return nil, 0, ParseError{l.no, fmt.Sprintf("unknown key %#v", l.key)}
+			}
+		}
+		its = append(its, it)
+	}
+
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, 0, ParseError{lines[i].no, "unexpected indentation"}
+	}
+	return its, i, nil
+}
+
+// splitLines returns the non empty lines of the source without comments
+func splitLines(src string) (res []line, err error) {
+	for no, s := range strings.Split(src, "\n") {
+		s = strings.TrimRight(s, " \t\r")
+		if t := strings.TrimSpace(s); t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+		if strings.ContainsRune(s, '\t') {
+			return nil, ParseError{no + 1, "tabs are not allowed"}
+		}
+
+		l := line{no: no + 1}
+		l.indent = len(s) - len(strings.TrimLeft(s, " "))
+		s = s[l.indent:]
+		if strings.HasPrefix(s, "- ") {
+			l.dash = true
+			s = strings.TrimLeft(s[2:], " ")
+		}
+
+		idx := strings.Index(s, ":")
+		if idx == -1 {
+			return nil, ParseError{l.no, "expected key: value"}
+		}
+		l.key = strings.TrimSpace(s[:idx])
+		l.value = unquote(strings.TrimSpace(s[idx+1:]))
+		res = append(res, l)
+	}
+	return
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		if s[0] == '"' {
+			if u, err := strconv.Unquote(s); err == nil {
+				return u
+			}
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
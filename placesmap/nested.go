@@ -0,0 +1,45 @@
+package placesmap
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+)
+
+// Nested is a SubMapper for nested data like decoded JSON. Registered as "user",
+// "user.address.city" maps the value of the key city within the map of the key address.
+// Maps of type map[string]interface{} and map[string]string are sub mappers, all other
+// values are formatted via fmt.Sprint, nil and maps map to the empty string.
+type Nested map[string]interface{}
+
+func (n Nested) Map(key string) string {
+	switch v := n[key].(type) {
+	case nil, map[string]interface{}, map[string]string, Nested:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Value returns the value for the given key, so that Nested is a TypedMapper
+func (n Nested) Value(key string) interface{} {
+	return n[key]
+}
+
+// Sub returns the mapper for the map of the given key
+func (n Nested) Sub(key string) (places.Mapper, bool) {
+	switch v := n[key].(type) {
+	case Nested:
+		return v, true
+	case map[string]interface{}:
+		return Nested(v), true
+	case map[string]string:
+		m := make(Nested, len(v))
+		for k, s := range v {
+			m[k] = s
+		}
+		return m, true
+	}
+	return nil, false
+}
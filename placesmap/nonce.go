@@ -0,0 +1,154 @@
+package placesmap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/metakeule/places"
+	"html"
+	"strings"
+)
+
+// NonceMapper provides the nonce of a request for a Content-Security-Policy.
+// It maps every key to the nonce.
+type NonceMapper struct {
+	nonce string
+}
+
+// NewNonceMapper returns a NonceMapper with a new random nonce
+func NewNonceMapper() (*NonceMapper, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &NonceMapper{nonce: base64.StdEncoding.EncodeToString(b)}, nil
+}
+
+// Map returns the nonce
+func (n *NonceMapper) Map(string) string {
+	return n.nonce
+}
+
+// Source returns the nonce as source expression for a Content-Security-Policy, i.e. 'nonce-...'
+func (n *NonceMapper) Source() string {
+	return "'nonce-" + n.nonce + "'"
+}
+
+// WithNonce returns a HTMLTemplateMapper with the same mappers that renders the nonce of n
+// for "-nonce" and adds it to all script and style tags of the templates
+func (h *HTMLTemplateMapper) WithNonce(n *NonceMapper) *HTMLTemplateMapper {
+	c := h.copy()
+	c.nonce = n
+	return c
+}
+
+// SetCSP sets the Content-Security-Policy that is sent by the render helpers (Render, Negotiate).
+// For every response a new nonce is generated, "{nonce}" within the policy is replaced by
+// its source expression and the nonce is added to the script and style tags.
+// The empty string disables the header.
+func (h *HTMLTemplate) SetCSP(policy string) {
+	h.Lock()
+	h.csp = policy
+	h.Unlock()
+}
+
+// cspMapper returns the mapper and the Content-Security-Policy header for a response
+func (h *HTMLTemplate) cspMapper(m map[string]places.Mapper) (*HTMLTemplateMapper, string, error) {
+	h.RLock()
+	policy, options := h.csp, h.options
+	h.RUnlock()
+
+	hm := h.NewMapper(m)
+	if policy == "" {
+		return hm, "", nil
+	}
+
+	n := options.nonce()
+	if !options.Deterministic {
+		var err error
+		if n, err = NewNonceMapper(); err != nil {
+			return nil, "", err
+		}
+	}
+	return hm.WithNonce(n), strings.Replace(policy, "{nonce}", n.Source(), -1), nil
+}
+
+// nonceAttr handles "-nonce-attr", that is inserted into script and style tags (see injectNonce)
+func (r *RenderContext) nonceAttr() string {
+	if r.caching > 0 {
+		return r.fragments.mark + "a"
+	}
+	if r.hm.nonce == nil {
+		return ""
+	}
+	return ` nonce="` + html.EscapeString(r.hm.nonce.nonce) + `"`
+}
+
+// nonceValue handles "-nonce". Renders for the fragment cache return the markers of the cache
+// instead of the nonce, see RenderContext.serveFragment.
+func (r *RenderContext) nonceValue() string {
+	if r.caching > 0 {
+		return r.fragments.mark + "v"
+	}
+	if r.hm.nonce == nil {
+		return ""
+	}
+	return html.EscapeString(r.hm.nonce.nonce)
+}
+
+// nonceTags are the tags that get the nonce attribute
+var nonceTags = [][]byte{[]byte("<script"), []byte("<style")}
+
+// injectNonce inserts the "-nonce-attr" placeholder into the script and style tags
+// of the given template that have no nonce attribute. Placeholders are left untouched,
+// so that tags within mapper values never get a nonce.
+func injectNonce(tpl []byte) []byte {
+	lower := bytes.ToLower(tpl)
+	if !bytes.Contains(lower, nonceTags[0]) && !bytes.Contains(lower, nonceTags[1]) {
+		return tpl
+	}
+
+	pos := places.Find(tpl)
+	var bf bytes.Buffer
+	last := 0
+	for i := 0; i <= len(pos); i += 2 {
+		end := len(tpl)
+		if i < len(pos) {
+			end = pos[i]
+		}
+		insertNonce(&bf, tpl[last:end], lower[last:], "<@-nonce-attr@>")
+		if i < len(pos) {
+			bf.Write(tpl[pos[i] : pos[i+1]+2])
+			last = pos[i+1] + 2
+		}
+	}
+	return bf.Bytes()
+}
+
+// insertNonce writes text with the given nonce attribute (or its placeholder) inserted into the
+// script and style tags. lower is the lower cased rest of the template, starting with text, since
+// tags may continue after the text (e.g. nonce="<@-nonce@>").
+func insertNonce(bf *bytes.Buffer, text, lower []byte, attr string) {
+	for i := 0; i < len(text); i++ {
+		bf.WriteByte(text[i])
+		if text[i] != '<' {
+			continue
+		}
+		for _, tag := range nonceTags {
+			end := i + len(tag)
+			if !bytes.HasPrefix(lower[i:], tag) || end >= len(text) {
+				continue
+			}
+			if c := text[end]; c != '>' && c != '/' && c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				continue
+			}
+			if close := bytes.IndexByte(lower[end:], '>'); close != -1 && bytes.Contains(lower[end:end+close], []byte("nonce=")) {
+				continue
+			}
+			bf.Write(text[i+1 : end])
+			bf.WriteString(attr)
+			i = end - 1
+			break
+		}
+	}
+}
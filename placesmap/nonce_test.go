@@ -0,0 +1,38 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderCSP(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", `<SCRIPT src="a.js"></SCRIPT><style>p{}</style><script nonce="<@-nonce@>"></script><@-raw user@><@-require part.html@>`,
+		"part.html", "<script>\n</script><scripts>",
+	)
+	tpl.SetCSP("script-src {nonce}; style-src {nonce}")
+
+	rec := httptest.NewRecorder()
+	if err := Render(rec, tpl, "page.html", map[string]places.Mapper{"user": String("<script>x</script>")}); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := rec.Header().Get("Content-Security-Policy")
+	if !strings.HasPrefix(policy, "script-src 'nonce-") {
+		t.Fatalf("unexpected policy: %#v", policy)
+	}
+	nonce := strings.TrimSuffix(strings.TrimPrefix(strings.SplitN(policy, ";", 2)[0], "script-src 'nonce-"), "'")
+
+	exp := strings.Replace(`<SCRIPT nonce="N" src="a.js"></SCRIPT><style nonce="N">p{}</style><script nonce="N"></script>`+
+		`<script>x</script><script nonce="N">`+"\n"+`</script><scripts>`, "N", nonce, -1)
+	if got := rec.Body.String(); got != exp {
+		t.Errorf("unexpected body: %#v, expected: %#v", got, exp)
+	}
+
+	// without a nonce, the templates are unchanged
+	if got := tpl.NewMapper(nil).Map("-require part.html"); got != "<script>\n</script><scripts>" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package placesmap
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalization are the options for the normalization of the values of the registered mappers,
+// see SetNormalization. The values of the elements of loops are not normalized.
+type Normalization struct {
+	// NFC composes the values to the Unicode normalization form C
+	NFC bool
+
+	// StripControl removes the control characters except tab, newline and carriage return
+	// and replaces invalid UTF-8 with the replacement character U+FFFD
+	StripControl bool
+
+	// ASCII escapes all non-ASCII characters as HTML character references, e.g. for legacy
+	// clients. It applies to placeholders without prefix and the -html and -attr directives.
+	ASCII bool
+}
+
+// SetNormalization sets the normalization for all renders that are started afterwards.
+// Values are normalized before they are escaped, so that every directive gets normalized values.
+func (h *HTMLTemplate) SetNormalization(n Normalization) {
+	h.Lock()
+	h.norm = n
+	h.Unlock()
+}
+
+// normalize normalizes the value of a mapper
+func (n Normalization) normalize(s string) string {
+	if n.StripControl {
+		s = stripControl(s)
+	}
+	if n.NFC {
+		s = nfc(s)
+	}
+	return s
+}
+
+// escapeASCII escapes the non-ASCII characters of the escaped value s, if enabled
+func (n Normalization) escapeASCII(s string) string {
+	if !n.ASCII {
+		return s
+	}
+	i := 0
+	for i < len(s) && s[i] < utf8.RuneSelf {
+		i++
+	}
+	if i == len(s) {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(s[:i])
+	for _, r := range s[i:] {
+		if r < utf8.RuneSelf {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteString("&#x" + strconv.FormatInt(int64(r), 16) + ";")
+	}
+	return b.String()
+}
+
+func stripControl(s string) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// the constants for the algorithmic (de)composition of Hangul syllables
+const (
+	hangulS      = 0xAC00
+	hangulL      = 0x1100
+	hangulV      = 0x1161
+	hangulT      = 0x11A7
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulCount  = 19 * hangulVCount * hangulTCount
+)
+
+// nfc returns s in the Unicode normalization form C, i.e. canonically decomposed,
+// ordered and composed again
+func nfc(s string) string {
+	// runes below U+0300 are never part of a decomposition, their UTF-8 starts below 0xCC
+	i := 0
+	for i < len(s) && s[i] < 0xCC {
+		i++
+	}
+	if i == len(s) {
+		return s
+	}
+
+	rs := decompose(s)
+	reorder(rs)
+	return string(compose(rs))
+}
+
+func decompose(s string) []rune {
+	rs := make([]rune, 0, len(s))
+	for _, r := range s {
+		if si := r - hangulS; si >= 0 && si < hangulCount {
+			rs = append(rs, hangulL+si/(hangulVCount*hangulTCount), hangulV+si%(hangulVCount*hangulTCount)/hangulTCount)
+			if t := si % hangulTCount; t != 0 {
+				rs = append(rs, hangulT+t)
+			}
+			continue
+		}
+		if d, has := decompositions[r]; has {
+			rs = append(rs, []rune(d)...)
+			continue
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// reorder sorts the runs of combining characters by their combining class
+func reorder(rs []rune) {
+	for i := 0; i < len(rs); i++ {
+		if combiningClasses[rs[i]] == 0 {
+			continue
+		}
+		j := i + 1
+		for j < len(rs) && combiningClasses[rs[j]] != 0 {
+			j++
+		}
+		if j-i > 1 {
+			run := rs[i:j]
+			sort.SliceStable(run, func(a, b int) bool {
+				return combiningClasses[run[a]] < combiningClasses[run[b]]
+			})
+		}
+		i = j
+	}
+}
+
+func compose(rs []rune) []rune {
+	res := rs[:0]
+	starter, last := -1, -1 // last is the class of the last character after the starter, -1 if there is none
+	for _, r := range rs {
+		class := int(combiningClasses[r])
+		if starter >= 0 && (last == -1 || last < class) {
+			if c, ok := composePair(res[starter], r); ok {
+				res[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter, last = len(res), -1
+		} else {
+			last = class
+		}
+		res = append(res, r)
+	}
+	return res
+}
+
+func composePair(a, b rune) (rune, bool) {
+	if l, v := a-hangulL, b-hangulV; l >= 0 && l < 19 && v >= 0 && v < hangulVCount {
+		return hangulS + (l*hangulVCount+v)*hangulTCount, true
+	}
+	if si, t := a-hangulS, b-hangulT; si >= 0 && si < hangulCount && si%hangulTCount == 0 && t > 0 && t < hangulTCount {
+		return a + t, true
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
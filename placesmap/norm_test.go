@@ -0,0 +1,30 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestNormalization(t *testing.T) {
+	tpl := newTemplate("page.html", `<p title="<@-attr name@>"><@name@></p><@-url name@>`)
+	hm := tpl.NewMapper(map[string]places.Mapper{"name": String("Ame\u0301lie\x00 \u212B \u1100\u1161\u11A8")})
+
+	tests := []struct {
+		norm Normalization
+		exp  string
+	}{
+		{Normalization{}, "<p title=\"Ame\u0301lie&#0;&#32;\u212B&#32;\u1100\u1161\u11A8\">Ame\u0301lie\x00 \u212B \u1100\u1161\u11A8</p>Ame%CC%81lie%00+%E2%84%AB+%E1%84%80%E1%85%A1%E1%86%A8"},
+		{Normalization{NFC: true, StripControl: true}, "<p title=\"Am\u00E9lie&#32;\u00C5&#32;\uAC01\">Am\u00E9lie \u00C5 \uAC01</p>Am%C3%A9lie+%C3%85+%EA%B0%81"},
+		{Normalization{NFC: true, ASCII: true}, "<p title=\"Am&#xe9;lie&#0;&#32;&#xc5;&#32;&#xac01;\">Am&#xe9;lie\x00 &#xc5; &#xac01;</p>Am%C3%A9lie%00+%C3%85+%EA%B0%81"},
+	}
+	for _, test := range tests {
+		tpl.SetNormalization(test.norm)
+		if got := hm.Map("-require page.html"); got != test.exp {
+			t.Errorf("%+v: unexpected result: %q, expected: %q", test.norm, got, test.exp)
+		}
+	}
+
+	if got, exp := nfc("a\u0302\u0323 d\u0307\u0323"), "\u1EAD \u1E0D\u0307"; got != exp {
+		t.Errorf("unexpected result: %q, expected: %q", got, exp)
+	}
+}
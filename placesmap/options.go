@@ -0,0 +1,175 @@
+package placesmap
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Strictness decides how problems of templates and renders are surfaced
+type Strictness int
+
+const (
+	// Lenient loads all templates that can be read; MapStrict returns the first error of a render
+	Lenient Strictness = iota
+
+	// Strict skips templates that are no valid UTF-8 or have placeholders at the position of tag or
+	// attribute names (see Validation) and records them in the LoadReport; MapStrict returns all
+	// errors of a render (see WithAllErrors)
+	Strict
+)
+
+// Logging decides what is logged via the log package
+type Logging int
+
+const (
+	// LogOff logs nothing but what is explicitly configured, e.g. slow renders (see SetSlowRender)
+	LogOff Logging = iota
+
+	// LogErrors additionally logs the errors of Map, that would be lost otherwise
+	LogErrors
+
+	// LogDebug additionally logs every placeholder that is rendered and every collection
+	// and template that can't be found
+	LogDebug
+)
+
+// Caching configures the caches of a HTMLTemplate
+type Caching struct {
+	Fragments bool          // enables the fragment cache, see EnableFragmentCache
+	Stale     time.Duration // the stale duration of the fragment cache
+}
+
+// Autoescape decides how the values of placeholders without prefix are escaped
+type Autoescape int
+
+const (
+	// AutoescapeHTML escapes the values as HTML
+	AutoescapeHTML Autoescape = iota
+
+	// AutoescapeOff inserts the values as they are, e.g. for plain text templates.
+	// Only for trusted values.
+	AutoescapeOff
+)
+
+// Options configure a HTMLTemplate, see NewHTMLTemplate. The zero value loads all templates,
+// logs nothing, caches nothing, escapes as HTML and has no limits.
+type Options struct {
+	Strictness Strictness
+	Logging    Logging
+	Caching    Caching
+	Autoescape Autoescape
+
+	// Limits apply to every template and render: MaxSize and MaxPlaceholders to the templates that
+	// are loaded (templates that exceed them are skipped and recorded in the LoadReport), MaxOutput
+	// and Timeout to every render (which renders the empty string then and reports a LimitError
+	// to MapStrict). The Timeout is the deadline of the context of the render (see ContextMapper),
+	// it does not time out single placeholders like Timeouts.Placeholder.
+	Limits Limits
+
+	// A11yLint checks the output of every render for accessibility problems (see CheckA11y) and
+	// reports them along with their positions within the templates to Hooks.A11y. For development.
+	A11yLint bool
+}
+
+// Option is a functional option of NewHTMLTemplate
+type Option func(*Options)
+
+// Development is the preset for development: strict, logging every placeholder, checking
+// accessibility, without caches
+func Development() Option {
+	return func(o *Options) {
+		*o = Options{Strictness: Strict, Logging: LogDebug, A11yLint: true}
+	}
+}
+
+// Production is the preset for production: lenient, logging errors, with a fragment cache
+// (stale for a minute) and renders that time out after 10 seconds
+func Production() Option {
+	return func(o *Options) {
+		*o = Options{
+			Logging: LogErrors,
+			Caching: Caching{Fragments: true, Stale: time.Minute},
+			Limits:  Limits{Timeout: 10 * time.Second},
+		}
+	}
+}
+
+// Testing is the preset for tests: strict, without logging and caches
+func Testing() Option {
+	return func(o *Options) {
+		*o = Options{Strictness: Strict}
+	}
+}
+
+// WithStrictness sets the Strictness, overriding the one of a preset given before
+func WithStrictness(s Strictness) Option {
+	return func(o *Options) { o.Strictness = s }
+}
+
+// WithLogging sets the Logging, overriding the one of a preset given before
+func WithLogging(l Logging) Option {
+	return func(o *Options) { o.Logging = l }
+}
+
+// WithCaching sets the Caching, overriding the one of a preset given before
+func WithCaching(c Caching) Option {
+	return func(o *Options) { o.Caching = c }
+}
+
+// WithAutoescape sets the Autoescape, overriding the one of a preset given before
+func WithAutoescape(a Autoescape) Option {
+	return func(o *Options) { o.Autoescape = a }
+}
+
+// WithLimits sets the Limits, overriding the ones of a preset given before
+func WithLimits(l Limits) Option {
+	return func(o *Options) { o.Limits = l }
+}
+
+// validation returns the Validation of the templates that are loaded
+func (o Options) validation() Validation {
+	v := Validation{MaxSize: o.Limits.MaxSize, MaxPlaceholders: o.Limits.MaxPlaceholders}
+	if o.Strictness == Strict {
+		v.RequireUTF8, v.StrictNames = true, true
+	}
+	return v
+}
+
+// Options returns the options the HTMLTemplate has been created with
+func (h *HTMLTemplate) Options() Options {
+	return h.profile
+}
+
+// debugf logs the given message, if the Logging is LogDebug
+func (r *RenderContext) debugf(format string, args ...interface{}) {
+	if r.hm.profile.Logging >= LogDebug {
+		log.Printf(format, args...)
+	}
+}
+
+// limited renders the given placeholder within the Limits of the Options
+func (r *RenderContext) limited(input string) string {
+	l := r.hm.profile.Limits
+	if l.Timeout > 0 {
+		// only the context carries the deadline, the placeholder timeout is kept, since it
+		// runs every mapper in a goroutine of its own
+		var cancel context.CancelFunc
+		r.ctx, cancel = context.WithTimeout(r.ctx, l.Timeout)
+		defer cancel()
+	}
+
+	res := r.render(input)
+	switch {
+	case l.Timeout > 0 && r.ctx.Err() == context.DeadlineExceeded:
+		r.fail(LimitError("Timeout"))
+		return ""
+	case l.MaxOutput > 0 && len(res) > l.MaxOutput:
+		r.fail(LimitError("MaxOutput"))
+		return ""
+	}
+	if r.hm.profile.A11yLint {
+		r.lintA11y(input, res)
+	}
+	return res
+}
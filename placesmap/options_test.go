@@ -0,0 +1,86 @@
+package placesmap
+
+import (
+	"bytes"
+	"github.com/metakeule/places"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitsTimeout(t *testing.T) {
+	tpl := NewHTMLTemplate(NewReadSeekerMap(), Production(), WithLimits(Limits{Timeout: 20 * time.Millisecond}))
+	var timedOut []string
+	tpl.SetHooks(Hooks{Timeout: func(kind, name string, d time.Duration) { timedOut = append(timedOut, kind) }})
+	got, err := tpl.NewMapper(map[string]places.Mapper{"slow": slow{}}).MapStrict("slow")
+	if got != "" || err != LimitError("Timeout") {
+		t.Errorf("unexpected result: %#v, %v", got, err)
+	}
+	// the deadline of the render is no placeholder timeout
+	if len(timedOut) != 0 {
+		t.Errorf("unexpected timeouts: %v", timedOut)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	pairs := func() *ReadSeekerMap {
+		rs := NewReadSeekerMap()
+		rs.Add("page.html", strings.NewReader("<p><@name@></p><@-each items row.html@>"))
+		rs.Add("name.html", strings.NewReader("<p <@name@>>"))
+		return rs
+	}
+	m := map[string]places.Mapper{"name": String("<Tom>")}
+
+	if o := NewHTMLTemplate(pairs()).Options(); o != (Options{}) {
+		t.Errorf("unexpected default options: %#v", o)
+	}
+
+	prod := NewHTMLTemplate(pairs(), Production(), WithLogging(LogDebug))
+	if o := prod.Options(); o.Logging != LogDebug || !o.Caching.Fragments || o.Limits.Timeout != 10*time.Second {
+		t.Errorf("unexpected production options: %#v", o)
+	}
+	if prod.fragments == nil {
+		t.Errorf("expected fragment cache for production")
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	prod.NewMapper(m).Map("-require page.html")
+	if !strings.Contains(logged.String(), `each: collection "items" not found`) {
+		t.Errorf("expected debug log, got %#v", logged.String())
+	}
+
+	logged.Reset()
+	NewHTMLTemplate(pairs()).NewMapper(m).Map("-require page.html")
+	if logged.Len() != 0 {
+		t.Errorf("expected no log by default, got %#v", logged.String())
+	}
+
+	test := NewHTMLTemplate(pairs(), Testing())
+	if test.Has("name.html") {
+		t.Errorf("expected template with name placeholder to be skipped")
+	}
+	got, err := test.NewMapper(m).MapStrict("-require page.html")
+	if exp := "<p>&lt;Tom&gt;</p>"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if errs, is := err.(RenderErrors); !is || len(errs) != 1 {
+		t.Errorf("expected RenderErrors, got %v", err)
+	}
+
+	raw := NewHTMLTemplate(pairs(), WithAutoescape(AutoescapeOff))
+	if got, exp := raw.NewMapper(m).Map("-require page.html"), "<p><Tom></p>"; got != exp {
+		t.Errorf("unexpected result without autoescape: %#v, expected: %#v", got, exp)
+	}
+
+	limited := NewHTMLTemplate(pairs(), WithLimits(Limits{MaxPlaceholders: 1, MaxOutput: 10}))
+	if limited.Has("page.html") || !limited.Has("name.html") {
+		t.Errorf("unexpected templates: %#v", limited.LoadReport())
+	}
+	if got, err := limited.NewMapper(m).MapStrict("name"); got != "" || err != LimitError("MaxOutput") {
+		t.Errorf("expected MaxOutput LimitError, got %#v, %v", got, err)
+	}
+}
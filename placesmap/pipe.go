@@ -0,0 +1,77 @@
+package placesmap
+
+import (
+	"bufio"
+	"github.com/metakeule/places"
+	"io"
+)
+
+// DefaultPipeBuffer is the size of the buffer of Pipe, if no size is given
+const DefaultPipeBuffer = 32 * 1024
+
+// Pipe renders the template with the given name in a new goroutine and returns the reading end of
+// the pipe the output is written to, encoded in the charset of the template (see HTMLTemplate.SetCharset).
+// At most size bytes (DefaultPipeBuffer if size < 1) are buffered: the render waits while the reader
+// does not consume the output, so that reverse proxies and compressors can pass the page to slow
+// clients without keeping it in memory. The page template is streamed, its includes are rendered
+// as a whole (ahead, see WithIncludePrefetch). After the output, Read returns the first error of
+// the render (see MapStrict) instead of io.EOF.
+// If the reader is closed before the end, the rest of the render is discarded.
+// If the template does not exist, TemplateNotFoundError is returned.
+func (h *HTMLTemplateMapper) Pipe(name string, size int) (io.ReadCloser, error) {
+	if !h.Has(name) {
+		return nil, TemplateNotFoundError(name)
+	}
+	if size < 1 {
+		size = DefaultPipeBuffer
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		r := h.newRender()
+		bf := bufio.NewWriterSize(h.Charset().encoder(pw), size)
+		r.stream(bf, name)
+		err := bf.Flush()
+		if err == nil {
+			err = r.err
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+// stream renders the template with the given name as outermost include into bf
+func (r *RenderContext) stream(bf *bufio.Writer, name string) {
+	t, ok := r.lookup(name)
+	if !ok {
+		r.fail(TemplateNotFoundError(name))
+		return
+	}
+	if !r.push(name) {
+		return
+	}
+	var m places.Mapper = r
+	if r.hm.prefetch {
+		m = r.prefetch(t)
+	}
+	c := &countingBuffer{Writer: bf}
+	t.ReplaceMapper(c, r.locate(t, m))
+	r.pop(c.n)
+}
+
+// countingBuffer counts the bytes written to the buffer
+type countingBuffer struct {
+	*bufio.Writer
+	n int
+}
+
+func (c *countingBuffer) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.n += n
+	return n, err
+}
+
+func (c *countingBuffer) WriteString(s string) (int, error) {
+	n, err := c.Writer.WriteString(s)
+	c.n += n
+	return n, err
+}
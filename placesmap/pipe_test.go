@@ -0,0 +1,53 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	tpl := newTemplate("page.html", "<@a@>"+strings.Repeat("x", 100)+"<@b@><@-require inc.html@>", "inc.html", "ü<@-require missing.html@>")
+	var mappedB int32
+	m := map[string]places.Mapper{
+		"a": String("A"),
+		"b": MapFunc(func(string) string {
+			atomic.StoreInt32(&mappedB, 1)
+			return "B"
+		}),
+	}
+
+	rd, err := tpl.NewMapper(m).Pipe("page.html", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := make([]byte, 4)
+	if _, err := io.ReadFull(rd, start); err != nil || string(start) != "Axxx" {
+		t.Fatalf("unexpected start: %#v, %v", string(start), err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadInt32(&mappedB) != 0 {
+		t.Errorf("the render must wait for the reader")
+	}
+	rest, err := ioutil.ReadAll(rd)
+	if got, exp := string(start)+string(rest), "A"+strings.Repeat("x", 100)+"Bü"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if err != TemplateNotFoundError("missing.html") {
+		t.Errorf("expected the error of the render, got %v", err)
+	}
+
+	tpl.SetCharset(Latin1)
+	rd, _ = tpl.NewMapper(m).Pipe("inc.html", 0)
+	if b, _ := ioutil.ReadAll(rd); string(b) != "\xfc" {
+		t.Errorf("unexpected encoding: %#v", string(b))
+	}
+
+	if _, err := tpl.NewMapper(m).Pipe("missing.html", 0); err != TemplateNotFoundError("missing.html") {
+		t.Errorf("expected TemplateNotFoundError, got %v", err)
+	}
+}
@@ -5,18 +5,22 @@ package placesmap
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/metakeule/places"
 	"html"
 	"io"
 	"io/ioutil"
+	"log"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // split splits the given input and returns the prefix and rest.
@@ -48,6 +52,38 @@ func split(input string) (prefix string, rest string) {
 	return
 }
 
+// isSpace returns whether c is a space, tab or line break
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// nextArg returns the first whitespace separated argument of s and the rest following it.
+// It does not allocate, so that directives can be parsed cheaply on every render.
+func nextArg(s string) (arg, rest string) {
+	i := 0
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	j := i
+	for j < len(s) && !isSpace(s[j]) {
+		j++
+	}
+	return s[i:j], s[j:]
+}
+
+// validPrefix returns whether the given prefix conforms to ^[a-z]+$
+func validPrefix(prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if prefix[i] < 'a' || prefix[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
 // Map is a registry of places.Mappers and itself a places.Mapper
 type Map interface {
 	places.Mapper
@@ -66,8 +102,6 @@ func (m MapperAlreadyExistsError) Error() string {
 	return fmt.Sprintf("Mapper for prefix %#v already exists", m)
 }
 
-var prefixRule = regexp.MustCompile("^[a-z]+$")
-
 type _map map[string]places.Mapper
 
 // Add registers a mapper in the registry for the given prefix
@@ -76,7 +110,7 @@ type _map map[string]places.Mapper
 // If prefix does not conform to the regular expression ^[a-z]+$, ErrInvalidPrefix is returned
 // If a mapper already exists for this prefix, MapperAlreadyExistsError is returned
 func (mp _map) Add(prefix string, mapper places.Mapper) error {
-	if prefix != "" && !prefixRule.MatchString(prefix) {
+	if prefix != "" && !validPrefix(prefix) {
 		return ErrInvalidPrefix
 	}
 	if _, has := mp[prefix]; has {
@@ -101,7 +135,7 @@ func (mp _map) Map(input string) string {
 		return ""
 	}
 
-	// If a prefix is identified but does not conform to prefixRule,
+	// If a prefix is identified but does not conform to validPrefix,
 	// an empty string will be returned, since Add makes sure the every prefix
 	// in the map conforms
 	m, ok := mp[prefix]
@@ -165,6 +199,8 @@ func (m ReadSeekerAlreadyExistsError) Error() string {
 // an error is returned.
 // There are no restrictions for the name
 func (r *ReadSeekerMap) Add(name string, rs io.ReadSeeker) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
 	if _, has := r.m[name]; has {
 		return ReadSeekerAlreadyExistsError(name)
 	}
@@ -172,8 +208,10 @@ func (r *ReadSeekerMap) Add(name string, rs io.ReadSeeker) error {
 	return nil
 }
 
+// Map returns the content of the io.ReadSeeker for the given name.
+// Since reading moves the offset of the io.ReadSeeker, reads are serialized.
 func (r *ReadSeekerMap) Map(name string) (val string) {
-	r.mx.RLock()
+	r.mx.Lock()
 	if rs, ok := r.m[name]; ok {
 		_, err := rs.Seek(0, 0)
 		if err == nil {
@@ -185,16 +223,34 @@ func (r *ReadSeekerMap) Map(name string) (val string) {
 		}
 	}
 
-	r.mx.RUnlock()
+	r.mx.Unlock()
 	return
 }
 
+// DefaultLoadConcurrency is the number of files a TemplateLoader reads at the same time by default
+const DefaultLoadConcurrency = 8
+
 // TemplateLoader loads templates recursively from a root directory for a given file extension
 type TemplateLoader struct {
 	*ReadSeekerMap
-	rootDir    string
-	extension  string
-	ignoreDirs *regexp.Regexp // directories to be ignored
+	rootDir     string
+	extension   string
+	ignoreDirs  *regexp.Regexp // directories to be ignored
+	rules       LoadRules      // see SetRules
+	realRoot    string         // the root directory with resolved symbolic links
+	concurrency int            // the number of files that are read at the same time, see SetConcurrency
+	files       []loadFile     // the files found by the walk
+}
+
+// loadFile is a template file to be read by Load
+type loadFile struct {
+	name, path string
+}
+
+// SetConcurrency sets the number of files that are read at the same time by Load.
+// Values below 1 mean DefaultLoadConcurrency.
+func (l *TemplateLoader) SetConcurrency(n int) {
+	l.concurrency = n
 }
 
 type RootIsNotDirectoryError string
@@ -209,35 +265,61 @@ func (r RootDoesNotExistError) Error() string {
 	return fmt.Sprintf("root %#v does not exist", r)
 }
 
-func (l *TemplateLoader) fileReader(path string) (io.ReadSeeker, error) {
-	b, err := ioutil.ReadFile(path)
+func (l *TemplateLoader) fileReader(f loadFile) (io.ReadSeeker, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err = l.checkOpened(file, f); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(file)
 	if err != nil {
 		return nil, err
 	}
 	return bytes.NewReader(b), nil
 }
 
-func (l *TemplateLoader) walk(path string, info os.FileInfo, err error) error {
-	if err != nil {
-		return err
+// readFiles reads the files found by the walk with a pool of workers. If reads fail,
+// the error of the first file in walk order is returned.
+func (l *TemplateLoader) readFiles() error {
+	workers := l.concurrency
+	if workers < 1 {
+		workers = DefaultLoadConcurrency
+	}
+	if workers > len(l.files) {
+		workers = len(l.files)
 	}
 
-	if l.ignoreDirs != nil && info.IsDir() && l.ignoreDirs.MatchString(info.Name()) {
-		return filepath.SkipDir
+	readers := make([]io.ReadSeeker, len(l.files))
+	errs := make([]error, len(l.files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				readers[i], errs[i] = l.fileReader(l.files[i])
+			}
+		}()
+	}
+	for i := range l.files {
+		next <- i
 	}
+	close(next)
+	wg.Wait()
 
-	if !info.IsDir() && filepath.Ext(path) == l.extension {
-		var rel string
-		rel, err = filepath.Rel(l.rootDir, path)
-		if err != nil {
+	for _, err := range errs {
+		if err != nil && err != errSkipFile {
 			return err
 		}
-		var rd io.ReadSeeker
-		rd, err = l.fileReader(path)
-		if err != nil {
-			return err
+	}
+	for i, f := range l.files {
+		if errs[i] == nil {
+			l.ReadSeekerMap.Add(f.name, readers[i])
 		}
-		l.ReadSeekerMap.Add(rel, rd)
 	}
 	return nil
 }
@@ -257,303 +339,359 @@ func (l *TemplateLoader) Load() (*ReadSeekerMap, error) {
 	}
 
 	l.ReadSeekerMap = NewReadSeekerMap()
+	l.files = nil
 
-	err := filepath.Walk(l.rootDir, l.walk)
+	if l.realRoot, errStat = filepath.EvalSymlinks(l.rootDir); errStat != nil {
+		return nil, errStat
+	}
+
+	var seen map[string]bool
+	if l.rules.Symlinks == SymlinkFollow {
+		seen = map[string]bool{}
+	}
+	err := l.walkDir(l.rootDir, "", seen)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if err = l.readFiles(); err != nil {
+		return nil, err
+	}
+
 	return l.ReadSeekerMap, nil
 }
 
 type HTMLTemplate struct {
 	sync.RWMutex
-	rs  *ReadSeekerMap
-	rsm map[string]*places.Template
-}
-
-func NewHTMLTemplate(rs *ReadSeekerMap) *HTMLTemplate {
-	h := &HTMLTemplate{
-		rs:  rs,
-		rsm: map[string]*places.Template{},
+	rs          *ReadSeekerMap
+	rsm         map[string]*places.Template
+	overrides   map[string]*places.Template // templates that shadow the loaded ones
+	audit       *audit                      // nil, if no audit is running
+	meta        map[string]Meta             // metadata from the front matter
+	report      LoadReport
+	timeouts    Timeouts
+	hooks       Hooks
+	charset     Charset
+	handlers    map[string]PrefixHandler // replaced on every change, see Handle
+	middlewares []Middleware
+	src         *source // nil, if not loaded from a Source
+	csp         string  // Content-Security-Policy of the render helpers
+	flags       FlagProvider
+	policy      Policy
+	lazy        *lazy         // nil, if not loaded lazily
+	stats       *stats        // nil, if the statistics are disabled
+	slow        time.Duration // the threshold for slow renders, see SetSlowRender
+	checksum    string        // the checksum header of the render helpers, see SetChecksumHeader
+	sri         sync.Map      // the integrity hashes by *places.Template, see SRI
+	themes      ThemeResolver
+	fallback    *HTMLTemplate     // the fallback of a MultiTenantSet
+	fragments   *fragments        // nil, if the fragment cache is disabled
+	aliases     map[string]string // replaced on every change, see Alias
+	usages      *deprecations     // the usages of the aliases
+	norm        Normalization
+	compressors []Compressor
+	guard       LengthGuard
+	positions   sync.Map // the positions of the placeholders by *places.Template, see WithAllErrors
+	access      AccessLog
+	options     RenderOptions
+	versions    sync.Map // the versions by *places.Template, see TemplateVersion
+	profile     Options  // set on creation, see NewHTMLTemplate
+	streaming   int      // the threshold of Stream, see SetStreamThreshold
+}
+
+// NewHTMLTemplate returns a HTMLTemplate with the templates of the given ReadSeekerMap, configured
+// by the given options, e.g. NewHTMLTemplate(rs, Production(), WithLogging(LogDebug)).
+// Templates that can't be read are skipped.
+func NewHTMLTemplate(rs *ReadSeekerMap, opts ...Option) *HTMLTemplate {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// invalid templates are skipped, so there is no error
+	h, _, _ := NewHTMLTemplateValidated(rs, o.validation())
+	h.profile = o
+	if o.Caching.Fragments {
+		h.fragments = newFragments(o.Caching.Stale)
 	}
-	h.rs.mx.RLock()
-
-	for k, rs := range h.rs.m {
-		_, err := rs.Seek(0, 0)
-		if err == nil {
-			var b []byte
-			b, err = ioutil.ReadAll(rs)
-			if err == nil {
-				h.rsm[k] = places.NewTemplate(b)
-			}
-		}
-	}
-	h.rs.mx.RUnlock()
 	return h
 }
 
-func (h *HTMLTemplate) NewMapper(m map[string]places.Mapper) *HTMLTemplateMapper {
-	return &HTMLTemplateMapper{HTMLTemplate: h, m: m}
+func newHTMLTemplate(rs *ReadSeekerMap) *HTMLTemplate {
+	return &HTMLTemplate{
+		rs:   rs,
+		rsm:  map[string]*places.Template{},
+		meta: map[string]Meta{},
+	}
 }
 
-type HTMLTemplateMapper struct {
-	sync.Mutex
-	*HTMLTemplate
-	m         map[string]places.Mapper
-	preferred places.Mapper
-	indexes   []NMapper // keep track of array indexes within nested objects
-	depth     int       // current depth of nested objects
+// Has returns whether a template with the given name has been loaded
+func (h *HTMLTemplate) Has(name string) bool {
+	_, has := h.template(name)
+	return has
 }
 
-func (h *HTMLTemplateMapper) require(name string, m places.Mapper) string {
-	// fmt.Printf("requiring: %#v\n", name)
-	h.HTMLTemplate.RLock()
-	defer h.HTMLTemplate.RUnlock()
-
-	if t, ok := h.HTMLTemplate.rsm[name]; ok {
-		var bf bytes.Buffer
-		t.ReplaceMapper(&bf, m)
-		return bf.String()
+// template returns the template with the given name, preferring overrides and
+// falling back to the fallback of a MultiTenantSet.
+// The lock is not held while the template is rendered, since
+// rendering may require other templates.
+func (h *HTMLTemplate) template(name string) (*places.Template, bool) {
+	if t, has := h.own(name); has {
+		return t, true
 	}
-	return ""
+	h.RLock()
+	fb := h.fallback
+	h.RUnlock()
+	if fb == nil {
+		return nil, false
+	}
+	return fb.template(name)
 }
 
-type NMapper interface {
-	places.Mapper
-	NMap(n int, sub string) places.Mapper
-	Len() int
-}
+// own returns the template with the given name of h, preferring overrides
+func (h *HTMLTemplate) own(name string) (t *places.Template, has bool) {
+	h.RLock()
+	t, has = h.overrides[name]
+	lz := h.lazy
+	if !has && lz == nil {
+		t, has = h.rsm[name]
+	}
+	h.RUnlock()
+	if has || lz == nil {
+		return
+	}
 
-type mapDelegate struct {
-	first, second places.Mapper
+	if !lz.load(h, name) {
+		return nil, false
+	}
+	h.RLock()
+	t, has = h.rsm[name]
+	h.RUnlock()
+	return
 }
 
-func (m *mapDelegate) Map(input string) string {
-	out := m.first.Map(input)
-	if out != "" {
-		return out
+// add parses the front matter of the given template and adds the template
+func (h *HTMLTemplate) add(name string, tpl []byte) {
+	meta, body, has := parseFrontMatter(tpl)
+	if has {
+		h.meta[name] = meta
 	}
-	return m.second.Map(input)
+	ts, positions := parseTemplates(name, tpl, body)
+	for n, t := range ts {
+		if old, has := h.rsm[n]; has && old != t {
+			h.forget(old)
+		}
+		h.rsm[n] = t
+	}
+	h.storePositions(positions)
 }
 
-/*
-func (h *HTMLTemplateMapper) _map_delegate(input string, m places.Mapper) string {
-	out := m.Map(input)
-	if out != "" {
-		return out
-	}
-	return h._map(input)
+// forget removes what is kept per template (the positions, the version and the integrity hash)
+// for the given template, that has been replaced or removed
+func (h *HTMLTemplate) forget(t *places.Template) {
+	h.positions.Delete(t)
+	h.versions.Delete(t)
+	h.sri.Delete(t)
 }
-*/
 
-func (h *HTMLTemplateMapper) Map(input string) string {
-	if h.preferred != nil {
-		out := h.preferred.Map(input)
-		if out != "" {
-			return out
+// Override replaces the template with the given name by tpl until RemoveOverride
+// is called for the name. The original template is kept and restored by RemoveOverride.
+// If there is no template for the given name, a new one is added.
+func (h *HTMLTemplate) Override(name string, tpl []byte) {
+	_, body, _ := parseFrontMatter(tpl)
+	ts, positions := parseTemplates(name, tpl, body)
+	h.storePositions(positions)
+	h.Lock()
+	if h.overrides == nil {
+		h.overrides = map[string]*places.Template{}
+	}
+	for n, t := range ts {
+		if old, has := h.overrides[n]; has && old != t {
+			h.forget(old)
 		}
+		h.overrides[n] = t
 	}
-	return h._map(input)
+	h.Unlock()
 }
 
-func (h *HTMLTemplateMapper) findMapper(depth int) NMapper {
+// RemoveOverride removes the override for the given name, so that the original
+// template is used again
+func (h *HTMLTemplate) RemoveOverride(name string) {
 	h.Lock()
-	defer h.Unlock()
-	if len(h.indexes) > depth {
-		return nil
+	for k, t := range h.overrides {
+		if k == name || strings.HasPrefix(k, name+"#") {
+			h.forget(t)
+			delete(h.overrides, k)
+		}
 	}
-	return h.indexes[depth-1]
+	h.Unlock()
 }
 
-// findNestedMapper finds a mapper for a nested object
-func (h *HTMLTemplateMapper) findNestedMapper(sub string) places.Mapper {
-	fmt.Printf("inside findNestedMapper: %#v, depth: %d\n", sub, h.depth)
-	if sub == "" {
-		return String("")
+// names returns the sorted names of all templates, including overrides
+func (h *HTMLTemplate) names() []string {
+	h.RLock()
+	names := make([]string, 0, len(h.rsm))
+	for k := range h.rsm {
+		names = append(names, k)
 	}
-	sb := strings.Split(sub, ".")
-
-	if len(sb) != h.depth {
-		return String("[Error] too deep var declaration")
+	for k := range h.overrides {
+		if _, has := h.rsm[k]; !has {
+			names = append(names, k)
+		}
 	}
+	lz := h.lazy
+	h.RUnlock()
 
-	var m places.Mapper
-
-	for d := 0; d <= len(sb); d++ {
-		nm := h.findMapper(d)
-
-		if nm == nil {
-			return m
+	// the templates of a lazy source that have not been used yet
+	if lz != nil {
+		all, _ := lz.src.List()
+		seen := make(map[string]bool, len(names))
+		for _, k := range names {
+			seen[k] = true
+		}
+		for _, k := range all {
+			if !seen[k] {
+				names = append(names, k)
+			}
 		}
-
-		m = nm
 	}
+	sort.Strings(names)
+	return names
+}
 
-	return m
+// Names returns the sorted names of all templates, including overrides, but not the templates
+// of inline bodies (see Placeholders)
+func (h *HTMLTemplate) Names() []string {
+	var names []string
+	for _, name := range h.names() {
+		if blockOwner(name) == name {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
-func (h *HTMLTemplateMapper) replaceVars(bf places.Buffer, t *places.Template, nm NMapper, sub string) {
-	fmt.Printf("replaceVars for mapper %#v, sub: %#v\n", nm, sub)
-	/*
-		if sub != "" {
-			for i := 0; i < l; i++ {
-				var m = nm.NMap(i, sub)
-				if nmm, isNM := m.(NMapper); isNM {
-					h.replaceVars(bf, t, nmm, sub)
-					continue
-				}
-				h.preferred = nm
-				t.ReplaceMapper(bf, h)
-				h.preferred = nil
+// Placeholders returns the placeholders of the template with the given name in the order of
+// their occurrence. The placeholders of an inline body follow the directive of the body.
+func (h *HTMLTemplate) Placeholders(name string) ([]string, bool) {
+	t, ok := h.template(name)
+	if !ok {
+		return nil, false
+	}
+	var res []string
+	_, phs, _ := authored(t)
+	for _, ph := range phs {
+		res = append(res, ph)
+		for _, ref := range references(ph) {
+			if owner := blockOwner(ref); owner != ref && ref != name && owner == blockOwner(name) {
+				body, _ := h.Placeholders(ref)
+				res = append(res, body...)
 			}
-			return
-		}
-	*/
-	/*
-		idx := strings.IndexRune(sub, '.')
-		if idx == -1 {
-			sub = ""
-		} else {
-			sub = sub[idx+1:]
-		}
-	*/
-
-	// h.depth++
-
-	l := nm.Len()
-	for i := 0; i < l; i++ {
-		var m = nm.NMap(i, sub)
-		if nmm, isNM := m.(NMapper); isNM {
-			h.replaceVars(bf, t, nmm, sub)
-		} else {
-			fmt.Printf("got mapper: %#v[%d]\n", m, i)
-			h.preferred = m
-			t.ReplaceMapper(bf, h)
-			h.preferred = nil
 		}
 	}
-	/*
-		// h.depth--
-		fmt.Printf("indexes: %#v, depth: %d, sub: %#v\n", h.indexes, h.depth, sub)
-		if h.depth == 1 && sub != "" {
-			fmt.Printf("now calling findNestedMapper\n")
-			h.depth = len(strings.Split(sub, "."))
-			h.preferred = h.findNestedMapper(sub)
-			fmt.Printf("found nested mapper: %#v\n", h.preferred)
-			t.ReplaceMapper(bf, h)
-			h.indexes = []NMapper{}
-			h.depth = 0
-		}
-		h.preferred = nil
-	*/
+	return res, true
 }
 
-func (h *HTMLTemplateMapper) _map(input string) string {
-	prefix, rest := split(input)
-
-	fmt.Printf("prefix: %#v rest: %#v\n", prefix, rest)
-	if prefix == "require" {
-		return h.require(rest, h)
+func (h *HTMLTemplate) NewMapper(m map[string]places.Mapper) *HTMLTemplateMapper {
+	if a := h.getAudit(); a != nil {
+		names := make([]string, 0, len(m))
+		for k := range m {
+			names = append(names, k)
+		}
+		a.register(names)
 	}
+	return &HTMLTemplateMapper{HTMLTemplate: h, m: m, all: h.profile.Strictness == Strict}
+}
 
-	if prefix == "each" {
-		s := strings.SplitN(rest, " ", 2)
-		mpName, inc := strings.TrimSpace(s[0]), strings.TrimSpace(s[1])
-		var sub string
-
-		if strings.ContainsRune(mpName, '.') {
-			sp := strings.SplitN(mpName, ".", 2)
-			mpName = sp[0]
-			sub = sp[1]
-		}
+// HTMLTemplateMapper is a places.Mapper that renders the placeholders of a HTMLTemplate
+// with the help of the given mappers. It may be used by many goroutines at the same time,
+// since the state of each render is kept in a separate render context.
+type HTMLTemplateMapper struct {
+	sync.Mutex
+	*HTMLTemplate
+	m         map[string]places.Mapper
+	variants  *VariantMapper
+	ctx       context.Context // nil means context.Background()
+	locale    string
+	nonce     *NonceMapper
+	memo      bool
+	fallbacks []map[string]places.Mapper // consulted for unregistered names, see WithFallbacks
+	theme     string                     // overrides the ThemeResolver, see WithTheme
+	print     bool                       // see WithPrintMode
+	all       bool                       // collect all errors, see WithAllErrors
+	prefetch  bool                       // see WithIncludePrefetch
+}
 
-		fmt.Printf("mpName: %#v, inc: %#v\n", mpName, inc)
-		h.Lock()
-		mp, ok := h.m[mpName]
-		h.Unlock()
-		if !ok {
-			fmt.Printf("mpName %#v not found", mpName)
-			return ""
-		}
+// copy returns a HTMLTemplateMapper with the same mappers and settings
+func (h *HTMLTemplateMapper) copy() *HTMLTemplateMapper {
+	return &HTMLTemplateMapper{HTMLTemplate: h.HTMLTemplate, m: h.m, variants: h.variants, ctx: h.ctx, locale: h.locale, nonce: h.nonce, memo: h.memo, fallbacks: h.fallbacks, theme: h.theme, print: h.print, all: h.all, prefetch: h.prefetch}
+}
 
-		h.HTMLTemplate.RLock()
-		t, hasTemplate := h.HTMLTemplate.rsm[inc]
-		h.HTMLTemplate.RLock()
-		if !hasTemplate {
-			fmt.Printf("template %#v not found", inc)
-			return ""
-		}
+// WithMemo returns a HTMLTemplateMapper with the same mappers that calls every registered mapper
+// at most once per key and render, i.e. the values are cached for the duration of a single render.
+// Only values of registered mappers are cached, not the ones of the current element of a loop.
+func (h *HTMLTemplateMapper) WithMemo() *HTMLTemplateMapper {
+	c := h.copy()
+	c.memo = true
+	return c
+}
 
-		// TODO: debug this properly
-		if nm, is := mp.(NMapper); is {
-			h.depth++
-			h.indexes = append(h.indexes, NMapper(nil))
-			var bf bytes.Buffer
-			l := nm.Len()
-			for i := 0; i < l; i++ {
-				var m = nm.NMap(i, sub)
-				fmt.Printf("got mapper: %#v[%d]\n", m, i)
-				if nmm, isNM := m.(NMapper); isNM {
-					h.indexes[h.depth-1] = nmm
-					h.replaceVars(&bf, t, nmm, sub)
-				} else {
-					// h.depth--
-					// fmt.Printf("indexes: %#v, depth: %d, sub: %#v\n", h.indexes, h.depth, sub)
-					if sub != "" {
-						fmt.Printf("now calling findNestedMapper\n")
-						h.depth = len(strings.Split(sub, "."))
-						h.preferred = h.findNestedMapper(sub)
-						fmt.Printf("found nested mapper: %#v\n", h.preferred)
-						t.ReplaceMapper(&bf, h)
-						h.indexes = []NMapper{}
-						h.depth = 0
-					} else {
-
-						h.preferred = nil
-						h.preferred = m
-						t.ReplaceMapper(&bf, h)
-						h.preferred = nil
-					}
-				}
-			}
+// WithLocale returns a HTMLTemplateMapper with the same mappers whose renders have the given locale,
+// which is available to prefix handlers via RenderContext.Locale
+func (h *HTMLTemplateMapper) WithLocale(locale string) *HTMLTemplateMapper {
+	c := h.copy()
+	c.locale = locale
+	return c
+}
 
-			h.replaceVars(&bf, t, nm, sub)
-			h.preferred = nil
-			h.indexes = h.indexes[:len(h.indexes)-1]
-			h.depth--
-			return bf.String()
-		} else {
-			return fmt.Sprintf("not a NMapper: %#v\n", mp)
-		}
+// Map starts a new render of the given placeholder
+func (h *HTMLTemplateMapper) Map(input string) string {
+	r := h.newRender()
+	res := r.limited(input)
+	if r.err != nil && h.profile.Logging >= LogErrors {
+		log.Printf("rendering %#v: %v", input, r.err)
 	}
+	return res
+}
 
-	h.Lock()
-	mp, ok := h.m[rest]
-	h.Unlock()
-	if !ok {
-		return ""
+// newRender returns the context for a new render
+func (h *HTMLTemplateMapper) newRender() *RenderContext {
+	r := &RenderContext{hm: h, ctx: h.ctx, rendered: new(int64)}
+	if r.ctx == nil {
+		r.ctx = context.Background()
 	}
-
-	switch prefix {
-	case "js":
-		return fmt.Sprintf("%#v", mp.Map(rest))
-	case "raw":
-		return mp.Map(rest)
-	case "html":
-		return mp.Map(rest)
-	case "url":
-		return url.QueryEscape(mp.Map(rest))
-	case "include":
-		if val := mp.Map(strings.TrimSpace(rest)); val != "" {
-			return h.require(val, h)
-		}
-		return ""
-	default:
-		return html.EscapeString(mp.Map(rest))
+	if h.memo {
+		r.memo = map[string]string{}
 	}
+	h.HTMLTemplate.RLock()
+	r.audit, r.timeouts, r.hooks = h.HTMLTemplate.audit, h.HTMLTemplate.timeouts, h.HTMLTemplate.hooks
+	r.handlers, r.middlewares = h.HTMLTemplate.handlers, h.HTMLTemplate.middlewares
+	r.flags, r.policy = h.HTMLTemplate.flags, h.HTMLTemplate.policy
+	r.aliases, r.usages, r.norm = h.HTMLTemplate.aliases, h.HTMLTemplate.usages, h.HTMLTemplate.norm
+	r.guard, r.options = h.HTMLTemplate.guard, h.HTMLTemplate.options
+	r.stats, r.slow, r.fragments = h.HTMLTemplate.stats, h.HTMLTemplate.slow, h.HTMLTemplate.fragments
+	themes := h.HTMLTemplate.themes
+	h.HTMLTemplate.RUnlock()
+	r.theme = r.resolveTheme(themes)
+	return r
+}
+
+type NMapper interface {
+	places.Mapper
+	NMap(n int, sub string) places.Mapper
+	Len() int
+}
+
+type mapDelegate struct {
+	first, second places.Mapper
+}
 
+func (m *mapDelegate) Map(input string) string {
+	out := m.first.Map(input)
+	if out != "" {
+		return out
+	}
+	return m.second.Map(input)
 }
 
 /*
@@ -586,11 +724,74 @@ func New() Map {
 	return _map{}
 }
 
+// ConcurrentMap is a Map that is safe for concurrent use and may be reconfigured at runtime.
+type ConcurrentMap interface {
+	Map
+
+	// Set registers a mapper for the given prefix, overwriting any existing mapper
+	// If prefix does not conform to the regular expression ^[a-z]+$, ErrInvalidPrefix is returned
+	Set(prefix string, mapper places.Mapper) error
+
+	// Delete removes the mapper for the given prefix
+	Delete(prefix string)
+
+	// Snapshot returns an immutable copy of the current registry, so that a consistent view
+	// can be used while the registry is being reconfigured
+	Snapshot() Map
+
+	// Swap replaces the whole content of the registry with the content of the given Map at once.
+	// If other is not a Map returned by New, NewConcurrent or Snapshot, ErrUnsupportedMap is returned.
+	Swap(other Map) error
+}
+
+var ErrImmutable = errors.New("map is immutable")
+
+var ErrUnsupportedMap = errors.New("map is not created by the placesmap package")
+
 // NewConcurrent returns a new Map that is safe for concurrent use.
-func NewConcurrent() Map {
+func NewConcurrent() ConcurrentMap {
 	return &_map_concurrent{m: _map{}}
 }
 
+// copy returns a copy of the map
+func (mp _map) copy() _map {
+	c := make(_map, len(mp))
+	for k, v := range mp {
+		c[k] = v
+	}
+	return c
+}
+
+// _map_frozen is an immutable snapshot of a Map
+type _map_frozen struct {
+	m _map
+}
+
+// Add always returns ErrImmutable
+func (f _map_frozen) Add(prefix string, mapper places.Mapper) error {
+	return ErrImmutable
+}
+
+func (f _map_frozen) Map(input string) string {
+	return f.m.Map(input)
+}
+
+// entries returns a copy of the entries of the given Map
+func entries(m Map) (_map, error) {
+	switch v := m.(type) {
+	case _map:
+		return v.copy(), nil
+	case _map_frozen:
+		return v.m.copy(), nil
+	case *_map_concurrent:
+		v.mx.RLock()
+		defer v.mx.RUnlock()
+		return v.m.copy(), nil
+	default:
+		return nil, ErrUnsupportedMap
+	}
+}
+
 type _map_concurrent struct {
 	mx sync.RWMutex
 	m  _map
@@ -609,3 +810,40 @@ func (c *_map_concurrent) Map(input string) string {
 	c.mx.RUnlock()
 	return res
 }
+
+func (c *_map_concurrent) Set(prefix string, mapper places.Mapper) error {
+	if prefix != "" && !validPrefix(prefix) {
+		return ErrInvalidPrefix
+	}
+	c.mx.Lock()
+	c.m[prefix] = mapper
+	c.mx.Unlock()
+	return nil
+}
+
+func (c *_map_concurrent) Delete(prefix string) {
+	c.mx.Lock()
+	delete(c.m, prefix)
+	c.mx.Unlock()
+}
+
+func (c *_map_concurrent) Snapshot() Map {
+	c.mx.RLock()
+	f := _map_frozen{m: c.m.copy()}
+	c.mx.RUnlock()
+	return f
+}
+
+func (c *_map_concurrent) Swap(other Map) error {
+	if other == Map(c) {
+		return nil
+	}
+	m, err := entries(other)
+	if err != nil {
+		return err
+	}
+	c.mx.Lock()
+	c.m = m
+	c.mx.Unlock()
+	return nil
+}
@@ -1 +1,171 @@
 package placesmap
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTemplate returns a HTMLTemplate for the given name/template pairs
+func newTemplate(pairs ...string) *HTMLTemplate {
+	rs := NewReadSeekerMap()
+	for i := 0; i < len(pairs); i += 2 {
+		rs.Add(pairs[i], strings.NewReader(pairs[i+1]))
+	}
+	return NewHTMLTemplate(rs)
+}
+
+// names returns mappers that map "name" to the given names
+func names(nms ...string) []places.Mapper {
+	res := make([]places.Mapper, len(nms))
+	for i, n := range nms {
+		res[i] = String(n)
+	}
+	return res
+}
+
+// list is a NMapper for the tests
+type list []places.Mapper
+
+func (l list) Map(string) string                  { return "" }
+func (l list) Len() int                           { return len(l) }
+func (l list) NMap(n int, _ string) places.Mapper { return l[n] }
+
+func TestOverride(t *testing.T) {
+	tpl := newTemplate("page.html", "<@-require banner.html@>", "banner.html", "welcome")
+	m := tpl.NewMapper(nil)
+
+	tpl.Override("banner.html", []byte("maintenance"))
+	if got := m.Map("-require page.html"); got != "maintenance" {
+		t.Errorf("unexpected result with override: %#v", got)
+	}
+
+	tpl.RemoveOverride("banner.html")
+	if got := m.Map("-require page.html"); got != "welcome" {
+		t.Errorf("unexpected result after removing override: %#v", got)
+	}
+}
+
+func TestConcurrentMapSnapshotSwap(t *testing.T) {
+	m := NewConcurrent()
+	m.Add("", String("old"))
+	snap := m.Snapshot()
+
+	if err := snap.Add("x", String("x")); err != ErrImmutable {
+		t.Errorf("expected ErrImmutable, got %v", err)
+	}
+
+	m.Set("", String("new"))
+	if got := snap.Map("key"); got != "old" {
+		t.Errorf("snapshot changed: %#v", got)
+	}
+
+	if got := m.Map("key"); got != "new" {
+		t.Errorf("unexpected result after Set: %#v", got)
+	}
+
+	rebuilt := New()
+	rebuilt.Add("x", String("rebuilt"))
+	if err := m.Swap(rebuilt); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, got2 := m.Map("key"), m.Map("-x key"); got != "" || got2 != "rebuilt" {
+		t.Errorf("unexpected result after Swap: %#v, %#v", got, got2)
+	}
+
+	m.Delete("x")
+	if got := m.Map("-x key"); got != "" {
+		t.Errorf("unexpected result after Delete: %#v", got)
+	}
+
+	if err := m.Swap(foreignMap{}); err != ErrUnsupportedMap {
+		t.Errorf("expected ErrUnsupportedMap, got %v", err)
+	}
+}
+
+// foreignMap is a Map that is not created by this package
+type foreignMap struct{ MapFunc }
+
+func (foreignMap) Add(string, places.Mapper) error { return nil }
+
+// keyed returns a mapper that maps only "name" to the given name
+func keyed(name string) places.Mapper {
+	return MapFunc(func(key string) string {
+		if key == "name" {
+			return name
+		}
+		return ""
+	})
+}
+
+func TestWithMemo(t *testing.T) {
+	tpl := newTemplate("page.html", "<@name@> <@-require part.html@>", "part.html", "<@name@><@-url name@>")
+	var calls int
+	name := MapFunc(func(string) string {
+		calls++
+		return "a b"
+	})
+
+	for memo, expCalls := range map[bool]int{false: 3, true: 1} {
+		calls = 0
+		hm := tpl.NewMapper(map[string]places.Mapper{"name": name})
+		if memo {
+			hm = hm.WithMemo()
+		}
+		got := hm.Map("-require page.html")
+		if exp := "a b a ba+b"; got != exp {
+			t.Errorf("memo %v: unexpected result: %#v, expected: %#v", memo, got, exp)
+		}
+		if calls != expCalls {
+			t.Errorf("memo %v: unexpected number of calls: %d, expected: %d", memo, calls, expCalls)
+		}
+	}
+}
+
+func TestTemplateLoaderConcurrency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placesmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i := 0; i < 50; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("d%d", i%5), fmt.Sprintf("t%02d.html", i))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(fmt.Sprint(i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := NewTemplateLoader(dir, ".html", nil)
+	l.SetConcurrency(4)
+	rs, err := l.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		name := filepath.Join(fmt.Sprintf("d%d", i%5), fmt.Sprintf("t%02d.html", i))
+		if got := rs.Map(name); got != fmt.Sprint(i) {
+			t.Errorf("%s: unexpected content: %#v", name, got)
+		}
+	}
+
+	// broken symlinks can't be read, the first one in walk order is reported
+	for _, name := range []string{"b.html", "a.html", "c.html"} {
+		if err := os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "d3", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		_, err := l.Load()
+		if err == nil || !strings.Contains(err.Error(), filepath.Join("d3", "a.html")) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+package placestest provides helpers for testing the mappers and templates of an app.
+
+Since mappers are usually shared by all requests, they must be safe for concurrent renders.
+Hammer renders a page from many goroutines at the same time and reports renders that differ
+from a sequential render. Together with the race detector (go test -race) it finds mappers that
+keep state without synchronization:
+
+	func TestPageConcurrency(t *testing.T) {
+		placestest.Hammer(t, func() (string, error) {
+			return tpl.NewMapper(mappers).MapStrict("-require page.html")
+		}, 16, 100)
+	}
+*/
+package placestest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// MaxReports is the maximum number of differing renders that Hammer reports
+var MaxReports = 5
+
+// Hammer calls render concurrently from the given number of goroutines, iterations times each,
+// and reports every result that differs from the result of a sequential call before (at most
+// MaxReports), including errors and panics. render must return the same result for every call.
+func Hammer(t testing.TB, render func() (string, error), concurrency, iterations int) {
+	t.Helper()
+	expOut, expErr := call(render)
+
+	var (
+		wg      sync.WaitGroup
+		mx      sync.Mutex
+		reports int
+		start   = make(chan struct{})
+	)
+	report := func(format string, args ...interface{}) {
+		mx.Lock()
+		defer mx.Unlock()
+		reports++
+		if reports <= MaxReports {
+			t.Errorf(format, args...)
+		}
+	}
+	for g := 0; g < concurrency; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			// start all goroutines at once to maximize the overlap of the renders
+			<-start
+			for i := 0; i < iterations; i++ {
+				out, err := call(render)
+				if out != expOut {
+					report("goroutine %d, iteration %d: unexpected result: %#v, expected: %#v", g, i, out, expOut)
+				}
+				if fmt.Sprint(err) != fmt.Sprint(expErr) {
+					report("goroutine %d, iteration %d: unexpected error: %v, expected: %v", g, i, err, expErr)
+				}
+			}
+		}(g)
+	}
+	close(start)
+	wg.Wait()
+	if reports > MaxReports {
+		t.Errorf("%d more differing renders", reports-MaxReports)
+	}
+}
+
+// call calls render and turns panics into errors
+func call(render func() (string, error)) (out string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return render()
+}
@@ -0,0 +1,61 @@
+package placestest
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// recorder records the errors reported by Hammer
+type recorder struct {
+	testing.TB
+	mx     sync.Mutex
+	errors int
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(string, ...interface{}) {
+	r.mx.Lock()
+	r.errors++
+	r.mx.Unlock()
+}
+
+func TestHammer(t *testing.T) {
+	r := &recorder{TB: t}
+	Hammer(r, func() (string, error) { return "page", nil }, 8, 50)
+	if r.errors != 0 {
+		t.Errorf("stable renders must not be reported, got %d errors", r.errors)
+	}
+
+	var n int32
+	r = &recorder{TB: t}
+	Hammer(r, func() (string, error) {
+		switch atomic.AddInt32(&n, 1) {
+		case 5:
+			return "other", nil
+		case 6:
+			return "page", errors.New("failed")
+		case 7:
+			panic("boom")
+		}
+		return "page", nil
+	}, 4, 10)
+	// the panic has no result and an error
+	if r.errors != 4 {
+		t.Errorf("expected 4 errors, got %d", r.errors)
+	}
+
+	n = 0
+	r = &recorder{TB: t}
+	Hammer(r, func() (string, error) {
+		if atomic.AddInt32(&n, 1) > 1 {
+			return "other", nil
+		}
+		return "page", nil
+	}, 4, 10)
+	if r.errors != MaxReports+1 {
+		t.Errorf("expected %d errors, got %d", MaxReports+1, r.errors)
+	}
+}
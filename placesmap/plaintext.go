@@ -0,0 +1,157 @@
+package placesmap
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// blockElements are the elements that start a new paragraph within plain text
+var blockElements = map[string]bool{
+	"p": true, "div": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "table": true, "tr": true, "blockquote": true, "pre": true, "hr": true,
+	"section": true, "article": true, "header": true, "footer": true, "main": true, "nav": true,
+}
+
+// skipElements are the elements whose content is no text
+var skipElements = map[string]bool{"head": true, "script": true, "style": true, "template": true}
+
+// attrValue returns the value of the attribute with the given name within the given tag
+func attrValue(tag, name string) string {
+	lower := strings.ToLower(tag)
+	for start := 0; ; {
+		idx := strings.Index(lower[start:], name+"=")
+		if idx == -1 {
+			return ""
+		}
+		idx += start
+		start = idx + len(name) + 1
+		if c := lower[idx-1]; c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			continue
+		}
+		v := tag[start:]
+		if v == "" {
+			return ""
+		}
+		if q := v[0]; q == '"' || q == '\'' {
+			if end := strings.IndexByte(v[1:], q); end != -1 {
+				return html.UnescapeString(v[1 : end+1])
+			}
+			return ""
+		}
+		if end := strings.IndexAny(v, " \t\n\r>"); end != -1 {
+			v = v[:end]
+		}
+		return html.UnescapeString(strings.TrimSuffix(v, "/"))
+	}
+}
+
+// textWriter collects the plain text with normalized whitespace
+type textWriter struct {
+	b     strings.Builder
+	space bool // a space is pending
+	lines int  // the number of pending line breaks
+}
+
+func (t *textWriter) text(s string) {
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			t.space = true
+			continue
+		}
+		if t.b.Len() > 0 {
+			switch {
+			case t.lines > 0:
+				t.b.WriteString(strings.Repeat("\n", t.lines))
+			case t.space:
+				t.b.WriteByte(' ')
+			}
+		}
+		t.space, t.lines = false, 0
+		t.b.WriteRune(r)
+	}
+}
+
+// lineBreak requests n line breaks before the next text
+func (t *textWriter) lineBreak(n int) {
+	if n > t.lines {
+		t.lines = n
+	}
+}
+
+// PlainText converts the given HTML to plain text, e.g. for the text part of an email: tags are removed,
+// entities are decoded, whitespace is normalized, block elements become paragraphs, list items
+// start with "- " and links are followed by their URL in parentheses.
+func PlainText(s string) string {
+	var (
+		t     textWriter
+		hrefs []string // the URLs of the open links
+	)
+
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "<!--") {
+			end := strings.Index(s[i:], "-->")
+			if end == -1 {
+				break
+			}
+			i += end + 3
+			continue
+		}
+
+		end := -1
+		if s[i] == '<' {
+			end = strings.IndexByte(s[i:], '>')
+		}
+		if end == -1 {
+			next := strings.IndexByte(s[i+1:], '<')
+			if next == -1 {
+				next = len(s) - i - 1
+			}
+			t.text(html.UnescapeString(s[i : i+next+1]))
+			i += next + 1
+			continue
+		}
+
+		tag := s[i+1 : i+end]
+		i += end + 1
+		name, isEnd := tagName(tag)
+
+		switch {
+		case skipElements[name] && !isEnd:
+			close := strings.Index(strings.ToLower(s[i:]), "</"+name)
+			if close == -1 {
+				i = len(s)
+				continue
+			}
+			i += close
+		case name == "br":
+			t.lineBreak(1)
+		case name == "li" && !isEnd:
+			t.lineBreak(1)
+			t.text("-")
+			t.space = true
+		case name == "td" || name == "th":
+			if !isEnd {
+				t.space = true
+			}
+		case name == "a" && !isEnd:
+			hrefs = append(hrefs, attrValue(tag, "href"))
+		case name == "a" && len(hrefs) > 0:
+			href := hrefs[len(hrefs)-1]
+			hrefs = hrefs[:len(hrefs)-1]
+			if href != "" && !strings.HasPrefix(href, "#") && !strings.HasSuffix(t.b.String(), href) {
+				t.text(" (" + href + ")")
+			}
+		case blockElements[name]:
+			t.lineBreak(2)
+		}
+	}
+	return t.b.String()
+}
+
+// MapDual renders the given input once and returns the HTML and its plain text version (see PlainText),
+// e.g. for the HTML and text bodies of an email, that are guaranteed to match.
+func (h *HTMLTemplateMapper) MapDual(input string) (htmlBody, textBody string) {
+	htmlBody = h.Map(input)
+	return htmlBody, PlainText(htmlBody)
+}
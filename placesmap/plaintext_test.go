@@ -0,0 +1,36 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := map[string]string{
+		"Hello   <b>World</b>\n":                              "Hello World",
+		"<h1>Title</h1><p>First\nline</p><p>Second</p>":       "Title\n\nFirst line\n\nSecond",
+		"a<br>b<br/>c":                                        "a\nb\nc",
+		"<ul><li>one</li><li>two &amp; three</li></ul>end":    "- one\n- two & three\n\nend",
+		`<a href="https://example.com/?a=1&amp;b=2">Shop</a>`: "Shop (https://example.com/?a=1&b=2)",
+		`<a href=#top>Top</a> <a href='/x'>/x</a>`:            "Top /x",
+		"<head><title>T</title><style>p{}</style></head>x":    "x",
+		"<!-- c -->a<script>if (a < b) {}</script> b":         "a b",
+		"<table><tr><td>1</td><td>2</td></tr></table>":        "1 2",
+		"1 < 2 ok": "1 < 2 ok",
+	}
+
+	for input, exp := range tests {
+		if got := PlainText(input); got != exp {
+			t.Errorf("%#v: unexpected result: %#v, expected: %#v", input, got, exp)
+		}
+	}
+
+	tpl := newTemplate("mail.html", "<p>Hello <@name@>,</p><p>your order.</p>")
+	htmlBody, textBody := tpl.NewMapper(map[string]places.Mapper{"name": String("Tom & Jerry")}).MapDual("-require mail.html")
+	if exp := "<p>Hello Tom &amp; Jerry,</p><p>your order.</p>"; htmlBody != exp {
+		t.Errorf("unexpected html: %#v, expected: %#v", htmlBody, exp)
+	}
+	if exp := "Hello Tom & Jerry,\n\nyour order."; textBody != exp {
+		t.Errorf("unexpected text: %#v, expected: %#v", textBody, exp)
+	}
+}
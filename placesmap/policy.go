@@ -0,0 +1,40 @@
+package placesmap
+
+import (
+	"context"
+	"fmt"
+)
+
+// Policy decides whether a placeholder may be resolved, e.g. to restrict the templates of
+// untrusted tenants. It is called with the context of the render (see NewMapperContext), the
+// prefix and the rest of every placeholder (the prefix being empty for placeholders without prefix)
+// before it is resolved and for every template that is rendered as include (by -require, -each,
+// -if etc.) with the prefix "require" and the name of the template as rest.
+type Policy func(ctx context.Context, prefix, rest string) bool
+
+// PolicyError is reported to MapStrict for placeholders and includes that are denied by the Policy
+type PolicyError struct {
+	Prefix string
+	Rest   string
+}
+
+func (p PolicyError) Error() string {
+	return fmt.Sprintf("placeholder with prefix %#v and rest %#v denied by policy", p.Prefix, p.Rest)
+}
+
+// SetPolicy sets the Policy for all renders that are started afterwards, nil allows everything.
+// Denied placeholders and includes are rendered as empty string.
+func (h *HTMLTemplate) SetPolicy(p Policy) {
+	h.Lock()
+	h.policy = p
+	h.Unlock()
+}
+
+// allowed returns whether the Policy allows the given placeholder and records a PolicyError if not
+func (r *RenderContext) allowed(prefix, rest string) bool {
+	if r.policy == nil || r.policy(r.ctx, prefix, rest) {
+		return true
+	}
+	r.fail(PolicyError{Prefix: prefix, Rest: rest})
+	return false
+}
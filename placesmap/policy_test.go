@@ -0,0 +1,32 @@
+package placesmap
+
+import (
+	"context"
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	tpl := newTemplate(
+		"tenant.html", "<@-require secret.html@>|<@-raw html@>|<@-html html@>|<@-if x part.html@>",
+		"part.html", "part",
+		"secret.html", "secret",
+	)
+	tpl.SetPolicy(func(ctx context.Context, prefix, rest string) bool {
+		return prefix != "raw" && !(prefix == "require" && rest == "secret.html")
+	})
+
+	hm := tpl.NewMapper(map[string]places.Mapper{"html": String("<b>"), "x": String("1")})
+	got, err := hm.MapStrict("-require tenant.html")
+	if exp := "||<b>|part"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if exp := (PolicyError{Prefix: "require", Rest: "secret.html"}); err != exp {
+		t.Errorf("unexpected error: %v, expected: %v", err, exp)
+	}
+
+	tpl.SetPolicy(nil)
+	if got, exp := hm.Map("-require tenant.html"), "secret|<b>|<b>|part"; got != exp {
+		t.Errorf("unexpected result without policy: %#v, expected: %#v", got, exp)
+	}
+}
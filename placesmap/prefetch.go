@@ -0,0 +1,129 @@
+package placesmap
+
+import "github.com/metakeule/places"
+
+// MaxPrefetch is the maximum number of includes that a streaming render renders ahead at the
+// same time, see WithIncludePrefetch
+var MaxPrefetch = 8
+
+// WithIncludePrefetch returns a HTMLTemplateMapper with the same mappers whose streaming renders
+// (see Pipe) render the includes of the streamed template ahead: the "-require"s of the template
+// start to render in the order of occurrence, up to MaxPrefetch at the same time, as soon as the
+// streaming starts, so that I/O-bound mappers of later includes run while the earlier parts of
+// the page are written to the reader. The output is the same as without prefetch, the includes
+// rendered ahead count towards the MaxIncludes of the render.
+// Includes after a "-set" (or after a placeholder that includes a template with "-set", according
+// to the dependency graph) are rendered in order, since they may depend on the local names.
+// The mappers must be safe for concurrent use and may be called more than once per key, even
+// with WithMemo.
+func (h *HTMLTemplateMapper) WithIncludePrefetch() *HTMLTemplateMapper {
+	c := h.copy()
+	c.prefetch = true
+	return c
+}
+
+// prefetchResult is the result of an include that has been rendered ahead
+type prefetchResult struct {
+	out string
+	c   *RenderContext
+}
+
+// prefetcher maps the includes of a streamed template to the results of their renders ahead,
+// other placeholders are mapped by the RenderContext
+type prefetcher struct {
+	r       *RenderContext
+	pending map[string][]chan prefetchResult // by placeholder in the order of occurrence
+}
+
+func (p *prefetcher) Map(input string) string {
+	q := p.pending[input]
+	if len(q) == 0 {
+		return p.r.Map(input)
+	}
+	p.pending[input] = q[1:]
+	res := <-q[0]
+	if p.r.err == nil {
+		p.r.err = res.c.err
+	}
+	p.r.errs = append(p.r.errs, res.c.errs...)
+	return res.out
+}
+
+// prefetchJob is an include that is rendered ahead by c
+type prefetchJob struct {
+	input string
+	c     *RenderContext
+	res   chan prefetchResult
+}
+
+// prefetch starts to render the includes of the given template ahead (see WithIncludePrefetch)
+// and returns the mapper that writes their results
+func (r *RenderContext) prefetch(t *places.Template) places.Mapper {
+	p := &prefetcher{r: r, pending: map[string][]chan prefetchResult{}}
+	var jobs []prefetchJob
+	for _, ph := range t.Placeholders() {
+		if r.hm.HTMLTemplate.setsLocals(ph) {
+			break
+		}
+		if prefix, _ := split(ph); prefix != "require" {
+			continue
+		}
+		// the clones share the include counter of the render, see push
+		c := r.clone(r.ctx)
+		c.err, c.errs, c.linted = nil, nil, nil
+		res := make(chan prefetchResult, 1)
+		jobs = append(jobs, prefetchJob{input: ph, c: c, res: res})
+		p.pending[ph] = append(p.pending[ph], res)
+	}
+
+	// the jobs are taken in order, so the include that is written next is always rendered
+	queue := make(chan prefetchJob, len(jobs))
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	workers := MaxPrefetch
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range queue {
+				job.res <- prefetchResult{out: job.c.Map(job.input), c: job.c}
+			}
+		}()
+	}
+	return p
+}
+
+// setsLocals returns whether the given placeholder is a "-set" or includes a template that has
+// a "-set" placeholder, directly, within its inline bodies or within its includes
+func (h *HTMLTemplate) setsLocals(placeholder string) bool {
+	return h.includesSet(placeholder, map[string]bool{})
+}
+
+// includesSet is like setsLocals, seen are the templates that have been checked
+func (h *HTMLTemplate) includesSet(placeholder string, seen map[string]bool) bool {
+	if prefix, _ := split(placeholder); prefix == "set" {
+		return true
+	}
+	for _, ref := range references(placeholder) {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		t, has := h.template(ref)
+		if !has {
+			continue
+		}
+		for _, ph := range t.Placeholders() {
+			if h.includesSet(ph, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIncludePrefetch(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<h1><@-require a.html@></h1><@-require b.html@><@-set x 1@><@-require c.html@><@-require missing.html@>",
+		"a.html", "<@a@>", "b.html", "<@b@>", "c.html", "<@x@><@c@>",
+	)
+	var (
+		bStarted = make(chan bool)
+		cMapped  int32
+	)
+	m := map[string]places.Mapper{
+		// a waits for b, so that the page can only be rendered, if b is rendered ahead
+		"a": MapFunc(func(string) string {
+			select {
+			case <-bStarted:
+				return "A"
+			case <-time.After(time.Second):
+				return "timeout"
+			}
+		}),
+		"b": MapFunc(func(string) string {
+			close(bStarted)
+			return "B"
+		}),
+		"c": MapFunc(func(string) string {
+			atomic.StoreInt32(&cMapped, 1)
+			return "C"
+		}),
+	}
+
+	rd, err := tpl.NewMapper(m).WithIncludePrefetch().Pipe("page.html", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(rd)
+	if exp := "<h1>A</h1>B1C"; string(got) != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", string(got), exp)
+	}
+	if err != TemplateNotFoundError("missing.html") {
+		t.Errorf("expected the error of the render, got %v", err)
+	}
+	if atomic.LoadInt32(&cMapped) != 1 {
+		t.Errorf("c must be mapped")
+	}
+
+	if !tpl.setsLocals("-if x c.html else page.html") || tpl.setsLocals("-require a.html") {
+		t.Errorf("unexpected result of setsLocals")
+	}
+
+	defer func(max, prefetch int) { MaxIncludes, MaxPrefetch = max, prefetch }(MaxIncludes, MaxPrefetch)
+	MaxIncludes, MaxPrefetch = 3, 2
+	tpl = newTemplate("page.html", strings.Repeat("<@-require n.html@>", 5), "n.html", "<@n@>")
+	var running, most int32
+	m = map[string]places.Mapper{"n": MapFunc(func(string) string {
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			prev := atomic.LoadInt32(&most)
+			if cur <= prev || atomic.CompareAndSwapInt32(&most, prev, cur) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return "x"
+	})}
+	rd, err = tpl.NewMapper(m).WithIncludePrefetch().Pipe("page.html", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the page and two includes reach MaxIncludes
+	if got, _ := ioutil.ReadAll(rd); string(got) != "xx" {
+		t.Errorf("unexpected result with MaxIncludes: %#v, expected: %#v", string(got), "xx")
+	}
+	if n := atomic.LoadInt32(&most); n > 2 {
+		t.Errorf("%d includes rendered ahead at the same time, expected at most %d", n, MaxPrefetch)
+	}
+}
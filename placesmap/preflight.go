@@ -0,0 +1,164 @@
+package placesmap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MissingInclude is a template that is referenced but does not exist
+type MissingInclude struct {
+	Template string // the referencing template, empty for the required includes
+	Include  string
+}
+
+// MissingPlaceholder is a placeholder that is required for a template but not declared by it
+type MissingPlaceholder struct {
+	Template    string
+	Placeholder string
+}
+
+// PreflightError lists the problems found by Preflight
+type PreflightError struct {
+	MissingIncludes     []MissingInclude
+	MissingPlaceholders []MissingPlaceholder
+}
+
+func (p PreflightError) Error() string {
+	var problems []string
+	for _, m := range p.MissingIncludes {
+		if m.Template == "" {
+			problems = append(problems, fmt.Sprintf("missing template %#v", m.Include))
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("missing template %#v, included by %#v", m.Include, m.Template))
+	}
+	for _, m := range p.MissingPlaceholders {
+		problems = append(problems, fmt.Sprintf("placeholder %#v not declared by %#v", m.Placeholder, m.Template))
+	}
+	return "preflight failed: " + strings.Join(problems, "; ")
+}
+
+// references returns the names of the templates that the given placeholder renders
+// via the built-in directives
+func references(placeholder string) (names []string) {
+	prefix, rest := split(placeholder)
+	rest = strings.TrimSpace(rest)
+	switch prefix {
+	case "require":
+		names = []string{rest}
+	case "each", "if", "with", "flag":
+		s := strings.SplitN(rest, " ", 2)
+		if len(s) == 2 {
+			then, otherwise := splitElse(s[1])
+			names = []string{then, otherwise}
+		}
+	case "eq", "ne", "gt", "ge", "lt", "le":
+		if _, _, _, then, otherwise, ok := splitCompare(rest); ok {
+			names = []string{then, otherwise}
+		}
+	case "tree":
+		if args := splitArgs(rest, 2); args != nil {
+			names = []string{args[1]}
+		}
+	case "island":
+		if args := splitArgs(rest, 3); args != nil {
+			names = []string{args[2]}
+		}
+	}
+
+	res := names[:0]
+	for _, n := range names {
+		if n != "" {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// declares returns the name that the given placeholder declares: the placeholder itself or
+// the first argument of a directive (e.g. body for "-html body")
+func declares(placeholder string) string {
+	prefix, rest := split(placeholder)
+	if prefix == "" {
+		return rest
+	}
+	if f := strings.Fields(rest); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
+// declared adds the names declared by the template with the given name and its includes
+func (h *HTMLTemplate) declared(name string, names map[string]bool, visited map[string]bool) {
+	if visited[name] {
+		return
+	}
+	visited[name] = true
+	t, has := h.template(name)
+	if !has {
+		return
+	}
+	for _, ph := range t.Placeholders() {
+		names[declares(ph)] = true
+		for _, inc := range references(ph) {
+			h.declared(inc, names, visited)
+		}
+	}
+}
+
+// Preflight verifies at startup that the given required includes exist, that all templates
+// that are referenced by the directives -require, -each, -if, -with, -eq etc., -flag, -tree and -island exist
+// and that the templates in requiredPlaceholders declare the given placeholders (directly or via
+// their includes), so that missing templates and placeholders are reported before any page is served.
+// A placeholder is declared, if the template contains it or a directive with it as first argument,
+// e.g. <@title@> and <@-html title@> both declare title.
+// If there are problems, a PreflightError is returned.
+func (h *HTMLTemplate) Preflight(requiredIncludes []string, requiredPlaceholders map[string][]string) error {
+	var e PreflightError
+
+	for _, name := range requiredIncludes {
+		if !h.Has(name) {
+			e.MissingIncludes = append(e.MissingIncludes, MissingInclude{Include: name})
+		}
+	}
+
+	for _, name := range h.names() {
+		t, has := h.template(name)
+		if !has {
+			continue
+		}
+		for _, ph := range t.Placeholders() {
+			for _, inc := range references(ph) {
+				if !h.Has(inc) {
+					e.MissingIncludes = append(e.MissingIncludes, MissingInclude{Template: name, Include: inc})
+				}
+			}
+		}
+	}
+
+	tpls := make([]string, 0, len(requiredPlaceholders))
+	for k := range requiredPlaceholders {
+		tpls = append(tpls, k)
+	}
+	sort.Strings(tpls)
+
+	for _, name := range tpls {
+		if !h.Has(name) {
+			e.MissingIncludes = append(e.MissingIncludes, MissingInclude{Include: name})
+			continue
+		}
+		names := map[string]bool{}
+		h.declared(name, names, map[string]bool{})
+		for _, ph := range requiredPlaceholders[name] {
+			if !names[ph] {
+				e.MissingPlaceholders = append(e.MissingPlaceholders, MissingPlaceholder{Template: name, Placeholder: ph})
+			}
+		}
+	}
+
+	if len(e.MissingIncludes) == 0 && len(e.MissingPlaceholders) == 0 {
+		return nil
+	}
+	return e
+}
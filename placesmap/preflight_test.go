@@ -0,0 +1,38 @@
+package placesmap
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@title@><@-require header.html@><@-each items row.html else empty.html@>",
+		"header.html", "<@-html logo@>",
+		"row.html", "<@name@>",
+		"other.html", "<@-if x yes.html@>",
+	)
+
+	err := tpl.Preflight([]string{"page.html", "footer.html"}, map[string][]string{
+		"page.html":  {"title", "logo", "items", "user"},
+		"index.html": {"title"},
+	})
+
+	exp := PreflightError{
+		MissingIncludes: []MissingInclude{
+			{Include: "footer.html"},
+			{Template: "other.html", Include: "yes.html"},
+			{Template: "page.html", Include: "empty.html"},
+			{Include: "index.html"},
+		},
+		MissingPlaceholders: []MissingPlaceholder{{Template: "page.html", Placeholder: "user"}},
+	}
+	if fmt.Sprint(err) != fmt.Sprint(exp) {
+		t.Errorf("unexpected error:\n%v\nexpected:\n%v", err, exp)
+	}
+
+	valid := newTemplate("page.html", "<@-require header.html@>", "header.html", "<@-html logo@>")
+	if err := valid.Preflight([]string{"page.html"}, map[string][]string{"page.html": {"logo"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
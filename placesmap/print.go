@@ -0,0 +1,212 @@
+package placesmap
+
+import (
+	"bytes"
+	"github.com/metakeule/places"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// PrintModeName is the name that is truthy for renders in print mode, e.g. "-if printmode print.html",
+// see WithPrintMode
+const PrintModeName = "printmode"
+
+// PrintProfile is the render profile of a print or PDF pipeline, see HTMLTemplateMapper.Print
+type PrintProfile struct {
+	// Stylesheet returns the content of the stylesheet with the given href, e.g. read from the
+	// directory of the static files. The stylesheets that are linked within the page are inlined
+	// as style tags, so that converters don't need to fetch them. nil keeps the links.
+	Stylesheet func(href string) ([]byte, error)
+
+	// Convert reads the rendered HTML and writes the converted document to w, e.g. an adapter for
+	// a HTML to PDF converter. nil writes the HTML.
+	Convert func(w io.Writer, html io.Reader) error
+
+	// ContentType is the content type of the converted document for RenderPrint, e.g. "application/pdf".
+	// The empty string means text/html in the charset of the template.
+	ContentType string
+
+	// Filename is the name of the file that RenderPrint offers as download.
+	// The empty string shows the document inline.
+	Filename string
+}
+
+// WithPrintMode returns a HTMLTemplateMapper with the same mappers whose renders are in print mode,
+// i.e. PrintModeName maps to "1", unless a mapper is registered for it
+func (h *HTMLTemplateMapper) WithPrintMode() *HTMLTemplateMapper {
+	c := h.copy()
+	c.print = true
+	return c
+}
+
+// Print renders the template with the given name in print mode and writes it via the profile to w:
+// linked stylesheets are inlined and the HTML is streamed into the converter of the profile.
+// The first error of the render (see MapStrict) is returned without writing anything.
+func (h *HTMLTemplateMapper) Print(w io.Writer, name string, p PrintProfile) error {
+	if !h.Has(name) {
+		return TemplateNotFoundError(name)
+	}
+	page, err := h.WithPrintMode().MapStrict("-require " + name)
+	if err != nil {
+		return err
+	}
+	if p.Stylesheet != nil {
+		if page, err = inlineStylesheets(page, p.Stylesheet); err != nil {
+			return err
+		}
+	}
+	if p.Convert == nil {
+		_, err = io.WriteString(w, page)
+		return err
+	}
+	return p.Convert(w, strings.NewReader(page))
+}
+
+// RenderPrint renders the template with the given name like Print to the ResponseWriter, with
+// the content type and file name of the profile. The HTML is encoded in the charset of the template.
+// If the template does not exist, TemplateNotFoundError is returned and nothing is written.
+func RenderPrint(wr http.ResponseWriter, t *HTMLTemplate, name string, m map[string]places.Mapper, p PrintProfile) error {
+	if !t.Has(name) {
+		return TemplateNotFoundError(name)
+	}
+	var bf bytes.Buffer
+	cs := t.Charset()
+	convert := p.Convert
+	p.Convert = func(w io.Writer, html io.Reader) error {
+		if convert != nil {
+			return convert(w, html)
+		}
+		_, err := io.Copy(cs.encoder(w), html)
+		return err
+	}
+	if err := t.NewMapper(m).Print(&bf, name, p); err != nil {
+		return err
+	}
+	contentType := p.ContentType
+	if contentType == "" {
+		contentType = cs.contentType()
+	}
+	wr.Header().Set("Content-Type", contentType)
+	disposition := "inline"
+	if p.Filename != "" {
+		disposition = mime.FormatMediaType("attachment", map[string]string{"filename": p.Filename})
+	}
+	wr.Header().Set("Content-Disposition", disposition)
+	_, err := wr.Write(bf.Bytes())
+	return err
+}
+
+// inlineStylesheets replaces the links to stylesheets within the page by style tags with the
+// content returned by stylesheet
+func inlineStylesheets(page string, stylesheet func(href string) ([]byte, error)) (string, error) {
+	lower := strings.ToLower(page)
+	var bf strings.Builder
+	last := 0
+	for i := strings.Index(lower, "<link"); i != -1; i = strings.Index(lower[last:], "<link") {
+		i += last
+		after := i + len("<link")
+		if after < len(page) && !strings.ContainsRune(" \t\r\n\f/>", rune(page[after])) {
+			// another tag, e.g. <links>
+			bf.WriteString(page[last:after])
+			last = after
+			continue
+		}
+		end := tagEnd(page, i)
+		if end == -1 {
+			break
+		}
+		attrs := tagAttrs(page[after:end])
+		if !isStylesheet(attrs["rel"]) || attrs["href"] == "" {
+			bf.WriteString(page[last:end])
+			last = end
+			continue
+		}
+		css, err := stylesheet(attrs["href"])
+		if err != nil {
+			return "", err
+		}
+		bf.WriteString(page[last:i])
+		bf.WriteString("<style")
+		if media, has := attrs["media"]; has {
+			bf.WriteString(` media="` + html.EscapeString(media) + `"`)
+		}
+		bf.WriteString(">")
+		// the stylesheet must not end the style tag
+		bf.WriteString(strings.Replace(string(css), "</", `<\/`, -1))
+		bf.WriteString("</style>")
+		last = end
+	}
+	bf.WriteString(page[last:])
+	return bf.String(), nil
+}
+
+// isStylesheet returns whether the given rel attribute contains the link type stylesheet
+func isStylesheet(rel string) bool {
+	for _, t := range strings.Fields(strings.ToLower(rel)) {
+		if t == "stylesheet" {
+			return true
+		}
+	}
+	return false
+}
+
+// tagEnd returns the position after the end of the tag that starts at the given position,
+// -1 if the tag is not closed
+func tagEnd(page string, start int) int {
+	var quote byte
+	for i := start; i < len(page); i++ {
+		switch c := page[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// tagAttrs returns the unescaped values of the attributes of a tag by their lower cased names
+func tagAttrs(s string) map[string]string {
+	attrs := map[string]string{}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, ">"), "/")
+	for {
+		s = strings.TrimLeft(s, " \t\r\n\f/")
+		if s == "" {
+			return attrs
+		}
+		n := strings.IndexAny(s, " \t\r\n\f=/")
+		if n == -1 {
+			n = len(s)
+		}
+		name := strings.ToLower(s[:n])
+		s = strings.TrimLeft(s[n:], " \t\r\n\f")
+		if !strings.HasPrefix(s, "=") {
+			attrs[name] = ""
+			continue
+		}
+		s = strings.TrimLeft(s[1:], " \t\r\n\f")
+		var val string
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end == -1 {
+				val, s = s[1:], ""
+			} else {
+				val, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t\r\n\f")
+			if end == -1 {
+				end = len(s)
+			}
+			val, s = s[:end], s[end:]
+		}
+		attrs[name] = html.UnescapeString(val)
+	}
+}
@@ -0,0 +1,61 @@
+package placesmap
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderPrint(t *testing.T) {
+	tpl := newTemplate("invoice.html", `<head><link rel="stylesheet" href="/css/print.css?v=1&amp;x=2" media="print"><link rel="icon" href="i.png"><links></links></head>`+
+		`<@-if printmode print.html else screen.html@>`, "print.html", "print", "screen.html", "screen")
+
+	var hrefs []string
+	p := PrintProfile{
+		Stylesheet: func(href string) ([]byte, error) {
+			hrefs = append(hrefs, href)
+			return []byte("body{color:black}</style>"), nil
+		},
+		Convert: func(w io.Writer, html io.Reader) error {
+			b, err := ioutil.ReadAll(html)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "%%PDF %s", b)
+			return err
+		},
+		ContentType: "application/pdf",
+		Filename:    "invoice 1.pdf",
+	}
+
+	rec := httptest.NewRecorder()
+	if err := RenderPrint(rec, tpl, "invoice.html", nil, p); err != nil {
+		t.Fatal(err)
+	}
+	exp := `%PDF <head><style media="print">body{color:black}<\/style></style><link rel="icon" href="i.png"><links></links></head>print`
+	if got := rec.Body.String(); got != exp {
+		t.Errorf("unexpected body: %#v, expected: %#v", got, exp)
+	}
+	if got, exp := strings.Join(hrefs, ","), "/css/print.css?v=1&x=2"; got != exp {
+		t.Errorf("unexpected stylesheets: %#v, expected: %#v", got, exp)
+	}
+	if got, exp := rec.Header().Get("Content-Type"), "application/pdf"; got != exp {
+		t.Errorf("unexpected content type: %#v, expected: %#v", got, exp)
+	}
+	if got, exp := rec.Header().Get("Content-Disposition"), `attachment; filename="invoice 1.pdf"`; got != exp {
+		t.Errorf("unexpected content disposition: %#v, expected: %#v", got, exp)
+	}
+
+	// without print mode
+	if got := tpl.NewMapper(nil).Map("-if printmode print.html else screen.html"); got != "screen" {
+		t.Errorf("unexpected result outside of print mode: %#v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	if err := RenderPrint(rec, tpl, "missing.html", nil, p); err != TemplateNotFoundError("missing.html") || rec.Body.Len() != 0 {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap/placestest"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// The render of a shared HTMLTemplateMapper must be safe for concurrent use with all features
+// that keep state between or within renders. Run with go test -race.
+
+func raceTemplate() *HTMLTemplate {
+	tpl := newTemplate(
+		"page.html", "<script>x</script><h1><@title@></h1><@-each items@><@-if name@><@-require row.html@><@-else@>none<@-end@><@-end@>"+
+			"<@-require cached.html@><@-time now date@><@-calc 1 + 2@><@-require missing.html@>",
+		"row.html", "<li><@name@> <@-bool checked name@></li>",
+		"cached.html", "---\ncache_ttl: 1m\n---\n<p><@title@></p>",
+	)
+	tpl.EnableFragmentCache(time.Minute)
+	tpl.EnableStats()
+	tpl.SetSlowRender(time.Hour)
+	tpl.SetAccessLog(AccessLog{Header: "X-Templates", Record: func(Composition) {}})
+	tpl.SetRenderOptions(RenderOptions{Deterministic: true})
+	tpl.SetCSP("script-src {nonce}")
+	return tpl
+}
+
+func raceMappers() map[string]places.Mapper {
+	return map[string]places.Mapper{
+		"title": String("a & b"),
+		"items": list{keyed("x"), keyed(""), keyed("z")},
+	}
+}
+
+func TestRaceMapStrict(t *testing.T) {
+	tpl := raceTemplate()
+	for _, hm := range []*HTMLTemplateMapper{
+		tpl.NewMapper(raceMappers()),
+		tpl.NewMapper(raceMappers()).WithMemo(),
+		tpl.NewMapper(raceMappers()).WithAllErrors(),
+	} {
+		placestest.Hammer(t, func() (string, error) {
+			return hm.MapStrict("-require page.html")
+		}, 8, 50)
+	}
+}
+
+func TestRaceRender(t *testing.T) {
+	tpl := raceTemplate()
+	m := raceMappers()
+	placestest.Hammer(t, func() (string, error) {
+		rec := httptest.NewRecorder()
+		err := Render(rec, tpl, "page.html", m)
+		return rec.Header().Get("X-Templates") + rec.Body.String(), err
+	}, 8, 50)
+}
+
+func TestRacePipe(t *testing.T) {
+	hm := raceTemplate().NewMapper(raceMappers())
+	placestest.Hammer(t, func() (string, error) {
+		rd, err := hm.Pipe("page.html", 8)
+		if err != nil {
+			return "", err
+		}
+		b, err := ioutil.ReadAll(rd)
+		return string(b), err
+	}, 8, 50)
+}
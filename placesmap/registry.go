@@ -0,0 +1,189 @@
+package placesmap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoPreviousVersion is returned by HTMLTemplateRegistry.Rollback, if there is no version before the current one
+var ErrNoPreviousVersion = errors.New("registry has no previous version")
+
+// UnknownVersionError is the error for tags that are not stored in a HTMLTemplateRegistry
+type UnknownVersionError string
+
+func (u UnknownVersionError) Error() string {
+	return fmt.Sprintf("version %#v is not stored in the registry", string(u))
+}
+
+// DuplicateVersionError is the error for tags that are already stored in a HTMLTemplateRegistry
+type DuplicateVersionError string
+
+func (d DuplicateVersionError) Error() string {
+	return fmt.Sprintf("version %#v is already stored in the registry", string(d))
+}
+
+// Version is a version of the templates that is stored in a HTMLTemplateRegistry
+type Version struct {
+	Tag      string    // e.g. the git SHA of the templates
+	Added    time.Time // the time the version has been added to the registry
+	Template *HTMLTemplate
+}
+
+// HTMLTemplateRegistry stores multiple versions of the templates of a server, e.g. for live editing
+// of templates in a CMS, and renders with the current one. Like a TemplateSet, the current version
+// is replaced atomically: renders that are running keep using the HTMLTemplate they started with.
+// Activate and Rollback switch to a stored version instantly, without loading the templates again.
+// Every version that becomes current gets the settings of the previous current version
+// (see TemplateSet.Reload).
+type HTMLTemplateRegistry struct {
+	current  atomic.Value // Version
+	mx       sync.Mutex   // serializes the changes
+	versions []Version    // the oldest first
+	history  []string     // the tags of the previously current versions, the latest last
+	max      int
+	now      func() time.Time
+}
+
+// NewHTMLTemplateRegistry returns a registry with h as current version, tagged with the given tag.
+// An empty tag is replaced by the time of adding in RFC 3339 format.
+// The registry keeps at most max versions (at least 2), the oldest versions are removed first.
+func NewHTMLTemplateRegistry(tag string, h *HTMLTemplate, max int) *HTMLTemplateRegistry {
+	if max < 2 {
+		max = 2
+	}
+	reg := &HTMLTemplateRegistry{max: max, now: time.Now}
+	v := reg.version(tag, h)
+	reg.versions = []Version{v}
+	reg.current.Store(v)
+	return reg
+}
+
+// version returns a new Version for the given tag and templates
+func (reg *HTMLTemplateRegistry) version(tag string, h *HTMLTemplate) Version {
+	added := reg.now()
+	if tag == "" {
+		tag = added.UTC().Format(time.RFC3339Nano)
+	}
+	return Version{Tag: tag, Added: added, Template: h}
+}
+
+// Load returns the HTMLTemplate of the current version
+func (reg *HTMLTemplateRegistry) Load() *HTMLTemplate {
+	return reg.Current().Template
+}
+
+// Current returns the current version
+func (reg *HTMLTemplateRegistry) Current() Version {
+	return reg.current.Load().(Version)
+}
+
+// Versions returns the stored versions, the oldest first
+func (reg *HTMLTemplateRegistry) Versions() []Version {
+	reg.mx.Lock()
+	defer reg.mx.Unlock()
+	return append([]Version(nil), reg.versions...)
+}
+
+// Add stores h as new version with the given tag and makes it the current version.
+// An empty tag is replaced by the time of adding in RFC 3339 format.
+func (reg *HTMLTemplateRegistry) Add(tag string, h *HTMLTemplate) error {
+	reg.mx.Lock()
+	defer reg.mx.Unlock()
+	v := reg.version(tag, h)
+	if _, has := reg.find(v.Tag); has {
+		return DuplicateVersionError(v.Tag)
+	}
+	reg.versions = append(reg.versions, v)
+	reg.activate(v)
+	reg.prune()
+	return nil
+}
+
+// AddLoaded loads the templates of the given TemplateLoader and adds them like Add.
+// If loading fails, the current version is kept.
+func (reg *HTMLTemplateRegistry) AddLoaded(tag string, l *TemplateLoader) error {
+	rs, err := l.Load()
+	if err != nil {
+		return err
+	}
+	return reg.Add(tag, NewHTMLTemplate(rs))
+}
+
+// Activate makes the stored version with the given tag the current version
+func (reg *HTMLTemplateRegistry) Activate(tag string) error {
+	reg.mx.Lock()
+	defer reg.mx.Unlock()
+	i, has := reg.find(tag)
+	if !has {
+		return UnknownVersionError(tag)
+	}
+	if tag != reg.Current().Tag {
+		reg.activate(reg.versions[i])
+	}
+	return nil
+}
+
+// Rollback makes the version the current one, that was current before the current version,
+// and returns it. Repeated rollbacks go further back in history.
+func (reg *HTMLTemplateRegistry) Rollback() (Version, error) {
+	reg.mx.Lock()
+	defer reg.mx.Unlock()
+	for len(reg.history) > 0 {
+		tag := reg.history[len(reg.history)-1]
+		reg.history = reg.history[:len(reg.history)-1]
+		// the version may have been removed in the meantime
+		if i, has := reg.find(tag); has && tag != reg.Current().Tag {
+			v := reg.versions[i]
+			reg.switchTo(v)
+			return v, nil
+		}
+	}
+	return Version{}, ErrNoPreviousVersion
+}
+
+// activate makes v the current version and remembers the previous one for Rollback
+func (reg *HTMLTemplateRegistry) activate(v Version) {
+	reg.history = append(reg.history, reg.Current().Tag)
+	reg.switchTo(v)
+}
+
+// switchTo makes v the current version with the settings of the current version
+func (reg *HTMLTemplateRegistry) switchTo(v Version) {
+	if cur := reg.Current(); cur.Template != v.Template {
+		v.Template.copySettings(cur.Template)
+	}
+	reg.current.Store(v)
+}
+
+// find returns the index of the version with the given tag
+func (reg *HTMLTemplateRegistry) find(tag string) (int, bool) {
+	for i, v := range reg.versions {
+		if v.Tag == tag {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// prune removes the oldest versions that exceed the maximum, except the current one
+func (reg *HTMLTemplateRegistry) prune() {
+	cur := reg.Current().Tag
+	for i := 0; len(reg.versions) > reg.max && i < len(reg.versions); {
+		if reg.versions[i].Tag == cur {
+			i++
+			continue
+		}
+		reg.versions = append(reg.versions[:i], reg.versions[i+1:]...)
+	}
+	// forget the history of removed versions
+	history := reg.history[:0]
+	for _, tag := range reg.history {
+		if _, has := reg.find(tag); has {
+			history = append(history, tag)
+		}
+	}
+	reg.history = history
+}
@@ -0,0 +1,68 @@
+package placesmap
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTMLTemplateRegistry(t *testing.T) {
+	render := func(reg *HTMLTemplateRegistry) string {
+		return reg.Load().NewMapper(nil).Map("-require page.html")
+	}
+	reg := NewHTMLTemplateRegistry("a1", newTemplate("page.html", "v1 <@-upper x@>"), 3)
+	reg.Load().Handle("upper", func(c *RenderContext, rest string) string { return strings.ToUpper(rest) })
+	reg.now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	if err := reg.Add("b2", newTemplate("page.html", "v2 <@-upper x@>")); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := render(reg), "v2 X"; got != exp {
+		t.Errorf("unexpected result after Add: %#v, expected: %#v", got, exp)
+	}
+	if err := reg.Add("a1", newTemplate("page.html", "v1")); err != DuplicateVersionError("a1") {
+		t.Errorf("expected DuplicateVersionError, got %v", err)
+	}
+
+	v, err := reg.Rollback()
+	if err != nil || v.Tag != "a1" || render(reg) != "v1 X" {
+		t.Errorf("unexpected rollback: %#v, %v, %#v", v.Tag, err, render(reg))
+	}
+	if _, err := reg.Rollback(); err != ErrNoPreviousVersion {
+		t.Errorf("expected ErrNoPreviousVersion, got %v", err)
+	}
+
+	if err := reg.Activate("b2"); err != nil || render(reg) != "v2 X" {
+		t.Errorf("unexpected activation: %v, %#v", err, render(reg))
+	}
+	if err := reg.Activate("c3"); err != UnknownVersionError("c3") {
+		t.Errorf("expected UnknownVersionError, got %v", err)
+	}
+
+	if err := reg.Add("", newTemplate("page.html", "v3")); err != nil {
+		t.Fatal(err)
+	}
+	if cur := reg.Current(); cur.Tag != "2020-01-02T03:04:05Z" || !cur.Added.Equal(reg.now()) {
+		t.Errorf("unexpected current version: %#v", cur)
+	}
+
+	// a1 is the oldest version and removed
+	if err := reg.Add("d4", newTemplate("page.html", "v4")); err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for _, v := range reg.Versions() {
+		tags = append(tags, v.Tag)
+	}
+	if got, exp := strings.Join(tags, ","), "b2,2020-01-02T03:04:05Z,d4"; got != exp {
+		t.Errorf("unexpected versions: %#v, expected: %#v", got, exp)
+	}
+	for _, exp := range []string{"2020-01-02T03:04:05Z", "b2"} {
+		if v, err := reg.Rollback(); err != nil || v.Tag != exp {
+			t.Errorf("unexpected rollback: %#v, %v, expected: %#v", v.Tag, err, exp)
+		}
+	}
+	if _, err := reg.Rollback(); err != ErrNoPreviousVersion {
+		t.Errorf("expected ErrNoPreviousVersion for removed version, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package placesmap
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ReloadPath is the conventional path for the ReloadHandler
+const ReloadPath = "/-/reload"
+
+// ReloadOnSignal reloads the templates of the set (see TemplateSet.Reload) whenever one of the
+// given signals is received, SIGHUP if none is given, until the returned stop function is called.
+// Errors are reported to the RefreshError hook of the current HTMLTemplate (see SetHooks).
+func ReloadOnSignal(set *TemplateSet, sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ch:
+				if err := set.Reload(); err != nil {
+					set.Load().refreshError(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// ReloadHandler returns a http.Handler that reloads the templates of the set on POST requests,
+// e.g. mounted at ReloadPath. If token is not empty, requests must authenticate with the header
+// "Authorization: Bearer <token>". It responds with 204 No Content on success and
+// 500 Internal Server Error if the reload failed, the error being reported to the RefreshError hook.
+func ReloadHandler(set *TemplateSet, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		if token != "" {
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "Bearer ") ||
+				subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) != 1 {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if err := set.Reload(); err != nil {
+			set.Load().refreshError(err)
+			http.Error(w, "reload failed", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,73 @@
+package placesmap
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placesmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "page.html")
+	ioutil.WriteFile(path, []byte("v1"), 0644)
+
+	set, err := NewTemplateSetLoader(NewTemplateLoader(dir, ".html", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	render := func() string { return set.Load().NewMapper(nil).Map("-require page.html") }
+
+	h := ReloadHandler(set, "secret")
+	tests := []struct {
+		method, auth string
+		status       int
+		exp          string
+	}{
+		{"GET", "Bearer secret", http.StatusMethodNotAllowed, "v1"},
+		{"POST", "", http.StatusUnauthorized, "v1"},
+		{"POST", "Bearer wrong", http.StatusUnauthorized, "v1"},
+		{"POST", "Bearer secret", http.StatusNoContent, "v2"},
+	}
+
+	ioutil.WriteFile(path, []byte("v2"), 0644)
+	for _, tt := range tests {
+		rq := httptest.NewRequest(tt.method, ReloadPath, nil)
+		if tt.auth != "" {
+			rq.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, rq)
+		if rec.Code != tt.status {
+			t.Errorf("%s %#v: unexpected status %d, expected %d", tt.method, tt.auth, rec.Code, tt.status)
+		}
+		if got := render(); got != tt.exp {
+			t.Errorf("%s %#v: unexpected result %#v, expected %#v", tt.method, tt.auth, got, tt.exp)
+		}
+	}
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := ReloadOnSignal(set, syscall.SIGHUP)
+	defer stop()
+	ioutil.WriteFile(path, []byte("v3"), 0644)
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Skip("signals not supported")
+	}
+	for i := 0; i < 100 && render() != "v3"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := render(); got != "v3" {
+		t.Errorf("unexpected result after signal: %#v", got)
+	}
+}
@@ -0,0 +1,383 @@
+package placesmap
+
+import (
+	"bytes"
+	"context"
+	"github.com/metakeule/places"
+	"html"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RenderContext is the context of a single render pass started by HTMLTemplateMapper.Map.
+// It keeps the state that changes while rendering (e.g. the current element of an each loop),
+// so that every render has its own state and renders may run concurrently.
+// The RenderContext is passed to the prefix handlers (see HTMLTemplate.Handle).
+type RenderContext struct {
+	hm          *HTMLTemplateMapper
+	preferred   places.Mapper   // the innermost loop scope
+	scopes      []places.Mapper // the stack of loop scopes
+	loops       []LoopScope     // the stack of the currently rendered loops
+	audit       *audit          // nil, if no audit is running
+	trees       []treeLevel     // the nodes of the currently rendered trees
+	ctx         context.Context
+	includes    []string          // the stack of the currently rendered includes
+	rendered    *int64            // the number of includes rendered so far, shared with the clones
+	err         error             // the first error of the render
+	errs        RenderErrors      // all errors of the render, see WithAllErrors
+	at          location          // the placeholder that is currently rendered, see WithAllErrors
+	memo        map[string]string // resolved values by key, nil if not memoized
+	timeouts    Timeouts
+	hooks       Hooks
+	handlers    map[string]PrefixHandler // custom prefix handlers
+	middlewares []Middleware
+	flags       FlagProvider
+	policy      Policy
+	stats       *stats        // nil, if the statistics are disabled
+	starts      []time.Time   // the start times of the includes, if stats or timings are enabled
+	slow        time.Duration // the threshold for slow renders, see SetSlowRender
+	timings     *timings      // nil, if slow renders are not reported
+	theme       string        // the active theme, see SetThemeResolver
+	fragments   *fragments    // nil, if the fragment cache is disabled
+	debug       *audit        // nil, if the render is not dumped, see Debug
+	aliases     map[string]string
+	usages      *deprecations
+	norm        Normalization
+	page        *fragment // the cached fragment of the outermost include, if any
+	caching     int       // the number of running renders of fragments for the cache, see cachedInclude
+	guard       LengthGuard
+	prefix      string       // the prefix of the placeholder that is currently handled
+	composed    *composition // nil, if the templates are not recorded, see SetAccessLog
+	options     RenderOptions
+	locals      map[string]places.Mapper // the local names, see set
+	linted      []lintedTemplate         // the rendered templates, if Options.A11yLint is set
+}
+
+func (r *RenderContext) Map(input string) string {
+	if r.audit != nil {
+		r.audit.placeholder(input)
+	}
+	if r.debug != nil {
+		r.debug.placeholder(input)
+	}
+	if r.preferred != nil {
+		out := r.preferred.Map(input)
+		if out != "" {
+			return r.escapeScoped(input, out)
+		}
+		if name, ok := r.alias(input); ok {
+			if out = r.preferred.Map(name); out != "" {
+				r.deprecated(input)
+				return r.escapeScoped(input, out)
+			}
+		}
+	}
+	return r._map(input)
+}
+
+// escapeScoped escapes the value of the current loop scope for the given placeholder like the
+// values of the registered mappers: placeholders without prefix are escaped, see escape.
+// The scopes therefore must not escape their values themselves.
+func (r *RenderContext) escapeScoped(input, val string) string {
+	if strings.HasPrefix(input, "-") {
+		return val
+	}
+	return r.escape(val)
+}
+
+// escape escapes the value of a placeholder without prefix according to the Autoescape of the options
+func (r *RenderContext) escape(val string) string {
+	if r.hm.profile.Autoescape == AutoescapeOff {
+		return val
+	}
+	return r.norm.escapeASCII(html.EscapeString(val))
+}
+
+// mapper returns the mapper for the given local name (see set) or registered name, see Namespace and WithFallbacks
+func (r *RenderContext) mapper(name string) (mp places.Mapper, ok bool) {
+	mp, ok = r.locals[name]
+	if !ok {
+		r.hm.Lock()
+		mp, ok = lookupName(r.hm.m, name)
+		r.hm.Unlock()
+	}
+	for i := 0; !ok && i < len(r.hm.fallbacks); i++ {
+		mp, ok = lookupName(r.hm.fallbacks[i], name)
+	}
+	if !ok && r.hm.print && name == PrintModeName {
+		mp, ok = String("1"), true
+	}
+	if !ok {
+		if alias, has := r.alias(name); has {
+			if mp, ok = r.mapper(alias); ok {
+				r.deprecated(name)
+			}
+			return
+		}
+	}
+	if ok && r.audit != nil {
+		r.audit.consult(name)
+	}
+	if r.debug != nil {
+		if ok {
+			r.debug.consult(name)
+		} else {
+			r.debug.miss(name)
+		}
+	}
+	return
+}
+
+// lookup returns the template for the given name, if the Policy allows to include it
+func (r *RenderContext) lookup(name string) (t *places.Template, ok bool) {
+	t, _, ok = r.lookupAs(name)
+	return
+}
+
+// lookupAs is like lookup and also returns the variant of the template, see lookupVariant
+func (r *RenderContext) lookupAs(name string) (t *places.Template, variant string, ok bool) {
+	if !r.allowed("require", name) {
+		return nil, "", false
+	}
+	t, variant, ok = r.lookupVariant(name)
+	if ok && r.audit != nil {
+		r.audit.include(name)
+	}
+	if ok && r.debug != nil {
+		r.debug.include(name)
+	}
+	if ok && r.composed != nil {
+		r.composed.include(r.hm.HTMLTemplate, name, t)
+	}
+	if ok && r.hm.profile.A11yLint {
+		r.lint(name, t)
+	}
+	return
+}
+
+// lookupVariant returns the template for the given name, respecting the assigned variant,
+// and the variant, that is empty for the original template
+func (r *RenderContext) lookupVariant(name string) (*places.Template, string, bool) {
+	if r.hm.variants == nil || r.hm.variants.Bucket == nil {
+		t, ok := r.template(name)
+		return t, "", ok
+	}
+
+	if variant := r.hm.variants.Bucket(name); variant != "" {
+		if t, ok := r.template(variantName(name, variant)); ok {
+			if r.hm.variants.Exposure != nil {
+				r.hm.variants.Exposure(name, variant)
+			}
+			return t, variant, ok
+		}
+	}
+
+	t, ok := r.template(name)
+	if ok && r.hm.variants.Exposure != nil {
+		r.hm.variants.Exposure(name, "")
+	}
+	return t, "", ok
+}
+
+// MaxIncludeDepth is the maximum nesting of includes, loops and trees.
+// Deeper includes (e.g. by templates that include themselves) are rendered as empty string.
+var MaxIncludeDepth = 64
+
+// MaxIncludes is the maximum number of includes, loops and trees within a single render.
+// Further includes are rendered as empty string. It prevents templates that include each other
+// from taking exponential time.
+var MaxIncludes = 100000
+
+// push adds the given include to the stack of the currently rendered includes.
+// It returns false, if MaxIncludeDepth or MaxIncludes would be exceeded.
+func (r *RenderContext) push(name string) bool {
+	if len(r.includes) >= MaxIncludeDepth || atomic.AddInt64(r.rendered, 1) > int64(MaxIncludes) {
+		return false
+	}
+	r.includes = append(r.includes, name)
+	if r.stats != nil || r.timings != nil {
+		r.starts = append(r.starts, time.Now())
+	}
+	return true
+}
+
+// pop removes the innermost include from the stack, size is the size of its output
+func (r *RenderContext) pop(size int) {
+	name := r.includes[len(r.includes)-1]
+	r.includes = r.includes[:len(r.includes)-1]
+	if r.stats == nil && r.timings == nil {
+		return
+	}
+	d := time.Since(r.starts[len(r.starts)-1])
+	r.starts = r.starts[:len(r.starts)-1]
+	if r.stats != nil {
+		r.stats.record(name, d, size)
+	}
+	if r.timings != nil {
+		r.timings.add(r.timings.includes, name, d)
+	}
+}
+
+func (r *RenderContext) require(name string, m places.Mapper) string {
+	// fmt.Printf("requiring: %#v\n", name)
+	if t, variant, ok := r.lookupAs(name); ok {
+		if res, cached := r.cachedInclude(name, variant, t, m); cached {
+			return res
+		}
+		return r.include(name, t, m)
+	}
+	if name != "" {
+		r.fail(TemplateNotFoundError(name))
+	}
+	return ""
+}
+
+// include renders the template t with the given name as include
+func (r *RenderContext) include(name string, t *places.Template, m places.Mapper) string {
+	if !r.push(name) {
+		return ""
+	}
+	res := r.renderTimeout(name, t, m)
+	r.pop(len(res))
+	return res
+}
+
+// renderWith renders the template with m as preferred mapper
+func (r *RenderContext) renderWith(bf places.Buffer, t *places.Template, m places.Mapper) {
+	prev := r.preferred
+	r.preferred = m
+	r.scopes = append(r.scopes, m)
+	t.ReplaceMapper(bf, r.locate(t, r))
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.preferred = prev
+}
+
+// splitArgs splits the given directive arguments at whitespace and returns them,
+// if there are at least min arguments
+func splitArgs(args string, min int) []string {
+	res := strings.Fields(args)
+	if len(res) < min {
+		return nil
+	}
+	return res
+}
+
+// splitElse splits the given directive arguments at the "else" keyword
+func splitElse(args string) (then, otherwise string) {
+	if idx := strings.Index(args, " else "); idx != -1 {
+		return strings.TrimSpace(args[:idx]), strings.TrimSpace(args[idx+6:])
+	}
+	return strings.TrimSpace(args), ""
+}
+
+// truthy returns whether the given value is considered to be true.
+// Everything is true, except the empty string, "0" and "false".
+func truthy(val string) bool {
+	return val != "" && val != "0" && val != "false"
+}
+
+// cond handles "-if name then.html else otherwise.html"
+// If the value for name is truthy, then.html is rendered, otherwise otherwise.html.
+// The else part is optional.
+func (r *RenderContext) cond(rest string) string {
+	name, args := nextArg(rest)
+	if strings.TrimSpace(args) == "" {
+		r.fail(MissingTemplateError("if"))
+		return ""
+	}
+	then, otherwise := splitElse(args)
+	if truthy(r.Map(name)) {
+		return r.require(then, r)
+	}
+	return r.require(otherwise, r)
+}
+
+// each handles "-each name row.html else empty.html"
+// The else part is optional and rendered, if the collection is empty or does not exist.
+func (r *RenderContext) each(rest string) (res string) {
+	mpName, args := nextArg(rest)
+	if strings.TrimSpace(args) == "" {
+		r.fail(MissingTemplateError("each"))
+		return ""
+	}
+	inc, otherwise := splitElse(args)
+	var sub string
+
+	// dotted names are paths within the collection, unless they name a mapper (see Namespace)
+	if _, isName := r.mapper(mpName); !isName && strings.ContainsRune(mpName, '.') {
+		idx := strings.IndexByte(mpName, '.')
+		mpName, sub = mpName[:idx], mpName[idx+1:]
+	}
+
+	r.debugf("each: collection %#v, template %#v", mpName, inc)
+	mp, ok := r.mapper(mpName)
+	if !ok {
+		r.debugf("each: collection %#v not found", mpName)
+		if otherwise == "" {
+			r.fail(UnknownCollectionError{Name: mpName})
+		}
+		return r.require(otherwise, r)
+	}
+
+	t, hasTemplate := r.lookup(inc)
+	if !hasTemplate {
+		r.debugf("each: template %#v not found", inc)
+		r.fail(TemplateNotFoundError(inc))
+		return ""
+	}
+	if !r.push(inc) {
+		return ""
+	}
+	defer func() { r.pop(len(res)) }()
+
+	// an Iterator has no known length, so we simply consume it
+	if it, is := mp.(Iterator); is {
+		var bf bytes.Buffer
+		for n := 0; ; n++ {
+			m, ok := it.Next()
+			if !ok {
+				if n == 0 {
+					return r.require(otherwise, r)
+				}
+				break
+			}
+			r.loops = append(r.loops, LoopScope{Index: n, Element: m})
+			ok = r.element(&bf, t, m, sub)
+			r.loops = r.loops[:len(r.loops)-1]
+			if !ok {
+				break
+			}
+		}
+		return bf.String()
+	}
+
+	nm, is := mp.(NMapper)
+	if !is {
+		r.fail(ErrNotNMapper)
+		return ""
+	}
+
+	l := nm.Len()
+	if l == 0 {
+		return r.require(otherwise, r)
+	}
+
+	var bf bytes.Buffer
+	r.loop(&bf, t, nm, sub)
+	return bf.String()
+}
+
+func (r *RenderContext) _map(input string) string {
+	prefix, rest := split(input)
+
+	r.debugf("placeholder: prefix %#v, rest %#v", prefix, rest)
+	if !r.allowed(prefix, rest) {
+		return ""
+	}
+	outer := r.prefix
+	r.prefix = prefix
+	res := r.handler(prefix)(r, rest)
+	r.prefix = outer
+	return res
+}
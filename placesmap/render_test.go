@@ -0,0 +1,83 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestEach(t *testing.T) {
+	tpl := newTemplate("list.html", "<@-each items row.html@>", "row.html", "[<@name@>]")
+	got := tpl.NewMapper(map[string]places.Mapper{"items": list(names("a", "b"))}).Map("-require list.html")
+
+	if exp := "[a][b]"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
+
+func TestConcurrentRender(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<h1><@title@></h1><@-each items row.html@><@-require footer.html@>",
+		"row.html", "<li><@name@></li>",
+		"footer.html", "<footer><@-url title@></footer>",
+	)
+
+	mapper := tpl.NewMapper(map[string]places.Mapper{
+		"title": String("a & b"),
+		"items": list(names("x", "y", "z")),
+	})
+
+	exp := "<h1>a &amp; b</h1><li>x</li><li>y</li><li>z</li><footer>a+%26+b</footer>"
+
+	const n = 20
+	results := make(chan string, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			results <- mapper.Map("-require page.html")
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if got := <-results; got != exp {
+			t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+		}
+	}
+}
+
+func TestEachElse(t *testing.T) {
+	tpl := newTemplate("list.html", "<@-each items row.html else empty.html@>", "row.html", "[<@name@>]", "empty.html", "no items")
+
+	tests := map[string]places.Mapper{
+		"[a]":      list(names("a")),
+		"no items": list{},
+	}
+
+	for exp, items := range tests {
+		got := tpl.NewMapper(map[string]places.Mapper{"items": items}).Map("-require list.html")
+		if got != exp {
+			t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+		}
+	}
+
+	if got := tpl.NewMapper(nil).Map("-require list.html"); got != "no items" {
+		t.Errorf("unexpected result for missing collection: %#v", got)
+	}
+}
+
+func TestIf(t *testing.T) {
+	tpl := newTemplate("page.html", "<@-if admin admin.html else user.html@>", "admin.html", "admin", "user.html", "user")
+
+	tests := map[string]string{
+		"true":  "admin",
+		"1":     "admin",
+		"false": "user",
+		"0":     "user",
+		"":      "user",
+	}
+
+	for val, exp := range tests {
+		got := tpl.NewMapper(map[string]places.Mapper{"admin": String(val)}).Map("-require page.html")
+		if got != exp {
+			t.Errorf("%#v: unexpected result: %#v, expected: %#v", val, got, exp)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package placesmap
+
+import (
+	"sort"
+	"strings"
+)
+
+// keysOf returns the names of the mappers that the given placeholder needs
+func keysOf(placeholder string) (keys []string) {
+	prefix, rest := split(placeholder)
+	rest = strings.TrimSpace(rest)
+	args := strings.Fields(rest)
+
+	switch prefix {
+	case "":
+		return []string{rest}
+	case "require", "children", "depth", "nonce", "nonce-attr", "variant", "go", "sri":
+		return nil
+	case "slot":
+		return []string{SlotsName}
+	case "calc":
+		// evaluate the expression to get the names, every name is 1
+		evalCalc(rest, func(name string) interface{} {
+			keys = append(keys, name)
+			return "1"
+		})
+		return keys
+	case "island":
+		if len(args) > 1 {
+			return args[1:2]
+		}
+		return nil
+	case "component":
+		// only the names of params taken from values
+		for _, arg := range args {
+			if idx := strings.Index(arg, "=$"); idx != -1 {
+				keys = append(keys, arg[idx+2:])
+			}
+		}
+		return keys
+	case "eq", "ne", "gt", "ge", "lt", "le":
+		name, value, byName, _, _, ok := splitCompare(rest)
+		if !ok {
+			return nil
+		}
+		if byName {
+			return []string{name, value}
+		}
+		return []string{name}
+	case "time":
+		if len(args) > 0 && args[0] == "now" {
+			return nil
+		}
+	}
+
+	if len(args) > 0 {
+		return args[:1]
+	}
+	return nil
+}
+
+// requirements adds the keys and includes needed by the template with the given name and its includes
+func (h *HTMLTemplate) requirements(name string, keys map[string]bool, includes map[string]bool) {
+	if includes[name] {
+		return
+	}
+	includes[name] = true
+	t, has := h.template(name)
+	if !has {
+		return
+	}
+	for _, ph := range t.Placeholders() {
+		for _, k := range keysOf(ph) {
+			if k != "" {
+				keys[k] = true
+			}
+		}
+		for _, inc := range references(ph) {
+			h.requirements(inc, keys, includes)
+		}
+	}
+}
+
+// RequiredKeys returns the sorted names of the mappers and the sorted names of the templates that
+// are needed to render the template with the given name (including itself), determined statically
+// by following the includes of the built-in directives (see Preflight). Includes whose names come
+// from mappers at render time can't be followed. Unknown directives are expected to take the name
+// of a mapper as first argument.
+func (h *HTMLTemplate) RequiredKeys(entryTemplate string) (keys []string, includes []string) {
+	k, inc := map[string]bool{}, map[string]bool{}
+	h.requirements(entryTemplate, k, inc)
+	return sortedKeys(k), sortedKeys(inc)
+}
+
+// Keys returns the sorted names of the mappers that are needed to render the given placeholder,
+// including the ones of the templates it includes, so that the HTMLTemplateMapper is a places.KeysMapper.
+func (h *HTMLTemplateMapper) Keys(placeholder string) []string {
+	keys, includes := map[string]bool{}, map[string]bool{}
+	for _, k := range keysOf(placeholder) {
+		if k != "" {
+			keys[k] = true
+		}
+	}
+	for _, inc := range references(placeholder) {
+		h.HTMLTemplate.requirements(inc, keys, includes)
+	}
+	return sortedKeys(keys)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,28 @@
+package placesmap
+
+import (
+	"fmt"
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestRequiredKeys(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@title@><@-require head.html@><@-each items row.html else empty.html@><@-calc price * (1 + tax)@><@-time now date@><@-nonce-attr@><@-island map props@><@-component button label=$save@>",
+		"head.html", "<@-html meta@><@-require page.html@>",
+		"row.html", "<@-time created date@>",
+	)
+
+	keys, includes := tpl.RequiredKeys("page.html")
+	if exp := `["created" "items" "meta" "price" "props" "save" "tax" "title"]`; fmt.Sprintf("%q", keys) != exp {
+		t.Errorf("unexpected keys: %q, expected: %s", keys, exp)
+	}
+	if exp := `["empty.html" "head.html" "page.html" "row.html"]`; fmt.Sprintf("%q", includes) != exp {
+		t.Errorf("unexpected includes: %q, expected: %s", includes, exp)
+	}
+
+	main := places.NewTemplate([]byte("<@-require head.html@><@x@>"))
+	if got, exp := fmt.Sprintf("%q", main.RequiredKeys(tpl.NewMapper(nil))), `["created" "items" "meta" "price" "props" "save" "tax" "title" "x"]`; got != exp {
+		t.Errorf("unexpected keys: %s, expected: %s", got, exp)
+	}
+}
@@ -0,0 +1,192 @@
+package placesmap
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// Router builds the URL for a named route with the given params.
+// Params that are not part of the route should be appended as query.
+type Router interface {
+	URL(route string, params map[string]string) (string, error)
+}
+
+// RouterFunc is a function that is a Router, e.g. to adapt the reverse routing of gorilla/mux:
+//
+//	placesmap.RouterFunc(func(route string, params map[string]string) (string, error) {
+//		var pairs []string
+//		for k, v := range params {
+//			pairs = append(pairs, k, v)
+//		}
+//		u, err := r.Get(route).URL(pairs...)
+//		if err != nil {
+//			return "", err
+//		}
+//		return u.String(), nil
+//	})
+type RouterFunc func(route string, params map[string]string) (string, error)
+
+func (r RouterFunc) URL(route string, params map[string]string) (string, error) {
+	return r(route, params)
+}
+
+type UnknownRouteError string
+
+func (u UnknownRouteError) Error() string {
+	return fmt.Sprintf("route %#v is not defined", u)
+}
+
+type MissingParamError struct {
+	Route string
+	Param string
+}
+
+func (m MissingParamError) Error() string {
+	return fmt.Sprintf("param %#v of route %#v is missing", m.Param, m.Route)
+}
+
+// UnsafeURLError is returned for URLs that could redirect to another host
+type UnsafeURLError string
+
+func (u UnsafeURLError) Error() string {
+	return fmt.Sprintf("URL %#v is not a local path", u)
+}
+
+// Patterns is a Router for route patterns in the style of net/http.ServeMux, chi and gorilla/mux,
+// mapping route names to patterns like
+//
+//	"/users/{id}"             a segment
+//	"/users/{id:[0-9]+}"      a segment with a regular expression (that is not checked)
+//	"/files/{path...}"        the rest of the path, slashes are kept
+//	"/{$}"                    the end of the path
+//
+// Params are escaped, params that are no part of the pattern become the sorted query
+// (if they are not empty).
+type Patterns map[string]string
+
+func (p Patterns) URL(route string, params map[string]string) (string, error) {
+	pattern, has := p[route]
+	if !has {
+		return "", UnknownRouteError(route)
+	}
+
+	used := map[string]bool{}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(pattern, '{')
+		if start == -1 {
+			b.WriteString(pattern)
+			break
+		}
+		end := strings.IndexByte(pattern[start:], '}')
+		if end == -1 {
+			b.WriteString(pattern)
+			break
+		}
+		b.WriteString(pattern[:start])
+		name := pattern[start+1 : start+end]
+		pattern = pattern[start+end+1:]
+
+		if name == "$" {
+			continue
+		}
+		if idx := strings.IndexByte(name, ':'); idx != -1 {
+			name = name[:idx]
+		}
+		rest := strings.HasSuffix(name, "...")
+		name = strings.TrimSuffix(name, "...")
+
+		val, has := params[name]
+		if !has || val == "" {
+			return "", MissingParamError{Route: route, Param: name}
+		}
+		used[name] = true
+
+		if !rest {
+			b.WriteString(url.PathEscape(val))
+			continue
+		}
+		segments := strings.Split(val, "/")
+		for i, s := range segments {
+			segments[i] = url.PathEscape(s)
+		}
+		b.WriteString(strings.Join(segments, "/"))
+	}
+
+	q := url.Values{}
+	for k, v := range params {
+		if !used[k] && v != "" {
+			q.Set(k, v)
+		}
+	}
+	if len(q) > 0 {
+		// Encode sorts by key
+		return b.String() + "?" + q.Encode(), nil
+	}
+	return b.String(), nil
+}
+
+// URLMapper builds URLs for the routes of the Router. Registered via Register, the directive
+//
+//	-route user.show id page=p
+//
+// renders the URL of the route user.show with the param id taken from the value for id and the
+// param page taken from the value for p (the current element of a loop is consulted first, see
+// RenderContext.Value). The URL is HTML escaped, so that it can be used within attributes.
+// Errors are reported to MapStrict and render as empty string.
+type URLMapper struct {
+	Router Router
+}
+
+// URL returns the URL for the given route and params. It only returns local paths, so that
+// the URL is safe to be used for redirects.
+func (u *URLMapper) URL(route string, params map[string]string) (string, error) {
+	s, err := u.Router.URL(route, params)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(s, "/") || strings.HasPrefix(s, "//") || strings.HasPrefix(s, "/\\") {
+		return "", UnsafeURLError(s)
+	}
+	return s, nil
+}
+
+// Map returns the HTML escaped URL for the route with the given name without params
+func (u *URLMapper) Map(route string) string {
+	s, err := u.URL(route, nil)
+	if err != nil {
+		return ""
+	}
+	return html.EscapeString(s)
+}
+
+// Register registers the "-route" directive for the given HTMLTemplate
+func (u *URLMapper) Register(t *HTMLTemplate) error {
+	return t.Handle("route", u.route)
+}
+
+// route handles "-route name param param=key..."
+func (u *URLMapper) route(c *RenderContext, rest string) string {
+	args := strings.Fields(rest)
+	if len(args) == 0 {
+		return ""
+	}
+
+	params := map[string]string{}
+	for _, arg := range args[1:] {
+		param, key := arg, arg
+		if idx := strings.IndexByte(arg, '='); idx != -1 {
+			param, key = arg[:idx], arg[idx+1:]
+		}
+		params[param] = c.Value(key)
+	}
+
+	s, err := u.URL(args[0], params)
+	if err != nil {
+		c.Fail(err)
+		return ""
+	}
+	return html.EscapeString(s)
+}
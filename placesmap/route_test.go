@@ -0,0 +1,65 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	p := Patterns{
+		"user.show": "/users/{id:[0-9]+}",
+		"file":      "/files/{path...}",
+		"home":      "/{$}",
+	}
+
+	tests := []struct {
+		route  string
+		params map[string]string
+		exp    string
+	}{
+		{"user.show", map[string]string{"id": "1 2", "tab": "a&b", "sort": "x"}, "/users/1%202?sort=x&tab=a%26b"},
+		{"file", map[string]string{"path": "a b/c?"}, "/files/a%20b/c%3F"},
+		{"home", nil, "/"},
+	}
+
+	for _, test := range tests {
+		got, err := p.URL(test.route, test.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.exp {
+			t.Errorf("%s: unexpected URL: %#v, expected: %#v", test.route, got, test.exp)
+		}
+	}
+
+	if _, err := p.URL("user.show", nil); err != (MissingParamError{Route: "user.show", Param: "id"}) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := p.URL("missing", nil); err != UnknownRouteError("missing") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	u := &URLMapper{Router: Patterns{
+		"user.show": "/users/{id}",
+		"evil":      "//evil.com/{id}",
+	}}
+	tpl := newTemplate("page.html", `<a href="<@-route user.show id page=p@>">`)
+	if err := u.Register(tpl); err != nil {
+		t.Fatal(err)
+	}
+
+	hm := tpl.NewMapper(map[string]places.Mapper{"id": String("5"), "p": String("2&3")})
+	got, err := hm.MapStrict("-require page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `<a href="/users/5?page=2%263">`; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	if _, err := hm.MapStrict("-route evil id"); err != UnsafeURLError("//evil.com/5") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package placesmap
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Source is a Source for templates within a bucket of an S3 compatible object storage.
+// Requests are signed with AWS signature version 4, if AccessKey is set, and use path style URLs
+// (Endpoint/Bucket/Key), which are supported by AWS S3 and most compatible storages.
+type S3Source struct {
+	Endpoint     string // e.g. https://s3.eu-central-1.amazonaws.com
+	Region       string // e.g. eu-central-1
+	Bucket       string
+	Prefix       string // only keys with the prefix are templates, their names are the keys without the prefix
+	Extension    string // if set, only keys with the extension are templates
+	AccessKey    string
+	SecretKey    string
+	SessionToken string       // optional
+	Client       *http.Client // nil means http.DefaultClient
+
+	mx       sync.Mutex
+	modTimes map[string]time.Time // modification times of the last List
+}
+
+type S3Error struct {
+	StatusCode int
+	Key        string
+}
+
+func (s S3Error) Error() string {
+	return fmt.Sprintf("s3 request for %#v failed with status %d", s.Key, s.StatusCode)
+}
+
+// s3ListResult is the response of ListObjectsV2
+type s3ListResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key          string
+		LastModified time.Time
+	}
+}
+
+// List returns the names of the templates within the bucket. The modification times are
+// remembered for ModTime, so that a refresh needs just one request per changed template.
+func (s *S3Source) List() ([]string, error) {
+	var (
+		names []string
+		mods  = map[string]time.Time{}
+		token string
+	)
+
+	for {
+		q := url.Values{"list-type": {"2"}}
+		if s.Prefix != "" {
+			q.Set("prefix", s.Prefix)
+		}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+
+		b, err := s.do("GET", "", q)
+		if err != nil {
+			return nil, err
+		}
+
+		var res s3ListResult
+		if err := xml.Unmarshal(b, &res); err != nil {
+			return nil, err
+		}
+
+		for _, c := range res.Contents {
+			if strings.HasSuffix(c.Key, "/") || s.Extension != "" && path.Ext(c.Key) != s.Extension {
+				continue
+			}
+			name := strings.TrimPrefix(c.Key, s.Prefix)
+			names = append(names, name)
+			mods[name] = c.LastModified
+		}
+
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			break
+		}
+		token = res.NextContinuationToken
+	}
+
+	s.mx.Lock()
+	s.modTimes = mods
+	s.mx.Unlock()
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Get returns the content of the template with the given name
+func (s *S3Source) Get(name string) ([]byte, error) {
+	return s.do("GET", s.Prefix+name, nil)
+}
+
+// ModTime returns the modification time of the template with the given name as returned by the
+// last List. For templates that are unknown to the last List, a HEAD request is made.
+func (s *S3Source) ModTime(name string) (time.Time, error) {
+	s.mx.Lock()
+	mod, has := s.modTimes[name]
+	s.mx.Unlock()
+	if has {
+		return mod, nil
+	}
+
+	rq, err := s.request("HEAD", s.Prefix+name, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp, err := s.client().Do(rq)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, S3Error{StatusCode: resp.StatusCode, Key: s.Prefix + name}
+	}
+	return http.ParseTime(resp.Header.Get("Last-Modified"))
+}
+
+func (s *S3Source) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// do makes a request for the given key and returns the body of the response
+func (s *S3Source) do(method, key string, q url.Values) ([]byte, error) {
+	rq, err := s.request(method, key, q)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client().Do(rq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, S3Error{StatusCode: resp.StatusCode, Key: key}
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// request returns a signed request for the given key within the bucket
+func (s *S3Source) request(method, key string, q url.Values) (*http.Request, error) {
+	u, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	p := "/" + s.Bucket
+	if key != "" {
+		p += "/" + key
+	}
+	// the path is escaped as required by signature version 4
+	u.RawPath = strings.TrimRight(u.EscapedPath(), "/") + awsEscape(p, true)
+	if u.Path, err = url.PathUnescape(u.RawPath); err != nil {
+		return nil, err
+	}
+	u.RawQuery = canonicalQuery(q)
+
+	rq, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.AccessKey != "" {
+		s.sign(rq, time.Now().UTC())
+	}
+	return rq, nil
+}
+
+// emptyHash is the SHA256 of an empty payload
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds the headers for AWS signature version 4 to the request
+func (s *S3Source) sign(rq *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	rq.Header.Set("X-Amz-Date", amzDate)
+	rq.Header.Set("X-Amz-Content-Sha256", emptyHash)
+	if s.SessionToken != "" {
+		rq.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": rq.URL.Host}
+	for k := range rq.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(rq.Header.Get(k))
+	}
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range keys {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(keys, ";")
+
+	canonicalRequest := strings.Join([]string{
+		rq.Method,
+		rq.URL.EscapedPath(),
+		rq.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	rq.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscape escapes everything except the unreserved characters of RFC 3986 (and slashes, if keepSlash)
+func awsEscape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9',
+			c == '-' || c == '_' || c == '.' || c == '~',
+			c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery returns the query sorted by keys with escaping as required by signature version 4
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
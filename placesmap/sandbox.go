@@ -0,0 +1,110 @@
+package placesmap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/metakeule/places"
+	"html"
+	"time"
+)
+
+// Limits are the limits for SandboxRender and the Options of a HTMLTemplate, zero values mean no limit
+type Limits struct {
+	MaxSize         int           // the maximum size of the template in bytes
+	MaxPlaceholders int           // the maximum number of placeholders of the template
+	MaxOutput       int           // the maximum size of the result in bytes
+	Timeout         time.Duration // the maximum duration of the render
+}
+
+// SandboxError is returned by SandboxRender for templates that use placeholders that are not allowed
+type SandboxError string
+
+func (s SandboxError) Error() string {
+	return fmt.Sprintf("placeholder %#v is not allowed", s)
+}
+
+// LimitError is returned by SandboxRender and reported to MapStrict (see Options) if a limit is exceeded
+type LimitError string
+
+func (l LimitError) Error() string {
+	return "limit " + string(l) + " exceeded"
+}
+
+// sandboxContexts are the HTML contexts where SandboxRender allows placeholders, since HTML
+// escaping is sufficient there
+var sandboxContexts = map[htmlContext]bool{ctxText: true, ctxQuoted: true, ctxComment: true}
+
+// SandboxRender renders an untrusted template, e.g. an email or page edited by a customer, with the
+// registered mappers. Only the given placeholders may be used, their values are always HTML escaped and
+// directives (e.g. -raw, -include or -require) are not allowed. Placeholders are only allowed within
+// text, comments and quoted attribute values, not at the position of tag or attribute names, within
+// unquoted attribute values or within script and style elements. The template is checked before it is
+// rendered, so that a template with placeholders that are not allowed returns a SandboxError without
+// calling any mappers. If a limit is exceeded, a LimitError is returned.
+func (h *HTMLTemplateMapper) SandboxRender(userTemplate []byte, allowedPlaceholders []string, limits Limits) (string, error) {
+	if limits.MaxSize > 0 && len(userTemplate) > limits.MaxSize {
+		return "", LimitError("MaxSize")
+	}
+
+	t := places.NewTemplate(userTemplate)
+	names := t.Placeholders()
+	if limits.MaxPlaceholders > 0 && len(names) > limits.MaxPlaceholders {
+		return "", LimitError("MaxPlaceholders")
+	}
+
+	allowed := make(map[string]bool, len(allowedPlaceholders))
+	for _, name := range allowedPlaceholders {
+		allowed[name] = true
+	}
+	ctxs := placeholderContexts(userTemplate, places.Find(userTemplate))
+	for i, name := range names {
+		if !allowed[name] || len(name) > 0 && name[0] == '-' || !sandboxContexts[ctxs[i]] {
+			return "", SandboxError(name)
+		}
+	}
+
+	r := h.newRender()
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		r.ctx, cancel = context.WithTimeout(r.ctx, limits.Timeout)
+		defer cancel()
+		if r.timeouts.Placeholder <= 0 || r.timeouts.Placeholder > limits.Timeout {
+			r.timeouts.Placeholder = limits.Timeout
+		}
+	}
+
+	var (
+		bf  bytes.Buffer
+		err error
+	)
+	t.ReplaceMapper(&bf, MapFunc(func(name string) string {
+		if err != nil {
+			return ""
+		}
+		if r.ctx.Err() != nil {
+			err = LimitError("Timeout")
+			return ""
+		}
+		mp, ok := r.mapper(name)
+		if !ok {
+			return ""
+		}
+		val := html.EscapeString(r.mapValue(mp, name))
+		if limits.MaxOutput > 0 && bf.Len()+len(val) > limits.MaxOutput {
+			err = LimitError("MaxOutput")
+			return ""
+		}
+		return val
+	}))
+
+	switch {
+	case err != nil:
+		return "", err
+	case r.ctx.Err() != nil:
+		return "", LimitError("Timeout")
+	case limits.MaxOutput > 0 && bf.Len() > limits.MaxOutput:
+		return "", LimitError("MaxOutput")
+	}
+	return bf.String(), nil
+}
@@ -0,0 +1,50 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSandboxRender(t *testing.T) {
+	tpl := newTemplate("secret.html", "secret")
+	hm := tpl.NewMapper(map[string]places.Mapper{
+		"name": String("<Tom>"),
+		"slow": MapFunc(func(string) string {
+			time.Sleep(100 * time.Millisecond)
+			return "slow"
+		}),
+	})
+	allowed := []string{"name", "slow", "-require secret.html"}
+
+	tests := []struct {
+		tpl    string
+		limits Limits
+		exp    string
+		err    error
+	}{
+		{"<p>Hello <@name@></p>", Limits{}, "<p>Hello &lt;Tom&gt;</p>", nil},
+		{"<@-require secret.html@>", Limits{}, "", SandboxError("-require secret.html")},
+		{"<@-raw name@>", Limits{}, "", SandboxError("-raw name")},
+		{"<@other@>", Limits{}, "", SandboxError("other")},
+		{"<p <@name@>>", Limits{}, "", SandboxError("name")},
+		{`<a title="<@name@>">x</a><!-- <@name@> -->`, Limits{}, `<a title="&lt;Tom&gt;">x</a><!-- &lt;Tom&gt; -->`, nil},
+		{"<a title=<@name@>>x</a>", Limits{}, "", SandboxError("name")},
+		{"<a title=x<@name@>>x</a>", Limits{}, "", SandboxError("name")},
+		{"<script>var n = '<@name@>';</script>", Limits{}, "", SandboxError("name")},
+		{"<style><@name@></style>", Limits{}, "", SandboxError("name")},
+		{"<@name@>", Limits{MaxSize: 5}, "", LimitError("MaxSize")},
+		{"<@name@><@name@>", Limits{MaxPlaceholders: 1}, "", LimitError("MaxPlaceholders")},
+		{"<@name@><@name@>", Limits{MaxOutput: 15}, "", LimitError("MaxOutput")},
+		{strings.Repeat("x", 20), Limits{MaxOutput: 15}, "", LimitError("MaxOutput")},
+		{"<@slow@><@name@>", Limits{Timeout: 10 * time.Millisecond}, "", LimitError("Timeout")},
+	}
+
+	for _, tt := range tests {
+		got, err := hm.SandboxRender([]byte(tt.tpl), allowed, tt.limits)
+		if got != tt.exp || err != tt.err {
+			t.Errorf("%#v: unexpected result: %#v, %v, expected: %#v, %v", tt.tpl, got, err, tt.exp, tt.err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+/*
+package session provides mappers for the values of a session and one-time flash messages.
+
+The session of a request is accessed via a Store, e.g. an adapter for the session library of
+the app. Registered as "session" (see Name), the Session maps
+
+	session.user         the value of the key user within the session
+
+and the directive "-flash" (see Register) renders the flash messages of a kind, that have been
+added via AddFlash, e.g. before a redirect:
+
+	-flash notice                the messages of the kind notice as <p class="flash flash-notice" role="status">
+	-flash error flash.html      the template flash.html for every message, mapping "message" and "kind"
+
+Rendered flash messages are removed from the Store (consume on render), so they are shown once.
+*/
+package session
+
+import (
+	"encoding/json"
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"html"
+	"strings"
+	"sync"
+)
+
+// Name is the name that the Session must be registered with for the "-flash" directive
+const Name = "session"
+
+// flashPrefix is the prefix of the keys that hold the flash messages within the Store
+const flashPrefix = "_flash."
+
+// Store is the session of a request. Changes must be saved by the Store, if needed.
+type Store interface {
+	Get(key string) (value string, ok bool)
+	Set(key, value string)
+	Delete(key string)
+}
+
+// MapStore is a Store that keeps the values in memory, e.g. for tests
+type MapStore struct {
+	mx     sync.Mutex
+	values map[string]string
+}
+
+func (m *MapStore) Get(key string) (string, bool) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	v, ok := m.values[key]
+	return v, ok
+}
+
+func (m *MapStore) Set(key, value string) {
+	m.mx.Lock()
+	if m.values == nil {
+		m.values = map[string]string{}
+	}
+	m.values[key] = value
+	m.mx.Unlock()
+}
+
+func (m *MapStore) Delete(key string) {
+	m.mx.Lock()
+	delete(m.values, key)
+	m.mx.Unlock()
+}
+
+// AddFlash adds a flash message of the given kind (e.g. notice or error) to the Store
+func AddFlash(s Store, kind, message string) {
+	msgs := flashes(s, kind)
+	b, _ := json.Marshal(append(msgs, message))
+	s.Set(flashPrefix+kind, string(b))
+}
+
+// flashes returns the flash messages of the given kind without removing them
+func flashes(s Store, kind string) (msgs []string) {
+	if v, ok := s.Get(flashPrefix + kind); ok {
+		json.Unmarshal([]byte(v), &msgs)
+	}
+	return
+}
+
+// Session maps the values of the Store, see the package documentation
+type Session struct {
+	Store Store
+}
+
+// New returns the Session for the given Store
+func New(s Store) *Session {
+	return &Session{Store: s}
+}
+
+// Map returns the value of the given key, flash messages are only available via Flashes
+func (s *Session) Map(key string) string {
+	if strings.HasPrefix(key, flashPrefix) {
+		return ""
+	}
+	v, _ := s.Store.Get(key)
+	return v
+}
+
+// Lookup returns the mapper for the given key within the session, so that the Session is a
+// placesmap.NamespaceMapper
+func (s *Session) Lookup(key string) (places.Mapper, bool) {
+	if strings.HasPrefix(key, flashPrefix) {
+		return nil, false
+	}
+	v, ok := s.Store.Get(key)
+	return placesmap.String(v), ok
+}
+
+// Flashes returns the flash messages of the given kind and removes them from the Store
+func (s *Session) Flashes(kind string) []string {
+	msgs := flashes(s.Store, kind)
+	if len(msgs) > 0 {
+		s.Store.Delete(flashPrefix + kind)
+	}
+	return msgs
+}
+
+// flash maps the message and kind of a flash message for its template
+type flash struct {
+	kind, message string
+}
+
+func (f flash) Map(key string) string {
+	switch key {
+	case "message":
+		return f.message
+	case "kind":
+		return f.kind
+	}
+	return ""
+}
+
+// Register registers the directive "-flash kind [template]" for the given HTMLTemplate,
+// see the package documentation
+func Register(t *placesmap.HTMLTemplate) error {
+	return t.Handle("flash", func(c *placesmap.RenderContext, rest string) string {
+		args := strings.Fields(rest)
+		if len(args) == 0 || len(args) > 2 {
+			return ""
+		}
+		mp, ok := c.Mapper(Name)
+		if !ok {
+			return ""
+		}
+		s, is := mp.(*Session)
+		if !is {
+			return ""
+		}
+
+		var b strings.Builder
+		for _, msg := range s.Flashes(args[0]) {
+			if len(args) == 2 {
+				b.WriteString(c.RequireWith(args[1], flash{args[0], msg}))
+				continue
+			}
+			b.WriteString(`<p class="flash flash-` + html.EscapeString(args[0]) + `" role="status">` + html.EscapeString(msg) + "</p>")
+		}
+		return b.String()
+	})
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"github.com/metakeule/places"
+	"github.com/metakeule/places/placesmap"
+	"strings"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	rs := placesmap.NewReadSeekerMap()
+	rs.Add("page.html", strings.NewReader(`<@session.user@>|<@-flash notice@>|<@-flash error flash.html@>`))
+	rs.Add("flash.html", strings.NewReader(`<b class="<@kind@>"><@message@></b>`))
+	tpl := placesmap.NewHTMLTemplate(rs)
+	if err := Register(tpl); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &MapStore{}
+	store.Set("user", "<Donald>")
+	AddFlash(store, "notice", "Saved")
+	AddFlash(store, "error", "a <b>")
+	AddFlash(store, "error", "c")
+
+	render := func() string {
+		return tpl.NewMapper(map[string]places.Mapper{Name: New(store)}).Map("-require page.html")
+	}
+	exp := `&lt;Donald&gt;|<p class="flash flash-notice" role="status">Saved</p>|<b class="error">a &lt;b&gt;</b><b class="error">c</b>`
+	if got := render(); got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	// the flash messages have been consumed
+	if got, exp := render(), "&lt;Donald&gt;||"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+	if got := New(store).Map("_flash.notice"); got != "" {
+		t.Errorf("flash messages must not be mapped: %#v", got)
+	}
+}
@@ -0,0 +1,96 @@
+package placesmap
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrNoLoader = errors.New("template set has no loader")
+
+// TemplateSet holds the current HTMLTemplate of a server and replaces it atomically, so that
+// templates can be reloaded while requests are served: renders that are running keep using the
+// HTMLTemplate they started with, new renders use the new one.
+type TemplateSet struct {
+	v      atomic.Value // *HTMLTemplate
+	mx     sync.Mutex   // serializes Swap and Reload
+	loader *TemplateLoader
+}
+
+// NewTemplateSet returns a TemplateSet with the given HTMLTemplate
+func NewTemplateSet(h *HTMLTemplate) *TemplateSet {
+	s := &TemplateSet{}
+	s.v.Store(h)
+	return s
+}
+
+// NewTemplateSetLoader returns a TemplateSet with the templates of the given TemplateLoader,
+// that are loaded again by Reload
+func NewTemplateSetLoader(l *TemplateLoader) (*TemplateSet, error) {
+	rs, err := l.Load()
+	if err != nil {
+		return nil, err
+	}
+	s := NewTemplateSet(NewHTMLTemplate(rs))
+	s.loader = l
+	return s, nil
+}
+
+// Load returns the current HTMLTemplate
+func (s *TemplateSet) Load() *HTMLTemplate {
+	return s.v.Load().(*HTMLTemplate)
+}
+
+// Swap replaces the current HTMLTemplate by h and returns the previous one
+func (s *TemplateSet) Swap(h *HTMLTemplate) (old *HTMLTemplate) {
+	s.mx.Lock()
+	old = s.Load()
+	s.v.Store(h)
+	s.mx.Unlock()
+	return old
+}
+
+// Reload runs the TemplateLoader of the set again and swaps in the new templates, keeping
+// the settings of the current HTMLTemplate (handlers, middlewares, timeouts, hooks, charset,
+// Content-Security-Policy, flag provider, policy, statistics, Options and stream threshold). If loading fails, the current HTMLTemplate is kept.
+// A TemplateSet that has not been created by NewTemplateSetLoader can't be reloaded and returns
+// ErrNoLoader.
+func (s *TemplateSet) Reload() error {
+	if s.loader == nil {
+		return ErrNoLoader
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	rs, err := s.loader.Load()
+	if err != nil {
+		return err
+	}
+	cur := s.Load()
+	// the templates are validated according to the Options of the current HTMLTemplate
+	h := NewHTMLTemplate(rs, func(o *Options) { *o = cur.Options() })
+	h.copySettings(cur)
+	s.v.Store(h)
+	return nil
+}
+
+// copySettings copies the settings of from to h
+func (h *HTMLTemplate) copySettings(from *HTMLTemplate) {
+	from.RLock()
+	defer from.RUnlock()
+	h.Lock()
+	defer h.Unlock()
+	h.handlers = from.handlers
+	h.middlewares = append([]Middleware(nil), from.middlewares...)
+	h.timeouts, h.hooks = from.timeouts, from.hooks
+	h.charset, h.csp, h.flags, h.policy = from.charset, from.csp, from.flags, from.policy
+	h.stats, h.slow, h.checksum, h.themes = from.stats, from.slow, from.checksum, from.themes
+	h.fallback = from.fallback
+	h.aliases, h.usages, h.norm = from.aliases, from.usages, from.norm
+	h.compressors, h.guard, h.access, h.options = from.compressors, from.guard, from.access, from.options
+	h.profile, h.streaming = from.profile, from.streaming
+	// the fragments of the old templates are outdated
+	if from.fragments != nil {
+		h.fragments = newFragments(from.fragments.stale)
+	}
+}
@@ -0,0 +1,87 @@
+package placesmap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placesmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("v1 <@-upper x@>")
+	set, err := NewTemplateSetLoader(NewTemplateLoader(dir, ".html", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Load().Handle("upper", func(c *RenderContext, rest string) string { return strings.ToUpper(rest) })
+
+	old := set.Load()
+	write("v2 <@-upper x@>")
+	if err := set.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := set.Load().NewMapper(nil).Map("-require page.html"), "v2 X"; got != exp {
+		t.Errorf("unexpected result after reload: %#v, expected: %#v", got, exp)
+	}
+	if got, exp := old.NewMapper(nil).Map("-require page.html"), "v1 X"; got != exp {
+		t.Errorf("unexpected result of the previous templates: %#v, expected: %#v", got, exp)
+	}
+
+	os.RemoveAll(dir)
+	if err := set.Reload(); err == nil {
+		t.Errorf("expected error for missing root")
+	}
+	if got, exp := set.Load().NewMapper(nil).Map("-require page.html"), "v2 X"; got != exp {
+		t.Errorf("unexpected result after failed reload: %#v, expected: %#v", got, exp)
+	}
+
+	if prev := set.Swap(old); prev == old || set.Load() != old {
+		t.Errorf("Swap did not replace the templates")
+	}
+	if err := NewTemplateSet(old).Reload(); err != ErrNoLoader {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTemplateSetReloadKeepsOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "placesmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte("<@a@><@b@>"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "small.html"), []byte("<@a@>"), 0644)
+
+	set, err := NewTemplateSetLoader(NewTemplateLoader(dir, ".html", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHTMLTemplate(NewReadSeekerMap(), Production(), WithLimits(Limits{MaxPlaceholders: 1}))
+	h.SetStreamThreshold(7)
+	set.Swap(h)
+	if err := set.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	cur := set.Load()
+	if cur.Options() != h.Options() || cur.streaming != 7 {
+		t.Errorf("the settings are lost on reload: %+v, %d", cur.Options(), cur.streaming)
+	}
+	// the templates are validated according to the limits
+	if cur.Has("page.html") || !cur.Has("small.html") {
+		t.Errorf("the templates are not validated according to the options")
+	}
+}
@@ -0,0 +1,102 @@
+package placesmap
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"sync"
+)
+
+// SlotsName is the name that the Slots of a request must be registered with for "-slot"
+const SlotsName = "slots"
+
+// Slots collects markup for the named slots of a layout, e.g. the extra tags for the head of a
+// page that are added by the handler of the page. Registered as "slots" (see SlotsName),
+// "-slot head" renders the entries of the slot head in the order they were added.
+// Entries with the same key are only rendered once: an entry replaces an earlier one with the
+// same key at its position. Script and style tags get the nonce of the render (see WithNonce).
+type Slots struct {
+	mx    sync.Mutex
+	slots map[string]*slot
+}
+
+type slot struct {
+	entries []string
+	keys    map[string]int // the index of the entry by key
+}
+
+// NewSlots returns empty Slots
+func NewSlots() *Slots {
+	return &Slots{slots: map[string]*slot{}}
+}
+
+// Add adds the given markup to the slot with the given name. The markup is not escaped.
+// If key is empty, the markup itself is the key.
+func (s *Slots) Add(name, key, markup string) {
+	if key == "" {
+		key = markup
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	sl, has := s.slots[name]
+	if !has {
+		sl = &slot{keys: map[string]int{}}
+		s.slots[name] = sl
+	}
+	if idx, has := sl.keys[key]; has {
+		sl.entries[idx] = markup
+		return
+	}
+	sl.keys[key] = len(sl.entries)
+	sl.entries = append(sl.entries, markup)
+}
+
+// Meta adds a meta tag with the given name and content to the slot head
+func (s *Slots) Meta(name, content string) {
+	s.Add("head", "meta "+name, `<meta name="`+html.EscapeString(name)+`" content="`+html.EscapeString(content)+`">`)
+}
+
+// Canonical adds the link to the canonical URL to the slot head
+func (s *Slots) Canonical(href string) {
+	s.Add("head", "canonical", `<link rel="canonical" href="`+html.EscapeString(href)+`">`)
+}
+
+// Script adds a script tag for the given source to the slot head
+func (s *Slots) Script(src string) {
+	s.Add("head", "script "+src, `<script src="`+html.EscapeString(src)+`"></script>`)
+}
+
+// Stylesheet adds a link to the given stylesheet to the slot head
+func (s *Slots) Stylesheet(href string) {
+	s.Add("head", "stylesheet "+href, `<link rel="stylesheet" href="`+html.EscapeString(href)+`">`)
+}
+
+// Map returns the markup of the slot with the given name
+func (s *Slots) Map(name string) string {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	sl, has := s.slots[name]
+	if !has {
+		return ""
+	}
+	return strings.Join(sl.entries, "\n")
+}
+
+// slot handles "-slot name"
+func (r *RenderContext) slot(rest string) string {
+	mp, ok := r.mapper(SlotsName)
+	if !ok {
+		return ""
+	}
+	s, is := mp.(*Slots)
+	if !is {
+		return ""
+	}
+	markup := s.Map(strings.TrimSpace(rest))
+	if r.hm.nonce == nil && r.caching == 0 || markup == "" {
+		return markup
+	}
+	var bf bytes.Buffer
+	insertNonce(&bf, []byte(markup), bytes.ToLower([]byte(markup)), r.nonceAttr())
+	return bf.String()
+}
@@ -0,0 +1,35 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+)
+
+func TestSlots(t *testing.T) {
+	tpl := newTemplate("layout.html", "<head><@-slot head@></head><@-slot footer@>")
+	s := NewSlots()
+	s.Meta("description", `a "page"`)
+	s.Script("/app.js")
+	s.Canonical("/old")
+	s.Script("/app.js")
+	s.Canonical("/page?a=1&b=2")
+	s.Add("head", "", "<style>p{}</style>")
+	s.Add("head", "", "<style>p{}</style>")
+
+	exp := "<head><meta name=\"description\" content=\"a &#34;page&#34;\">\n<script src=\"/app.js\"></script>\n" +
+		"<link rel=\"canonical\" href=\"/page?a=1&amp;b=2\">\n<style>p{}</style></head>"
+	hm := tpl.NewMapper(map[string]places.Mapper{SlotsName: s})
+	if got := hm.Map("-require layout.html"); got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	n, err := NewNonceMapper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := hm.WithNonce(n).Map("-require layout.html")
+	if c := strings.Count(got, `nonce="`+n.nonce+`"`); c != 2 {
+		t.Errorf("expected nonces for the script and style tags: %#v", got)
+	}
+}
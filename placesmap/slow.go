@@ -0,0 +1,115 @@
+package placesmap
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SlowRenderTop is the number of the slowest includes and mappers that a SlowRender reports
+var SlowRenderTop = 5
+
+// Timing is the time spent for an include or mapper during a render
+type Timing struct {
+	Name     string
+	Duration time.Duration
+	Count    int
+}
+
+// SlowRender is the report of a render that exceeded the threshold set via SetSlowRender
+type SlowRender struct {
+	Input    string // the rendered placeholder, e.g. "-require page.html"
+	Duration time.Duration
+
+	// the slowest includes and mappers, the slowest first. The durations of includes
+	// contain their nested includes.
+	Includes []Timing
+	Mappers  []Timing
+}
+
+func (s SlowRender) String() string {
+	format := func(ts []Timing) string {
+		parts := make([]string, len(ts))
+		for i, t := range ts {
+			parts[i] = fmt.Sprintf("%s %s (%dx)", t.Name, t.Duration, t.Count)
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprintf("slow render of %#v took %s; slowest includes: %s; slowest mappers: %s",
+		s.Input, s.Duration, format(s.Includes), format(s.Mappers))
+}
+
+// SetSlowRender sets the threshold for slow renders of all renders that are started afterwards.
+// Renders via Map and MapStrict that take at least the threshold are reported to Hooks.SlowRender
+// or logged via the log package, if there is no hook. 0 disables the reporting.
+func (h *HTMLTemplate) SetSlowRender(threshold time.Duration) {
+	h.Lock()
+	h.slow = threshold
+	h.Unlock()
+}
+
+// timings collects the durations of includes and mappers within a render. It is shared
+// with the copies of the render for timeouts.
+type timings struct {
+	mx       sync.Mutex
+	includes map[string]*Timing
+	mappers  map[string]*Timing
+}
+
+func (ts *timings) add(m map[string]*Timing, name string, d time.Duration) {
+	ts.mx.Lock()
+	defer ts.mx.Unlock()
+	t, has := m[name]
+	if !has {
+		t = &Timing{Name: name}
+		m[name] = t
+	}
+	t.Duration += d
+	t.Count++
+}
+
+// slowest returns the SlowRenderTop slowest timings, the slowest first
+func slowest(m map[string]*Timing) []Timing {
+	res := make([]Timing, 0, len(m))
+	for _, t := range m {
+		res = append(res, *t)
+	}
+	sort.Slice(res, func(a, b int) bool {
+		if res[a].Duration != res[b].Duration {
+			return res[a].Duration > res[b].Duration
+		}
+		return res[a].Name < res[b].Name
+	})
+	if len(res) > SlowRenderTop {
+		res = res[:SlowRenderTop]
+	}
+	return res
+}
+
+// render renders the given placeholder, reporting it if it is slow (see SetSlowRender)
+func (r *RenderContext) render(input string) string {
+	if r.slow <= 0 {
+		return r.Map(input)
+	}
+
+	r.timings = &timings{includes: map[string]*Timing{}, mappers: map[string]*Timing{}}
+	start := time.Now()
+	res := r.Map(input)
+	d := time.Since(start)
+	if d < r.slow {
+		return res
+	}
+
+	r.timings.mx.Lock()
+	s := SlowRender{Input: input, Duration: d, Includes: slowest(r.timings.includes), Mappers: slowest(r.timings.mappers)}
+	r.timings.mx.Unlock()
+	if r.hooks.SlowRender != nil {
+		r.hooks.SlowRender(s)
+	} else {
+		log.Print(s)
+	}
+	return res
+}
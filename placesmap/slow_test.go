@@ -0,0 +1,43 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+	"time"
+)
+
+func TestSlowRender(t *testing.T) {
+	tpl := newTemplate("page.html", "<@slow@><@-require row.html@><@-require row.html@>", "row.html", "<@fast@>")
+	tpl.SetSlowRender(10 * time.Millisecond)
+
+	var reports []SlowRender
+	tpl.SetHooks(Hooks{SlowRender: func(s SlowRender) { reports = append(reports, s) }})
+
+	m := map[string]places.Mapper{
+		"slow": MapFunc(func(string) string { time.Sleep(20 * time.Millisecond); return "s" }),
+		"fast": String("f"),
+	}
+	if got := tpl.NewMapper(m).Map("-require page.html"); got != "sff" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected one report, got %d", len(reports))
+	}
+
+	s := reports[0]
+	if s.Input != "-require page.html" || s.Duration < 20*time.Millisecond {
+		t.Errorf("unexpected report: %s", s)
+	}
+	if len(s.Includes) != 2 || s.Includes[0].Name != "page.html" || s.Includes[1].Name != "row.html" || s.Includes[1].Count != 2 {
+		t.Errorf("unexpected includes: %v", s.Includes)
+	}
+	if len(s.Mappers) != 2 || s.Mappers[0].Name != "slow" || s.Mappers[1].Name != "fast" || s.Mappers[1].Count != 2 {
+		t.Errorf("unexpected mappers: %v", s.Mappers)
+	}
+
+	delete(m, "slow")
+	tpl.NewMapper(m).Map("-require page.html")
+	if len(reports) != 1 {
+		t.Errorf("expected no report for a fast render")
+	}
+}
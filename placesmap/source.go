@@ -0,0 +1,198 @@
+package placesmap
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source provides templates from a remote or local storage, e.g. an object storage (see S3Source).
+type Source interface {
+	// List returns the names of all templates
+	List() ([]string, error)
+
+	// Get returns the content of the template with the given name
+	Get(name string) ([]byte, error)
+
+	// ModTime returns the time of the last modification of the template with the given name
+	ModTime(name string) (time.Time, error)
+}
+
+// source is the Source of a HTMLTemplate, along with the state needed to refresh it
+type source struct {
+	Source
+	mx         sync.Mutex
+	validation Validation
+	modTimes   map[string]time.Time
+}
+
+// NewHTMLTemplateSource returns a HTMLTemplate with the templates of the given Source that pass
+// the given validation, like NewHTMLTemplateValidated. The templates may be refreshed via
+// Refresh and RefreshEvery.
+func NewHTMLTemplateSource(src Source, v Validation) (*HTMLTemplate, LoadReport, error) {
+	names, err := src.List()
+	if err != nil {
+		return nil, LoadReport{}, err
+	}
+	sort.Strings(names)
+
+	h := newHTMLTemplate(NewReadSeekerMap())
+	s := &source{Source: src, validation: v, modTimes: map[string]time.Time{}}
+	var report LoadReport
+
+	for _, k := range names {
+		mod, err := src.ModTime(k)
+		var b []byte
+		if err == nil {
+			b, err = src.Get(k)
+		}
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedTemplate{Name: k, Reason: err.Error()})
+			continue
+		}
+
+		if reason := v.check(b); reason != "" {
+			if v.policy(k) == FailOnInvalid {
+				return nil, report, InvalidTemplateError{Name: k, Reason: reason}
+			}
+			report.Skipped = append(report.Skipped, SkippedTemplate{Name: k, Reason: reason})
+			continue
+		}
+
+		h.add(k, b)
+		s.modTimes[k] = mod
+		report.Loaded = append(report.Loaded, k)
+	}
+
+	h.report = report
+	h.src = s
+	return h, report, nil
+}
+
+// Refresh reloads the templates of the Source that have been changed, added or removed since
+// the last load and returns their names. Templates that can't be read or are invalid keep their
+// previous version and the first such error is returned.
+// Without a Source (see NewHTMLTemplateSource), Refresh does nothing.
+func (h *HTMLTemplate) Refresh() (changed []string, err error) {
+	h.RLock()
+	s := h.src
+	h.RUnlock()
+	if s == nil {
+		return nil, nil
+	}
+
+	// refreshes must not overlap, since they share the modification times
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	names, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var (
+		updated = map[string][]byte{}
+		mods    = map[string]time.Time{}
+		exists  = map[string]bool{}
+	)
+
+	setErr := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+
+	for _, k := range names {
+		exists[k] = true
+		mod, e := s.ModTime(k)
+		if e != nil {
+			setErr(e)
+			continue
+		}
+		if prev, has := s.modTimes[k]; has && prev.Equal(mod) {
+			continue
+		}
+		b, e := s.Get(k)
+		if e != nil {
+			setErr(e)
+			continue
+		}
+		if reason := s.validation.check(b); reason != "" {
+			setErr(InvalidTemplateError{Name: k, Reason: reason})
+			continue
+		}
+		updated[k] = b
+		mods[k] = mod
+	}
+
+	h.Lock()
+	for k, b := range updated {
+		h.remove(k)
+		h.add(k, b)
+		s.modTimes[k] = mods[k]
+		changed = append(changed, k)
+	}
+	for k := range s.modTimes {
+		if !exists[k] {
+			h.remove(k)
+			delete(s.modTimes, k)
+			changed = append(changed, k)
+		}
+	}
+	h.Unlock()
+
+	sort.Strings(changed)
+	return changed, err
+}
+
+// RefreshEvery calls Refresh in the background every d until the returned stop function is called.
+// Errors are reported to the RefreshError hook (see SetHooks).
+func (h *HTMLTemplate) RefreshEvery(d time.Duration) (stop func()) {
+	ticker := time.NewTicker(d)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := h.Refresh(); err != nil {
+					h.refreshError(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// refreshError reports the given error to the RefreshError hook
+func (h *HTMLTemplate) refreshError(err error) {
+	h.RLock()
+	hook := h.hooks.RefreshError
+	h.RUnlock()
+	if hook != nil {
+		hook(err)
+	}
+}
+
+// remove removes the template with the given name and its metadata, the caller must hold the lock
+func (h *HTMLTemplate) remove(name string) {
+	delete(h.meta, name)
+	// including the templates of the inline bodies
+	for k, t := range h.rsm {
+		if k == name || strings.HasPrefix(k, name+"#") {
+			h.forget(t)
+			delete(h.rsm, k)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package placesmap
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type file struct {
+	content string
+	mod     time.Time
+}
+
+// memSource is a Source in memory
+type memSource struct {
+	sync.Mutex
+	files map[string]file
+}
+
+func (m *memSource) set(name, content string, mod time.Time) {
+	m.Lock()
+	m.files[name] = file{content, mod}
+	m.Unlock()
+}
+
+func (m *memSource) List() (names []string, err error) {
+	m.Lock()
+	defer m.Unlock()
+	for k := range m.files {
+		names = append(names, k)
+	}
+	return
+}
+
+func (m *memSource) Get(name string) ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+	return []byte(m.files[name].content), nil
+}
+
+func (m *memSource) ModTime(name string) (time.Time, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.files[name].mod, nil
+}
+
+func TestRefresh(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	src := &memSource{files: map[string]file{
+		"page.html": {"<@-require part.html@>", t0},
+		"part.html": {"v1", t0},
+		"old.html":  {"old", t0},
+	}}
+
+	tpl, _, err := NewHTMLTemplateSource(src, Validation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func() string { return tpl.NewMapper(nil).Map("-require page.html") }
+
+	if got := render(); got != "v1" {
+		t.Fatalf("unexpected result: %#v", got)
+	}
+
+	src.set("part.html", "v2", t0.Add(time.Second))
+	src.set("new.html", "new", t0)
+	src.Lock()
+	delete(src.files, "old.html")
+	src.Unlock()
+
+	changed, err := tpl.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"new.html", "old.html", "part.html"}; !reflect.DeepEqual(changed, exp) {
+		t.Errorf("unexpected changes: %v, expected: %v", changed, exp)
+	}
+	if got := render(); got != "v2" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+	if tpl.Has("old.html") || !tpl.Has("new.html") {
+		t.Errorf("old.html should be removed and new.html added")
+	}
+
+	if changed, _ = tpl.Refresh(); len(changed) != 0 {
+		t.Errorf("unexpected changes: %v", changed)
+	}
+
+	src.set("part.html", "v3", t0.Add(2*time.Second))
+	stop := tpl.RefreshEvery(time.Millisecond)
+	defer stop()
+	for i := 0; render() != "v3"; i++ {
+		if i > 1000 {
+			t.Fatalf("not refreshed in background")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
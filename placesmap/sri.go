@@ -0,0 +1,83 @@
+package placesmap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"github.com/metakeule/places"
+)
+
+// DynamicTemplateError is returned for templates with placeholders, whose output can't be hashed in advance
+type DynamicTemplateError string
+
+func (d DynamicTemplateError) Error() string {
+	return fmt.Sprintf("template %#v has placeholders", string(d))
+}
+
+// integrity returns the subresource integrity hash of the given template
+func (h *HTMLTemplate) integrity(name string, t *places.Template) (string, error) {
+	if s, has := h.sri.Load(t); has {
+		return s.(string), nil
+	}
+	if len(t.Placeholders()) > 0 {
+		return "", DynamicTemplateError(name)
+	}
+	var bf bytes.Buffer
+	t.ReplaceMapper(&bf, Empty{})
+	sum := sha512.Sum384(bf.Bytes())
+	s := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	h.sri.Store(t, s)
+	return s, nil
+}
+
+// SRI returns the subresource integrity hash (SHA-384) of the template with the given name,
+// e.g. of a script or stylesheet that is served from the templates. The hash is computed once
+// per loaded template. Templates with placeholders return a DynamicTemplateError, since
+// their output is not known in advance.
+func (h *HTMLTemplate) SRI(name string) (string, error) {
+	t, has := h.template(name)
+	if !has {
+		return "", TemplateNotFoundError(name)
+	}
+	return h.integrity(name, t)
+}
+
+// sri handles "-sri name", that renders the integrity hash of the template with the given name,
+// e.g. <script src="/js/app.js" integrity="<@-sri js/app.js@>" crossorigin="anonymous"></script>
+// Errors are reported to MapStrict and render as empty string.
+func (r *RenderContext) sri(name string) string {
+	t, ok := r.lookup(name)
+	if !ok {
+		r.fail(TemplateNotFoundError(name))
+		return ""
+	}
+	s, err := r.hm.HTMLTemplate.integrity(name, t)
+	if err != nil {
+		r.fail(err)
+		return ""
+	}
+	return s
+}
+
+// SetChecksumHeader sets the name of the response header that Render and Stream send with the SHA-256
+// checksum of the rendered page (before the charset encoding), e.g. to debug cache consistency.
+// Stream sends it as trailer, if the response is started before the page is complete.
+// The empty string disables the header.
+func (h *HTMLTemplate) SetChecksumHeader(header string) {
+	h.Lock()
+	h.checksum = header
+	h.Unlock()
+}
+
+// checksum returns the checksum of a rendered page for the checksum header
+func checksum(page string) string {
+	sum := sha256.Sum256([]byte(page))
+	return encodeChecksum(sum[:])
+}
+
+// encodeChecksum returns the given SHA-256 sum for the checksum header
+func encodeChecksum(sum []byte) string {
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum)
+}
@@ -0,0 +1,43 @@
+package placesmap
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSRI(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", `<script src="/js/app.js" integrity="<@-sri js/app.js@>"></script>`,
+		"js/app.js", "alert(1)",
+		"dynamic.js", "alert(<@n@>)",
+	)
+	// printf 'alert(1)' | openssl dgst -sha384 -binary | base64
+	exp := "sha384-HT2E9NfWiuQ/w1PRai+hTyqW16NIoCGA/m8VQDUopfAtcz6YQjtsMmQd5uRbVDpW"
+
+	if got, err := tpl.SRI("js/app.js"); err != nil || got != exp {
+		t.Errorf("unexpected hash: %#v, %v, expected: %#v", got, err, exp)
+	}
+	if got, err := tpl.NewMapper(nil).MapStrict("-require page.html"); err != nil || got != `<script src="/js/app.js" integrity="`+exp+`"></script>` {
+		t.Errorf("unexpected result: %#v, %v", got, err)
+	}
+	if _, err := tpl.SRI("dynamic.js"); err != DynamicTemplateError("dynamic.js") {
+		t.Errorf("expected DynamicTemplateError, got %v", err)
+	}
+	if _, err := tpl.NewMapper(nil).MapStrict("-sri missing.js"); err != TemplateNotFoundError("missing.js") {
+		t.Errorf("expected TemplateNotFoundError, got %v", err)
+	}
+}
+
+func TestRenderChecksum(t *testing.T) {
+	tpl := newTemplate("page.html", "hello")
+	tpl.SetChecksumHeader("X-Checksum")
+
+	rec := httptest.NewRecorder()
+	if err := Render(rec, tpl, "page.html", nil); err != nil {
+		t.Fatal(err)
+	}
+	// printf hello | openssl dgst -sha256 -binary | base64
+	if got, exp := rec.Header().Get("X-Checksum"), "sha256-LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="; got != exp {
+		t.Errorf("unexpected checksum: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,91 @@
+package placesmap
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stats collects the render statistics of the templates
+type stats struct {
+	mx        sync.RWMutex
+	templates map[string]*templateStats
+}
+
+type templateStats struct {
+	renders  int64
+	duration int64 // nanoseconds
+	size     int64
+}
+
+func (s *stats) record(name string, d time.Duration, size int) {
+	s.mx.RLock()
+	ts, has := s.templates[name]
+	s.mx.RUnlock()
+	if !has {
+		s.mx.Lock()
+		if ts, has = s.templates[name]; !has {
+			ts = &templateStats{}
+			s.templates[name] = ts
+		}
+		s.mx.Unlock()
+	}
+	atomic.AddInt64(&ts.renders, 1)
+	atomic.AddInt64(&ts.duration, int64(d))
+	atomic.AddInt64(&ts.size, int64(size))
+}
+
+// TemplateStats are the render statistics of a template. Durations include the nested includes.
+type TemplateStats struct {
+	Renders         int64         `json:"renders"`
+	TotalDuration   time.Duration `json:"total_duration_ns"`
+	AverageDuration time.Duration `json:"average_duration_ns"`
+	TotalSize       int64         `json:"total_size"`
+	AverageSize     int64         `json:"average_size"`
+}
+
+// EnableStats enables the collection of render statistics for all renders that are started
+// afterwards. Every render of a template as page or include (by -require, -if etc.) counts
+// as a render, while loops (-each) and trees (-tree) count as one render of their templates.
+func (h *HTMLTemplate) EnableStats() {
+	h.Lock()
+	if h.stats == nil {
+		h.stats = &stats{templates: map[string]*templateStats{}}
+	}
+	h.Unlock()
+}
+
+// Stats returns the render statistics by template name, nil if they are not enabled
+func (h *HTMLTemplate) Stats() map[string]TemplateStats {
+	h.RLock()
+	s := h.stats
+	h.RUnlock()
+	if s == nil {
+		return nil
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	res := make(map[string]TemplateStats, len(s.templates))
+	for name, ts := range s.templates {
+		st := TemplateStats{
+			Renders:       atomic.LoadInt64(&ts.renders),
+			TotalDuration: time.Duration(atomic.LoadInt64(&ts.duration)),
+			TotalSize:     atomic.LoadInt64(&ts.size),
+		}
+		if st.Renders > 0 {
+			st.AverageDuration = st.TotalDuration / time.Duration(st.Renders)
+			st.AverageSize = st.TotalSize / st.Renders
+		}
+		res[name] = st
+	}
+	return res
+}
+
+// PublishStats enables the statistics and publishes them via expvar under the given name.
+// Like expvar.Publish, it panics if the name is already in use.
+func (h *HTMLTemplate) PublishStats(name string) {
+	h.EnableStats()
+	expvar.Publish(name, expvar.Func(func() interface{} { return h.Stats() }))
+}
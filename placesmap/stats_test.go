@@ -0,0 +1,37 @@
+package placesmap
+
+import (
+	"expvar"
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	tpl := newTemplate("page.html", "<@-require part.html@><@-each items row.html@>", "part.html", "part", "row.html", "<@name@>")
+	hm := tpl.NewMapper(map[string]places.Mapper{"items": list{keyed("a"), keyed("b")}})
+
+	if hm.Map("-require page.html"); tpl.Stats() != nil {
+		t.Errorf("stats collected without EnableStats")
+	}
+
+	tpl.PublishStats("placesmap_test_stats")
+	hm.Map("-require page.html")
+	hm.Map("-require page.html")
+
+	stats := tpl.Stats()
+	exp := map[string][2]int64{"page.html": {2, 6}, "part.html": {2, 4}, "row.html": {2, 2}}
+	for name, e := range exp {
+		st := stats[name]
+		if st.Renders != e[0] || st.AverageSize != e[1] || st.TotalSize != e[0]*e[1] {
+			t.Errorf("%s: unexpected stats: %#v", name, st)
+		}
+		if st.TotalDuration <= 0 || st.AverageDuration > st.TotalDuration {
+			t.Errorf("%s: unexpected durations: %#v", name, st)
+		}
+	}
+
+	if v := expvar.Get("placesmap_test_stats"); v == nil || !strings.Contains(v.String(), `"page.html":{"renders":2`) {
+		t.Errorf("unexpected expvar: %v", v)
+	}
+}
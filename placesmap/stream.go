@@ -0,0 +1,149 @@
+package placesmap
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"github.com/metakeule/places"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+)
+
+// DefaultStreamThreshold is the number of bytes that Stream buffers, if no threshold is set
+const DefaultStreamThreshold = 32 * 1024
+
+// RenderErrorTrailer is the HTTP trailer that Stream sends, if a render failed after the response
+// has been started. Its value is always RenderErrorMessage, the error itself is not sent to the client.
+const RenderErrorTrailer = "Render-Error"
+
+// RenderErrorMessage is the value of the RenderErrorTrailer
+const RenderErrorMessage = "render failed"
+
+// errStreamFailed is returned by a streamWriter whose render failed before the response was started
+var errStreamFailed = errors.New("render failed before the response was started")
+
+// SetStreamThreshold sets the number of bytes that Stream buffers before the response is started.
+// n < 1 means DefaultStreamThreshold.
+func (h *HTMLTemplate) SetStreamThreshold(n int) {
+	h.Lock()
+	h.streaming = n
+	h.Unlock()
+}
+
+// streamWriter starts the response on its first write, i.e. when the buffer of the stream is full,
+// unless the render has failed before
+type streamWriter struct {
+	p       *response
+	sum     hash.Hash // the checksum of the page, nil without checksum header
+	done    bool      // whether the render was complete before the response was started
+	started bool
+	failed  bool
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.failed {
+		return 0, errStreamFailed
+	}
+	if !s.started {
+		if s.p.r.err != nil {
+			s.failed = true
+			return 0, errStreamFailed
+		}
+		s.start()
+	}
+	n, err := s.p.wr.Write(p)
+	if f, ok := s.p.wr.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
+
+// start sets the headers of the response. If the render is not complete, the checksum and
+// the composition of the page are announced as trailers.
+func (s *streamWriter) start() {
+	s.started = true
+	s.p.header()
+	if s.done {
+		s.p.record(s.p.wr.Header(), s.checksum())
+		return
+	}
+	h := s.p.wr.Header()
+	h.Add("Trailer", RenderErrorTrailer)
+	if s.p.checksum != "" {
+		h.Add("Trailer", s.p.checksum)
+	}
+	if s.p.r.composed != nil && s.p.access.Header != "" {
+		h.Add("Trailer", s.p.access.Header)
+	}
+}
+
+// checksum returns the checksum of the page for the checksum header
+func (s *streamWriter) checksum() string {
+	if s.sum == nil {
+		return ""
+	}
+	return encodeChecksum(s.sum.Sum(nil))
+}
+
+// stream renders and streams the page, see Stream
+func (p *response) stream(threshold int) error {
+	s := &streamWriter{p: p}
+	w := p.cs.encoder(s)
+	if p.checksum != "" {
+		s.sum = sha256.New()
+		w = io.MultiWriter(s.sum, w)
+	}
+	bf := bufio.NewWriterSize(w, threshold)
+	p.r.stream(bf, p.name)
+	s.done = !s.started
+
+	if !s.started && p.r.err != nil {
+		return p.fail()
+	}
+	if err := bf.Flush(); err != nil && p.r.err == nil {
+		return err
+	}
+	if !s.started {
+		// empty page
+		s.start()
+		return nil
+	}
+	if s.done {
+		return p.r.err
+	}
+	p.record(p.wr.Header(), s.checksum())
+	if p.r.err != nil {
+		p.wr.Header().Set(RenderErrorTrailer, RenderErrorMessage)
+		if p.r.hooks.StreamError != nil {
+			p.r.hooks.StreamError(p.name, p.r.err)
+		} else {
+			log.Print("streaming ", p.name, ": ", p.r.err)
+		}
+	}
+	return p.r.err
+}
+
+// Stream renders the template with the given name and the given mappers as text/html to the
+// ResponseWriter like Render, but streams the page (see Pipe), so that the client gets the first
+// bytes before the page is complete. The output is buffered until the threshold set via
+// HTMLTemplate.SetStreamThreshold is reached; if the render fails (see MapStrict) before, nothing
+// of the page is sent, but a 500 Internal Server Error.
+// The headers are the same as those of Render, but once the response has been started before the
+// page is complete, the checksum header and the header of the AccessLog are sent as trailers.
+// If the render fails after the response has been started, the trailer RenderErrorTrailer is sent
+// and the error is reported to Hooks.StreamError or logged via log.Print, since the
+// status can't be changed anymore.
+// Stream returns the first error of the render. If the template does not exist,
+// TemplateNotFoundError is returned and nothing is written.
+// Requests for a DebugDump get the dump instead of the page, see Debug.
+func Stream(wr http.ResponseWriter, t *HTMLTemplate, name string, m map[string]places.Mapper) error {
+	t.RLock()
+	threshold := t.streaming
+	t.RUnlock()
+	if threshold < 1 {
+		threshold = DefaultStreamThreshold
+	}
+	return serve(wr, nil, t, name, m, threshold)
+}
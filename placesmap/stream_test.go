@@ -0,0 +1,104 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamHeaders(t *testing.T) {
+	tpl := newTemplate(
+		"small.html", "<b><@name@></b>",
+		"big.html", strings.Repeat("x", 100)+"<@name@>",
+	)
+	tpl.SetStreamThreshold(16)
+	tpl.SetChecksumHeader("X-Checksum")
+	var records int
+	tpl.SetAccessLog(AccessLog{Header: "X-Templates", Record: func(Composition) { records++ }})
+	m := map[string]places.Mapper{"name": String("Donald")}
+
+	// the small page is complete before the response is started
+	rec := httptest.NewRecorder()
+	if err := Stream(rec, tpl, "small.html", m); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := rec.Header().Get("X-Checksum"), checksum("<b>Donald</b>"); got != exp {
+		t.Errorf("unexpected checksum header: %#v, expected: %#v", got, exp)
+	}
+	if !strings.HasPrefix(rec.Header().Get("X-Templates"), "small.html@") {
+		t.Errorf("unexpected templates header: %#v", rec.Header().Get("X-Templates"))
+	}
+
+	rec = httptest.NewRecorder()
+	if err := Stream(rec, tpl, "big.html", m); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if got, exp := res.Trailer.Get("X-Checksum"), checksum(strings.Repeat("x", 100)+"Donald"); got != exp {
+		t.Errorf("unexpected checksum trailer: %#v, expected: %#v", got, exp)
+	}
+	if !strings.HasPrefix(res.Trailer.Get("X-Templates"), "big.html@") {
+		t.Errorf("unexpected templates trailer: %#v", res.Trailer.Get("X-Templates"))
+	}
+	if records != 2 {
+		t.Errorf("expected 2 records, got %d", records)
+	}
+
+	handler := Debug(func(*http.Request) bool { return true })(http.HandlerFunc(func(wr http.ResponseWriter, rq *http.Request) {
+		Stream(wr, tpl, "big.html", m)
+	}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/?"+DebugParam+"=1", nil))
+	if got := rec.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("expected the DebugDump, got %#v", got)
+	}
+}
+
+func TestStream(t *testing.T) {
+	tpl := newTemplate(
+		"early.html", "<p><@-require missing.html@></p>"+strings.Repeat("x", 100),
+		"late.html", strings.Repeat("x", 100)+"<@-require missing.html@>",
+		"ok.html", "<b><@name@></b>",
+	)
+	tpl.SetStreamThreshold(16)
+	var reported []string
+	tpl.SetHooks(Hooks{StreamError: func(name string, err error) { reported = append(reported, name+": "+err.Error()) }})
+	m := map[string]places.Mapper{"name": String("Donald")}
+
+	rec := httptest.NewRecorder()
+	if err := Stream(rec, tpl, "early.html", m); err != TemplateNotFoundError("missing.html") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "<p>") {
+		t.Errorf("expected a clean 500, got %d %#v", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	if err := Stream(rec, tpl, "late.html", m); err != TemplateNotFoundError("missing.html") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK || rec.Body.String() != strings.Repeat("x", 100) || !rec.Flushed {
+		t.Errorf("unexpected response: %d %#v", res.StatusCode, rec.Body.String())
+	}
+	if got := res.Trailer.Get(RenderErrorTrailer); got != RenderErrorMessage {
+		t.Errorf("unexpected trailer: %#v", got)
+	}
+	if len(reported) != 1 || reported[0] != `late.html: template "missing.html" not found` {
+		t.Errorf("unexpected reported errors: %v", reported)
+	}
+
+	rec = httptest.NewRecorder()
+	if err := Stream(rec, tpl, "ok.html", m); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "<b>Donald</b>" || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("unexpected response: %d %#v", rec.Code, rec.Body.String())
+	}
+
+	if err := Stream(httptest.NewRecorder(), tpl, "missing.html", m); err != TemplateNotFoundError("missing.html") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package placesmap
+
+import (
+	"bytes"
+	"html"
+	"math"
+	"net/url"
+	"strconv"
+)
+
+// Aggregates for the footer of a Column
+const (
+	AggregateSum   = "sum"
+	AggregateCount = "count"
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+)
+
+// Column is a column of a TableMapper
+type Column struct {
+	Label string // the header of the column
+	Key   string // the key that is mapped by the rows
+
+	// Escape is the escaping of the values: "html" (the default), "url" or "raw".
+	// Raw values must be safe HTML.
+	Escape string
+
+	// RowHeader makes the cells headers of their rows, e.g. for the column of names
+	RowHeader bool
+
+	// Aggregate is the value of the footer: one of AggregateSum, AggregateCount, AggregateAvg,
+	// AggregateMin and AggregateMax. Count counts the rows with a value, the others skip values
+	// that are no numbers (see Float). "" means no aggregate.
+	Aggregate string
+}
+
+// TableMapper renders the rows of an NMapper as accessible table via the directive
+//
+//	-table name
+//
+// where name is the name under which the TableMapper is registered. The header cells have the scope col,
+// cells of RowHeader columns are headers with the scope row. If a column has an aggregate, the
+// table gets a footer with the aggregates and the FooterLabel.
+type TableMapper struct {
+	Rows        NMapper
+	Columns     []Column
+	Caption     string // "" means no caption
+	Class       string // the class of the table, "" means none
+	FooterLabel string // the label of the footer, rendered within the first column without aggregate
+}
+
+// Map returns the empty string, the TableMapper is used via its directive
+func (t *TableMapper) Map(string) string { return "" }
+
+// escape returns the value escaped for the column
+func (c Column) escape(val string) string {
+	switch c.Escape {
+	case "raw":
+		return val
+	case "url":
+		return html.EscapeString(url.QueryEscape(val))
+	}
+	return html.EscapeString(val)
+}
+
+// aggregate returns the aggregate of the column
+func (c Column) aggregate(rows NMapper) string {
+	var (
+		vals  []float64
+		count int
+	)
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.NMap(i, "")
+		if row.Map(c.Key) != "" {
+			count++
+		}
+		if f, err := Float(row, c.Key); err == nil {
+			vals = append(vals, f)
+		}
+	}
+
+	if c.Aggregate == AggregateCount {
+		return strconv.Itoa(count)
+	}
+	if len(vals) == 0 && c.Aggregate != AggregateSum {
+		return ""
+	}
+	var res float64
+	switch c.Aggregate {
+	case AggregateSum, AggregateAvg:
+		for _, f := range vals {
+			res += f
+		}
+		if c.Aggregate == AggregateAvg {
+			res /= float64(len(vals))
+		}
+	case AggregateMin, AggregateMax:
+		res = vals[0]
+		for _, f := range vals[1:] {
+			if c.Aggregate == AggregateMin {
+				res = math.Min(res, f)
+			} else {
+				res = math.Max(res, f)
+			}
+		}
+	default:
+		return ""
+	}
+	return strconv.FormatFloat(res, 'f', -1, 64)
+}
+
+// Table returns the markup of the table
+func (t *TableMapper) Table() string {
+	var bf bytes.Buffer
+	bf.WriteString("<table")
+	if t.Class != "" {
+		bf.WriteString(` class="` + AttrEscape(t.Class) + `"`)
+	}
+	bf.WriteString(">")
+	if t.Caption != "" {
+		bf.WriteString("<caption>" + html.EscapeString(t.Caption) + "</caption>")
+	}
+
+	bf.WriteString("<thead><tr>")
+	for _, c := range t.Columns {
+		bf.WriteString(`<th scope="col">` + html.EscapeString(c.Label) + "</th>")
+	}
+	bf.WriteString("</tr></thead><tbody>")
+
+	if t.Rows != nil {
+		for i := 0; i < t.Rows.Len(); i++ {
+			row := t.Rows.NMap(i, "")
+			bf.WriteString("<tr>")
+			for _, c := range t.Columns {
+				val := c.escape(row.Map(c.Key))
+				if c.RowHeader {
+					bf.WriteString(`<th scope="row">` + val + "</th>")
+				} else {
+					bf.WriteString("<td>" + val + "</td>")
+				}
+			}
+			bf.WriteString("</tr>")
+		}
+	}
+	bf.WriteString("</tbody>")
+
+	if t.hasAggregates() {
+		label := t.FooterLabel
+		bf.WriteString("<tfoot><tr>")
+		for _, c := range t.Columns {
+			val := ""
+			if c.Aggregate != "" {
+				if t.Rows != nil {
+					val = html.EscapeString(c.aggregate(t.Rows))
+				}
+			} else {
+				val, label = html.EscapeString(label), ""
+			}
+			if c.RowHeader {
+				bf.WriteString(`<th scope="row">` + val + "</th>")
+			} else {
+				bf.WriteString("<td>" + val + "</td>")
+			}
+		}
+		bf.WriteString("</tr></tfoot>")
+	}
+	bf.WriteString("</table>")
+	return bf.String()
+}
+
+// hasAggregates returns whether one of the columns has an aggregate
+func (t *TableMapper) hasAggregates() bool {
+	for _, c := range t.Columns {
+		if c.Aggregate != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// table handles "-table name"
+func (r *RenderContext) table(rest string) string {
+	mp, ok := r.mapper(rest)
+	if !ok {
+		return ""
+	}
+	// only a TableMapper guarantees proper escaping of the values
+	if t, is := mp.(*TableMapper); is {
+		return t.Table()
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	tpl := newTemplate("page.html", "<@-table users@><@-table plain@>")
+	users := &TableMapper{
+		Rows:    list{typed{"name": "<Tom>", "age": 30, "link": "<b>x</b>"}, typed{"name": "Ann", "age": "x", "link": ""}, typed{"name": "Bob", "age": 42.5}},
+		Columns: []Column{{Label: "Name", Key: "name", RowHeader: true}, {Label: "Age", Key: "age", Aggregate: AggregateAvg}, {Label: "Link", Key: "link", Escape: "raw", Aggregate: AggregateCount}},
+		Caption: "Users & Admins", Class: "users", FooterLabel: "Average",
+	}
+	got := tpl.NewMapper(map[string]places.Mapper{"users": users, "plain": String("<table>")}).Map("-require page.html")
+	exp := `<table class="users"><caption>Users &amp; Admins</caption>` +
+		`<thead><tr><th scope="col">Name</th><th scope="col">Age</th><th scope="col">Link</th></tr></thead><tbody>` +
+		`<tr><th scope="row">&lt;Tom&gt;</th><td>30</td><td><b>x</b></td></tr>` +
+		`<tr><th scope="row">Ann</th><td>x</td><td></td></tr>` +
+		`<tr><th scope="row">Bob</th><td>42.5</td><td></td></tr></tbody>` +
+		`<tfoot><tr><th scope="row">Average</th><td>36.25</td><td>1</td></tr></tfoot></table>`
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	for agg, exp := range map[string]string{AggregateSum: "72.5", AggregateMin: "30", AggregateMax: "42.5", AggregateCount: "3"} {
+		if got := (Column{Key: "age", Aggregate: agg}).aggregate(users.Rows); got != exp {
+			t.Errorf("%s: unexpected aggregate: %#v, expected: %#v", agg, got, exp)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package placesmap
+
+import (
+	"crypto/sha256"
+	"github.com/metakeule/places"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// TenantFunc returns the tenant of a request
+type TenantFunc func(rq *http.Request) string
+
+// HostTenant is a TenantFunc that returns the lower cased host of the request without port
+func HostTenant(rq *http.Request) string {
+	host := rq.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// MultiTenantSet maps tenants (e.g. hostnames) to independent HTMLTemplates, so that one server
+// can serve many tenant customized sites. Templates that a tenant does not define are taken from
+// the shared fallback HTMLTemplate. Identical templates of the tenants and the fallback share
+// their memory.
+type MultiTenantSet struct {
+	Tenant TenantFunc // nil means HostTenant
+
+	mx       sync.RWMutex
+	fallback *HTMLTemplate
+	tenants  map[string]*HTMLTemplate
+	pool     map[[sha256.Size]byte]*places.Template // the templates by the hash of their source
+}
+
+// NewMultiTenantSet returns a MultiTenantSet with the given fallback
+func NewMultiTenantSet(fallback *HTMLTemplate) *MultiTenantSet {
+	m := &MultiTenantSet{fallback: fallback, tenants: map[string]*HTMLTemplate{}, pool: map[[sha256.Size]byte]*places.Template{}}
+	m.share(fallback)
+	return m
+}
+
+// share replaces the templates of h that are identical to templates within the pool
+// and adds the others to the pool
+func (m *MultiTenantSet) share(h *HTMLTemplate) {
+	h.Lock()
+	defer h.Unlock()
+	for name, t := range h.rsm {
+		sum := sha256.Sum256(t.Bytes())
+		if shared, has := m.pool[sum]; has && shared != t {
+			// the positions are the same for the same source
+			if p, has := h.positions.Load(t); has {
+				h.positions.Store(shared, p)
+			}
+			h.forget(t)
+			h.rsm[name] = shared
+			continue
+		}
+		m.pool[sum] = t
+	}
+}
+
+// Add adds the HTMLTemplate of the given tenant, replacing the previous one.
+// Missing templates of h are taken from the fallback of the set from now on.
+func (m *MultiTenantSet) Add(tenant string, h *HTMLTemplate) {
+	h.Lock()
+	h.fallback = m.fallback
+	h.Unlock()
+
+	m.mx.Lock()
+	m.share(h)
+	m.tenants[tenant] = h
+	m.mx.Unlock()
+}
+
+// Remove removes the HTMLTemplate of the given tenant
+func (m *MultiTenantSet) Remove(tenant string) {
+	m.mx.Lock()
+	delete(m.tenants, tenant)
+	m.mx.Unlock()
+}
+
+// Get returns the HTMLTemplate of the given tenant, the fallback for unknown tenants
+func (m *MultiTenantSet) Get(tenant string) *HTMLTemplate {
+	m.mx.RLock()
+	h, has := m.tenants[tenant]
+	m.mx.RUnlock()
+	if !has {
+		return m.fallback
+	}
+	return h
+}
+
+// ForRequest returns the HTMLTemplate of the tenant of the given request
+func (m *MultiTenantSet) ForRequest(rq *http.Request) *HTMLTemplate {
+	fn := m.Tenant
+	if fn == nil {
+		fn = HostTenant
+	}
+	return m.Get(fn(rq))
+}
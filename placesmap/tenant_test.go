@@ -0,0 +1,38 @@
+package placesmap
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMultiTenantSet(t *testing.T) {
+	base := newTemplate("page.html", "<@-require header.html@>|<@-require footer.html@>", "header.html", "base", "footer.html", "footer")
+	acme := newTemplate("header.html", "acme", "footer.html", "footer")
+	m := NewMultiTenantSet(base)
+	m.Add("acme.example.com", acme)
+
+	tests := map[string]string{
+		"acme.example.com":      "acme|footer",
+		"ACME.example.com:8080": "acme|footer",
+		"other.example.com":     "base|footer",
+	}
+	for host, exp := range tests {
+		rq := httptest.NewRequest("GET", "/", nil)
+		rq.Host = host
+		if got := m.ForRequest(rq).NewMapper(nil).Map("-require page.html"); got != exp {
+			t.Errorf("%s: unexpected result: %#v, expected: %#v", host, got, exp)
+		}
+	}
+
+	if a, b := acme.rsm["footer.html"], base.rsm["footer.html"]; a != b {
+		t.Errorf("identical templates are not shared")
+	}
+	if a, b := acme.rsm["header.html"], base.rsm["header.html"]; a == b {
+		t.Errorf("different templates are shared")
+	}
+
+	m.Remove("acme.example.com")
+	if m.Get("acme.example.com") != base {
+		t.Errorf("expected the fallback for removed tenants")
+	}
+}
@@ -0,0 +1,68 @@
+package placesmap
+
+import (
+	"context"
+	"github.com/metakeule/places"
+)
+
+// ThemeDir is the directory of the themes. The templates of the theme "dark" are
+// looked up within themes/dark/ before the base templates.
+const ThemeDir = "themes/"
+
+// ThemeResolver returns the active theme for the context of a render (see NewMapperContext),
+// e.g. derived from a cookie, the host or the tenant of the request. The empty string means
+// the base templates.
+type ThemeResolver func(ctx context.Context) string
+
+type themeKey struct{}
+
+// ContextWithTheme returns a context that carries the given theme for ThemeFromContext
+func ContextWithTheme(ctx context.Context, theme string) context.Context {
+	return context.WithValue(ctx, themeKey{}, theme)
+}
+
+// ThemeFromContext is a ThemeResolver that returns the theme set via ContextWithTheme
+func ThemeFromContext(ctx context.Context) string {
+	theme, _ := ctx.Value(themeKey{}).(string)
+	return theme
+}
+
+// SetThemeResolver sets the ThemeResolver for all renders that are started afterwards.
+// Themes override single templates: templates that are missing within the theme are taken from the
+// base templates. Themes with invalid names (that are no valid attribute names, e.g. containing
+// slashes) are ignored, so that themes may come from untrusted input like cookies.
+func (h *HTMLTemplate) SetThemeResolver(t ThemeResolver) {
+	h.Lock()
+	h.themes = t
+	h.Unlock()
+}
+
+// WithTheme returns a HTMLTemplateMapper with the same mappers whose renders use the given theme
+// instead of the one of the ThemeResolver
+func (h *HTMLTemplateMapper) WithTheme(theme string) *HTMLTemplateMapper {
+	c := h.copy()
+	c.theme = theme
+	return c
+}
+
+// resolveTheme returns the theme of a new render
+func (r *RenderContext) resolveTheme(resolver ThemeResolver) string {
+	theme := r.hm.theme
+	if theme == "" && resolver != nil {
+		theme = resolver(r.ctx)
+	}
+	if theme != "" && !validAttrName(theme) {
+		return ""
+	}
+	return theme
+}
+
+// template returns the template with the given name, preferring the one of the active theme
+func (r *RenderContext) template(name string) (*places.Template, bool) {
+	if r.theme != "" {
+		if t, ok := r.hm.HTMLTemplate.template(ThemeDir + r.theme + "/" + name); ok {
+			return t, ok
+		}
+	}
+	return r.hm.HTMLTemplate.template(name)
+}
@@ -0,0 +1,34 @@
+package placesmap
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTheme(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@-require header.html@>|<@-require footer.html@>",
+		"header.html", "base header",
+		"footer.html", "base footer",
+		"themes/dark/header.html", "dark header",
+		"themes/dark/page.html", "dark page <@-require footer.html@>",
+	)
+	tpl.SetThemeResolver(ThemeFromContext)
+
+	tests := map[string]string{
+		"":          "base header|base footer",
+		"dark":      "dark page base footer",
+		"light":     "base header|base footer",
+		"../themes": "base header|base footer",
+	}
+	for theme, exp := range tests {
+		hm := tpl.NewMapperContext(ContextWithTheme(context.Background(), theme), nil)
+		if got := hm.Map("-require page.html"); got != exp {
+			t.Errorf("%#v: unexpected result: %#v, expected: %#v", theme, got, exp)
+		}
+	}
+
+	if got := tpl.NewMapper(nil).WithTheme("dark").Map("-require header.html"); got != "dark header" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+}
@@ -0,0 +1,213 @@
+package placesmap
+
+import (
+	"bytes"
+	"context"
+	"github.com/metakeule/places"
+	"time"
+)
+
+// ContextMapper is a places.Mapper that can be cancelled via the context of the render.
+// The context is set via HTMLTemplate.NewMapperContext and carries the timeouts
+// configured via HTMLTemplate.SetTimeouts.
+type ContextMapper interface {
+	places.Mapper
+	MapContext(ctx context.Context, key string) string
+}
+
+// Timeouts limits the time that includes and placeholders may take to render.
+// The include timeout applies to every include within the rendered page.
+// If a timeout is exceeded, Fallback is rendered instead.
+// Mappers that are no ContextMappers can't be cancelled and keep running in the background.
+type Timeouts struct {
+	Include     time.Duration // 0 means no timeout
+	Placeholder time.Duration // 0 means no timeout
+	Fallback    string
+}
+
+// Kinds of what is timed out, reported via Hooks.Timeout
+const (
+	KindInclude     = "include"
+	KindPlaceholder = "placeholder"
+)
+
+// Hooks are called on certain events while rendering, nil hooks are ignored.
+type Hooks struct {
+	// Timeout is called when an include or placeholder (see KindInclude and KindPlaceholder)
+	// with the given name exceeded the timeout d
+	Timeout func(kind, name string, d time.Duration)
+
+	// RefreshError is called when a background refresh (see HTMLTemplate.RefreshEvery) or
+	// a reload (see ReloadOnSignal and ReloadHandler) failed
+	RefreshError func(err error)
+
+	// SlowRender is called for renders that exceeded the threshold set via HTMLTemplate.SetSlowRender
+	SlowRender func(s SlowRender)
+
+	// Deprecated is called for every usage of an alias (see HTMLTemplate.Alias) within the given template
+	Deprecated func(template, old, new string)
+
+	// A11y is called with the accessibility problems of the rendered input, if Options.A11yLint is set.
+	// Without it, the problems are logged via log.Print.
+	A11y func(input string, issues []A11yIssue)
+
+	// StreamError is called with the error of the render of the template with the given name by
+	// Stream, if the response has been started before. Without it, the error is logged via log.Print.
+	StreamError func(name string, err error)
+}
+
+// SetTimeouts sets the timeouts for all renders that are started afterwards
+func (h *HTMLTemplate) SetTimeouts(t Timeouts) {
+	h.Lock()
+	h.timeouts = t
+	h.Unlock()
+}
+
+// SetHooks sets the hooks for all renders that are started afterwards
+func (h *HTMLTemplate) SetHooks(hooks Hooks) {
+	h.Lock()
+	h.hooks = hooks
+	h.Unlock()
+}
+
+// NewMapperContext returns a mapper like NewMapper whose renders are bound to the given context,
+// which is passed to ContextMappers
+func (h *HTMLTemplate) NewMapperContext(ctx context.Context, m map[string]places.Mapper) *HTMLTemplateMapper {
+	hm := h.NewMapper(m)
+	hm.ctx = ctx
+	return hm
+}
+
+// mapValue returns the value of the given mapper for the given key,
+// respecting the context, the placeholder timeout and the memo cache (see WithMemo)
+func (r *RenderContext) mapValue(mp places.Mapper, key string) string {
+	if val, has := r.memo[key]; has {
+		return r.guardLength(key, val)
+	}
+	var start time.Time
+	if r.timings != nil {
+		start = time.Now()
+	}
+	val, ok := r.resolve(mp, key)
+	if r.timings != nil {
+		r.timings.add(r.timings.mappers, key, time.Since(start))
+	}
+	val = r.norm.normalize(val)
+	if ok && r.memo != nil {
+		r.memo[key] = val
+	}
+	return r.guardLength(key, val)
+}
+
+// resolve returns the value of the given mapper for the given key, respecting the context
+// and the placeholder timeout. It returns false, if the placeholder timed out.
+func (r *RenderContext) resolve(mp places.Mapper, key string) (string, bool) {
+	val, ok := r.timed(key, func(ctx context.Context) interface{} {
+		if cm, is := mp.(ContextMapper); is {
+			return cm.MapContext(ctx, key)
+		}
+		return mp.Map(key)
+	})
+	if !ok {
+		return r.timeouts.Fallback, false
+	}
+	return val.(string), true
+}
+
+// timed returns the result of fn for the placeholder with the given key within the placeholder
+// timeout. It returns false, if the placeholder timed out.
+func (r *RenderContext) timed(key string, fn func(ctx context.Context) interface{}) (interface{}, bool) {
+	d := r.timeouts.Placeholder
+	if d <= 0 {
+		return fn(r.ctx), true
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, d)
+	defer cancel()
+
+	res := make(chan interface{}, 1)
+	go func() {
+		res <- fn(ctx)
+	}()
+
+	select {
+	case val := <-res:
+		return val, true
+	case <-ctx.Done():
+		r.timedOut(KindPlaceholder, key, d)
+		return nil, false
+	}
+}
+
+func (r *RenderContext) timedOut(kind, name string, d time.Duration) {
+	if r.hooks.Timeout != nil {
+		r.hooks.Timeout(kind, name, d)
+	}
+}
+
+// clone returns a copy of the render state with the given context, so that
+// the copy may be used in another goroutine
+func (r *RenderContext) clone(ctx context.Context) *RenderContext {
+	c := *r
+	c.ctx = ctx
+	c.scopes = append([]places.Mapper(nil), r.scopes...)
+	if r.memo != nil {
+		c.memo = make(map[string]string, len(r.memo))
+		for k, v := range r.memo {
+			c.memo[k] = v
+		}
+	}
+	if r.locals != nil {
+		c.locals = make(map[string]places.Mapper, len(r.locals))
+		for k, v := range r.locals {
+			c.locals[k] = v
+		}
+	}
+	c.loops = append([]LoopScope(nil), r.loops...)
+	c.trees = append([]treeLevel(nil), r.trees...)
+	c.includes = append([]string(nil), r.includes...)
+	c.starts = append([]time.Time(nil), r.starts...)
+	c.linted = append([]lintedTemplate(nil), r.linted...)
+	return &c
+}
+
+// renderTimeout renders the given template, respecting the include timeout.
+// The timeout does not apply to the outermost template (the page).
+func (r *RenderContext) renderTimeout(name string, t *places.Template, m places.Mapper) string {
+	d := r.timeouts.Include
+	if d <= 0 || len(r.includes) < 2 {
+		var bf bytes.Buffer
+		t.ReplaceMapper(&bf, r.locate(t, m))
+		return bf.String()
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, d)
+	defer cancel()
+
+	// the include is rendered by a copy of the render state, so that
+	// a timed out render does not interfere with the rest of the page
+	c := r.clone(ctx)
+	if m == places.Mapper(r) {
+		m = c
+	}
+
+	res := make(chan string, 1)
+	go func() {
+		var bf bytes.Buffer
+		t.ReplaceMapper(&bf, c.locate(t, m))
+		res <- bf.String()
+	}()
+
+	select {
+	case val := <-res:
+		if r.err == nil {
+			r.err = c.err
+		}
+		r.errs = c.errs
+		r.linted = c.linted
+		return val
+	case <-ctx.Done():
+		r.timedOut(KindInclude, name, d)
+		return r.timeouts.Fallback
+	}
+}
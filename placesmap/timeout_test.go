@@ -0,0 +1,56 @@
+package placesmap
+
+import (
+	"context"
+	"github.com/metakeule/places"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slow is a ContextMapper that waits for cancellation
+type slow struct{}
+
+func (slow) Map(string) string { return "too late" }
+func (slow) MapContext(ctx context.Context, key string) string {
+	select {
+	case <-ctx.Done():
+		return ""
+	case <-time.After(time.Second):
+		return "too late"
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	tpl := newTemplate("page.html", "<@title@>|<@-require slow.html@>", "slow.html", "<@slow@>")
+	m := map[string]places.Mapper{"title": String("t"), "slow": slow{}}
+
+	var mx sync.Mutex
+	var timeouts []string
+	tpl.SetHooks(Hooks{Timeout: func(kind, name string, d time.Duration) {
+		mx.Lock()
+		timeouts = append(timeouts, kind+":"+name)
+		mx.Unlock()
+	}})
+
+	tpl.SetTimeouts(Timeouts{Placeholder: 10 * time.Millisecond, Fallback: "n/a"})
+	if got := tpl.NewMapper(m).Map("-require page.html"); got != "t|n/a" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+
+	tpl.SetTimeouts(Timeouts{Include: 10 * time.Millisecond, Fallback: "later"})
+	if got := tpl.NewMapperContext(context.Background(), m).Map("-require page.html"); got != "t|later" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+
+	if len(timeouts) != 2 || timeouts[0] != "placeholder:slow" || timeouts[1] != "include:slow.html" {
+		t.Errorf("unexpected timeouts: %#v", timeouts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tpl.SetTimeouts(Timeouts{})
+	if got := tpl.NewMapperContext(ctx, m).Map("slow"); got != "" {
+		t.Errorf("context should be passed to ContextMapper, got %#v", got)
+	}
+}
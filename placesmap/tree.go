@@ -0,0 +1,102 @@
+package placesmap
+
+import (
+	"bytes"
+	"github.com/metakeule/places"
+	"strconv"
+)
+
+// TreeNMapper is a NMapper for recursive structures like nested comments or menus
+type TreeNMapper interface {
+	NMapper
+
+	// Children returns the children of the n-th element, nil if there are none
+	Children(n int) NMapper
+}
+
+// treeLevel is the tree node that is currently rendered
+type treeLevel struct {
+	nm    NMapper
+	n     int
+	t     *places.Template
+	depth int
+}
+
+// tree handles "-tree name node.html"
+// node.html is rendered for every element of the TreeNMapper that is registered as name.
+// Within node.html "-children" renders the children of the current element with node.html
+// and "-depth" returns the depth of the current element, starting with 0 for the top level elements.
+func (r *RenderContext) tree(rest string) string {
+	args := splitArgs(rest, 2)
+	if args == nil {
+		return ""
+	}
+
+	mp, ok := r.mapper(args[0])
+	if !ok {
+		r.fail(UnknownCollectionError{Name: args[0]})
+		return ""
+	}
+
+	nm, is := mp.(NMapper)
+	if !is {
+		r.fail(ErrNotNMapper)
+		return ""
+	}
+
+	return r.RenderTree(nm, args[1])
+}
+
+// RenderTree renders the template with the given name for every element of nm like "-tree".
+// If nm is a TreeNMapper, "-children" renders the children of the current element.
+func (r *RenderContext) RenderTree(nm NMapper, name string) (res string) {
+	t, ok := r.lookup(name)
+	if !ok {
+		r.fail(TemplateNotFoundError(name))
+		return ""
+	}
+	if !r.push(name) {
+		return ""
+	}
+	defer func() { r.pop(len(res)) }()
+
+	var bf bytes.Buffer
+	r.renderTree(&bf, nm, t, 0)
+	return bf.String()
+}
+
+func (r *RenderContext) renderTree(bf *bytes.Buffer, nm NMapper, t *places.Template, depth int) {
+	l := nm.Len()
+	for i := 0; i < l; i++ {
+		r.trees = append(r.trees, treeLevel{nm: nm, n: i, t: t, depth: depth})
+		r.renderWith(bf, t, nm.NMap(i, ""))
+		r.trees = r.trees[:len(r.trees)-1]
+	}
+}
+
+// children handles "-children" within a node template of "-tree"
+func (r *RenderContext) children() string {
+	if len(r.trees) == 0 {
+		return ""
+	}
+	level := r.trees[len(r.trees)-1]
+	tnm, is := level.nm.(TreeNMapper)
+	if !is {
+		return ""
+	}
+	children := tnm.Children(level.n)
+	if children == nil {
+		return ""
+	}
+	var bf bytes.Buffer
+	r.renderTree(&bf, children, level.t, level.depth+1)
+	return bf.String()
+}
+
+// treeDepth handles "-depth" within a node template of "-tree"
+func (r *RenderContext) treeDepth() string {
+	if len(r.trees) == 0 {
+		return ""
+	}
+	return strconv.Itoa(r.trees[len(r.trees)-1].depth)
+}
@@ -0,0 +1,45 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+// node is a TreeNMapper for the tests
+type node struct {
+	text     string
+	children []node
+}
+
+type nodes []node
+
+func (n nodes) Map(string) string { return "" }
+func (n nodes) Len() int          { return len(n) }
+func (n nodes) NMap(i int, _ string) places.Mapper {
+	return MapFunc(func(key string) string {
+		if key == "text" {
+			return n[i].text
+		}
+		return ""
+	})
+}
+func (n nodes) Children(i int) NMapper {
+	if len(n[i].children) == 0 {
+		return nil
+	}
+	return nodes(n[i].children)
+}
+
+func TestTree(t *testing.T) {
+	tpl := newTemplate("comments.html", "<@-tree comments comment.html@>", "comment.html", "<p><@-depth@>:<@text@><@-children@></p>")
+	comments := nodes{
+		{text: "a", children: []node{{text: "a1", children: []node{{text: "a11"}}}, {text: "a2"}}},
+		{text: "b"},
+	}
+
+	got := tpl.NewMapper(map[string]places.Mapper{"comments": comments}).Map("-require comments.html")
+	exp := "<p>0:a<p>1:a1<p>2:a11</p></p><p>1:a2</p></p><p>0:b</p>"
+	if got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,149 @@
+package placesmap
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ellipsis is appended to truncated texts
+var Ellipsis = "…"
+
+// voidElements are the elements without end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// TruncateRunes truncates the text of the given HTML to n runes (entities count as one rune).
+// If the text is truncated, the Ellipsis is appended and the open tags are closed.
+func TruncateRunes(s string, n int) string {
+	return truncateHTML(s, n, false)
+}
+
+// TruncateWords truncates the text of the given HTML to n words, see TruncateRunes
+func TruncateWords(s string, n int) string {
+	return truncateHTML(s, n, true)
+}
+
+// tagName returns the lower cased name of the given tag (without < and >) and whether it is an end tag
+func tagName(tag string) (name string, end bool) {
+	if strings.HasPrefix(tag, "/") {
+		end = true
+		tag = tag[1:]
+	}
+	i := 0
+	for i < len(tag) && tag[i] != ' ' && tag[i] != '/' && tag[i] != '\t' && tag[i] != '\n' && tag[i] != '\r' {
+		i++
+	}
+	return strings.ToLower(tag[:i]), end
+}
+
+func truncateHTML(s string, n int, words bool) string {
+	var (
+		b     strings.Builder
+		open  []string
+		count int
+		space = true // the previous rune was a space, so that the next one starts a word
+	)
+
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case strings.HasPrefix(s[i:], "<!--"):
+			end := strings.Index(s[i:], "-->")
+			if end == -1 {
+				return b.String()
+			}
+			i += end + 3
+			continue
+		case c == '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end == -1 {
+				// no tag, but text
+				break
+			}
+			tag := s[i : i+end+1]
+			name, isEnd := tagName(tag[1 : len(tag)-1])
+			switch {
+			case name == "":
+			case isEnd:
+				for j := len(open) - 1; j >= 0; j-- {
+					if open[j] == name {
+						open = open[:j]
+						break
+					}
+				}
+			case voidElements[name] || strings.HasSuffix(tag, "/>"):
+			default:
+				open = append(open, name)
+			}
+			b.WriteString(tag)
+			i += end + 1
+			// the content of raw text elements is no text
+			if !isEnd && (name == "script" || name == "style") {
+				close := strings.Index(strings.ToLower(s[i:]), "</"+name)
+				if close == -1 {
+					close = len(s) - i
+				}
+				b.WriteString(s[i : i+close])
+				i += close
+			}
+			continue
+		}
+
+		// text: a rune or an entity
+		size := 1
+		if s[i] == '&' {
+			if end := strings.IndexByte(s[i:], ';'); end > 1 && end < 12 {
+				size = end + 1
+			}
+		}
+		r, rsize := utf8.DecodeRuneInString(s[i:])
+		if size == 1 {
+			size = rsize
+		}
+
+		isSpace := unicode.IsSpace(r)
+		if words {
+			if !isSpace && space {
+				count++
+			}
+		} else {
+			count++
+		}
+		space = isSpace
+
+		if count > n {
+			res := strings.TrimRightFunc(b.String(), unicode.IsSpace) + Ellipsis
+			for j := len(open) - 1; j >= 0; j-- {
+				res += "</" + open[j] + ">"
+			}
+			return res
+		}
+		b.WriteString(s[i : i+size])
+		i += size
+	}
+	return s
+}
+
+// truncate handles "-trunc n name" (n runes) and "-excerpt n name" (n words).
+// Like with "-html", the value is HTML and not escaped.
+func truncate(words bool) PrefixHandler {
+	return func(r *RenderContext, rest string) string {
+		s := strings.SplitN(strings.TrimSpace(rest), " ", 2)
+		if len(s) != 2 {
+			return ""
+		}
+		n, err := strconv.Atoi(s[0])
+		if err != nil || n < 0 {
+			return ""
+		}
+		name := strings.TrimSpace(s[1])
+		mp, ok := r.mapper(name)
+		if !ok {
+			return ""
+		}
+		return truncateHTML(r.mapValue(mp, name), n, words)
+	}
+}
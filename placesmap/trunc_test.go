@@ -0,0 +1,40 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		input string
+		n     int
+		words bool
+		exp   string
+	}{
+		{"Hello World", 20, false, "Hello World"},
+		{"Hello World", 7, false, "Hello W…"},
+		{"Hello World", 6, false, "Hello…"},
+		{"<p>Fish &amp; Chips</p>", 6, false, "<p>Fish &amp;…</p>"},
+		{"<p><b>bold</b> and <i>italic <u>text</u></i></p>", 13, false, "<p><b>bold</b> and <i>ital…</i></p>"},
+		{"a<br>b<img src=x.png/>cd", 3, false, "a<br>b<img src=x.png/>c…"},
+		{"<!-- x --><em>añb</em>", 2, false, "<em>añ…</em>"},
+		{"<script>var x = 1;</script>abc", 1, false, "<script>var x = 1;</script>a…"},
+		{"one two  three four", 2, true, "one two…"},
+		{"<p>one <b>two three</b> four</p>", 2, true, "<p>one <b>two…</b></p>"},
+		{"one two", 2, true, "one two"},
+		{"a < b", 3, false, "a <…"},
+	}
+
+	for _, tt := range tests {
+		if got := truncateHTML(tt.input, tt.n, tt.words); got != tt.exp {
+			t.Errorf("%#v (%d): unexpected result: %#v, expected: %#v", tt.input, tt.n, got, tt.exp)
+		}
+	}
+
+	tpl := newTemplate()
+	m := tpl.NewMapper(map[string]places.Mapper{"text": String("<p>one two three</p>")})
+	if got, exp := m.Map("-excerpt 2 text")+"|"+m.Map("-trunc 3 text"), "<p>one two…</p>|<p>one…</p>"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+}
@@ -0,0 +1,183 @@
+package placesmap
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// UnresolvedPlaceholder is a placeholder of a template that no registered mapper can satisfy
+type UnresolvedPlaceholder struct {
+	Template string `json:"template"`
+	Key      string `json:"key"`
+}
+
+// UnusedReport lists the templates and placeholders found by Unused
+type UnusedReport struct {
+	// Templates are the sorted names of the templates that are not reachable from the entry templates
+	Templates []string `json:"templates"`
+
+	// Placeholders are the unresolved placeholders of the reachable templates, sorted by template and key
+	Placeholders []UnresolvedPlaceholder `json:"placeholders"`
+}
+
+// scopedDirectives are the directives whose templates are rendered with a scope, that may
+// provide the values instead of the registered mappers
+var scopedDirectives = map[string]bool{"each": true, "with": true, "tree": true, "island": true, "component": true}
+
+// Unused reports the templates that are not reachable from the given entry templates and the
+// placeholders of the reachable templates that can't be satisfied by mappers with the given names
+// (directly, via an alias or as part of a namespace, e.g. "user.name" by the mapper "user").
+// Like RequiredKeys, the includes are followed statically. The themes and variants of a reachable
+// template and the components in ComponentDir are reachable, too. Templates that are rendered
+// within a loop, -with, -tree, -island or as component are not checked for placeholders,
+// since their values may come from the scope.
+func (h *HTMLTemplate) Unused(entries []string, mappers []string) UnusedReport {
+	names := h.names()
+	alternatives := map[string][]string{}
+	has := map[string]bool{}
+	for _, name := range names {
+		has[name] = true
+	}
+	for _, name := range names {
+		if base := themeBase(name); base != name {
+			alternatives[base] = append(alternatives[base], name)
+		}
+		if base := variantBase(name); base != name && has[base] {
+			alternatives[base] = append(alternatives[base], name)
+		}
+	}
+
+	type visit struct {
+		name   string
+		scoped bool
+	}
+	reached, scoped := map[string]bool{}, map[string]bool{}
+	var queue []visit
+	for _, e := range entries {
+		queue = append(queue, visit{e, false})
+	}
+	for len(queue) > 0 {
+		v := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if reached[v.name] && (scoped[v.name] || !v.scoped) {
+			continue
+		}
+		reached[v.name] = true
+		if v.scoped {
+			scoped[v.name] = true
+		}
+		for _, alt := range alternatives[v.name] {
+			queue = append(queue, visit{alt, v.scoped})
+		}
+		t, ok := h.template(v.name)
+		if !ok {
+			continue
+		}
+		for _, ph := range t.Placeholders() {
+			prefix, rest := split(ph)
+			sc := v.scoped || scopedDirectives[prefix]
+			for _, ref := range references(ph) {
+				queue = append(queue, visit{ref, sc})
+			}
+			if prefix == "component" {
+				if arg, _ := nextArg(rest); arg != "" {
+					queue = append(queue, visit{ComponentDir + arg + ".html", true})
+				}
+			}
+		}
+	}
+
+	report := UnusedReport{Templates: []string{}, Placeholders: []UnresolvedPlaceholder{}}
+	for _, name := range names {
+		if !reached[name] && blockOwner(name) == name {
+			report.Templates = append(report.Templates, name)
+		}
+	}
+
+	resolvable := h.resolvable(mappers)
+	seen := map[UnresolvedPlaceholder]bool{}
+	for name := range reached {
+		t, ok := h.template(name)
+		if !ok || scoped[name] {
+			continue
+		}
+		for _, ph := range t.Placeholders() {
+			for _, k := range keysOf(ph) {
+				u := UnresolvedPlaceholder{Template: blockOwner(name), Key: k}
+				if k == "" || resolvable(k) || seen[u] {
+					continue
+				}
+				seen[u] = true
+				report.Placeholders = append(report.Placeholders, u)
+			}
+		}
+	}
+	sort.Slice(report.Placeholders, func(i, j int) bool {
+		a, b := report.Placeholders[i], report.Placeholders[j]
+		if a.Template != b.Template {
+			return a.Template < b.Template
+		}
+		return a.Key < b.Key
+	})
+	return report
+}
+
+// resolvable returns a function that reports whether a key can be satisfied by the mappers
+// with the given names
+func (h *HTMLTemplate) resolvable(mappers []string) func(key string) bool {
+	h.RLock()
+	aliases := h.aliases
+	h.RUnlock()
+	// the print mode is provided by the render, see WithPrintMode
+	registered := map[string]bool{PrintModeName: true}
+	for _, m := range mappers {
+		registered[m] = true
+	}
+	var resolvable func(key string, depth int) bool
+	resolvable = func(key string, depth int) bool {
+		for k := key; ; {
+			if registered[k] {
+				return true
+			}
+			idx := strings.LastIndexByte(k, '.')
+			if idx == -1 {
+				break
+			}
+			k = k[:idx]
+		}
+		if alias, has := aliases[key]; has && depth < len(aliases) {
+			return resolvable(alias, depth+1)
+		}
+		return false
+	}
+	return func(key string) bool {
+		return resolvable(key, 0)
+	}
+}
+
+// themeBase returns the name of the template that the template with the given name
+// overrides within its theme, the name itself for templates outside of ThemeDir
+func themeBase(name string) string {
+	if !strings.HasPrefix(name, ThemeDir) {
+		return name
+	}
+	rest := name[len(ThemeDir):]
+	idx := strings.IndexByte(rest, '/')
+	if idx == -1 {
+		return name
+	}
+	return rest[idx+1:]
+}
+
+// variantBase returns the name of the template that the given name would be a variant of
+// (see variantName), e.g. page.html for page.b.html
+func variantBase(name string) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	v := filepath.Ext(stem)
+	if v == "" || strings.ContainsRune(v, '/') {
+		return name
+	}
+	return strings.TrimSuffix(stem, v) + ext
+}
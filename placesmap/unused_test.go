@@ -0,0 +1,33 @@
+package placesmap
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnused(t *testing.T) {
+	tpl := newTemplate(
+		"page.html", "<@title@><@user.name@><@-require layout.html@><@-each items@><@label@><@-end@>",
+		"layout.html", "<@-with user card.html@><@-component button@><@footer@><@headline@>",
+		"card.html", "<@name@>",
+		"themes/dark/layout.html", "<@-require dark.html@>",
+		"dark.html", "<@color@>",
+		"page.b.html", "variant",
+		"components/button.html", "<@text@>",
+		"old.html", "<@-require older.html@>",
+		"older.html", "<@never@>",
+	)
+	if err := tpl.Alias("headline", "title"); err != nil {
+		t.Fatal(err)
+	}
+
+	report := tpl.Unused([]string{"page.html"}, []string{"title", "user", "items"})
+	if got, exp := strings.Join(report.Templates, " "), "old.html older.html"; got != exp {
+		t.Errorf("unexpected unused templates: %#v, expected: %#v", got, exp)
+	}
+	exp := []UnresolvedPlaceholder{{"dark.html", "color"}, {"layout.html", "footer"}}
+	if fmt.Sprint(report.Placeholders) != fmt.Sprint(exp) {
+		t.Errorf("unexpected placeholders: %v, expected: %v", report.Placeholders, exp)
+	}
+}
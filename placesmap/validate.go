@@ -0,0 +1,148 @@
+package placesmap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/metakeule/places"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ValidationPolicy decides what happens with a template that does not pass the validation
+type ValidationPolicy int
+
+const (
+	// SkipInvalid skips the template and records it in the LoadReport
+	SkipInvalid ValidationPolicy = iota
+
+	// FailOnInvalid makes the loading fail with an InvalidTemplateError
+	FailOnInvalid
+)
+
+// Validation configures the checks for the templates loaded by NewHTMLTemplateValidated
+type Validation struct {
+	// MaxSize is the maximum size of a template in bytes, 0 means no limit
+	MaxSize int
+
+	// MaxPlaceholders is the maximum number of placeholders of a template, 0 means no limit
+	MaxPlaceholders int
+
+	// RequireUTF8 rejects templates that are no valid UTF-8 or contain NUL bytes (i.e. binary files)
+	RequireUTF8 bool
+
+	// NonEmpty rejects empty templates
+	NonEmpty bool
+
+	// StrictNames rejects templates with placeholders at the position of tag or attribute names.
+	// Without it, the values of such placeholders are rendered only if they are valid attribute names.
+	// Directives that render attribute lists (-attrs, -aria and -nonce-attr) are allowed in any case.
+	StrictNames bool
+
+	// Policy is the policy for invalid templates
+	Policy ValidationPolicy
+
+	// PolicyFor, if set, returns the policy for the template with the given name
+	// and overrides Policy
+	PolicyFor func(name string) ValidationPolicy
+}
+
+func (v Validation) policy(name string) ValidationPolicy {
+	if v.PolicyFor != nil {
+		return v.PolicyFor(name)
+	}
+	return v.Policy
+}
+
+// check returns the reason why the given template is invalid or the empty string
+func (v Validation) check(tpl []byte) string {
+	switch {
+	case v.NonEmpty && len(tpl) == 0:
+		return "empty"
+	case v.MaxSize > 0 && len(tpl) > v.MaxSize:
+		return "larger than " + strconv.Itoa(v.MaxSize) + " bytes"
+	case v.MaxPlaceholders > 0 && len(places.NewTemplate(tpl).Placeholders()) > v.MaxPlaceholders:
+		return "more than " + strconv.Itoa(v.MaxPlaceholders) + " placeholders"
+	case v.RequireUTF8 && !utf8.Valid(tpl):
+		return "invalid UTF-8"
+	case v.RequireUTF8 && bytes.IndexByte(tpl, 0) != -1:
+		return "binary"
+	case v.StrictNames && hasNamePlaceholders(tpl):
+		return "placeholder at tag or attribute name position"
+	}
+	return ""
+}
+
+type InvalidTemplateError struct {
+	Name   string
+	Reason string
+}
+
+func (i InvalidTemplateError) Error() string {
+	return fmt.Sprintf("invalid template %#v: %s", i.Name, i.Reason)
+}
+
+// SkippedTemplate is a template that has not been loaded
+type SkippedTemplate struct {
+	Name   string
+	Reason string
+}
+
+// LoadReport lists the templates that have been loaded and skipped
+type LoadReport struct {
+	Loaded  []string          // sorted names of the loaded templates
+	Skipped []SkippedTemplate // skipped templates, sorted by name
+}
+
+// LoadReport returns the report of the loading of the templates
+func (h *HTMLTemplate) LoadReport() LoadReport {
+	h.RLock()
+	defer h.RUnlock()
+	return h.report
+}
+
+// NewHTMLTemplateValidated returns a HTMLTemplate with the templates of the given ReadSeekerMap
+// that pass the given validation, along with a report of what has been loaded and skipped.
+// Templates that can't be read are skipped. If an invalid template has the policy FailOnInvalid,
+// an InvalidTemplateError is returned.
+func NewHTMLTemplateValidated(rs *ReadSeekerMap, v Validation) (*HTMLTemplate, LoadReport, error) {
+	h := newHTMLTemplate(rs)
+	var report LoadReport
+
+	rs.mx.Lock()
+	names := make([]string, 0, len(rs.m))
+	for k := range rs.m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		rd := rs.m[k]
+		_, err := rd.Seek(0, 0)
+		var b []byte
+		if err == nil {
+			b, err = ioutil.ReadAll(rd)
+		}
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedTemplate{Name: k, Reason: err.Error()})
+			continue
+		}
+
+		if reason := v.check(b); reason != "" {
+			if v.policy(k) == FailOnInvalid {
+				rs.mx.Unlock()
+				return nil, report, InvalidTemplateError{Name: k, Reason: reason}
+			}
+			report.Skipped = append(report.Skipped, SkippedTemplate{Name: k, Reason: reason})
+			continue
+		}
+
+		h.add(k, b)
+		report.Loaded = append(report.Loaded, k)
+	}
+	rs.mx.Unlock()
+
+	h.report = report
+	return h, report, nil
+}
@@ -0,0 +1,49 @@
+package placesmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewHTMLTemplateValidated(t *testing.T) {
+	rs := NewReadSeekerMap()
+	rs.Add("ok.html", strings.NewReader("ok"))
+	rs.Add("empty.html", strings.NewReader(""))
+	rs.Add("image.html", strings.NewReader("\x89PNG\x00\x00"))
+	rs.Add("big.html", strings.NewReader(strings.Repeat("x", 20)))
+
+	v := Validation{MaxSize: 10, RequireUTF8: true, NonEmpty: true}
+	tpl, report, err := NewHTMLTemplateValidated(rs, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Loaded) != 1 || report.Loaded[0] != "ok.html" || !tpl.Has("ok.html") {
+		t.Errorf("unexpected loaded templates: %#v", report.Loaded)
+	}
+
+	exp := []SkippedTemplate{
+		{"big.html", "larger than 10 bytes"},
+		{"empty.html", "empty"},
+		{"image.html", "invalid UTF-8"},
+	}
+	if len(report.Skipped) != len(exp) {
+		t.Fatalf("unexpected skipped templates: %#v", report.Skipped)
+	}
+	for i, s := range exp {
+		if report.Skipped[i] != s {
+			t.Errorf("unexpected skipped template: %#v, expected: %#v", report.Skipped[i], s)
+		}
+	}
+
+	v.PolicyFor = func(name string) ValidationPolicy {
+		if name == "empty.html" {
+			return FailOnInvalid
+		}
+		return SkipInvalid
+	}
+
+	if _, _, err = NewHTMLTemplateValidated(rs, v); err != (InvalidTemplateError{"empty.html", "empty"}) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
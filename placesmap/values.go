@@ -0,0 +1,78 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FormValues maps the names of form fields to their submitted values, e.g. to render a form
+// with the submitted values again. Registered as "form", it is a NamespaceMapper:
+//
+//	form.email     the first value of the field email
+//	form.tags.1    the second value of the field tags
+//
+// Field names may contain dots. Like the values of all registered mappers, the values are HTML
+// escaped by placeholders without prefix. "-each form.tags" loops over the values of a field,
+// its elements map every key to the value, escaped like the values of every loop scope.
+type FormValues url.Values
+
+// Values returns the FormValues for the given values, e.g. of http.Request.PostForm
+func Values(v url.Values) FormValues {
+	return FormValues(v)
+}
+
+// Map returns the value for the given key, that is the name of a field, optionally followed
+// by a dot and the index of the value
+func (f FormValues) Map(key string) string {
+	mp, ok := f.Lookup(key)
+	if !ok {
+		return ""
+	}
+	return mp.Map(key)
+}
+
+// Lookup returns the mapper for the given field name or field name with index
+func (f FormValues) Lookup(name string) (places.Mapper, bool) {
+	if vals, has := f[name]; has {
+		return formField(vals), true
+	}
+	idx := strings.LastIndexByte(name, '.')
+	if idx == -1 {
+		return nil, false
+	}
+	vals, has := f[name[:idx]]
+	i, err := strconv.Atoi(name[idx+1:])
+	if !has || err != nil || i < 0 || i >= len(vals) {
+		return nil, false
+	}
+	return String(vals[i]), true
+}
+
+// Keys returns the sorted names of the fields, so that FormValues are Keyed
+func (f FormValues) Keys() []string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formField are the values of a field, mapping the first value
+type formField []string
+
+func (f formField) Map(string) string {
+	if len(f) == 0 {
+		return ""
+	}
+	return f[0]
+}
+
+func (f formField) Len() int { return len(f) }
+
+func (f formField) NMap(n int, _ string) places.Mapper {
+	return String(f[n])
+}
@@ -0,0 +1,29 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	form := Values(url.Values{
+		"email":        {"a<b>@example.com"},
+		"tags":         {"go", "<html>"},
+		"address.city": {"Berlin"},
+	})
+	tpl := newTemplate("form.html", `<input value="<@form.email@>"><@form.tags.1@>|<@form.address.city@>|<@form.tags.2@>|<@-each form.tags tag.html@>`,
+		"tag.html", "[<@x@>]")
+	got := tpl.NewMapper(map[string]places.Mapper{"form": form}).Map("-require form.html")
+	if exp := `<input value="a&lt;b&gt;@example.com">&lt;html&gt;|Berlin||[go][&lt;html&gt;]`; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	if got := form.Map("tags.0"); got != "go" {
+		t.Errorf("unexpected value: %#v", got)
+	}
+	if got := strings.Join(form.Keys(), " "); got != "address.city email tags" {
+		t.Errorf("unexpected keys: %#v", got)
+	}
+}
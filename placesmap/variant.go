@@ -0,0 +1,64 @@
+package placesmap
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VariantMapper selects variants of templates per request, e.g. for A/B testing.
+// Variants are loaded like any other template and follow the naming convention
+// name.variant.ext, e.g. welcome.b.html is the variant "b" of welcome.html.
+// If a VariantMapper is set via HTMLTemplateMapper.WithVariants, "-require welcome.html"
+// renders the variant assigned by Bucket instead of the original.
+// The VariantMapper itself maps a template name to the assigned variant.
+type VariantMapper struct {
+	// Bucket returns the variant that is assigned to the request for the given template.
+	// The empty string selects the original template.
+	Bucket func(name string) string
+
+	// Exposure, if set, is called with the variant that has been rendered for the template
+	// (the empty string for the original template)
+	Exposure func(name, variant string)
+}
+
+// variantName returns the name of the given variant of the template name
+func variantName(name, variant string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + variant + ext
+}
+
+// Map returns the variant that is assigned to the template with the given name
+func (v *VariantMapper) Map(name string) string {
+	if v.Bucket == nil {
+		return ""
+	}
+	return v.Bucket(name)
+}
+
+// Variants returns the sorted names of the loaded variants of the template with the given name,
+// i.e. "b" for welcome.b.html if name is welcome.html
+func (h *HTMLTemplate) Variants(name string) (variants []string) {
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "."
+	h.RLock()
+	for k := range h.rsm {
+		if k != name && strings.HasPrefix(k, prefix) && strings.HasSuffix(k, ext) {
+			v := strings.TrimSuffix(strings.TrimPrefix(k, prefix), ext)
+			if v != "" && !strings.Contains(v, ".") {
+				variants = append(variants, v)
+			}
+		}
+	}
+	h.RUnlock()
+	sort.Strings(variants)
+	return
+}
+
+// WithVariants returns a HTMLTemplateMapper with the same mappers that renders the
+// variants selected by v
+func (h *HTMLTemplateMapper) WithVariants(v *VariantMapper) *HTMLTemplateMapper {
+	c := h.copy()
+	c.variants = v
+	return c
+}
@@ -0,0 +1,30 @@
+package placesmap
+
+import "testing"
+
+func TestVariants(t *testing.T) {
+	tpl := newTemplate("page.html", "<@-require welcome.html@>", "welcome.html", "hello", "welcome.b.html", "hi")
+
+	if got := tpl.Variants("welcome.html"); len(got) != 1 || got[0] != "b" {
+		t.Errorf("unexpected variants: %#v", got)
+	}
+
+	var exposed []string
+	v := &VariantMapper{
+		Bucket:   func(name string) string { return "b" },
+		Exposure: func(name, variant string) { exposed = append(exposed, name+":"+variant) },
+	}
+
+	m := tpl.NewMapper(nil)
+	if got := m.Map("-require page.html"); got != "hello" {
+		t.Errorf("unexpected result without variants: %#v", got)
+	}
+
+	if got := m.WithVariants(v).Map("-require page.html"); got != "hi" {
+		t.Errorf("unexpected result with variants: %#v", got)
+	}
+
+	if len(exposed) != 2 || exposed[0] != "page.html:" || exposed[1] != "welcome.html:b" {
+		t.Errorf("unexpected exposures: %#v", exposed)
+	}
+}
@@ -0,0 +1,50 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+)
+
+// withScope is the current scope of "-with". It leaves directives to the template, the values
+// are escaped like the values of every loop scope (see RenderContext.Map).
+type withScope struct {
+	mp places.Mapper
+}
+
+func (w withScope) Map(key string) string {
+	if strings.HasPrefix(key, "-") {
+		return ""
+	}
+	if sm, is := w.mp.(SubMapper); is && strings.ContainsRune(key, '.') {
+		mp, ok := walk(sm, key)
+		if !ok {
+			return ""
+		}
+		return mp.Map(key)
+	}
+	return w.mp.Map(key)
+}
+
+// with handles "-with name then.html else otherwise.html"
+// then.html is rendered with the mapper registered for name as current scope, so that the names
+// within then.html are looked up within the mapper first, e.g. <@city@> for "-with user.address ..."
+// (see SubMapper). The values of the scope are escaped like the values of the registered mappers.
+// If there is no mapper for name, otherwise.html is rendered. The else part is optional.
+func (r *RenderContext) with(rest string) string {
+	name, args := nextArg(rest)
+	if strings.TrimSpace(args) == "" {
+		r.fail(MissingTemplateError("with"))
+		return ""
+	}
+	then, otherwise := splitElse(args)
+
+	mp, ok := r.mapper(name)
+	if !ok {
+		return r.require(otherwise, r)
+	}
+	if _, ok := r.lookup(then); !ok {
+		r.fail(TemplateNotFoundError(then))
+		return ""
+	}
+	return r.RequireWith(then, withScope{mp})
+}
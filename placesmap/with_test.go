@@ -0,0 +1,32 @@
+package placesmap
+
+import (
+	"github.com/metakeule/places"
+	"strings"
+	"testing"
+)
+
+func TestScopesAreEscaped(t *testing.T) {
+	pairs := func() *ReadSeekerMap {
+		rs := NewReadSeekerMap()
+		rs.Add("page.html", strings.NewReader("<@-each items row.html@>|<@-with user user.html@>|<@-each items raw.html@>"))
+		rs.Add("row.html", strings.NewReader("[<@name@>]"))
+		rs.Add("user.html", strings.NewReader("<@name@>"))
+		rs.Add("raw.html", strings.NewReader("<@-raw name@>"))
+		return rs
+	}
+	m := map[string]places.Mapper{
+		"items": list(names("a&b", "<i>")),
+		"user":  String("<Tom>"),
+	}
+
+	got := NewHTMLTemplate(pairs()).NewMapper(m).Map("-require page.html")
+	if exp := "[a&amp;b][&lt;i&gt;]|&lt;Tom&gt;|a&b<i>"; got != exp {
+		t.Errorf("unexpected result: %#v, expected: %#v", got, exp)
+	}
+
+	got = NewHTMLTemplate(pairs(), WithAutoescape(AutoescapeOff)).NewMapper(m).Map("-require page.html")
+	if exp := "[a&b][<i>]|<Tom>|a&b<i>"; got != exp {
+		t.Errorf("unexpected result without autoescape: %#v, expected: %#v", got, exp)
+	}
+}
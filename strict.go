@@ -0,0 +1,70 @@
+package places
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ParseError is returned by NewTemplateStrict for invalid templates.
+// Line and Column start with 1, the column counts runes.
+type ParseError struct {
+	Line   int
+	Column int
+	Reason string
+}
+
+func (p ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", p.Line, p.Column, p.Reason)
+}
+
+// position returns the line and column of the given offset within the template
+func position(template []byte, offset int) (line, column int) {
+	before := template[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	if idx := bytes.LastIndexByte(before, '\n'); idx != -1 {
+		before = before[idx+1:]
+	}
+	return line, utf8.RuneCount(before) + 1
+}
+
+// NewTemplateStrict is like NewTemplate but returns a ParseError for templates with unterminated
+// placeholders, placeholders that contain the start delimiter or control characters (e.g. line breaks)
+// and templates that are no valid UTF-8.
+func NewTemplateStrict(t []byte) (*Template, error) {
+	fail := func(offset int, reason string) error {
+		line, col := position(t, offset)
+		return ParseError{Line: line, Column: col, Reason: reason}
+	}
+
+	if !utf8.Valid(t) {
+		for i := 0; i < len(t); {
+			r, size := utf8.DecodeRune(t[i:])
+			if r == utf8.RuneError && size == 1 {
+				return nil, fail(i, "invalid UTF-8")
+			}
+			i += size
+		}
+	}
+
+	places := Find(t)
+	last := 0 // Find stops at the first unterminated placeholder
+	for i := 0; i < len(places); i += 2 {
+		start, end := places[i], places[i+1]
+		name := t[start+2 : end]
+		if idx := bytes.Index(name, startDel); idx != -1 {
+			return nil, fail(start+2+idx, "start delimiter within placeholder")
+		}
+		for j, c := range name {
+			if c < ' ' || c == 0x7F {
+				return nil, fail(start+2+j, fmt.Sprintf("control character %#x within placeholder", c))
+			}
+		}
+		last = end + 2
+	}
+	if idx := indexStart(t, last); idx != -1 {
+		return nil, fail(idx, "unterminated placeholder")
+	}
+
+	return newTemplate(t, places), nil
+}